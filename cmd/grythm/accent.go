@@ -0,0 +1,49 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"grythm/geom"
+)
+
+// accentSteps are the accent patterns V cycles through: none, every second,
+// third and fourth line, 4/4 with a half accent on beat three and 6/8.
+var accentSteps = [][]float64{
+	nil,
+	geom.AccentEvery(2),
+	geom.AccentEvery(3),
+	geom.AccentEvery(4),
+	{1, 0, 0.5, 0},
+	{1, 0, 0, 0.5, 0, 0},
+}
+
+// nextAccent returns the step after pattern a; custom patterns go back to none.
+func nextAccent(a []float64) []float64 {
+	for i, s := range accentSteps {
+		if slices.Equal(s, a) {
+			return accentSteps[(i+1)%len(accentSteps)]
+		}
+	}
+	return nil
+}
+
+// accentLabel draws an accent pattern for the HUD: > for strong lines, - for
+// half accents and . for weak ones.
+func accentLabel(a []float64) string {
+	if len(a) == 0 {
+		return "none"
+	}
+	var b strings.Builder
+	for _, l := range a {
+		switch {
+		case l >= 0.75:
+			b.WriteByte('>')
+		case l > 0:
+			b.WriteByte('-')
+		default:
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,31 @@
+package main
+
+// Width animation (see geom.Animator). An LFO follows the beat: the clock's
+// position in tempo mode, otherwise counted at its BPM from the start, like
+// quantization. A decay starts over whenever the grid triggers, audible or
+// not, as its flashes do.
+
+// noteHit remembers that grid gi triggered at game time t.
+func (g *Game) noteHit(gi int, t float64) {
+	for len(g.lastHits) < len(g.Grids) {
+		g.lastHits = append(g.lastHits, -1)
+	}
+	g.lastHits[gi] = t
+}
+
+// swell returns the level of grid gi's width animation now.
+func (g *Game) swell(gi int) float64 {
+	a := g.Grids[gi].Anim
+	if a == nil {
+		return 0
+	}
+	since := -1.0
+	if gi < len(g.lastHits) && g.lastHits[gi] >= 0 {
+		since = max(0, g.Time-g.lastHits[gi])
+	}
+	beats := g.Time * g.Clock.BPM / 60
+	if g.TempoMode {
+		beats = g.Clock.Beats
+	}
+	return a.Level(beats, since)
+}
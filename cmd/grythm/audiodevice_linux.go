@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// selectAudioDevice makes the audio output open the ALSA card name (an index
+// or a card name like "USB"). The default ALSA device reads the card from the
+// environment when the output opens; where ALSA is routed through a sound
+// server the server picks the device instead.
+func selectAudioDevice(name string) error {
+	if err := os.Setenv("ALSA_CARD", name); err != nil {
+		return fmt.Errorf("audio device: %w", err)
+	}
+	return nil
+}
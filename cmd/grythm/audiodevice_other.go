@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// selectAudioDevice reports that the output device can't be chosen: the
+// audio backend always plays on the system's default device here.
+func selectAudioDevice(name string) error {
+	return fmt.Errorf("audio device %q: choosing the output device is only supported on Linux", name)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Autosave and crash recovery. Every -autosave seconds the scene, with the
+// animated state of its grids and the view (a snapshot sidecar, see
+// snapshot.go), is written to a new file in -autosave-dir, and the oldest
+// beyond -autosave-keep are removed, so a few rolling backups are always at
+// hand. Nothing is written while the scene is as it was at the last autosave.
+// Each session marks itself as running with a file named after its process id
+// in the directory, and a clean exit removes it. Finding the marker of a
+// process that is no longer running at startup means that session crashed or
+// was killed (another instance that is still running is left alone): the
+// newest autosave is then offered, Enter restoring it, Tab
+// stepping to an older one and Esc keeping the scene that was loaded. The
+// backups stay on disk either way and load like any scene with -scene.
+
+const (
+	autosavePrefix = "autosave-"
+	autosaveStamp  = "20060102-150405" // time format of the file names, sorting oldest first
+	autosaveMarker = "running-"        // prefix of the markers, followed by the session's process id
+)
+
+// autosaver writes the rolling backups and holds the recovery offer.
+type autosaver struct {
+	dir      string
+	marker   string   // this session's marker
+	interval float64  // seconds between autosaves; 0 is off
+	keep     int      // backups kept
+	wait     float64  // seconds since the last autosave
+	last     []byte   // scene last written, to skip unchanged ones
+	offer    []string // backups offered after an unclean exit, newest first
+	pick     int      // the one of them Enter restores
+}
+
+// defaultAutosaveDir is where backups go without -autosave-dir.
+func defaultAutosaveDir() string {
+	return filepath.Join(os.TempDir(), "grythm-autosave")
+}
+
+// startAutosave turns autosaving on and marks the session as running,
+// offering the latest backup if the last one didn't end cleanly.
+func (g *Game) startAutosave(dir string, interval float64, keep int) error {
+	if !hasFileSystem || interval <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("autosave: %w", err)
+	}
+	a := &g.autosave
+	*a = autosaver{dir: dir, interval: interval, keep: keep}
+	crashed, err := clearCrashed(dir)
+	if err != nil {
+		return fmt.Errorf("autosave: %w", err)
+	}
+	if crashed {
+		saves, err := listAutosaves(dir)
+		if err != nil {
+			return fmt.Errorf("autosave: %w", err)
+		}
+		slices.Reverse(saves)
+		a.offer = saves
+	}
+	a.marker = filepath.Join(dir, autosaveMarker+strconv.Itoa(os.Getpid()))
+	if err := os.WriteFile(a.marker, nil, 0o644); err != nil {
+		return fmt.Errorf("autosave: %w", err)
+	}
+	return nil
+}
+
+// clearCrashed removes the markers in dir of sessions whose process is gone,
+// reporting whether there were any. A marker with this process's id is from
+// an earlier process the id was given again, and is gone as well.
+func clearCrashed(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	crashed := false
+	for _, e := range entries {
+		id, ok := strings.CutPrefix(e.Name(), autosaveMarker)
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(id)
+		if err == nil && pid != os.Getpid() && processAlive(pid) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+		crashed = true
+	}
+	return crashed, nil
+}
+
+// stopAutosave marks the session as ended cleanly.
+func (g *Game) stopAutosave() {
+	a := &g.autosave
+	if a.interval <= 0 {
+		return
+	}
+	if err := os.Remove(a.marker); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("autosave: %v", err)
+	}
+}
+
+// listAutosaves returns the backups in dir, oldest first.
+func listAutosaves(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		if n := e.Name(); strings.HasPrefix(n, autosavePrefix) && filepath.Ext(n) == ".json" {
+			out = append(out, filepath.Join(dir, n))
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+// updateAutosave writes a backup when one is due. While a backup is offered
+// none is written, so the one that would restore the crashed session is
+// never rolled away.
+func (g *Game) updateAutosave(dt float64) {
+	a := &g.autosave
+	if a.interval <= 0 || len(a.offer) > 0 {
+		return
+	}
+	if a.wait += dt; a.wait < a.interval {
+		return
+	}
+	a.wait = 0
+	if err := g.writeAutosave(); err != nil {
+		log.Printf("autosave: %v", err)
+	}
+}
+
+// writeAutosave writes the scene to a new backup unless it is unchanged, and
+// removes the backups beyond the number kept.
+func (g *Game) writeAutosave() error {
+	a := &g.autosave
+	sn := g.snapshot()
+	scene, err := json.Marshal(sn.Scene)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(scene, a.last) {
+		return nil
+	}
+	data, err := json.MarshalIndent(sn, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Written in full before it takes its name, so a crash mid-write leaves
+	// no broken backup
+	path := filepath.Join(a.dir, autosavePrefix+time.Now().Format(autosaveStamp)+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	a.last = scene
+	saves, err := listAutosaves(a.dir)
+	if err != nil {
+		return err
+	}
+	for len(saves) > a.keep {
+		if err := os.Remove(saves[0]); err != nil {
+			return err
+		}
+		saves = saves[1:]
+	}
+	return nil
+}
+
+// updateRecovery handles the keys of the recovery offer. It returns true
+// while one is open, in which case the keyboard belongs to it.
+func (g *Game) updateRecovery() bool {
+	a := &g.autosave
+	if len(a.offer) == 0 {
+		return false
+	}
+	switch {
+	case g.in.KeyJustPressed(ebiten.KeyTab):
+		a.pick = (a.pick + 1) % len(a.offer)
+	case g.in.KeyJustPressed(ebiten.KeyEscape):
+		a.offer, a.pick = nil, 0
+	case g.in.KeyJustPressed(ebiten.KeyEnter) || g.in.KeyJustPressed(ebiten.KeyNumpadEnter):
+		path := a.offer[a.pick]
+		if err := g.restoreAutosave(path); err != nil {
+			log.Printf("restore %s: %v", path, err)
+			// Another backup may still be good
+			a.offer = slices.Delete(a.offer, a.pick, a.pick+1)
+			a.pick = 0
+			break
+		}
+		a.offer, a.pick = nil, 0
+	}
+	return true
+}
+
+// restoreAutosave replaces the scene with the backup at path.
+func (g *Game) restoreAutosave(path string) error {
+	sn, err := readScene(path)
+	if err != nil {
+		return err
+	}
+	if err := g.ApplyScene(sn.Scene); err != nil {
+		return err
+	}
+	g.applySnapshot(sn)
+	return nil
+}
+
+// recoveryLabel describes the recovery offer for the HUD; "" when there is none.
+func (g *Game) recoveryLabel() string {
+	a := &g.autosave
+	if len(a.offer) == 0 {
+		return ""
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(a.offer[a.pick]), autosavePrefix), ".json")
+	when := name
+	if t, err := time.ParseInLocation(autosaveStamp, name, time.Local); err == nil {
+		when = t.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf("The last session did not end cleanly. Restore its autosave from %s (%d of %d)?  Enter: restore  Tab: older  Esc: keep this scene", when, a.pick+1, len(a.offer))
+}
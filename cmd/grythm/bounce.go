@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Bounce writes the mixer's output to a 16-bit stereo WAV file. It taps the
+// stream the audio player reads, so the file holds exactly what was heard,
+// and stops by itself after the chosen duration. Writing happens on a
+// background goroutine to keep file I/O off the audio thread: the tap copies
+// into buffers set aside at the start and hands them over without waiting,
+// so a slow disk never holds up the audio. When the writer falls so far
+// behind that no buffer is free, the audio is dropped and counted instead.
+type Bounce struct {
+	out        string
+	sampleRate int
+
+	mu      sync.Mutex
+	left    int // bytes still to capture
+	closed  bool
+	dropped int         // bytes that found no free buffer
+	chunks  chan []byte // filled buffers, in order, for the writer
+	free    chan []byte // buffers the writer is done with
+	done    chan error
+}
+
+const (
+	wavHeaderSize = 44 // size of the RIFF/fmt/data header written before the samples
+
+	bounceBuffers    = 64       // buffers set aside for a bounce
+	bounceBufferSize = 16 << 10 // bytes in each of them, a tenth of a second at 44.1kHz
+)
+
+// StartBounce begins capturing seconds of audio into a new WAV file in dir.
+func StartBounce(dir string, sampleRate int, seconds float64) (*Bounce, error) {
+	if !hasFileSystem {
+		return nil, errNoFileSystem
+	}
+	if seconds <= 0 {
+		return nil, fmt.Errorf("bounce length %gs must be positive", seconds)
+	}
+	stamp := time.Now().Format("20060102-150405")
+	b := &Bounce{
+		out:        filepath.Join(dir, "grythm-"+stamp+".wav"),
+		sampleRate: sampleRate,
+		left:       int(seconds*float64(sampleRate)) * 4,
+		chunks:     make(chan []byte, bounceBuffers),
+		free:       make(chan []byte, bounceBuffers),
+		done:       make(chan error, 1),
+	}
+	for range bounceBuffers {
+		b.free <- make([]byte, 0, bounceBufferSize)
+	}
+	f, err := os.Create(b.out)
+	if err != nil {
+		return nil, err
+	}
+	go b.write(f)
+	return b, nil
+}
+
+// Write implements io.Writer for the mixer tap. It copies p (up to the
+// remaining duration) into free buffers, neither blocking nor allocating, and
+// never fails.
+func (b *Bounce) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return len(p), nil
+	}
+	n := max(0, min(len(p), b.left))
+	for rest := p[:n]; len(rest) > 0; {
+		var buf []byte
+		select {
+		case buf = <-b.free:
+		default:
+			// The writer is behind; rather lose audio than hold up the output
+			b.dropped += len(rest)
+			rest = nil
+			continue
+		}
+		k := min(len(rest), cap(buf))
+		// Never blocks: there are no more buffers than room in chunks
+		b.chunks <- append(buf[:0], rest[:k]...)
+		rest = rest[k:]
+	}
+	b.left -= n
+	if b.left <= 0 {
+		b.closed = true
+		close(b.chunks)
+	}
+	return len(p), nil
+}
+
+// Finished reports whether the requested duration has been captured.
+func (b *Bounce) Finished() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// Elapsed returns the captured duration in seconds.
+func (b *Bounce) Elapsed(total float64) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return total - float64(b.left)/4/float64(b.sampleRate)
+}
+
+// Stop ends the capture (early, if the duration was not reached yet), waits
+// for the file to be finalized and returns its path.
+func (b *Bounce) Stop() (string, error) {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.chunks)
+	}
+	b.mu.Unlock()
+	err := <-b.done
+	if b.dropped > 0 {
+		dropped := fmt.Errorf("%.2fs of audio dropped, the disk could not keep up", float64(b.dropped)/4/float64(b.sampleRate))
+		if err == nil {
+			log.Printf("bounce %s: %v", b.out, dropped)
+		} else {
+			err = errors.Join(err, dropped)
+		}
+	}
+	return b.out, err
+}
+
+func (b *Bounce) write(f *os.File) {
+	w := bufio.NewWriter(f)
+	// Sizes are unknown until the end; the header is rewritten when done
+	err := writeWAVHeader(w, b.sampleRate, 0)
+	size := 0
+	for c := range b.chunks {
+		if err == nil {
+			_, err = w.Write(c)
+		}
+		size += len(c)
+		b.free <- c
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		err = writeWAVHeader(f, b.sampleRate, size)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	b.done <- err
+}
+
+// writeWAVHeader writes a canonical PCM WAV header for 16-bit stereo with
+// dataSize bytes of samples.
+func writeWAVHeader(w io.Writer, sampleRate, dataSize int) error {
+	const channels, bits = 2, 16
+	h := make([]byte, 0, wavHeaderSize)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(wavHeaderSize-8+dataSize))
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16) // fmt chunk size
+	h = binary.LittleEndian.AppendUint16(h, 1)  // PCM
+	h = binary.LittleEndian.AppendUint16(h, channels)
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(sampleRate*channels*bits/8))
+	h = binary.LittleEndian.AppendUint16(h, channels*bits/8)
+	h = binary.LittleEndian.AppendUint16(h, bits)
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(dataSize))
+	_, err := w.Write(h)
+	return err
+}
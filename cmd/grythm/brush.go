@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/geom"
+)
+
+// Point brushes place many points with one drag: a line of evenly spaced
+// points from press to release, a circle around the press point, a random
+// scatter or a regular fill of the dragged rectangle. The grid tool draws a
+// grid family instead: the drag goes from one line to the next, setting
+// their direction and spacing (see geom.FamilyFromSegment). Q cycles the
+// tools (or click one in the palette), Shift+Q types the number of points or
+// the fill spacing. A brush stroke is a single undo step; a click without
+// dragging places one point as usual.
+
+// brushTool is the shape a press on empty space places points in.
+type brushTool int
+
+const (
+	brushPoint brushTool = iota
+	brushLine
+	brushCircle
+	brushScatter
+	brushFill
+	brushGrid
+	brushToolCount
+)
+
+var brushToolNames = []string{"point", "line", "circle", "scatter", "fill", "grid"}
+
+func (t brushTool) String() string {
+	if t < 0 || t >= brushToolCount {
+		return "unknown"
+	}
+	return brushToolNames[t]
+}
+
+// brushGridAlpha is the opacity the grid tool previews its family at.
+const brushGridAlpha = 0.4
+
+// maxBrushPoints bounds a single stroke so a wide fill with a small spacing
+// can't flood the scene.
+const maxBrushPoints = 400
+
+// brushState is the active tool and a stroke in progress.
+type brushState struct {
+	tool    brushTool
+	count   int     // points of a line, circle or scatter
+	spacing float64 // distance between the points of a fill (world pixels)
+
+	active   bool      // a stroke is being dragged
+	moved    bool      // the stroke has left the press position
+	from, to geom.Vec2 // world positions of the press and the cursor
+	seed     int64     // scatter positions, fixed per stroke so the preview is what gets placed
+}
+
+// defaultBrush places lines, circles and scatters of 8 points and fills at the default grid spacing.
+var defaultBrush = brushState{count: 8, spacing: 60}
+
+// startBrush begins a stroke at world position p.
+func (g *Game) startBrush(p geom.Vec2) {
+	b := &g.brush
+	b.active, b.moved = true, false
+	b.from, b.to = p, p
+	b.seed = g.rng.Int63()
+}
+
+// updateBrush follows the cursor during a stroke and places the points on
+// release; a stroke interrupted by a pinch is dropped.
+func (g *Game) updateBrush(mouse geom.Vec2, ptr pointer, pinching bool) {
+	b := &g.brush
+	if !b.active {
+		return
+	}
+	b.to = mouse
+	if !b.moved && mouse.Sub(b.from).Len() > 3.0/g.cam.Zoom {
+		b.moved = true
+	}
+	if pinching {
+		b.active = false
+		return
+	}
+	if !ptr.JustReleased {
+		return
+	}
+	b.active = false
+	if b.tool == brushGrid && b.moved {
+		if gf, ok := g.segmentGrid(); ok {
+			g.exec(addGridsCmd{at: len(g.Grids), sel: g.selGrid, grids: []geom.GridFamily{gf}})
+		}
+		return
+	}
+	var batch batchCmd
+	for _, p := range b.positions() {
+		c := g.placeCmd(p)
+		c.Do(g)
+		batch = append(batch, c)
+	}
+	if len(batch) > 0 {
+		g.record(batch)
+	}
+}
+
+// positions returns where the stroke places its points.
+func (b *brushState) positions() []geom.Vec2 {
+	if !b.moved {
+		return []geom.Vec2{b.from}
+	}
+	n := b.count
+	if n < 1 {
+		n = 1
+	}
+	var out []geom.Vec2
+	switch b.tool {
+	case brushLine:
+		if n == 1 {
+			return []geom.Vec2{b.from}
+		}
+		d := b.to.Sub(b.from)
+		for k := 0; k < n; k++ {
+			out = append(out, b.from.Add(d.Mul(float64(k)/float64(n-1))))
+		}
+	case brushCircle:
+		// The first point sits under the cursor
+		d := b.to.Sub(b.from)
+		r, a0 := d.Len(), math.Atan2(d.Y, d.X)
+		for k := 0; k < n; k++ {
+			a := a0 + 2*math.Pi*float64(k)/float64(n)
+			out = append(out, b.from.Add(geom.Vec2{X: math.Cos(a), Y: math.Sin(a)}.Mul(r)))
+		}
+	case brushScatter:
+		lo, hi := b.rect()
+		rng := rand.New(rand.NewSource(b.seed))
+		for k := 0; k < n; k++ {
+			out = append(out, geom.Vec2{X: lo.X + rng.Float64()*(hi.X-lo.X), Y: lo.Y + rng.Float64()*(hi.Y-lo.Y)})
+		}
+	case brushFill:
+		lo, hi := b.rect()
+		sp := b.spacing
+		if sp <= 0 {
+			sp = defaultBrush.spacing
+		}
+		for y := lo.Y; y <= hi.Y && len(out) < maxBrushPoints; y += sp {
+			for x := lo.X; x <= hi.X && len(out) < maxBrushPoints; x += sp {
+				out = append(out, geom.Vec2{X: x, Y: y})
+			}
+		}
+	}
+	return out
+}
+
+// segmentGrid returns the family the grid tool's stroke draws, colored as the
+// next family, reporting false while the stroke is too short.
+func (g *Game) segmentGrid() (geom.GridFamily, bool) {
+	gf, ok := geom.FamilyFromSegment(g.brush.from, g.brush.to, g.Center())
+	gf.Color = g.theme.GridColor(len(g.Grids))
+	return gf, ok
+}
+
+// rect returns the corners of the dragged rectangle.
+func (b *brushState) rect() (lo, hi geom.Vec2) {
+	lo = geom.Vec2{X: math.Min(b.from.X, b.to.X), Y: math.Min(b.from.Y, b.to.Y)}
+	hi = geom.Vec2{X: math.Max(b.from.X, b.to.X), Y: math.Max(b.from.Y, b.to.Y)}
+	return lo, hi
+}
+
+// label describes the tool for the HUD.
+func (b *brushState) label() string {
+	switch b.tool {
+	case brushPoint, brushGrid:
+		return b.tool.String()
+	case brushFill:
+		return fmt.Sprintf("%s every %.0f px", b.tool, b.spacing)
+	}
+	return fmt.Sprintf("%s of %d", b.tool, b.count)
+}
+
+// Palette layout: one row per tool in the top right corner, below the
+// recording indicator.
+const (
+	paletteTop  = 32
+	paletteRowH = 18
+	paletteW    = 64
+)
+
+// paletteRow returns the screen rectangle of tool t in the palette.
+func (g *Game) paletteRow(t brushTool) (x, y, w, h float64) {
+	return float64(g.W) - paletteW - 8, paletteTop + float64(t)*paletteRowH, paletteW, paletteRowH - 2
+}
+
+// pickBrush selects the tool under screen position p, reporting whether the
+// palette was hit.
+func (g *Game) pickBrush(p geom.Vec2) bool {
+	for t := brushTool(0); t < brushToolCount; t++ {
+		x, y, w, h := g.paletteRow(t)
+		if p.X >= x && p.X < x+w && p.Y >= y && p.Y < y+h {
+			g.brush.tool = t
+			return true
+		}
+	}
+	return false
+}
+
+// drawPalette draws the tool palette, highlighting the active tool.
+func (g *Game) drawPalette(dst *ebiten.Image) {
+	for t := brushTool(0); t < brushToolCount; t++ {
+		x, y, w, h := g.paletteRow(t)
+		bg := g.theme.Panel
+		if t == g.brush.tool {
+			bg = g.theme.PanelOn
+		}
+		vector.DrawFilledRect(dst, float32(x), float32(y), float32(w), float32(h), bg, false)
+		g.printAt(dst, t.String(), int(x)+4, int(y))
+	}
+}
+
+// drawBrush previews the points of a stroke in progress.
+func (g *Game) drawBrush(dst *ebiten.Image) {
+	b := &g.brush
+	if !b.active || !b.moved {
+		return
+	}
+	guide := g.theme.Guide
+	switch b.tool {
+	case brushScatter, brushFill:
+		lo, hi := b.rect()
+		a, c := g.cam.ToScreen(lo), g.cam.ToScreen(hi)
+		vector.StrokeRect(dst, float32(a.X), float32(a.Y), float32(c.X-a.X), float32(c.Y-a.Y), 1, guide, false)
+	case brushCircle:
+		c := g.cam.ToScreen(b.from)
+		r := b.to.Sub(b.from).Len() * g.cam.Scale()
+		vector.StrokeCircle(dst, float32(c.X), float32(c.Y), float32(r), 1, guide, true)
+	case brushGrid:
+		// The family faintly, with the segment that spans one spacing
+		a, c := g.cam.ToScreen(b.from), g.cam.ToScreen(b.to)
+		vector.StrokeLine(dst, float32(a.X), float32(a.Y), float32(c.X), float32(c.Y), 1, guide, true)
+		if gf, ok := g.segmentGrid(); ok {
+			gf.Alpha = brushGridAlpha
+			gf.Draw(dst, &g.cam, g.Center())
+		}
+		return
+	}
+	for _, p := range b.positions() {
+		drawCross(dst, g.cam.ToScreen(p), 5, g.theme.Guide)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"grythm/engine"
+	"grythm/synth"
+)
+
+// Consumers of the engine's events (see engine/bus.go). The sound, the
+// visual feedback, the timeline, the MIDI capture and the metronome are
+// subscribed when the game is created;
+// MIDI and OSC output and a script subscribe themselves when they are opened.
+
+// subscribe connects the game's own consumers to the engine's events.
+func (g *Game) subscribe() {
+	g.Bus.OnTrigger(g.playTrigger)
+	g.Bus.OnTrigger(g.showTrigger)
+	g.Bus.OnTrigger(g.logTrigger)
+	g.Bus.OnTrigger(g.captureTrigger)
+	g.Bus.OnIntersection(g.playIntersection)
+	g.Bus.OnIntersection(g.showIntersection)
+	g.Bus.OnIntersection(g.captureIntersection)
+	g.Bus.OnBeat(g.metronomeBeat)
+}
+
+// triggerNotes returns the MIDI notes a trigger plays and their length: the
+// grid's drum, the point's chord on a synth voice, or else its single note.
+func (g *Game) triggerNotes(t engine.TriggerEvent) ([]int, float64) {
+	gf, p := &g.Grids[t.Grid], g.Points[t.Point]
+	length := synth.ResolveNoteLength(gf.Length, p.Length)
+	switch {
+	case g.sample(p.Sample) != nil || g.sample(gf.Sample) != nil:
+		return []int{t.Note}, length
+	case gf.Drum != synth.DrumNone:
+		return []int{gf.Drum.MIDINote()}, length
+	}
+	return synth.ChordNotes(t.Note, p.Chord), length
+}
+
+// playTrigger sounds a trigger.
+func (g *Game) playTrigger(t engine.TriggerEvent) {
+	if !t.Audible {
+		return
+	}
+	if t.Gap {
+		g.playGap(t)
+		return
+	}
+	gf := &g.Grids[t.Grid]
+	p := g.Points[t.Point]
+	// A sample on the point wins over one on the grid; without either the synth plays
+	sends := synth.ResolveSends(gf.Sends, g.sends)
+	notes, length := g.triggerNotes(t)
+	gate := synth.NoteGate{
+		Owner: t.Point + 1,
+		Cut:   synth.ResolveRetrigger(gf.Retrigger, p.Retrigger) == synth.RetriggerCut,
+		At:    g.soundFrame(t.Time, t.At),
+	}
+	if smp := g.sample(p.Sample); smp != nil {
+		gate.Frames = int(length * float64(g.blipSampleRate))
+		g.mixer.Play(smp, t.Velocity, sends, gate)
+	} else if smp := g.sample(gf.Sample); smp != nil {
+		gate.Frames = int(length * float64(g.blipSampleRate))
+		g.mixer.Play(smp, t.Velocity, sends, gate)
+	} else if gf.Drum != synth.DrumNone {
+		// Drums are unpitched, so all points share one rendering
+		g.playBlip(synth.Voice{Drum: gf.Drum, Length: length, Filter: resolveFilter(gf.Filter)}, t.Velocity, sends, gate)
+	} else {
+		// Synth notes are rendered at their length, so the envelope releases
+		// naturally; a chord plays one voice per note, and only the root cuts
+		// the point's old notes so it doesn't cut its own
+		gain := t.Velocity * synth.ChordGain(len(notes))
+		for i, n := range notes {
+			g.playBlip(synth.Voice{
+				Freq:   synth.ChordFreq(n, i),
+				Wave:   synth.ResolveWave(gf.Wave, p.Wave),
+				Env:    synth.ResolveEnvelope(gf.Env, p.Env),
+				Length: length,
+				Filter: resolveFilter(gf.Filter),
+				Glide:  resolveGlide(gf.Glide),
+				FM:     synth.ResolveFM(gf.FM, p.FM),
+			}, gain, sends, gate)
+			gate.Cut = false
+		}
+	}
+}
+
+// showTrigger flashes the line that hit the point and sends a ripple along
+// it, and bursts particles from a point that sounds.
+func (g *Game) showTrigger(t engine.TriggerEvent) {
+	// Silenced groups keep their visual cue so the pattern stays readable
+	g.flashLine(t.Grid, t.Pos)
+	g.rippleLine(t.Grid, t.Pos)
+	g.noteHit(t.Grid, t.Time)
+	g.lightCell(t.Grid, t.Pos, t.Velocity)
+	if t.Audible {
+		g.particles.Burst(t.Pos, g.Grids[t.Grid].Color, 4+int(12*t.Velocity))
+	}
+}
+
+// logTrigger adds a trigger that sounds to the timeline, at the time it sounds.
+func (g *Game) logTrigger(t engine.TriggerEvent) {
+	if t.Audible {
+		g.events.Add(LoggedTrigger{Time: t.At, Grid: t.Grid, Point: t.Point})
+	}
+}
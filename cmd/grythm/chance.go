@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"grythm/engine"
+)
+
+// chanceSteps are the values C cycles through; 0 (always) comes first.
+var chanceSteps = []float64{0, 0.75, 0.5, 0.25}
+
+// nextChance returns the step after c.
+func nextChance(c float64) float64 {
+	for i, s := range chanceSteps {
+		if s == c {
+			return chanceSteps[(i+1)%len(chanceSteps)]
+		}
+	}
+	return 0
+}
+
+// chanceLabel formats a chance for the HUD.
+func chanceLabel(c float64) string {
+	return fmt.Sprintf("%.0f%%", engine.EffectiveChance(c)*100)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hajimehoshi/ebiten/v2"
+	"grythm/engine"
+	"grythm/synth"
+)
+
+// Config holds the startup options. Defaults are overridden by the config file
+// (~/.config/grythm/config.toml, or -config), which is overridden by flags.
+// The window keys are written back on quit (see window.go). File keys are
+// the flag names, e.g.
+//
+//	width = 1280
+//	fullscreen = true
+//	midi-port = "/dev/snd/midiC1D0"
+type Config struct {
+	Width          int    `toml:"width"`
+	Height         int    `toml:"height"`
+	Fullscreen     bool   `toml:"fullscreen"`
+	WindowPosition string `toml:"window-position"` // "x,y" on the monitor; "" lets the system place the window
+	Monitor        string `toml:"monitor"`
+	VSync          bool   `toml:"vsync"`
+	TPS            int    `toml:"tps"`
+	TickRate       int    `toml:"tick-rate"`
+
+	SampleRate  int     `toml:"sample-rate"`
+	BufferMS    int     `toml:"audio-buffer-ms"`
+	AudioDevice string  `toml:"audio-device"`
+	MaxVoices   int     `toml:"max-voices"`
+	Volume      float64 `toml:"volume"`
+	Limiter     bool    `toml:"limiter"`
+
+	Scene         string  `toml:"scene"`
+	WatchScene    bool    `toml:"watch-scene"`
+	Setlist       string  `toml:"setlist"`
+	Morph         string  `toml:"morph"`
+	Crossfade     float64 `toml:"crossfade"`
+	PresetDir     string  `toml:"preset-dir"`
+	RecordDir     string  `toml:"record-dir"`
+	RecordFormat  string  `toml:"record-format"`
+	BounceSeconds float64 `toml:"bounce-seconds"`
+	Metronome     bool    `toml:"metronome"`
+	CountIn       int     `toml:"count-in"`
+	RescalePoints bool    `toml:"rescale-points"`
+	PointLife     string  `toml:"point-life"`
+	Symmetry      string  `toml:"symmetry"`
+	Scale         string  `toml:"scale"`
+	Intersections string  `toml:"intersections"`
+	Theme         string  `toml:"theme"`
+	Tiling        bool    `toml:"tiling"`
+	Bindings      string  `toml:"bindings"`
+	Script        string  `toml:"script"`
+	Seed          int64   `toml:"seed"`
+	RecordSession string  `toml:"record-session"`
+	Replay        string  `toml:"replay"`
+	Autosave      float64 `toml:"autosave"`
+	AutosaveDir   string  `toml:"autosave-dir"`
+	AutosaveKeep  int     `toml:"autosave-keep"`
+
+	MIDIPort    string `toml:"midi-port"`
+	MIDIChannel int    `toml:"midi-channel"`
+	MIDIClock   bool   `toml:"midi-clock"`
+	OSCHost     string `toml:"osc-host"`
+	OSCPort     int    `toml:"osc-port"`
+
+	MIDIIn          string `toml:"midi-in"`
+	MIDICCSpeed     int    `toml:"midi-cc-speed"`
+	MIDICCDirection int    `toml:"midi-cc-direction"`
+	MIDICCSpacing   int    `toml:"midi-cc-spacing"`
+	MIDICCDash      int    `toml:"midi-cc-dash"`
+	MIDICCMorph     int    `toml:"midi-cc-morph"`
+
+	Remote string `toml:"remote"`
+
+	DelaySend     float64 `toml:"delay-send"`
+	ReverbSend    float64 `toml:"reverb-send"`
+	DelayTime     float64 `toml:"delay-time"`
+	DelayFeedback float64 `toml:"delay-feedback"`
+}
+
+// DefaultConfig returns the built-in defaults.
+func DefaultConfig() Config {
+	return Config{
+		Width:         960,
+		Height:        640,
+		VSync:         true,
+		TPS:           ebiten.DefaultTPS,
+		TickRate:      engine.DefaultTickRate,
+		SampleRate:    48000,
+		BufferMS:      30,
+		MaxVoices:     32,
+		Volume:        1.0,
+		Limiter:       true,
+		Scene:         "grythm.json",
+		Crossfade:     1.5,
+		PresetDir:     "presets",
+		RecordDir:     ".",
+		RecordFormat:  "gif",
+		BounceSeconds: 30,
+		Autosave:      60,
+		AutosaveKeep:  5,
+		Scale:         synth.Scales[0].Name,
+		Intersections: "off",
+		Theme:         Themes[0].Name,
+		MIDIChannel:   1,
+		OSCHost:       "127.0.0.1",
+		// General purpose controllers 20-24 are unassigned in the MIDI spec
+		MIDICCSpeed:     20,
+		MIDICCDirection: 21,
+		MIDICCSpacing:   22,
+		MIDICCDash:      23,
+		MIDICCMorph:     24,
+		DelaySend:       synth.DefaultSends.Delay,
+		ReverbSend:      synth.DefaultSends.Reverb,
+		DelayTime:       0.375,
+		DelayFeedback:   0.35,
+	}
+}
+
+// RegisterFlags binds every option to a flag of the same name, using the
+// current values as defaults.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "config file (default ~/.config/grythm/config.toml)")
+	fs.IntVar(&c.Width, "width", c.Width, "window width in pixels")
+	fs.IntVar(&c.Height, "height", c.Height, "window height in pixels")
+	fs.BoolVar(&c.Fullscreen, "fullscreen", c.Fullscreen, "start in fullscreen")
+	fs.StringVar(&c.WindowPosition, "window-position", c.WindowPosition, "window position x,y on the monitor; empty lets the system place it")
+	fs.StringVar(&c.Monitor, "monitor", c.Monitor, "name of the monitor to open the window on; empty is the primary one")
+	fs.BoolVar(&c.VSync, "vsync", c.VSync, "synchronize drawing with the display refresh")
+	fs.IntVar(&c.TPS, "tps", c.TPS, "input updates per second; -1 updates once per drawn frame")
+	fs.IntVar(&c.TickRate, "tick-rate", c.TickRate, "simulation ticks per second; higher is more precise")
+	fs.IntVar(&c.SampleRate, "sample-rate", c.SampleRate, "audio sample rate in Hz")
+	fs.IntVar(&c.BufferMS, "audio-buffer-ms", c.BufferMS, "audio buffer size in milliseconds; smaller is lower latency, larger stutters less")
+	fs.StringVar(&c.AudioDevice, "audio-device", c.AudioDevice, "ALSA sound card to play on, by index or name (Linux only); empty is the system default")
+	fs.IntVar(&c.MaxVoices, "max-voices", c.MaxVoices, "number of notes that can sound at once")
+	fs.Float64Var(&c.Volume, "volume", c.Volume, fmt.Sprintf("master gain (0-%g, 1 leaves the mix as it is)", maxGain))
+	fs.BoolVar(&c.Limiter, "limiter", c.Limiter, "limit the mixed output instead of letting it clip")
+	fs.StringVar(&c.Scene, "scene", c.Scene, "scene file to load at startup (if it exists) and save to with Ctrl+S")
+	fs.BoolVar(&c.WatchScene, "watch-scene", c.WatchScene, "merge the scene file into the running scene whenever it changes on disk")
+	fs.StringVar(&c.Setlist, "setlist", c.Setlist, "file listing scene files, one per line, to switch between with PageUp/PageDown")
+	fs.StringVar(&c.Morph, "morph", c.Morph, "scene file to morph the loaded scene into with Alt+- and Alt+=, the slider or a MIDI controller")
+	fs.Float64Var(&c.Crossfade, "crossfade", c.Crossfade, "seconds a setlist switch fades the old scene and its notes out; 0 cuts")
+	fs.StringVar(&c.PresetDir, "preset-dir", c.PresetDir, "directory for user preset slots saved with Ctrl+1-9")
+	fs.StringVar(&c.RecordDir, "record-dir", c.RecordDir, "directory for recordings made with R")
+	fs.StringVar(&c.RecordFormat, "record-format", c.RecordFormat, "recording format: gif or png (image sequence)")
+	fs.Float64Var(&c.BounceSeconds, "bounce-seconds", c.BounceSeconds, "length of WAV bounces started with B")
+	fs.BoolVar(&c.Metronome, "metronome", c.Metronome, "click on every beat of the clock at startup (Shift+M switches it)")
+	fs.IntVar(&c.CountIn, "count-in", c.CountIn, "bars of metronome clicks before a recording or bounce starts; 0 starts at once")
+	fs.BoolVar(&c.Tiling, "tiling", c.Tiling, "fill the cells between the lines of the first grids and light them on triggers (F7 switches it)")
+	fs.BoolVar(&c.RescalePoints, "rescale-points", c.RescalePoints, "scale point positions with the window when it is resized")
+	fs.StringVar(&c.Symmetry, "symmetry", c.Symmetry, "mirror points placed by hand: off, vertical, horizontal, both or radial-N (N 2-12)")
+	fs.StringVar(&c.PointLife, "point-life", c.PointLife, "lifespan of points placed by hand: triggers (8), seconds (4s) or both (8,4s); empty lives forever")
+	fs.StringVar(&c.Scale, "scale", c.Scale, "scale name or comma separated semitone steps, e.g. 0,2,3,7,9")
+	fs.StringVar(&c.Intersections, "intersections", c.Intersections, "intersection triggers between linear families: off, points or align")
+	fs.StringVar(&c.Theme, "theme", c.Theme, "color theme: dark, light, neon or monochrome")
+	fs.StringVar(&c.Bindings, "bindings", c.Bindings, "key bindings file; default bindings.toml next to the config file")
+	fs.StringVar(&c.Script, "script", c.Script, "Lua script with onTrigger/onBeat/onUpdate hooks")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "random seed for trigger chances; 0 picks one and logs it")
+	fs.StringVar(&c.RecordSession, "record-session", c.RecordSession, "file to record every input of the session to, for -replay")
+	fs.StringVar(&c.Replay, "replay", c.Replay, "session file recorded with -record-session to play back")
+	fs.Float64Var(&c.Autosave, "autosave", c.Autosave, "seconds between autosaves of the scene, offered for restoring after a crash; 0 disables them")
+	fs.StringVar(&c.AutosaveDir, "autosave-dir", c.AutosaveDir, "directory for autosaves; default grythm-autosave in the temp directory")
+	fs.IntVar(&c.AutosaveKeep, "autosave-keep", c.AutosaveKeep, "number of autosaves kept as rolling backups")
+	fs.StringVar(&c.MIDIPort, "midi-port", c.MIDIPort, "MIDI port (a name from -midi-port list) or raw MIDI device to send notes to, e.g. /dev/snd/midiC1D0")
+	fs.IntVar(&c.MIDIChannel, "midi-channel", c.MIDIChannel, "MIDI channel (1-16) for note output")
+	fs.BoolVar(&c.MIDIClock, "midi-clock", c.MIDIClock, "send MIDI clock, start/stop and song position on the MIDI port while the tempo clock (T) runs")
+	fs.StringVar(&c.OSCHost, "osc-host", c.OSCHost, "host to send OSC trigger messages to")
+	fs.IntVar(&c.OSCPort, "osc-port", c.OSCPort, "UDP port for OSC trigger messages; 0 disables OSC")
+	fs.StringVar(&c.MIDIIn, "midi-in", c.MIDIIn, "MIDI port (a name from -midi-in list) or raw MIDI device to read notes and controllers from")
+	fs.IntVar(&c.MIDICCSpeed, "midi-cc-speed", c.MIDICCSpeed, "controller number for the speed; -1 disables it")
+	fs.IntVar(&c.MIDICCDirection, "midi-cc-direction", c.MIDICCDirection, "controller number for the direction; -1 disables it")
+	fs.IntVar(&c.MIDICCSpacing, "midi-cc-spacing", c.MIDICCSpacing, "controller number for the selected grid's spacing; -1 disables it")
+	fs.IntVar(&c.MIDICCDash, "midi-cc-dash", c.MIDICCDash, "controller number for the selected grid's dash phase; -1 disables it")
+	fs.IntVar(&c.MIDICCMorph, "midi-cc-morph", c.MIDICCMorph, "controller number for the morph between scenes (see -morph); -1 disables it")
+	fs.StringVar(&c.Remote, "remote", c.Remote, "address to serve the remote control page and API on, e.g. :8080; empty disables it")
+	fs.Float64Var(&c.DelaySend, "delay-send", c.DelaySend, "default delay send level (0-1) for grids without their own")
+	fs.Float64Var(&c.ReverbSend, "reverb-send", c.ReverbSend, "default reverb send level (0-1) for grids without their own")
+	fs.Float64Var(&c.DelayTime, "delay-time", c.DelayTime, "delay time in seconds")
+	fs.Float64Var(&c.DelayFeedback, "delay-feedback", c.DelayFeedback, "delay feedback (0-0.95)")
+}
+
+// validate checks the options that would otherwise fail obscurely later.
+func (c *Config) validate() error {
+	switch {
+	case c.Width <= 0 || c.Height <= 0:
+		return fmt.Errorf("window size %dx%d must be positive", c.Width, c.Height)
+	case c.TickRate <= 0:
+		return fmt.Errorf("tick rate must be positive")
+	case c.TPS <= 0 && c.TPS != ebiten.SyncWithFPS:
+		return fmt.Errorf("tps must be positive or %d", ebiten.SyncWithFPS)
+	case c.SampleRate < 8000 || c.SampleRate > 192000:
+		return fmt.Errorf("sample rate %d out of range 8000-192000", c.SampleRate)
+	case c.BufferMS <= 0 || c.BufferMS > 1000:
+		return fmt.Errorf("audio buffer %d ms out of range 1-1000", c.BufferMS)
+	case c.MaxVoices <= 0:
+		return fmt.Errorf("max voices must be positive")
+	case c.Volume < 0 || c.Volume > maxGain:
+		return fmt.Errorf("volume %g out of range 0-%g", c.Volume, maxGain)
+	case c.BounceSeconds <= 0:
+		return fmt.Errorf("bounce-seconds must be positive")
+	case c.Crossfade < 0:
+		return fmt.Errorf("crossfade must not be negative")
+	case c.CountIn < 0:
+		return fmt.Errorf("count-in must not be negative")
+	case c.Autosave < 0:
+		return fmt.Errorf("autosave must not be negative")
+	case c.AutosaveKeep <= 0:
+		return fmt.Errorf("autosave-keep must be positive")
+	case c.Replay != "" && c.RecordSession != "":
+		return fmt.Errorf("a session can't be recorded while one is replayed")
+	}
+	if _, _, _, err := parseWindowPosition(c.WindowPosition); err != nil {
+		return err
+	}
+	if _, err := ThemeNamed(c.Theme); err != nil {
+		return err
+	}
+	for _, cc := range []int{c.MIDICCSpeed, c.MIDICCDirection, c.MIDICCSpacing, c.MIDICCDash, c.MIDICCMorph} {
+		if cc < midiNoCC || cc > 127 {
+			return fmt.Errorf("midi controller %d out of range 0-127", cc)
+		}
+	}
+	return nil
+}
+
+// LoadConfig builds the configuration from the defaults, the config file and
+// the command line args (without the program name).
+func LoadConfig(args []string) (Config, error) {
+	cfg := DefaultConfig()
+	path, explicit := configPath(args)
+	if path != "" && hasFileSystem {
+		if _, err := toml.DecodeFile(path, &cfg); err != nil {
+			// A missing default config file is normal; a missing explicit one is not
+			if explicit || !errors.Is(err, fs.ErrNotExist) {
+				return cfg, fmt.Errorf("config: %w", err)
+			}
+		}
+	}
+	fs := flag.NewFlagSet("grythm", flag.ExitOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// configPath returns the config file named by -config in args, or the default
+// one in the user's config directory. The file must be read before the flags
+// are parsed, so the flag is looked up by hand.
+func configPath(args []string) (path string, explicit bool) {
+	for i, a := range args {
+		name, val, hasVal := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if !strings.HasPrefix(a, "-") || name != "config" {
+			continue
+		}
+		if hasVal {
+			return val, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, "grythm", "config.toml"), false
+}
@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+// cooldownSteps are the retrigger guards (see engine/cooldown.go), in
+// milliseconds, that Alt+C cycles through; 0 (none) comes first.
+var cooldownSteps = []float64{0, 30, 60, 120, 250}
+
+// nextCooldown returns the step after ms; other values go back to none.
+func nextCooldown(ms float64) float64 {
+	for i, s := range cooldownSteps {
+		if s == ms {
+			return cooldownSteps[(i+1)%len(cooldownSteps)]
+		}
+	}
+	return 0
+}
+
+// cooldownLabel formats a cooldown for the HUD.
+func cooldownLabel(ms float64) string {
+	return fmt.Sprintf("%g ms", ms)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Exact angles and spacings. Holding Shift while rotating snaps to multiples
+// of snapDegrees, and A, Shift+A and D open a numeric entry to type the
+// selected grid's angle, its spacing or the movement direction. Shift+U types
+// the loop length, Shift+Q the size of the point brush, Shift+F the cutoff
+// of the selected grid's filter and Alt+V its volume in percent. Y types a
+// polyrhythm ratio (see poly.go) and Shift+D the families of a star (see
+// templates.go).
+
+// snapDegrees is the angle step used while Shift is held.
+const snapDegrees = 15.0
+
+// entryField is the value a numeric entry sets.
+type entryField int
+
+const (
+	entryNone entryField = iota
+	entryGridAngle
+	entryGridSpacing
+	entryDirection
+	entryLoopBeats
+	entryLoopPixels
+	entryBrushCount
+	entryBrushSpacing
+	entryFilterCutoff
+	entryRatio
+	entryStar
+	entryGridVolume
+)
+
+var entryPrompts = []string{"", "Grid angle (degrees)", "Grid spacing (px)", "Direction (degrees)", "Loop length (beats)", "Loop length (px)", "Brush points", "Fill spacing (px)", "Filter cutoff (Hz)", "Ratio from the selected grid (e.g. 3:4:5)", "Star families", "Grid volume (%)"}
+
+// numEntry is a number being typed.
+type numEntry struct {
+	field entryField
+	text  string
+	err   string    // why the last Enter was rejected
+	at    geom.Vec2 // where a ratio or star aligns its grids
+}
+
+// updateEntry starts, edits and applies a numeric entry. It returns true while
+// an entry is open, in which case the keyboard belongs to it.
+func (g *Game) updateEntry() bool {
+	e := &g.entry
+	if e.field == entryNone {
+		switch {
+		case g.pressed(actGridSpacing) && g.selGrid < len(g.Grids):
+			*e = numEntry{field: entryGridSpacing}
+		case g.pressed(actGridAngle) && g.selGrid < len(g.Grids):
+			*e = numEntry{field: entryGridAngle}
+		case g.pressed(actGridVolume) && g.selGrid < len(g.Grids):
+			*e = numEntry{field: entryGridVolume}
+		case g.pressed(actStar) && g.selGrid < len(g.Grids):
+			*e = numEntry{field: entryStar, at: g.cam.Center}
+			if g.hoverIdx >= 0 {
+				e.at = g.Points[g.hoverIdx].Pos
+			}
+		case g.pressed(actDirection):
+			*e = numEntry{field: entryDirection}
+		case g.pressed(actPolyrhythm) && g.selGrid < len(g.Grids):
+			*e = numEntry{field: entryRatio, at: g.cam.Center}
+			if g.hoverIdx >= 0 {
+				e.at = g.Points[g.hoverIdx].Pos
+			}
+		case g.pressed(actLoopLength) && g.TempoMode:
+			*e = numEntry{field: entryLoopBeats}
+		case g.pressed(actLoopLength):
+			*e = numEntry{field: entryLoopPixels}
+		case g.pressed(actGridCutoff) && g.selGrid < len(g.Grids) && g.Grids[g.selGrid].Filter != nil:
+			*e = numEntry{field: entryFilterCutoff}
+		case g.pressed(actBrushSize) && g.brush.tool == brushFill:
+			*e = numEntry{field: entryBrushSpacing}
+		case g.pressed(actBrushSize) && g.brush.tool != brushGrid:
+			*e = numEntry{field: entryBrushCount}
+		default:
+			return false
+		}
+		return true
+	}
+	for _, r := range g.in.Chars {
+		if strings.ContainsRune("0123456789.-", r) || (r == ':' && e.field == entryRatio) {
+			e.text += string(r)
+		}
+	}
+	switch {
+	case g.in.KeyJustPressed(ebiten.KeyBackspace) && e.text != "":
+		e.text = e.text[:len(e.text)-1]
+	case g.in.KeyJustPressed(ebiten.KeyEscape):
+		*e = numEntry{}
+	case g.in.KeyJustPressed(ebiten.KeyEnter) || g.in.KeyJustPressed(ebiten.KeyNumpadEnter):
+		if err := g.applyEntry(); err != nil {
+			e.err = err.Error()
+			break
+		}
+		*e = numEntry{}
+	}
+	return true
+}
+
+// applyEntry sets the entry's field to the typed number.
+func (g *Game) applyEntry() error {
+	if g.entry.field == entryRatio {
+		ratio, err := parseRatio(g.entry.text)
+		if err != nil {
+			return err
+		}
+		return g.applyRatio(ratio, g.entry.at)
+	}
+	v, err := strconv.ParseFloat(g.entry.text, 64)
+	if err != nil {
+		return fmt.Errorf("not a number")
+	}
+	switch g.entry.field {
+	case entryStar:
+		if v != float64(int(v)) {
+			return fmt.Errorf("a whole number of families")
+		}
+		return g.applyStar(int(v), g.entry.at)
+	case entryGridAngle:
+		return g.setGridAngle(g.selGrid, v)
+	case entryGridSpacing:
+		if v <= 0 {
+			return fmt.Errorf("spacing must be positive")
+		}
+		sp := g.Grids[g.selGrid].Spacing
+		g.exec(gridEditCmd[float64]{idx: g.selGrid, from: sp, to: v, set: setGridSpacing})
+	case entryGridVolume:
+		if v <= 0 || v > geom.MaxVolume*100 {
+			return fmt.Errorf("volume must be above 0 and at most %g%%", geom.MaxVolume*100)
+		}
+		vol := g.Grids[g.selGrid].Volume
+		to := v / 100
+		if to == 1 {
+			to = 0
+		}
+		g.exec(gridEditCmd[float64]{idx: g.selGrid, from: vol, to: to,
+			set: func(gf *geom.GridFamily, v float64) { gf.Volume = v }})
+	case entryDirection:
+		dir := angleDir(v)
+		if own := g.selectedMotion(); own != nil {
+			own.Dir = dir
+		} else {
+			g.MoveDir = dir
+		}
+	case entryLoopBeats, entryLoopPixels:
+		if v <= 0 {
+			return fmt.Errorf("loop length must be positive")
+		}
+		g.Loop.Length = v
+		g.Loop.Beats = g.entry.field == entryLoopBeats
+	case entryBrushCount:
+		if v < 1 || v > maxBrushPoints {
+			return fmt.Errorf("between 1 and %d points", maxBrushPoints)
+		}
+		g.brush.count = int(v)
+	case entryBrushSpacing:
+		if v <= 0 {
+			return fmt.Errorf("spacing must be positive")
+		}
+		g.brush.spacing = v
+	case entryFilterCutoff:
+		from := g.Grids[g.selGrid].Filter
+		to := *from
+		to.Cutoff = v
+		if err := to.Validate(); err != nil {
+			return err
+		}
+		g.exec(gridEditCmd[*synth.Filter]{idx: g.selGrid, from: from, to: &to,
+			set: func(gf *geom.GridFamily, v *synth.Filter) { gf.Filter = v }})
+	}
+	return nil
+}
+
+// label describes the open entry for the HUD.
+func (e numEntry) label() string {
+	s := fmt.Sprintf("%s: %s_  (Enter apply, Esc cancel)", entryPrompts[e.field], e.text)
+	if e.err != "" {
+		s += "  " + e.err
+	}
+	return s
+}
+
+// setGridSpacing changes a family's spacing, regenerating a Euclidean dash
+// pattern whose steps follow it.
+func setGridSpacing(gf *geom.GridFamily, v float64) {
+	gf.Spacing = v
+	if gf.Euclid != nil {
+		gf.ApplyEuclid()
+	}
+}
+
+// gridAngle returns the orientation of a family in degrees: the direction of
+// its normal, or the angle of the first ray. Radial families have none.
+func gridAngle(gf *geom.GridFamily) (float64, bool) {
+	switch gf.Kind {
+	case geom.GridRadial:
+		return 0, false
+	case geom.GridRay:
+		return gf.Angle * 180 / math.Pi, true
+	}
+	return dirAngle(gf.Normal), true
+}
+
+// setGridAngle turns family gi to deg degrees as an undoable edit.
+func (g *Game) setGridAngle(gi int, deg float64) error {
+	gf := &g.Grids[gi]
+	switch gf.Kind {
+	case geom.GridRadial:
+		return fmt.Errorf("radial grids have no angle")
+	case geom.GridRay:
+		g.exec(gridEditCmd[float64]{idx: gi, from: gf.Angle, to: deg * math.Pi / 180,
+			set: func(gf *geom.GridFamily, v float64) { gf.Angle = v }})
+	default:
+		g.exec(gridEditCmd[geom.Vec2]{idx: gi, from: gf.Normal, to: angleDir(deg),
+			set: func(gf *geom.GridFamily, v geom.Vec2) { gf.Normal = v }})
+	}
+	return nil
+}
+
+// rotateGridKeys turns the selected grid with rotate-grid-left/right (, and .)
+// by one degree per press, or to the neighbouring multiple of snapDegrees
+// with Shift. With Alt they turn its layer instead (see layer.go).
+func (g *Game) rotateGridKeys() {
+	if g.turnLayerKeys() {
+		return
+	}
+	d := g.step(actRotateGridRight, actRotateGridLeft)
+	if d == 0 || g.selGrid >= len(g.Grids) {
+		return
+	}
+	a, ok := gridAngle(&g.Grids[g.selGrid])
+	if !ok {
+		return
+	}
+	if g.in.KeyPressed(ebiten.KeyShift) {
+		a = snapAngle(a, d)
+	} else {
+		a += float64(d)
+	}
+	_ = g.setGridAngle(g.selGrid, a)
+}
+
+// snapAngle returns the next multiple of snapDegrees after deg in direction d (+1 or -1).
+func snapAngle(deg float64, d int) float64 {
+	const eps = 1e-6
+	if d > 0 {
+		return (math.Floor(deg/snapDegrees+eps) + 1) * snapDegrees
+	}
+	return (math.Ceil(deg/snapDegrees-eps) - 1) * snapDegrees
+}
+
+// dirAngle returns the angle of v in degrees.
+func dirAngle(v geom.Vec2) float64 {
+	return math.Atan2(v.Y, v.X) * 180 / math.Pi
+}
+
+// angleDir returns the unit vector at deg degrees.
+func angleDir(deg float64) geom.Vec2 {
+	a := deg * math.Pi / 180
+	return geom.Vec2{X: math.Cos(a), Y: math.Sin(a)}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// LoggedTrigger records one crossing of a point by a grid line.
+type LoggedTrigger struct {
+	Time  float64 // simulation time in seconds
+	Grid  int
+	Point int
+}
+
+// EventLog is a fixed-size ring buffer of the most recent trigger events.
+type EventLog struct {
+	buf  []LoggedTrigger
+	next int  // index the next event is written to
+	full bool // whether the buffer has wrapped
+}
+
+// NewEventLog creates a log holding up to size events.
+func NewEventLog(size int) *EventLog {
+	return &EventLog{buf: make([]LoggedTrigger, size)}
+}
+
+// Add appends e, overwriting the oldest event when full.
+func (l *EventLog) Add(e LoggedTrigger) {
+	l.buf[l.next] = e
+	l.next++
+	if l.next == len(l.buf) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// Len returns the number of stored events.
+func (l *EventLog) Len() int {
+	if l.full {
+		return len(l.buf)
+	}
+	return l.next
+}
+
+// At returns the i-th stored event, oldest first.
+func (l *EventLog) At(i int) LoggedTrigger {
+	if l.full {
+		return l.buf[(l.next+i)%len(l.buf)]
+	}
+	return l.buf[i]
+}
+
+// timelineSeconds is the span of history shown in the timeline strip.
+const timelineSeconds = 4.0
+
+// drawTimeline renders a strip at the bottom of the screen with one lane per
+// grid; each trigger is a tick that scrolls left as time passes.
+func (g *Game) drawTimeline(dst *ebiten.Image) {
+	const laneH = 10.0
+	lanes := len(g.Grids)
+	if lanes == 0 {
+		return
+	}
+	h := laneH*float64(lanes) + 8
+	w := float64(g.W)
+	top := float64(g.H) - h
+	vector.DrawFilledRect(dst, 0, float32(top), float32(w), float32(h), g.theme.Panel, false)
+	pxPerSec := w / timelineSeconds
+
+	// Beat lines when a tempo is running make the rhythm easy to read
+	if g.TempoMode && g.Clock.BPM > 0 {
+		beatSec := 60 / g.Clock.BPM
+		// Time of the most recent beat
+		last := g.Time - (g.Clock.Beats-float64(int(g.Clock.Beats)))*beatSec
+		for t := last; t > g.Time-timelineSeconds; t -= beatSec {
+			x := w - (g.Time-t)*pxPerSec
+			vector.StrokeLine(dst, float32(x), float32(top), float32(x), float32(top+h), 1, g.theme.Beat, false)
+		}
+	}
+
+	for i := 0; i < g.events.Len(); i++ {
+		e := g.events.At(i)
+		age := g.Time - e.Time
+		if age > timelineSeconds || e.Grid >= lanes {
+			continue
+		}
+		x := w - age*pxPerSec
+		y := top + 4 + laneH*float64(e.Grid)
+		vector.StrokeLine(dst, float32(x), float32(y+1), float32(x), float32(y+laneH-1), 2, g.Grids[e.Grid].Color, false)
+	}
+}
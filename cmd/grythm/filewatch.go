@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchFile notifies on the returned channel whenever the file at path is
+// written or replaced, until stop is called. Editors often save by writing a
+// new file and renaming it over the old one, so the directory is watched and
+// its events are filtered by name. Where the system has no file
+// notifications (in the browser) an error is returned.
+func watchFile(path string) (changes <-chan struct{}, stop func(), err error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("watch %s: %w", path, err)
+	}
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return nil, nil, fmt.Errorf("watch %s: %w", path, err)
+	}
+	ch := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(ev.Name) != name || !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) {
+					continue
+				}
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case _, ok := <-w.Errors:
+				// An overflow loses events, but the next save is seen again
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return ch, func() { w.Close() }, nil
+}
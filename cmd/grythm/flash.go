@@ -0,0 +1,69 @@
+package main
+
+import "grythm/geom"
+
+// Onset flashes. When a line touches a point, that line (not the whole
+// family) is drawn brighter and thicker for a moment, and a ripple runs along
+// it away from the point. Each grid keeps the keys of its recently triggered
+// lines (see geom.LineKey) and its ripples, and the drawn copies of the grids
+// get them as their Flash and Ripples.
+
+// flashSeconds is how long a flash takes to fade.
+const flashSeconds = 0.15
+
+// flashLine starts the flash of the line of grid gi touching p.
+func (g *Game) flashLine(gi int, p geom.Vec2) {
+	if len(g.flashes) != len(g.Grids) {
+		// Grids were added or removed; flashes are short-lived, so start over
+		g.flashes = make([][]geom.LineFlash, len(g.Grids))
+	}
+	center := g.Center()
+	key, ok := g.Grids[gi].LineAt(p, center)
+	if !ok {
+		return
+	}
+	for i := range g.flashes[gi] {
+		if g.flashes[gi][i].Key == key {
+			g.flashes[gi][i].Level = 1
+			return
+		}
+	}
+	g.flashes[gi] = append(g.flashes[gi], geom.LineFlash{Key: key, Level: 1})
+}
+
+// rippleLine starts a ripple along the line of grid gi touching p.
+func (g *Game) rippleLine(gi int, p geom.Vec2) {
+	if len(g.ripples) != len(g.Grids) {
+		g.ripples = make([][]geom.LineRipple, len(g.Grids))
+	}
+	center := g.Center()
+	gf := &g.Grids[gi]
+	key, ok := gf.LineAt(p, center)
+	if !ok {
+		return
+	}
+	g.ripples[gi] = append(g.ripples[gi], geom.LineRipple{Key: key, From: gf.Along(p, center)})
+}
+
+// updateFlashes fades the flashes and ages the ripples by dt seconds and
+// drops finished ones.
+func (g *Game) updateFlashes(dt float64) {
+	for gi, row := range g.flashes {
+		live := row[:0]
+		for _, f := range row {
+			if f.Level -= dt / flashSeconds; f.Level > 0 {
+				live = append(live, f)
+			}
+		}
+		g.flashes[gi] = live
+	}
+	for gi, row := range g.ripples {
+		live := row[:0]
+		for _, r := range row {
+			if r.Age += dt; r.Age < geom.RippleSeconds {
+				live = append(live, r)
+			}
+		}
+		g.ripples[gi] = live
+	}
+}
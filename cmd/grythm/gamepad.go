@@ -0,0 +1,140 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/geom"
+)
+
+// Gamepad control scheme (standard layout, first connected pad):
+//
+//	left stick X          rotate the movement direction
+//	right stick Y, R2/L2  speed up/down (BPM in tempo mode)
+//	D-pad                 move the edit cursor
+//	A                     add a point at the cursor, or remove the one under it
+//	X                     cycle the waveform of the point under the cursor
+//	Y                     toggle tempo mode
+//	LB/RB                 select the previous/next grid
+//	Start                 reset the view
+
+// padDeadZone ignores small stick deflections so a resting stick doesn't drift.
+const padDeadZone = 0.2
+
+// padCursorSpeed is how fast the D-pad moves the edit cursor, in screen pixels per second.
+const padCursorSpeed = 300.0
+
+// gamepad returns the first connected pad with a standard layout, whose
+// state is captured into each frame's Input.
+func gamepad() (ebiten.GamepadID, bool) {
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// padAxis reads a stick axis with the dead zone removed and rescaled to -1..1.
+func padAxis(pad *PadInput, axis ebiten.StandardGamepadAxis) float64 {
+	v := pad.Axes[axis]
+	if math.Abs(v) < padDeadZone {
+		return 0
+	}
+	return math.Copysign((math.Abs(v)-padDeadZone)/(1-padDeadZone), v)
+}
+
+// updateGamepad applies the gamepad controls for this frame.
+func (g *Game) updateGamepad(dt float64) {
+	pad := g.in.Pad
+	g.padActive = pad != nil
+	if pad == nil {
+		return
+	}
+	pressed := pad.JustPressed
+
+	// Motion: the selected grid's own motion if it has one, like the arrow keys
+	dir, speed := &g.MoveDir, &g.Speed
+	if own := g.selectedMotion(); own != nil {
+		dir, speed = &own.Dir, &own.Speed
+	}
+	if x := padAxis(pad, ebiten.StandardGamepadAxisLeftStickHorizontal); x != 0 {
+		angle := math.Atan2(dir.Y, dir.X) + x*math.Pi/2*dt
+		*dir = geom.Vec2{X: math.Cos(angle), Y: math.Sin(angle)}
+	}
+	accel := -padAxis(pad, ebiten.StandardGamepadAxisRightStickVertical) +
+		pad.Values[ebiten.StandardGamepadButtonFrontBottomRight] -
+		pad.Values[ebiten.StandardGamepadButtonFrontBottomLeft]
+	if accel != 0 {
+		if g.TempoMode && speed == &g.Speed {
+			g.Clock.SetBPM(g.Clock.BPM + accel*30*dt)
+		} else {
+			*speed = math.Max(0, *speed+accel*120*dt)
+		}
+	}
+
+	// Cursor: starts at the view center and moves in screen space with the D-pad
+	var move geom.Vec2
+	if pad.Held(ebiten.StandardGamepadButtonLeftLeft) {
+		move.X--
+	}
+	if pad.Held(ebiten.StandardGamepadButtonLeftRight) {
+		move.X++
+	}
+	if pad.Held(ebiten.StandardGamepadButtonLeftTop) {
+		move.Y--
+	}
+	if pad.Held(ebiten.StandardGamepadButtonLeftBottom) {
+		move.Y++
+	}
+	if !g.padCursorSet {
+		g.padCursor = g.cam.Center
+		g.padCursorSet = true
+	}
+	g.padCursor = g.padCursor.Add(move.Mul(padCursorSpeed * dt / g.cam.Zoom))
+
+	center := g.Center()
+	under := g.Index.Nearest(g.Points, g.padCursor, center, 10.0/g.cam.Zoom)
+	if pressed(ebiten.StandardGamepadButtonRightBottom) && g.dragIdx < 0 {
+		if under >= 0 {
+			g.exec(g.removeCmd(under))
+		} else {
+			g.exec(g.placeCmd(g.padCursor))
+		}
+	}
+	if pressed(ebiten.StandardGamepadButtonRightLeft) && under >= 0 {
+		before := g.Points[under]
+		after := before
+		after.Wave = before.Wave.Next(true)
+		g.exec(editPointCmd{idx: under, before: before, after: after})
+	}
+	if pressed(ebiten.StandardGamepadButtonRightTop) {
+		g.TempoMode = !g.TempoMode
+		if g.TempoMode {
+			g.Clock.SetSpeed(g.Speed)
+		}
+	}
+	if n := len(g.Grids); n > 0 {
+		if pressed(ebiten.StandardGamepadButtonFrontTopRight) {
+			g.selGrid = (g.selGrid + 1) % n
+		}
+		if pressed(ebiten.StandardGamepadButtonFrontTopLeft) {
+			g.selGrid = (g.selGrid + n - 1) % n
+		}
+	}
+	if pressed(ebiten.StandardGamepadButtonCenterRight) {
+		g.cam.Center = center
+		g.cam.Zoom = 1
+		g.padCursor = center
+	}
+}
+
+// drawPadCursor draws the gamepad edit cursor as a small ring.
+func (g *Game) drawPadCursor(dst *ebiten.Image) {
+	if !g.padActive {
+		return
+	}
+	p := g.cam.ToScreen(g.padCursor)
+	vector.StrokeCircle(dst, float32(p.X), float32(p.Y), 10, 1.5, g.theme.Cursor, true)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"grythm/engine"
+	"grythm/synth"
+)
+
+// Gap sounds (see engine/gap.go). Alt+D makes the selected dashed grid also
+// trigger the points that come into its gaps; the host plays those as a soft
+// triangle pluck an octave below the note, whatever the point or grid would
+// play on a dash, so the gaps are a second voice under the first.
+
+// playGap sounds a trigger in a gap of a dashed line.
+func (g *Game) playGap(t engine.TriggerEvent) {
+	gf := &g.Grids[t.Grid]
+	g.playBlip(synth.Voice{
+		Freq:   synth.MIDIToFreq(t.Note - 12),
+		Wave:   synth.WaveTriangle,
+		Env:    synth.EnvelopePresets[1].Env,
+		Filter: resolveFilter(gf.Filter),
+	}, t.Velocity, synth.ResolveSends(gf.Sends, g.sends), synth.NoteGate{At: g.soundFrame(t.Time, t.At)})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Placement preview. Over empty space a ghost point follows the cursor with
+// the pitch a click would give it and how far it is from the nearest line of
+// each family, so points can be put just before or after a line on purpose.
+// A guide ring marks a crossing of two lines near the cursor. Holding Ctrl
+// snaps the ghost (and a point being dragged) to the nearest crossing
+// however far it is, and a click then places the point exactly there.
+
+const (
+	ghostSnap     = 12.0 // window pixels within which a crossing is marked
+	ghostMaxGrids = 8    // families whose distance is listed before the rest are left out
+)
+
+// ghostState is the pending placement under the cursor.
+type ghostState struct {
+	pos     geom.Vec2 // where a click would place a point
+	cross   geom.Vec2 // crossing near the cursor, valid with near
+	near    bool
+	snapped bool // pos is the crossing
+}
+
+// snapping reports whether points are snapped to line crossings.
+func (g *Game) snapping() bool {
+	return g.in.KeyPressed(ebiten.KeyControl)
+}
+
+// snapPoint returns p moved onto the nearest line crossing when snapping.
+func (g *Game) snapPoint(p geom.Vec2) geom.Vec2 {
+	if !g.snapping() {
+		return p
+	}
+	if x, ok := geom.NearestCrossing(g.Grids, p, g.Center(), math.Inf(1)); ok {
+		return x
+	}
+	return p
+}
+
+// updateGhost works out where a click at mouse (world coordinates) would
+// place a point.
+func (g *Game) updateGhost(mouse geom.Vec2) {
+	g.ghost = ghostState{pos: mouse}
+	radius := ghostSnap / g.cam.Zoom
+	if g.snapping() {
+		radius = math.Inf(1)
+	}
+	if x, ok := geom.NearestCrossing(g.Grids, mouse, g.Center(), radius); ok {
+		g.ghost.cross, g.ghost.near = x, true
+		if g.snapping() {
+			g.ghost.pos, g.ghost.snapped = x, true
+		}
+	}
+}
+
+// showGhost reports whether the preview is drawn: only for single points,
+// with a mouse over empty space.
+func (g *Game) showGhost() bool {
+	return g.brush.tool == brushPoint && g.hoverIdx < 0 && g.dragIdx < 0 &&
+		!g.panning && !g.touch.active && !g.padActive
+}
+
+// drawGhost renders the ghost point and its readout.
+func (g *Game) drawGhost(dst *ebiten.Image) {
+	if !g.showGhost() {
+		return
+	}
+	d := g.cam.Device()
+	sp := g.cam.ToScreen(g.ghost.pos)
+	drawCross(dst, sp, 6*d, g.theme.Guide)
+	if g.ghost.near {
+		cp := g.cam.ToScreen(g.ghost.cross)
+		vector.StrokeCircle(dst, float32(cp.X), float32(cp.Y), float32(ghostSnap*d), float32(d), g.theme.Guide, true)
+	}
+
+	deg := g.NewPointDegree(g.ghost.pos)
+	note := g.Scale.Note(g.Key, deg)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Degree %d: %s (%.1f Hz)", deg, synth.NoteName(note), synth.MIDIToFreq(note))
+	switch {
+	case g.ghost.snapped:
+		b.WriteString("  on crossing")
+	case g.ghost.near:
+		b.WriteString("  Ctrl: snap to crossing")
+	}
+	center := g.Center()
+	for gi := range g.Grids {
+		if gi == ghostMaxGrids {
+			b.WriteString("\n...")
+			break
+		}
+		fmt.Fprintf(&b, "\nG%d %.0f px", gi+1, g.Grids[gi].Distance(g.ghost.pos, center))
+	}
+	g.printAt(dst, b.String(), int(sp.X+12*d), int(sp.Y+8*d))
+}
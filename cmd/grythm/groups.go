@@ -0,0 +1,18 @@
+package main
+
+// groupLabel names a group for the HUD, including its switches.
+func (g *Game) groupLabel(name string) string {
+	if name == "" {
+		return "none"
+	}
+	st := g.Groups[name]
+	switch {
+	case st.Mute && st.Solo:
+		return name + " [muted, solo]"
+	case st.Mute:
+		return name + " [muted]"
+	case st.Solo:
+		return name + " [solo]"
+	}
+	return name
+}
@@ -0,0 +1,97 @@
+package main
+
+import "strings"
+
+// Key help. The HUD keeps to the status of the scene; ? shows every key,
+// grouped by what it works on, above the status until it is pressed again.
+// HUD lines are wrapped to the window between their entries, which are set
+// apart by two spaces, so narrow windows show them in full.
+
+// hudCharWidth is the width of a character of the debug font, in pixels.
+const hudCharWidth = 6
+
+// helpGroups lists the keys by what they work on.
+var helpGroups = []struct {
+	title string
+	keys  []string
+}{
+	{"Mouse", []string{
+		"click or tap: add/remove point", "drag: move point (Ctrl: snap to crossings)",
+		"middle drag or pinch: pan/zoom", "two-finger turn: direction", "three-finger tap: pinch sets speed",
+		"wheel: zoom (on a point: its velocity)", "Home: reset view",
+		"Shift+drag: select points (Shift+click: one)",
+	}},
+	{"Motion", []string{
+		"Left/Right: rotate (Shift: snap 15°)", "Up/Down: speed (BPM in tempo mode)", "D: type direction",
+		"T: tempo mode", "Z: quantize (Shift+Z: arpeggio, ;: its direction, Alt+Z: swing)",
+		"U: loop (Shift+U: length)", "Alt+- Alt+=: morph to -morph scene", "ESC: quit",
+	}},
+	{"Grids", []string{
+		"Tab: select grid", "O: own motion (Shift+O: opacity)", "Space: pause", "/: reverse",
+		"- =: speed", "' Shift+': nudge phase (\\: by 1/2, 1/3, 1/4, Shift+\\: reset)", ", .: rotate (Shift: snap)",
+		"A/Shift+A: type angle/spacing", "Y: type polyrhythm ratio", "H: hex tiling",
+		"Ctrl+D: duplicate (Shift+D: star, Alt+D: gap sounds)", "V: accents (Shift+V: width animation)",
+		"Shift+B: blend", "Alt+B: line width", "Alt+T: show trigger band", "Alt+E: trigger edge", "Alt+V: volume",
+		"Alt+G: layer (Alt+, Alt+.: turn it, Shift+- Shift+=: its speed, Alt+drag: move it)",
+	}},
+	{"Sound", []string{
+		"W: waveform (Shift+W: grid drum, Alt+W: FM voice)", "E: envelope (Shift+E: grid glide)",
+		"F/Shift+F: grid filter/cutoff", "[ ]: degree -/+ (hovered point or selected grid)", "K/Shift+K: key",
+		"L: scale", "P: pitch from position, then distance to a tonal center (Shift+P: point chord)",
+		"C: trigger chance (Alt+C: retrigger guard)", "N: note length (Shift+N: cut/overlap)", "I: intersections",
+		"Shift+M: metronome", "Ctrl+- Ctrl+=: master gain (Ctrl+L: limiter)",
+	}},
+	{"Points", []string{
+		"G: group (Shift+G: select group)", "M: mute group (or selected grid)", "S: solo group (or selected grid)",
+		"X: hovered point ignores selected grid (Shift+X: symmetry)", "J: path (Shift+J: path speed)",
+		"Shift+L: lifespan", "Q: brush (Shift+Q: count/spacing)", "Shift+Tab: add selected grid to selection",
+		"Ctrl+A: select all", "Esc: select none", "Del: delete selection", "Ctrl+C/Ctrl+X/Ctrl+V: copy/cut/paste (Ctrl+Shift+V: paste offset)",
+	}},
+	{"Scenes", []string{
+		"Ctrl+Z/Ctrl+Shift+Z: undo/redo", "Ctrl+S: save", "1-9: preset", "Ctrl+1-9: save slot",
+		"PgUp/PgDn: setlist scene",
+	}},
+	{"Output", []string{
+		"R: record (Shift+R: capture MIDI)", "B: bounce WAV", "F4: MIDI learn", "F12: snapshot",
+	}},
+	{"Display", []string{
+		"?: this help", "F1: debug overlay (Shift+F1: dock)", "F2: timeline", "F3: trails", "F5: theme",
+		"F6: stats (Shift+F6: reset)", "F7: tiling", "F11: fullscreen",
+	}},
+}
+
+// helpText returns the key help, one group after the other.
+func helpText() string {
+	var b strings.Builder
+	for _, grp := range helpGroups {
+		b.WriteString(grp.title + ":  " + strings.Join(grp.keys, "  ") + "\n")
+	}
+	return b.String()
+}
+
+// wrapHUD wraps every line of s to cols characters, breaking between the
+// entries of a line. An entry longer than a line is left whole.
+func wrapHUD(s string, cols int) string {
+	var b strings.Builder
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		n := 0
+		for j, entry := range strings.Split(line, "  ") {
+			w := len([]rune(entry))
+			switch {
+			case j == 0:
+			case n+2+w > cols:
+				b.WriteString("\n  ")
+				n = 2
+			default:
+				b.WriteString("  ")
+				n += 2
+			}
+			b.WriteString(entry)
+			n += w
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Command is a reversible edit of the scene. Do applies the edit and Undo
+// reverts it; both may be called repeatedly as the user walks the history.
+type Command interface {
+	Do(g *Game)
+	Undo(g *Game)
+}
+
+// History holds the undo and redo stacks.
+type History struct {
+	undo []Command
+	redo []Command
+}
+
+// maxHistory bounds the undo stack so long sessions don't grow without limit.
+const maxHistory = 256
+
+// exec applies c and records it for undo. Any redo history is discarded.
+func (g *Game) exec(c Command) {
+	c.Do(g)
+	g.record(c)
+}
+
+// record pushes an already applied command onto the undo stack.
+func (g *Game) record(c Command) {
+	h := &g.history
+	h.undo = append(h.undo, c)
+	if len(h.undo) > maxHistory {
+		h.undo = h.undo[len(h.undo)-maxHistory:]
+	}
+	h.redo = h.redo[:0]
+}
+
+// Undo reverts the most recent command, if any.
+func (g *Game) Undo() {
+	h := &g.history
+	if len(h.undo) == 0 {
+		return
+	}
+	c := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	c.Undo(g)
+	h.redo = append(h.redo, c)
+}
+
+// Redo reapplies the most recently undone command, if any.
+func (g *Game) Redo() {
+	h := &g.history
+	if len(h.redo) == 0 {
+		return
+	}
+	c := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	c.Do(g)
+	h.undo = append(h.undo, c)
+}
+
+// addPointCmd inserts a point at idx.
+type addPointCmd struct {
+	idx int
+	p   engine.Point
+}
+
+func (c addPointCmd) Do(g *Game)   { g.InsertPoint(c.idx, c.p) }
+func (c addPointCmd) Undo(g *Game) { g.RemovePoint(c.idx) }
+
+// removePointCmd deletes the point at idx, remembering it for undo.
+type removePointCmd struct {
+	idx int
+	p   engine.Point
+}
+
+func (c removePointCmd) Do(g *Game)   { g.RemovePoint(c.idx) }
+func (c removePointCmd) Undo(g *Game) { g.InsertPoint(c.idx, c.p) }
+
+// editPointCmd replaces the point at idx, covering moves and parameter changes.
+type editPointCmd struct {
+	idx           int
+	before, after engine.Point
+}
+
+func (c editPointCmd) Do(g *Game)   { g.SetPoint(c.idx, c.after) }
+func (c editPointCmd) Undo(g *Game) { g.SetPoint(c.idx, c.before) }
+
+// batchCmd applies several commands as one undo step.
+type batchCmd []Command
+
+func (c batchCmd) Do(g *Game) {
+	for _, cmd := range c {
+		cmd.Do(g)
+	}
+}
+
+func (c batchCmd) Undo(g *Game) {
+	for i := len(c) - 1; i >= 0; i-- {
+		c[i].Undo(g)
+	}
+}
+
+// gridEditCmd changes a single parameter of the grid family at idx. Only the
+// edited field is touched so the family's animated state (Offset, DashPhase)
+// keeps running across undo/redo.
+type gridEditCmd[T any] struct {
+	idx      int
+	from, to T
+	set      func(gf *geom.GridFamily, v T)
+}
+
+func (c gridEditCmd[T]) Do(g *Game)   { c.set(&g.Grids[c.idx], c.to) }
+func (c gridEditCmd[T]) Undo(g *Game) { c.set(&g.Grids[c.idx], c.from) }
+
+// addGridsCmd appends grid families after the existing ones and selects the
+// first of them; Undo removes them again and restores the selection.
+type addGridsCmd struct {
+	at    int // index of the first family added
+	sel   int // family selected before
+	grids []geom.GridFamily
+}
+
+func (c addGridsCmd) Do(g *Game) {
+	for _, gf := range c.grids {
+		g.AppendGrid(gf.Clone())
+	}
+	g.selGrid = c.at
+}
+
+func (c addGridsCmd) Undo(g *Game) {
+	g.TruncateGrids(c.at)
+	g.selGrid = c.sel
+}
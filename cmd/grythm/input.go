@@ -0,0 +1,302 @@
+package main
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"grythm/geom"
+)
+
+// Input is everything the player did in one frame: keys, mouse, touches, the
+// gamepad, MIDI input and remote commands, with the frame's length and window size. The game
+// reads its input only from here, never from Ebiten directly, so a recorded
+// session replays exactly (see replay.go).
+type Input struct {
+	DT      float64         `json:"dt"` // seconds since the previous frame
+	W       int             `json:"w"`  // canvas size in device pixels
+	H       int             `json:"h"`
+	Scale   float64         `json:"scale,omitempty"`   // device pixels per window pixel; 0 means 1
+	Keys    []ebiten.Key    `json:"keys,omitempty"`    // held down
+	Pressed []ebiten.Key    `json:"pressed,omitempty"` // went down this frame
+	Chars   string          `json:"chars,omitempty"`   // typed text
+	Cursor  geom.Vec2       `json:"cursor"`
+	Buttons mouseButtons    `json:"buttons,omitempty"` // held down
+	Clicks  mouseButtons    `json:"clicks,omitempty"`  // went down this frame
+	Ups     mouseButtons    `json:"ups,omitempty"`     // went up this frame
+	Wheel   geom.Vec2       `json:"wheel"`
+	Touches []TouchInput    `json:"touches,omitempty"`
+	Pad     *PadInput       `json:"pad,omitempty"` // nil without a standard gamepad
+	MIDI    []MIDIMessage   `json:"midi,omitempty"`
+	Remote  []RemoteCommand `json:"remote,omitempty"` // see remote.go
+}
+
+// mouseButtons has bit b set for mouse button b.
+type mouseButtons uint8
+
+// TouchInput is a finger on the screen.
+type TouchInput struct {
+	ID       ebiten.TouchID `json:"id"`
+	Pos      geom.Vec2      `json:"pos"`
+	Pressed  bool           `json:"pressed,omitempty"`  // touched down this frame
+	Released bool           `json:"released,omitempty"` // lifted this frame; Pos is meaningless
+}
+
+// PadInput is the state of the first gamepad with a standard layout.
+type PadInput struct {
+	Axes    [ebiten.StandardGamepadAxisMax + 1]float64   `json:"axes"`
+	Values  [ebiten.StandardGamepadButtonMax + 1]float64 `json:"values"`
+	Down    uint32                                       `json:"down,omitempty"`    // bit b: button b is held down
+	Pressed uint32                                       `json:"pressed,omitempty"` // bit b: button b went down this frame
+}
+
+// captureInput reads this frame's input from Ebiten, the MIDI port and the
+// remote control server. The
+// window size is the canvas size Layout has just given the game.
+func (g *Game) captureInput(dt float64) *Input {
+	in := &Input{
+		DT:      dt,
+		W:       g.W,
+		H:       g.H,
+		Scale:   g.cam.DeviceScale,
+		Keys:    inpututil.AppendPressedKeys(nil),
+		Pressed: inpututil.AppendJustPressedKeys(nil),
+		Chars:   string(ebiten.AppendInputChars(nil)),
+	}
+	mx, my := ebiten.CursorPosition()
+	in.Cursor = geom.Vec2{X: float64(mx), Y: float64(my)}
+	for b := ebiten.MouseButton0; b <= ebiten.MouseButtonMax; b++ {
+		bit := mouseButtons(1) << b
+		if ebiten.IsMouseButtonPressed(b) {
+			in.Buttons |= bit
+		}
+		if inpututil.IsMouseButtonJustPressed(b) {
+			in.Clicks |= bit
+		}
+		if inpututil.IsMouseButtonJustReleased(b) {
+			in.Ups |= bit
+		}
+	}
+	in.Wheel.X, in.Wheel.Y = ebiten.Wheel()
+	justTouched := inpututil.AppendJustPressedTouchIDs(nil)
+	for _, id := range ebiten.AppendTouchIDs(nil) {
+		x, y := ebiten.TouchPosition(id)
+		in.Touches = append(in.Touches, TouchInput{ID: id, Pos: geom.Vec2{X: float64(x), Y: float64(y)}, Pressed: slices.Contains(justTouched, id)})
+	}
+	for _, id := range inpututil.AppendJustReleasedTouchIDs(nil) {
+		in.Touches = append(in.Touches, TouchInput{ID: id, Released: true})
+	}
+	if id, ok := gamepad(); ok {
+		pad := &PadInput{}
+		for a := range pad.Axes {
+			pad.Axes[a] = ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxis(a))
+		}
+		for b := range pad.Values {
+			sb := ebiten.StandardGamepadButton(b)
+			pad.Values[b] = ebiten.StandardGamepadButtonValue(id, sb)
+			if ebiten.IsStandardGamepadButtonPressed(id, sb) {
+				pad.Down |= 1 << b
+			}
+			if inpututil.IsStandardGamepadButtonJustPressed(id, sb) {
+				pad.Pressed |= 1 << b
+			}
+		}
+		in.Pad = pad
+	}
+	in.MIDI = g.pollMIDIIn()
+	in.Remote = g.pollRemote()
+	return in
+}
+
+// KeyPressed reports whether k is held down.
+func (in *Input) KeyPressed(k ebiten.Key) bool {
+	return slices.Contains(in.Keys, k)
+}
+
+// KeyJustPressed reports whether k went down this frame.
+func (in *Input) KeyJustPressed(k ebiten.Key) bool {
+	return slices.Contains(in.Pressed, k)
+}
+
+// MousePressed reports whether mouse button b is held down.
+func (in *Input) MousePressed(b ebiten.MouseButton) bool {
+	return in.Buttons&(1<<b) != 0
+}
+
+// MouseJustPressed reports whether mouse button b went down this frame.
+func (in *Input) MouseJustPressed(b ebiten.MouseButton) bool {
+	return in.Clicks&(1<<b) != 0
+}
+
+// MouseJustReleased reports whether mouse button b went up this frame.
+func (in *Input) MouseJustReleased(b ebiten.MouseButton) bool {
+	return in.Ups&(1<<b) != 0
+}
+
+// touching returns the fingers on the screen, in the order Ebiten reported them.
+func (in *Input) touching() []TouchInput {
+	var out []TouchInput
+	for _, t := range in.Touches {
+		if !t.Released {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// touch returns finger id, if it is on the screen or was lifted this frame.
+func (in *Input) touch(id ebiten.TouchID) (TouchInput, bool) {
+	for _, t := range in.Touches {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return TouchInput{}, false
+}
+
+// Held reports whether standard button b is held down.
+func (p *PadInput) Held(b ebiten.StandardGamepadButton) bool {
+	return p.Down&(1<<b) != 0
+}
+
+// JustPressed reports whether standard button b went down this frame.
+func (p *PadInput) JustPressed(b ebiten.StandardGamepadButton) bool {
+	return p.Pressed&(1<<b) != 0
+}
+
+// pointer is the primary pointing input of a frame: the left mouse button, or
+// a single finger on touch screens, so that editing works the same on both.
+type pointer struct {
+	Pos          geom.Vec2 // screen position
+	JustPressed  bool
+	JustReleased bool
+}
+
+// Touch gestures. A tap adds or removes a point and a drag moves it (see
+// readPointer). Two fingers pan, and turning them around each other turns the
+// movement direction once the turn passes touchTwistDeadZone, so a plain
+// pinch doesn't nudge it; spreading them zooms, or changes the speed after a
+// three-finger tap has switched the pinch over. Like the arrow keys the
+// gestures steer the selected grid when it moves on its own.
+
+const (
+	touchTwistDeadZone = 10 * math.Pi / 180 // radians the fingers turn before the direction follows
+	touchSpeedGain     = 1.0                // px/s of speed per pixel the fingers spread
+	touchBPMGain       = 0.25               // BPM per pixel the fingers spread, in tempo mode
+)
+
+// touchState tracks the finger that acts as the pointer and an ongoing pinch.
+type touchState struct {
+	id         ebiten.TouchID
+	active     bool      // a finger is acting as the pointer
+	pos        geom.Vec2 // last position of that finger
+	pinching   bool
+	pinchMid   geom.Vec2 // midpoint of the two pinching fingers last frame
+	pinchLen   float64   // distance between them last frame
+	pinchAng   float64   // angle from the first finger to the second last frame
+	twist      float64   // angle the fingers have turned since the pinch began
+	pinchSpeed bool      // spreading the fingers changes the speed instead of zooming
+}
+
+// readPointer returns the pointer for this frame. While a finger is down it
+// takes precedence over the mouse.
+func (g *Game) readPointer() pointer {
+	ts := &g.touch
+	if ts.active {
+		t, ok := g.in.touch(ts.id)
+		if ok && t.Released {
+			ts.active = false
+			return pointer{Pos: ts.pos, JustReleased: true}
+		}
+		if ok {
+			ts.pos = t.Pos
+		}
+		return pointer{Pos: ts.pos}
+	}
+	for _, t := range g.in.touching() {
+		if t.Pressed {
+			ts.id, ts.active, ts.pos = t.ID, true, t.Pos
+			return pointer{Pos: ts.pos, JustPressed: true}
+		}
+	}
+	return pointer{
+		Pos:          g.in.Cursor,
+		JustPressed:  g.in.MouseJustPressed(ebiten.MouseButtonLeft),
+		JustReleased: g.in.MouseJustReleased(ebiten.MouseButtonLeft),
+	}
+}
+
+// updatePinch handles the gestures of two fingers or more: they pan and zoom
+// the camera, like the middle mouse button and wheel, turn the movement
+// direction and, switched over by a three-finger tap, change the speed. It
+// reports whether a pinch is in progress.
+func (g *Game) updatePinch() bool {
+	ts := &g.touch
+	fingers := g.in.touching()
+	if len(fingers) < 2 {
+		ts.pinching = false
+		return false
+	}
+	if len(fingers) >= 3 && slices.ContainsFunc(fingers, func(t TouchInput) bool { return t.Pressed }) {
+		ts.pinchSpeed = !ts.pinchSpeed
+	}
+	a, b := fingers[0].Pos, fingers[1].Pos
+	mid := a.Add(b).Mul(0.5)
+	d := b.Sub(a)
+	l, ang := d.Len(), math.Atan2(d.Y, d.X)
+	if !ts.pinching {
+		ts.twist = 0
+	} else if ts.pinchLen > 0 && l > 0 {
+		g.cam.Pan(mid.Sub(ts.pinchMid))
+		if !ts.pinchSpeed {
+			g.cam.ZoomAt(mid, l/ts.pinchLen)
+		}
+		// The direction follows the part of the turn past the dead zone
+		da := math.Remainder(ang-ts.pinchAng, 2*math.Pi)
+		before := ts.twist
+		ts.twist += da
+		turn := excess(ts.twist, touchTwistDeadZone) - excess(before, touchTwistDeadZone)
+		spread := 0.0
+		if ts.pinchSpeed {
+			spread = (l - ts.pinchLen) / g.cam.Device()
+		}
+		g.pinchMotion(turn, spread)
+	}
+	ts.pinching, ts.pinchMid, ts.pinchLen, ts.pinchAng = true, mid, l, ang
+	return true
+}
+
+// excess returns how far v lies outside -zone..zone, signed.
+func excess(v, zone float64) float64 {
+	switch {
+	case v > zone:
+		return v - zone
+	case v < -zone:
+		return v + zone
+	}
+	return 0
+}
+
+// pinchMotion turns the movement direction by da radians (clockwise on
+// screen) and changes the speed (or the BPM in tempo mode) for fingers that
+// spread by spread pixels, acting on the selected grid's own motion when it
+// has one.
+func (g *Game) pinchMotion(da, spread float64) {
+	dir, speed := &g.MoveDir, &g.Speed
+	own := g.selectedMotion()
+	if own != nil {
+		dir, speed = &own.Dir, &own.Speed
+	}
+	if da != 0 {
+		a := math.Atan2(dir.Y, dir.X) + da
+		*dir = geom.Vec2{X: math.Cos(a), Y: math.Sin(a)}
+	}
+	switch {
+	case spread == 0:
+	case own == nil && g.TempoMode:
+		g.Clock.SetBPM(g.Clock.BPM + spread*touchBPMGain)
+	default:
+		*speed = math.Max(0, *speed+spread*touchSpeedGain)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"grythm/engine"
+	"grythm/synth"
+)
+
+// intersectionNote returns the note of an intersection: the degrees of both
+// families (and of the point) added, an octave above the regular voices so
+// it stands out from the line triggers.
+func (g *Game) intersectionNote(x engine.IntersectionEvent) int {
+	deg := g.Grids[x.A].Degree + g.Grids[x.B].Degree
+	if x.Point >= 0 {
+		deg += g.Points[x.Point].Degree
+	}
+	return g.Scale.Note(g.Key, deg) + 12
+}
+
+// playIntersection sounds an intersection as a bright triangle pluck.
+func (g *Game) playIntersection(x engine.IntersectionEvent) {
+	g.playBlip(synth.Voice{
+		Freq: synth.MIDIToFreq(g.intersectionNote(x)),
+		Wave: synth.WaveTriangle,
+		Env:  synth.EnvelopePresets[1].Env,
+	}, x.Velocity, synth.ResolveSends(g.Grids[x.A].Sends, g.sends), synth.NoteGate{At: g.soundFrame(x.Time, x.At)})
+}
+
+// showIntersection bursts particles where the lines crossed.
+func (g *Game) showIntersection(x engine.IntersectionEvent) {
+	g.particles.Burst(x.Pos, g.theme.Intersect, 6+int(12*x.Velocity))
+}
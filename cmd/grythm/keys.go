@@ -0,0 +1,524 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Key bindings. Every keyboard action has a name and default keys, and a
+// bindings file maps names to other keys:
+//
+//	rotate-left = "Numpad4"
+//	speed-up = ["ArrowUp", "Numpad8"]
+//	save = "Ctrl+Shift+S"
+//	record = ""              # unbound
+//
+// A binding is a key (as Ebiten names it) with the modifiers (Ctrl, Shift,
+// Alt) that must be held. Most actions fire only with exactly those
+// modifiers held, so W and Shift+W can do different things; the steering
+// and rotating ones fire whatever else is held, since Shift changes how
+// they act (it snaps, or takes coarse steps). The text entry keys (digits,
+// Backspace, Enter, Escape) and Ctrl for snapping points are not bindable.
+
+// action is something the keyboard does.
+type action int
+
+const (
+	actRotateLeft action = iota
+	actRotateRight
+	actSpeedUp
+	actSpeedDown
+	actViewReset
+	actTempoMode
+	actAddPoint
+	actSelectGrid
+	actSelectFamily
+	actSelectAll
+	actSelectNone
+	actDelete
+	actCopy
+	actCut
+	actPaste
+	actPasteOffset
+	actWave
+	actGridDrum
+	actFM
+	actDegreeUp
+	actDegreeDown
+	actKeyUp
+	actKeyDown
+	actScale
+	actLifespan
+	actSave
+	actDuplicateGrid
+	actHexTiling
+	actEnvelope
+	actGridGlide
+	actGridFilter
+	actGridCutoff
+	actChance
+	actCooldown
+	actQuantize
+	actSwing
+	actArpeggio
+	actArpeggioDirection
+	actGridAccent
+	actGridAnimation
+	actNoteLength
+	actRetrigger
+	actPointGroup
+	actSelectGroup
+	actMute
+	actMetronome
+	actGainUp
+	actGainDown
+	actLimiter
+	actSolo
+	actIgnoreGrid
+	actSymmetry
+	actPitchMap
+	actChord
+	actIntersections
+	actLoop
+	actLoopLength
+	actBrush
+	actBrushSize
+	actTrails
+	actSnapshot
+	actTheme
+	actFullscreen
+	actTimeline
+	actTiling
+	actStats
+	actStatsReset
+	actProfile
+	actProfileDock
+	actHelp
+	actMIDILearn
+	actRecord
+	actCapture
+	actBounce
+	actGridBlend
+	actGridEdge
+	actGridGaps
+	actSetlistNext
+	actSetlistPrevious
+	actUndo
+	actRedo
+	actPath
+	actPathSpeed
+	actGridMotion
+	actGridOpacity
+	actGridWidth
+	actGridBand
+	actGridVolume
+	actMorphBack
+	actMorphForward
+	actGridLayer
+	actLayerTurnLeft
+	actLayerTurnRight
+	actLayerSlower
+	actLayerFaster
+	actPhaseForward
+	actPhaseBack
+	actPhaseFraction
+	actPhaseReset
+	actGridPause
+	actGridReverse
+	actGridSlower
+	actGridFaster
+	actRotateGridLeft
+	actRotateGridRight
+	actGridAngle
+	actGridSpacing
+	actStar
+	actDirection
+	actPolyrhythm
+	actSlot1 // actSlot1+i loads slot i+1
+	actSlot2
+	actSlot3
+	actSlot4
+	actSlot5
+	actSlot6
+	actSlot7
+	actSlot8
+	actSlot9
+	actSaveSlot1 // actSaveSlot1+i saves slot i+1
+	actSaveSlot2
+	actSaveSlot3
+	actSaveSlot4
+	actSaveSlot5
+	actSaveSlot6
+	actSaveSlot7
+	actSaveSlot8
+	actSaveSlot9
+	actionCount
+)
+
+// actions names every action, with its default keys (comma separated) and
+// whether it fires whatever modifiers are held.
+var actions = [actionCount]struct {
+	name  string
+	keys  string
+	loose bool
+}{
+	actRotateLeft:        {"rotate-left", "ArrowLeft", true},
+	actRotateRight:       {"rotate-right", "ArrowRight", true},
+	actSpeedUp:           {"speed-up", "ArrowUp", true},
+	actSpeedDown:         {"speed-down", "ArrowDown", true},
+	actViewReset:         {"view-reset", "Home", false},
+	actTempoMode:         {"tempo-mode", "T", false},
+	actAddPoint:          {"add-point", "Insert", false},
+	actSelectGrid:        {"select-grid", "Tab", false},
+	actSelectFamily:      {"select-family", "Shift+Tab", false},
+	actSelectAll:         {"select-all", "Ctrl+A", false},
+	actSelectNone:        {"select-none", "Escape", false},
+	actDelete:            {"delete", "Delete", false},
+	actCopy:              {"copy", "Ctrl+C", false},
+	actCut:               {"cut", "Ctrl+X", false},
+	actPaste:             {"paste", "Ctrl+V", false},
+	actPasteOffset:       {"paste-offset", "Ctrl+Shift+V", false},
+	actWave:              {"wave", "W", false},
+	actGridDrum:          {"grid-drum", "Shift+W", false},
+	actFM:                {"fm", "Alt+W", false},
+	actDegreeUp:          {"degree-up", "BracketRight", false},
+	actDegreeDown:        {"degree-down", "BracketLeft", false},
+	actKeyUp:             {"key-up", "K", false},
+	actKeyDown:           {"key-down", "Shift+K", false},
+	actScale:             {"scale", "L", false},
+	actLifespan:          {"lifespan", "Shift+L", false},
+	actSave:              {"save", "Ctrl+S", false},
+	actDuplicateGrid:     {"duplicate-grid", "Ctrl+D", false},
+	actHexTiling:         {"hex-tiling", "H", false},
+	actEnvelope:          {"envelope", "E", false},
+	actGridGlide:         {"grid-glide", "Shift+E", false},
+	actGridFilter:        {"grid-filter", "F", false},
+	actGridCutoff:        {"grid-cutoff", "Shift+F", false},
+	actChance:            {"chance", "C", false},
+	actCooldown:          {"cooldown", "Alt+C", false},
+	actQuantize:          {"quantize", "Z", false},
+	actSwing:             {"swing", "Alt+Z", false},
+	actArpeggio:          {"arpeggio", "Shift+Z", false},
+	actArpeggioDirection: {"arpeggio-direction", "Semicolon", false},
+	actGridAccent:        {"grid-accent", "V", false},
+	actGridAnimation:     {"grid-animation", "Shift+V", false},
+	actNoteLength:        {"note-length", "N", false},
+	actRetrigger:         {"retrigger", "Shift+N", false},
+	actPointGroup:        {"point-group", "G", false},
+	actSelectGroup:       {"select-group", "Shift+G", false},
+	actMute:              {"mute", "M", false},
+	actMetronome:         {"metronome", "Shift+M", false},
+	actGainUp:            {"gain-up", "Ctrl+Equal", false},
+	actGainDown:          {"gain-down", "Ctrl+Minus", false},
+	actLimiter:           {"limiter", "Ctrl+L", false},
+	actSolo:              {"solo", "S", false},
+	actIgnoreGrid:        {"ignore-grid", "X", false},
+	actSymmetry:          {"symmetry", "Shift+X", false},
+	actPitchMap:          {"pitch-map", "P", false},
+	actChord:             {"chord", "Shift+P", false},
+	actIntersections:     {"intersections", "I", false},
+	actLoop:              {"loop", "U", false},
+	actLoopLength:        {"loop-length", "Shift+U", false},
+	actBrush:             {"brush", "Q", false},
+	actBrushSize:         {"brush-size", "Shift+Q", false},
+	actTrails:            {"trails", "F3", false},
+	actSnapshot:          {"snapshot", "F12", false},
+	actTheme:             {"theme", "F5", false},
+	actFullscreen:        {"fullscreen", "F11", false},
+	actTimeline:          {"timeline", "F2", false},
+	actTiling:            {"tiling", "F7", false},
+	actStats:             {"stats", "F6", false},
+	actStatsReset:        {"stats-reset", "Shift+F6", false},
+	actProfile:           {"profile", "F1", false},
+	actProfileDock:       {"profile-dock", "Shift+F1", false},
+	actHelp:              {"help", "Shift+Slash", false},
+	actMIDILearn:         {"midi-learn", "F4", false},
+	actRecord:            {"record", "R", false},
+	actCapture:           {"capture", "Shift+R", false},
+	actBounce:            {"bounce", "B", false},
+	actGridBlend:         {"grid-blend", "Shift+B", false},
+	actGridEdge:          {"grid-edge", "Alt+E", false},
+	actGridGaps:          {"grid-gaps", "Alt+D", false},
+	actSetlistNext:       {"setlist-next", "PageDown", false},
+	actSetlistPrevious:   {"setlist-previous", "PageUp", false},
+	actUndo:              {"undo", "Ctrl+Z", false},
+	actRedo:              {"redo", "Ctrl+Shift+Z", false},
+	actPath:              {"path", "J", false},
+	actPathSpeed:         {"path-speed", "Shift+J", false},
+	actGridMotion:        {"grid-motion", "O", false},
+	actGridOpacity:       {"grid-opacity", "Shift+O", false},
+	actGridWidth:         {"grid-width", "Alt+B", false},
+	actGridBand:          {"grid-band", "Alt+T", false},
+	actGridVolume:        {"grid-volume", "Alt+V", false},
+	actMorphBack:         {"morph-back", "Alt+Minus", false},
+	actMorphForward:      {"morph-forward", "Alt+Equal", false},
+	actGridLayer:         {"grid-layer", "Alt+G", false},
+	actLayerTurnLeft:     {"turn-layer-left", "Alt+Comma", true},
+	actLayerTurnRight:    {"turn-layer-right", "Alt+Period", true},
+	actLayerSlower:       {"layer-slower", "Shift+Minus", false},
+	actLayerFaster:       {"layer-faster", "Shift+Equal", false},
+	actPhaseForward:      {"phase-forward", "Quote", false},
+	actPhaseBack:         {"phase-back", "Shift+Quote", false},
+	actPhaseFraction:     {"phase-fraction", "Backslash", false},
+	actPhaseReset:        {"phase-reset", "Shift+Backslash", false},
+	actGridPause:         {"grid-pause", "Space", false},
+	actGridReverse:       {"grid-reverse", "Slash", false},
+	actGridSlower:        {"grid-slower", "Minus", false},
+	actGridFaster:        {"grid-faster", "Equal", false},
+	actRotateGridLeft:    {"rotate-grid-left", "Comma", true},
+	actRotateGridRight:   {"rotate-grid-right", "Period", true},
+	actGridAngle:         {"grid-angle", "A", false},
+	actGridSpacing:       {"grid-spacing", "Shift+A", false},
+	actStar:              {"star", "Shift+D", false},
+	actDirection:         {"direction", "D", false},
+	actPolyrhythm:        {"polyrhythm", "Y", false},
+	actSlot1:             {"slot-1", "Digit1", false},
+	actSlot2:             {"slot-2", "Digit2", false},
+	actSlot3:             {"slot-3", "Digit3", false},
+	actSlot4:             {"slot-4", "Digit4", false},
+	actSlot5:             {"slot-5", "Digit5", false},
+	actSlot6:             {"slot-6", "Digit6", false},
+	actSlot7:             {"slot-7", "Digit7", false},
+	actSlot8:             {"slot-8", "Digit8", false},
+	actSlot9:             {"slot-9", "Digit9", false},
+	actSaveSlot1:         {"save-slot-1", "Ctrl+Digit1", false},
+	actSaveSlot2:         {"save-slot-2", "Ctrl+Digit2", false},
+	actSaveSlot3:         {"save-slot-3", "Ctrl+Digit3", false},
+	actSaveSlot4:         {"save-slot-4", "Ctrl+Digit4", false},
+	actSaveSlot5:         {"save-slot-5", "Ctrl+Digit5", false},
+	actSaveSlot6:         {"save-slot-6", "Ctrl+Digit6", false},
+	actSaveSlot7:         {"save-slot-7", "Ctrl+Digit7", false},
+	actSaveSlot8:         {"save-slot-8", "Ctrl+Digit8", false},
+	actSaveSlot9:         {"save-slot-9", "Ctrl+Digit9", false},
+}
+
+// actionNamed returns the action called name.
+func actionNamed(name string) (action, bool) {
+	for a := range actions {
+		if actions[a].name == name {
+			return action(a), true
+		}
+	}
+	return 0, false
+}
+
+// keyBinding is a key and the modifiers held with it.
+type keyBinding struct {
+	key              ebiten.Key
+	ctrl, shift, alt bool
+}
+
+// modifierKeys can only be held with a key, not bound on their own.
+var modifierKeys = []ebiten.Key{
+	ebiten.KeyControl, ebiten.KeyControlLeft, ebiten.KeyControlRight,
+	ebiten.KeyShift, ebiten.KeyShiftLeft, ebiten.KeyShiftRight,
+	ebiten.KeyAlt, ebiten.KeyAltLeft, ebiten.KeyAltRight,
+}
+
+// parseBinding reads a binding such as "W", "Shift+W" or "Ctrl+Shift+Z".
+func parseBinding(s string) (keyBinding, error) {
+	var b keyBinding
+	parts := strings.Split(s, "+")
+	for _, m := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(m)) {
+		case "ctrl", "control":
+			b.ctrl = true
+		case "shift":
+			b.shift = true
+		case "alt":
+			b.alt = true
+		default:
+			return b, fmt.Errorf("key %q: unknown modifier %q", s, m)
+		}
+	}
+	name := strings.TrimSpace(parts[len(parts)-1])
+	if err := b.key.UnmarshalText([]byte(name)); err != nil {
+		return b, fmt.Errorf("key %q: unknown key %q", s, name)
+	}
+	if slices.Contains(modifierKeys, b.key) {
+		return b, fmt.Errorf("key %q: a modifier can't be bound on its own", s)
+	}
+	return b, nil
+}
+
+// String formats the binding as parseBinding reads it.
+func (b keyBinding) String() string {
+	s := ""
+	if b.ctrl {
+		s += "Ctrl+"
+	}
+	if b.shift {
+		s += "Shift+"
+	}
+	if b.alt {
+		s += "Alt+"
+	}
+	return s + b.key.String()
+}
+
+// Keymap holds the bindings of every action.
+type Keymap [actionCount][]keyBinding
+
+// DefaultKeymap returns the built-in bindings.
+func DefaultKeymap() Keymap {
+	var km Keymap
+	for a := range actions {
+		km[a], _ = parseBindings(actions[a].keys)
+	}
+	return km
+}
+
+// parseBindings reads a comma separated list of bindings; "" is none.
+func parseBindings(s string) ([]keyBinding, error) {
+	var out []keyBinding
+	for _, k := range strings.Split(s, ",") {
+		if strings.TrimSpace(k) == "" {
+			continue
+		}
+		b, err := parseBinding(k)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// LoadKeymap reads the bindings file at path over the defaults. A missing
+// file leaves the defaults, unless it was named explicitly.
+func LoadKeymap(path string, explicit bool) (Keymap, error) {
+	km := DefaultKeymap()
+	if path == "" || !hasFileSystem {
+		return km, nil
+	}
+	var file map[string]any
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return km, nil
+		}
+		return km, fmt.Errorf("bindings: %w", err)
+	}
+	for name, v := range file {
+		a, ok := actionNamed(name)
+		if !ok {
+			return km, fmt.Errorf("bindings %s: unknown action %q", path, name)
+		}
+		var keys []string
+		switch v := v.(type) {
+		case string:
+			keys = []string{v}
+		case []any:
+			for _, k := range v {
+				s, ok := k.(string)
+				if !ok {
+					return km, fmt.Errorf("bindings %s: %s: keys must be strings", path, name)
+				}
+				keys = append(keys, s)
+			}
+		default:
+			return km, fmt.Errorf("bindings %s: %s: want a key or a list of keys", path, name)
+		}
+		bs, err := parseBindings(strings.Join(keys, ","))
+		if err != nil {
+			return km, fmt.Errorf("bindings %s: %s: %w", path, name, err)
+		}
+		km[a] = bs
+	}
+	return km, km.check()
+}
+
+// check reports a key bound to two actions that would both fire on it.
+func (km *Keymap) check() error {
+	for a := range km {
+		for b := a + 1; b < len(km); b++ {
+			for _, x := range km[a] {
+				for _, y := range km[b] {
+					if x.key == y.key && (x == y || actions[a].loose || actions[b].loose) {
+						return fmt.Errorf("bindings: %s is bound to both %s and %s", x, actions[a].name, actions[b].name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// changed lists the actions bound differently from the defaults, as
+// name=keys, for the HUD.
+func (km *Keymap) changed() []string {
+	def := DefaultKeymap()
+	var out []string
+	for a := range km {
+		if slices.Equal(km[a], def[a]) {
+			continue
+		}
+		keys := make([]string, len(km[a]))
+		for i, b := range km[a] {
+			keys[i] = b.String()
+		}
+		out = append(out, actions[a].name+"="+strings.Join(keys, ","))
+	}
+	return out
+}
+
+// bindingsPath returns the bindings file to read: the configured one, or
+// bindings.toml next to the default config file.
+func bindingsPath(cfg Config) (path string, explicit bool) {
+	if cfg.Bindings != "" {
+		return cfg.Bindings, true
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, "grythm", "bindings.toml"), false
+}
+
+// modifiers reports whether the modifiers of binding b are held, exactly
+// unless the action is loose.
+func (g *Game) modifiers(b keyBinding, loose bool) bool {
+	ctrl, shift, alt := g.in.KeyPressed(ebiten.KeyControl), g.in.KeyPressed(ebiten.KeyShift), g.in.KeyPressed(ebiten.KeyAlt)
+	if loose {
+		return (ctrl || !b.ctrl) && (shift || !b.shift) && (alt || !b.alt)
+	}
+	return ctrl == b.ctrl && shift == b.shift && alt == b.alt
+}
+
+// pressed reports whether action a was pressed this frame.
+func (g *Game) pressed(a action) bool {
+	for _, b := range g.keys[a] {
+		if g.in.KeyJustPressed(b.key) && g.modifiers(b, actions[a].loose) {
+			return true
+		}
+	}
+	return false
+}
+
+// held reports whether a key of action a is held down.
+func (g *Game) held(a action) bool {
+	for _, b := range g.keys[a] {
+		if g.in.KeyPressed(b.key) && g.modifiers(b, actions[a].loose) {
+			return true
+		}
+	}
+	return false
+}
+
+// step returns +1 if action up was just pressed, -1 if down was, else 0.
+func (g *Game) step(up, down action) int {
+	switch {
+	case g.pressed(up):
+		return 1
+	case g.pressed(down):
+		return -1
+	}
+	return 0
+}
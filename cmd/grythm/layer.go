@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Grid layers (see engine.Layer). Alt+G puts the selected grid in the next
+// layer (or in none after the last), and the selected grid's layer is then
+// handled as one: Alt+, and Alt+. turn it by a degree (Shift snaps),
+// Shift+- and Shift+= halve and double its speed and Alt+drag moves it. The
+// families keep their own settings underneath, so a lattice built from
+// several of them turns and retimes without coming apart, and a grid put in
+// a layer that was turned or moved is turned and moved with it. Every change
+// is an undo step.
+
+// layerCmd puts grid family idx from layer from in layer to, giving it the
+// new layer's transform in place of the old one's (see engine.SetLayer).
+type layerCmd struct {
+	idx, from, to int
+}
+
+func (c layerCmd) Do(g *Game)   { g.SetLayer(c.idx, c.to) }
+func (c layerCmd) Undo(g *Game) { g.SetLayer(c.idx, c.from) }
+
+// layerTurnCmd turns layer n by deg degrees.
+type layerTurnCmd struct {
+	n   int
+	deg float64
+}
+
+func (c layerTurnCmd) Do(g *Game)   { g.TurnLayer(c.n, c.deg) }
+func (c layerTurnCmd) Undo(g *Game) { g.TurnLayer(c.n, -c.deg) }
+
+// layerMoveCmd moves layer n by d.
+type layerMoveCmd struct {
+	n int
+	d geom.Vec2
+}
+
+func (c layerMoveCmd) Do(g *Game)   { g.MoveLayer(c.n, c.d) }
+func (c layerMoveCmd) Undo(g *Game) { g.MoveLayer(c.n, c.d.Mul(-1)) }
+
+// layerRateCmd sets the rate of layer n.
+type layerRateCmd struct {
+	n        int
+	from, to float64
+}
+
+func (c layerRateCmd) Do(g *Game)   { g.Layers[c.n-1].Rate = c.to }
+func (c layerRateCmd) Undo(g *Game) { g.Layers[c.n-1].Rate = c.from }
+
+// layerDrag is an Alt+drag of a layer in progress.
+type layerDrag struct {
+	n     int       // the layer held, from 1; 0 when none is
+	last  geom.Vec2 // the cursor in the world at the last frame
+	moved geom.Vec2 // how far it has been moved since the press
+}
+
+// selLayer returns the layer of the selected grid, or 0.
+func (g *Game) selLayer() int {
+	if g.selGrid >= len(g.Grids) {
+		return 0
+	}
+	return g.Grids[g.selGrid].Layer
+}
+
+// layerKeys handles the keys that put the selected grid in a layer and
+// retime its layer.
+func (g *Game) layerKeys() {
+	if g.selGrid >= len(g.Grids) {
+		return
+	}
+	if g.pressed(actGridLayer) {
+		from := g.Grids[g.selGrid].Layer
+		g.exec(layerCmd{idx: g.selGrid, from: from, to: (from + 1) % (engine.MaxLayers + 1)})
+	}
+	n := g.selLayer()
+	if n == 0 {
+		return
+	}
+	from := g.Layers[n-1].Rate
+	rate := from
+	switch {
+	case g.pressed(actLayerSlower):
+		rate = geom.ScaleRate(rate, 0.5)
+	case g.pressed(actLayerFaster):
+		rate = geom.ScaleRate(rate, 2)
+	}
+	if rate == 1 {
+		rate = 0
+	}
+	if rate != from {
+		g.exec(layerRateCmd{n: n, from: from, to: rate})
+	}
+}
+
+// turnLayerKeys turns the selected grid's layer with turn-layer-left/right
+// (Alt+, and Alt+.) by one degree per press, or to the next multiple of
+// snapDegrees with Shift, reporting whether one of them was pressed.
+func (g *Game) turnLayerKeys() bool {
+	d := g.step(actLayerTurnRight, actLayerTurnLeft)
+	if d == 0 {
+		return false
+	}
+	if n := g.selLayer(); n > 0 {
+		deg := float64(d)
+		if g.in.KeyPressed(ebiten.KeyShift) {
+			a := g.Layers[n-1].Angle
+			deg = snapAngle(a, d) - a
+		}
+		g.exec(layerTurnCmd{n: n, deg: deg})
+	}
+	return true
+}
+
+// grabLayer starts moving the selected grid's layer on an Alt+press at world
+// position p, reporting whether it did.
+func (g *Game) grabLayer(p geom.Vec2) bool {
+	n := g.selLayer()
+	if n == 0 || !g.in.KeyPressed(ebiten.KeyAlt) {
+		return false
+	}
+	g.layerDrag = layerDrag{n: n, last: p}
+	return true
+}
+
+// updateLayerDrag moves the held layer with the mouse and records the move
+// on release.
+func (g *Game) updateLayerDrag(mouse geom.Vec2, ptr pointer) {
+	ld := &g.layerDrag
+	if ld.n == 0 {
+		return
+	}
+	d := mouse.Sub(ld.last)
+	g.MoveLayer(ld.n, d)
+	ld.moved = ld.moved.Add(d)
+	ld.last = mouse
+	if !ptr.JustReleased {
+		return
+	}
+	if ld.moved != (geom.Vec2{}) {
+		g.record(layerMoveCmd{n: ld.n, d: ld.moved})
+	}
+	*ld = layerDrag{}
+}
+
+// layerLabel describes the selected grid's layer, which it must have, for the
+// status line.
+func (g *Game) layerLabel() string {
+	n := g.selLayer()
+	members := 0
+	for _, gf := range g.Grids {
+		if gf.Layer == n {
+			members++
+		}
+	}
+	return fmt.Sprintf("%s, %d grids", g.LayerLabel(n), members)
+}
@@ -0,0 +1,47 @@
+package main
+
+import "grythm/geom"
+
+// resize adapts the scene to a new window size. The grid anchor is the window
+// center, so the world is moved along with it: by default everything is
+// translated so the arrangement (and its rhythm) stays intact and centered;
+// with rescalePoints set, points and ring origins are instead scaled
+// proportionally to the new window size.
+func (g *Game) resize(w, h int) {
+	if w <= 0 || h <= 0 || (w == g.W && h == g.H) {
+		return
+	}
+	oldW, oldH := float64(g.W), float64(g.H)
+	g.W, g.H = w, h
+	g.cam.Screen = geom.Vec2{X: float64(w), Y: float64(h)}
+
+	var move func(geom.Vec2) geom.Vec2
+	if g.rescalePoints {
+		sx, sy := float64(w)/oldW, float64(h)/oldH
+		move = func(p geom.Vec2) geom.Vec2 { return geom.Vec2{X: p.X * sx, Y: p.Y * sy} }
+	} else {
+		delta := geom.Vec2{X: (float64(w) - oldW) / 2, Y: (float64(h) - oldH) / 2}
+		move = func(p geom.Vec2) geom.Vec2 { return p.Add(delta) }
+	}
+	for i := range g.Points {
+		p := &g.Points[i]
+		to := move(p.Pos)
+		if p.Path.Moves() {
+			// Paths are only translated, so their shapes stay the same
+			p.Path.Translate(to.Sub(p.Pos))
+		}
+		p.Pos = to
+	}
+	for i := range g.Grids {
+		if k := g.Grids[i].Kind; k == geom.GridRadial || k == geom.GridRay {
+			g.Grids[i].Origin = move(g.Grids[i].Origin)
+		}
+	}
+	for i := range g.particles.list {
+		g.particles.list[i].Pos = move(g.particles.list[i].Pos)
+	}
+	g.cam.Center = move(g.cam.Center)
+	g.Index.Invalidate()
+	// Undo history holds absolute positions from before the resize
+	g.history = History{}
+}
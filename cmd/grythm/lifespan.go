@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Ephemeral points (see engine/lifespan.go). Points placed by hand take the
+// lifespan set with Shift+L (or -point-life), so clicks can add accents that
+// play a few times and go instead of permanent notes. Shift+L over a point
+// changes that point's lifespan. A point that has faded out is removed like
+// a click removes it, so undo brings it back for another life.
+
+// lifeSteps are the lifespans Shift+L cycles through; forever comes first.
+var lifeSteps = []engine.Lifespan{
+	{},
+	{Triggers: 1},
+	{Triggers: 2},
+	{Triggers: 4},
+	{Triggers: 8},
+	{Seconds: 2},
+	{Seconds: 4},
+	{Seconds: 8},
+}
+
+// nextLife returns the step after the lifespan l was placed with.
+func nextLife(l engine.Lifespan) engine.Lifespan {
+	l = l.Renewed()
+	for i, s := range lifeSteps {
+		if s == l {
+			return lifeSteps[(i+1)%len(lifeSteps)]
+		}
+	}
+	return lifeSteps[0]
+}
+
+// lifeLabel formats a lifespan for the HUD.
+func lifeLabel(l engine.Lifespan) string {
+	switch {
+	case !l.Mortal():
+		return "forever"
+	case l.Expired():
+		return "over"
+	}
+	var s string
+	if l.Triggers > 0 {
+		s = fmt.Sprintf("%d/%d triggers", l.Hits, l.Triggers)
+	}
+	if l.Seconds > 0 {
+		if s != "" {
+			s += ", "
+		}
+		s += fmt.Sprintf("%.1f/%gs", l.Age, l.Seconds)
+	}
+	return s
+}
+
+// newPoint returns a point placed by hand at pos with degree deg, living as
+// long as new points do.
+func (g *Game) newPoint(pos geom.Vec2, deg int) engine.Point {
+	return engine.Point{Pos: pos, Degree: deg, Life: g.newLife}
+}
+
+// despawn removes the points that have faded out, except one being dragged
+// and its mirror copies.
+func (g *Game) despawn() {
+	for i := len(g.Points) - 1; i >= 0; i-- {
+		if !g.Points[i].Life.Over() {
+			continue
+		}
+		if g.dragIdx >= 0 && (i == g.dragIdx || g.Points[i].Mirrors(g.Points[g.dragIdx])) {
+			continue
+		}
+		p := g.Points[i]
+		p.Life = p.Life.Renewed()
+		g.exec(removePointCmd{idx: i, p: p})
+		switch {
+		case g.hoverIdx == i:
+			g.hoverIdx = -1
+		case g.hoverIdx > i:
+			g.hoverIdx--
+		}
+		if g.dragIdx > i {
+			g.dragIdx--
+		}
+		for k := range g.dragCopies {
+			if g.dragCopies[k].idx > i {
+				g.dragCopies[k].idx--
+			}
+		}
+	}
+}
+
+// fade returns c (premultiplied) at level times its opacity.
+func fade(c color.RGBA, level float64) color.RGBA {
+	if level >= 1 {
+		return c
+	}
+	return color.RGBA{uint8(float64(c.R) * level), uint8(float64(c.G) * level), uint8(float64(c.B) * level), uint8(float64(c.A) * level)}
+}
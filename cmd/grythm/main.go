@@ -0,0 +1,1644 @@
+// Command grythm is the grid rhythm visualizer: an Ebiten game around the
+// simulation in package engine.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/engine"
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Game holds the entire app state: the simulated scene (see package engine)
+// and everything around it.
+type Game struct {
+	*engine.Engine
+
+	// the palette everything is drawn in (see theme.go), and the scratch
+	// layer text is printed onto to tint it
+	theme     *Theme
+	textLayer *ebiten.Image
+
+	// particle bursts spawned by triggers, tinted with the triggering grid's color
+	particles Particles
+
+	// trails mode (F3): grids and particles accumulate on an offscreen image
+	// that fades by trailFade per frame instead of being cleared
+	trails    bool
+	trailFade float64
+	trail     *ebiten.Image
+
+	// tiling mode (F7): the cells between the lines are filled and light up
+	// on triggers (see tiling.go)
+	tiling bool
+	cells  []litCell
+
+	// randomness of the host (scatter brush), seeded from the engine's seed
+	// so that a replayed session scatters the same points
+	rng *rand.Rand
+
+	// the recent triggers for the timeline strip (F2)
+	events       *EventLog
+	showTimeline bool
+
+	// the trigger statistics overlay (F6)
+	showStats bool
+	// frame timings and the debug overlay (F1, see profile.go)
+	prof profiler
+	// every key listed above the status (?, see help.go)
+	showHelp bool
+
+	// scale points with the window on resize instead of keeping them centered
+	rescalePoints bool
+
+	// view transform; points and grids live in world coordinates
+	cam     geom.Camera
+	panning bool
+	panLast geom.Vec2 // cursor position of the previous frame while panning
+	touch   touchState
+
+	// gamepad edit cursor (world coordinates), shown while a pad is connected
+	padActive    bool
+	padCursor    geom.Vec2
+	padCursorSet bool
+
+	// window state saved to the config file on quit (see window.go); unset
+	// until the window has been seen
+	window     windowState
+	windowSeen bool
+
+	// hover/click state
+	hoverIdx int // -1 if none hovered
+
+	// editor: grid family targeted by per-grid keyboard edits
+	selGrid int
+
+	// point brushes (see brush.go): the active tool and a stroke in progress
+	brush brushState
+
+	// placement preview under the cursor (see ghost.go)
+	ghost ghostState
+
+	// point groups: the group targeted by M/S when no point is hovered
+	selGroup string
+
+	// drag state: a press on a point grabs it; release without moving removes it
+	dragIdx    int            // -1 if no point is held
+	dragMoved  bool           // whether the held point has been moved since the press
+	dragFrom   geom.Vec2      // cursor position at press
+	dragGrab   geom.Vec2      // offset from cursor to the point's position
+	dragOrig   engine.Point   // the held point as it was at press, for undo
+	dragCopies []editPointCmd // its mirror copies (or the rest of the selection) as they were at press, for undo
+	dragGroup  bool           // whether the held point drags the rest of the selection
+
+	// selected points and families, the clipboard and the rubber band being
+	// dragged (see selection.go)
+	sel  selection
+	clip clipboard
+	band rubberBand
+	// drag of the pitch map's tonal center (see pitchmap.go)
+	tonic tonicDrag
+	// the scenes morphed between (see morph.go)
+	morph morph
+	// Alt+drag of the selected grid's layer (see layer.go)
+	layerDrag layerDrag
+
+	// mirroring of points placed by hand (see symmetry.go)
+	symmetry geom.Symmetry
+
+	// undo/redo of edits
+	history History
+
+	// audio; sends are the effect levels for grids without their own
+	sends          synth.Sends
+	audioCtx       *audio.Context
+	mixer          *synth.Mixer
+	player         *audio.Player             // single streaming player reading from mixer
+	outMeter       outputMeter               // output level shown in the HUD (see output.go)
+	blips          map[synth.Voice][]float32 // rendered notes per voice
+	samples        map[string][]float32      // decoded sample files by path; nil if decoding failed
+	blipSampleRate int
+	audioBuffer    float64 // seconds of audio the player buffers
+
+	// scene file used for Ctrl+S, and the directory of user preset slots
+	scenePath string
+	presetDir string
+	// merging of the scene file when it changes on disk (see scenemerge.go)
+	watch sceneWatch
+	// rolling backups of the scene and the recovery offer (see autosave.go)
+	autosave autosaver
+
+	// scenes of a performance switched with PageUp/PageDown (see setlist.go);
+	// nil without a setlist
+	setlist *Setlist
+	fade    crossfade
+
+	// lifespan of points placed by hand (see lifespan.go)
+	newLife engine.Lifespan
+
+	// metronome click, and a recording waiting for its count-in (see metronome.go)
+	metronome   bool
+	countInBars int
+	countIn     *countIn
+
+	// phase nudging of the selected grid: the fraction of a spacing ' moves it
+	// by, and how long the phase panel stays up (see phase.go)
+	phaseStep int
+	phaseShow float64
+
+	// canvas recording (R key); nil when not recording
+	recorder     *Recorder
+	recordDir    string
+	recordFormat string
+
+	// frame export with a scene sidecar (F12), taken on the next Draw
+	snapshotDue bool
+
+	// audio bounce to WAV (B key); nil when not bouncing
+	bounce        *Bounce
+	bounceSeconds float64
+	// capture of the notes played to a MIDI file (Shift+R, see midifile.go);
+	// nil when not capturing
+	capture *MIDICapture
+
+	// optional MIDI output; nil when no port is configured
+	midi *MIDIOut
+	// optional OSC output; nil when no port is configured
+	osc *OSCOut
+	// optional MIDI input (see midiin.go); nil when no port is configured
+	midiIn    *MIDIIn
+	midiCC    [midiTargetCount]int // controller number bound to each target, or midiNoCC
+	midiLearn int                  // 1 + target being learned; 0 when not learning
+	// optional remote control server (see remote.go); nil when not serving
+	remote     *Remote
+	remoteWait float64 // seconds until the state is published again
+	// optional Lua script hooks; nil when no script is loaded
+	script *Script
+
+	// this frame's input, live or replayed (see input.go and replay.go)
+	in *Input
+	// keys of every action (see keys.go)
+	keys Keymap
+	// session being recorded to a file, and session being replayed; nil when not
+	session *SessionRecorder
+	replay  *SessionPlayer
+
+	// numeric entry of an exact angle or spacing (see entry.go)
+	entry numEntry
+
+	// mapping of game time to the mixer's sample clock (see schedule.go)
+	audioSched audioSchedule
+
+	// fixed simulation ticks and the grids as drawn between them (see timestep.go)
+	timestep  engine.Timestep
+	drawGrids []geom.GridFamily
+	// the points as drawn between ticks, moved along their paths (see path.go)
+	drawPoints []engine.Point
+	// per grid, the lines that recently touched a point and the ripples
+	// running along them (see flash.go)
+	flashes [][]geom.LineFlash
+	ripples [][]geom.LineRipple
+	// game time each grid last triggered, for its width animation (see animate.go)
+	lastHits []float64
+}
+
+// NewGame creates the game with the default scene and starts audio output.
+func NewGame(cfg Config) (*Game, error) {
+	w, h := cfg.Width, cfg.Height
+	theme, err := ThemeNamed(cfg.Theme)
+	if err != nil {
+		return nil, err
+	}
+	// Define some default grids
+	grids := []geom.GridFamily{
+		{
+			Normal:     geom.Vec2{X: 1, Y: 0}.Norm(),
+			Spacing:    60,
+			Offset:     0,
+			Color:      theme.GridColor(0),
+			Thickness:  2,
+			GapLength:  60,
+			DashLength: 60,
+			DashOffset: 15,
+		},
+		{
+			Normal:     geom.Vec2{X: 1, Y: 0}.Norm(),
+			Spacing:    60,
+			Offset:     30,
+			Color:      theme.GridColor(0),
+			Thickness:  2,
+			GapLength:  60,
+			DashLength: 60,
+			DashOffset: 75,
+		},
+		{
+			Normal:    geom.Vec2{X: 0, Y: 1}.Norm(),
+			Spacing:   60,
+			Offset:    0,
+			Color:     theme.GridColor(1),
+			Thickness: 2,
+		},
+		{
+			Kind:       geom.GridRadial,
+			Origin:     geom.Vec2{X: float64(w) * 0.25, Y: float64(h) * 0.5},
+			Spacing:    120,
+			Color:      theme.GridColor(2),
+			Thickness:  2,
+			DashLength: 40,
+			GapLength:  20,
+		},
+	}
+	// fixed point
+	points := []engine.Point{
+		{Pos: geom.Vec2{X: float64(w) * 0.5, Y: float64(h) * 0.5}},
+	}
+
+	// Audio context at the configured sample rate, on the configured device
+	if cfg.AudioDevice != "" {
+		if err := selectAudioDevice(cfg.AudioDevice); err != nil {
+			return nil, err
+		}
+	}
+	sampleRate := cfg.SampleRate
+	ac := audio.NewContext(sampleRate)
+	mixer := synth.NewMixer(sampleRate, cfg.MaxVoices, cfg.Volume)
+	mixer.SetLimiter(cfg.Limiter)
+	player, err := ac.NewPlayer(mixer)
+	if err != nil {
+		return nil, err
+	}
+	// Small buffer keeps trigger latency low; the mixer is cheap to pull from
+	player.SetBufferSize(time.Duration(cfg.BufferMS) * time.Millisecond)
+	player.Play()
+
+	g := &Game{
+		Engine:         engine.New(w, h, cfg.Seed),
+		theme:          theme,
+		trailFade:      defaultTrailFade,
+		cam:            geom.Camera{Center: geom.Vec2{X: float64(w) / 2, Y: float64(h) / 2}, Zoom: 1, Screen: geom.Vec2{X: float64(w), Y: float64(h)}},
+		events:         NewEventLog(1024),
+		hoverIdx:       -1,
+		dragIdx:        -1,
+		audioCtx:       ac,
+		mixer:          mixer,
+		outMeter:       outputMeter{limit: 1},
+		player:         player,
+		blips:          make(map[synth.Voice][]float32),
+		samples:        make(map[string][]float32),
+		blipSampleRate: sampleRate,
+		audioBuffer:    float64(cfg.BufferMS) / 1000,
+		audioSched:     newAudioSchedule(float64(cfg.BufferMS) / 1000),
+		sends:          synth.DefaultSends,
+		brush:          defaultBrush,
+		keys:           DefaultKeymap(),
+	}
+	g.Grids = grids
+	g.Points = points
+	g.subscribe()
+	g.rng = rand.New(rand.NewSource(g.Seed))
+	g.particles.rng = rand.New(rand.NewSource(g.Seed))
+	g.timestep = engine.NewTimestep(cfg.TickRate)
+	g.midiCC = [midiTargetCount]int{cfg.MIDICCSpeed, cfg.MIDICCDirection, cfg.MIDICCSpacing, cfg.MIDICCDash, cfg.MIDICCMorph}
+	g.ResetPointState()
+	return g, nil
+}
+
+func (g *Game) Update() error {
+	// Controls: Left/Right rotate direction, Up/Down adjust speed additively
+	// Timing: input is handled once per frame, the simulation in fixed ticks
+	g.in = g.readInput()
+	dt := g.in.DT
+	g.prof.endFrame(dt)
+	defer g.prof.since(profUpdate, time.Now())
+	g.trackWindow()
+	g.measureLatency()
+	g.watchScene(dt)
+	g.updateAutosave(dt)
+	// A bounce that has its length is finished whatever has the keyboard
+	if g.bounce != nil && g.bounce.Finished() {
+		g.toggleBounce()
+	}
+
+	// Camera: middle-mouse drag (or a two-finger pinch) pans, wheel zooms around
+	// the cursor, Home resets
+	ptr := g.readPointer()
+	cursor := ptr.Pos
+	pinching := g.updatePinch()
+	if pinching {
+		// The second finger turns the gesture into a pinch; drop any touch edit
+		g.touch.active = false
+		ptr = pointer{Pos: cursor}
+	}
+	if g.in.MouseJustPressed(ebiten.MouseButtonMiddle) {
+		g.panning = true
+		g.panLast = cursor
+	}
+	if g.panning {
+		g.cam.Pan(cursor.Sub(g.panLast))
+		g.panLast = cursor
+		if !g.in.MousePressed(ebiten.MouseButtonMiddle) {
+			g.panning = false
+		}
+	}
+	if g.pressed(actViewReset) {
+		g.cam.Center = g.Center()
+		g.cam.Zoom = 1
+	}
+
+	// A press on the brush palette only selects the tool
+	if ptr.JustPressed && g.pickBrush(cursor) {
+		ptr.JustPressed = false
+	}
+	// and one on the tonal center marker drags it (see pitchmap.go)
+	if ptr.JustPressed && g.grabTonic(cursor) {
+		ptr.JustPressed = false
+	}
+	// and one on the morph slider moves it (see morph.go)
+	if ptr.JustPressed && g.grabMorph(cursor) {
+		ptr.JustPressed = false
+	}
+	// and one with Alt moves the selected grid's layer (see layer.go)
+	if ptr.JustPressed && g.grabLayer(g.cam.ToWorld(cursor)) {
+		ptr.JustPressed = false
+	}
+
+	// Handle mouse hover, click and drag for adding/removing/moving points
+	mouse := g.cam.ToWorld(cursor)
+	// Hover detection within small on-screen radius
+	center := g.Center()
+	g.hoverIdx = g.Index.Nearest(g.Points, mouse, center, 10.0/g.cam.Zoom)
+	if g.dragIdx >= 0 {
+		// Keep highlighting the held point even if the cursor outruns it
+		g.hoverIdx = g.dragIdx
+	}
+	// The wheel sets the velocity of a hovered point (see velocity.go) and
+	// zooms elsewhere; zooming keeps the world under the cursor in place
+	if wy := g.in.Wheel.Y; wy != 0 && !g.wheelVelocity(wy) {
+		g.cam.ZoomAt(cursor, math.Pow(1.1, wy))
+	}
+	g.updateGhost(mouse)
+	// Click (or tap) handling
+	if ptr.JustPressed {
+		if g.in.KeyPressed(ebiten.KeyShift) {
+			// Shift+click selects the point, Shift+drag spans a rubber band
+			g.selectPress(g.hoverIdx, mouse)
+		} else if g.hoverIdx >= 0 {
+			// Grab hovered point; whether this is a move or a removal is decided on release
+			g.dragIdx = g.hoverIdx
+			g.dragMoved = false
+			g.dragFrom = mouse
+			g.dragGrab = g.Points[g.hoverIdx].Pos.Sub(mouse)
+			g.dragOrig = g.Points[g.hoverIdx]
+			g.dragCopies = g.dragCopies[:0]
+			g.dragGroup = g.dragsSelection(g.hoverIdx)
+			for _, j := range g.dragCompanions(g.hoverIdx) {
+				g.dragCopies = append(g.dragCopies, editPointCmd{idx: j, before: g.Points[j]})
+			}
+		} else if g.brush.tool != brushPoint {
+			// Brushes place their points on release
+			g.startBrush(mouse)
+		} else {
+			// Add new point where the ghost is (the mouse, or with Ctrl the
+			// crossing it snapped to), cycling through the scale degrees (or taking its
+			// degree from the position with the pitch map)
+			g.exec(g.placeCmd(g.ghost.pos))
+		}
+	}
+	if g.pressed(actAddPoint) && g.showGhost() {
+		// The add-point key places a point where a click would
+		g.exec(g.placeCmd(g.ghost.pos))
+	}
+	g.updateBrush(mouse, ptr, pinching)
+	g.updateBand(mouse, ptr, pinching)
+	g.updateTonic(mouse, ptr)
+	g.updateMorph(cursor, ptr)
+	g.updateLayerDrag(mouse, ptr)
+	if g.dragIdx >= 0 {
+		// Small jitter during a click should not count as a move
+		dragThreshold := 3.0 / g.cam.Zoom
+		if !g.dragMoved && mouse.Sub(g.dragFrom).Len() > dragThreshold {
+			g.dragMoved = true
+		}
+		if g.dragMoved {
+			// A point on a path takes its path along
+			held := &g.Points[g.dragIdx]
+			to := g.snapPoint(mouse.Add(g.dragGrab))
+			delta := to.Sub(held.Pos)
+			if held.Path.Moves() {
+				held.Path.Translate(to.Sub(held.Pos))
+			}
+			held.Pos = to
+			if g.PitchMap.Enabled {
+				g.Points[g.dragIdx].Degree = g.DegreeAt(g.Points[g.dragIdx].Pos)
+			}
+			if g.dragGroup {
+				var idx []int
+				for _, c := range g.dragCopies {
+					idx = append(idx, c.idx)
+				}
+				g.movePoints(idx, delta)
+			} else {
+				g.moveMirrors(g.dragIdx)
+			}
+			g.Index.Invalidate()
+		}
+		if ptr.JustReleased || pinching {
+			if g.dragMoved {
+				// The point and its copies are already at their new place;
+				// only record the move
+				batch := batchCmd{editPointCmd{idx: g.dragIdx, before: g.dragOrig, after: g.Points[g.dragIdx]}}
+				for _, c := range g.dragCopies {
+					c.after = g.Points[c.idx]
+					batch = append(batch, c)
+				}
+				if len(batch) == 1 {
+					g.record(batch[0])
+				} else {
+					g.record(batch)
+				}
+			} else if !pinching {
+				// Plain click on a point removes it, with its mirror copies
+				g.exec(g.removeCmd(g.dragIdx))
+			}
+			g.dragIdx = -1
+			g.hoverIdx = -1
+		}
+	}
+
+	// Keyboard editing, unless an autosave is offered (see autosave.go) or a
+	// number is being typed (see entry.go)
+	if !g.updateRecovery() && !g.updateEntry() {
+		g.editKeys()
+		g.selectionKeys(mouse)
+	}
+	g.updateMIDIIn()
+	g.updateRemote(dt)
+
+	// Arrow keys steer the selected grid when it moves independently, otherwise the global motion
+	own := g.selectedMotion()
+	if own != nil {
+		g.steer(&own.Dir, &own.Speed, dt, true)
+	} else {
+		g.steer(&g.MoveDir, &g.Speed, dt, !g.TempoMode)
+	}
+
+	g.updateGamepad(dt)
+
+	// Toggle tempo mode, keeping the current motion so the switch is seamless
+	if g.pressed(actTempoMode) {
+		g.TempoMode = !g.TempoMode
+		if g.TempoMode {
+			g.Clock.SetSpeed(g.Speed)
+		}
+	}
+
+	if g.TempoMode {
+		if own == nil {
+			// Nudge BPM per key press (Shift for coarse steps)
+			nudge := 1.0
+			if g.in.KeyPressed(ebiten.KeyShift) {
+				nudge = 10
+			}
+			if g.pressed(actSpeedUp) {
+				g.Clock.SetBPM(g.Clock.BPM + nudge)
+			}
+			if g.pressed(actSpeedDown) {
+				g.Clock.SetBPM(g.Clock.BPM - nudge)
+			}
+		}
+		g.Speed = g.Clock.Speed()
+	}
+
+	g.Held = -1
+	if g.dragMoved {
+		g.Held = g.dragIdx
+	}
+	g.fade.update(dt)
+	g.phaseShow = max(0, g.phaseShow-dt)
+	g.updateMeter(dt)
+	g.View = g.cam.Center
+	for g.timestep.Next() {
+		g.tick(g.timestep.Step())
+	}
+	g.despawn()
+	return nil
+}
+
+// tick runs one simulation tick and the per-tick work around it.
+func (g *Game) tick(dt float64) {
+	defer g.prof.since(profTick, time.Now())
+	g.Tick(dt)
+	g.prof.addTick(g.Profile)
+
+	if g.script != nil {
+		g.script.Update(g, dt)
+	}
+
+	if g.midi != nil {
+		g.midi.Update(dt)
+		g.midi.Clock(g.Clock.Beats, g.TempoMode)
+	}
+
+	g.particles.Update(dt)
+	g.updateFlashes(dt)
+	g.updateCells(dt)
+}
+
+// editKeys handles the keyboard shortcuts that edit the scene and its sound.
+func (g *Game) editKeys() {
+	// , and . rotate the selected grid (Shift snaps), Alt+, and Alt+. its layer
+	g.rotateGridKeys()
+	// Alt+G puts the selected grid in a layer, Shift+- and Shift+= retime it
+	g.layerKeys()
+
+	// Editor: Tab selects the next grid family, W cycles the waveform of the
+	// hovered point (or of the selected grid when no point is hovered)
+	if g.pressed(actSelectGrid) && len(g.Grids) > 0 {
+		g.selGrid = (g.selGrid + 1) % len(g.Grids)
+	}
+	if g.pressed(actGridDrum) {
+		// Shift+W cycles the selected grid's drum
+		if g.selGrid < len(g.Grids) {
+			d := g.Grids[g.selGrid].Drum
+			g.exec(gridEditCmd[synth.Drum]{idx: g.selGrid, from: d, to: d.Next(),
+				set: func(gf *geom.GridFamily, v synth.Drum) { gf.Drum = v }})
+		}
+	} else if g.pressed(actWave) {
+		if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.Wave = before.Wave.Next(true)
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else if g.selGrid < len(g.Grids) {
+			w := g.Grids[g.selGrid].Wave
+			g.exec(gridEditCmd[synth.Waveform]{idx: g.selGrid, from: w, to: w.Next(false),
+				set: func(gf *geom.GridFamily, v synth.Waveform) { gf.Wave = v }})
+		}
+	}
+
+	// Alt+W cycles the FM voice of the hovered point (or the selected grid)
+	if g.pressed(actFM) {
+		if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.FM = synth.NextFM(before.FM, true)
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else if g.selGrid < len(g.Grids) {
+			fm := g.Grids[g.selGrid].FM
+			g.exec(gridEditCmd[*synth.FM]{idx: g.selGrid, from: fm, to: synth.NextFM(fm, false),
+				set: func(gf *geom.GridFamily, v *synth.FM) { gf.FM = v }})
+		}
+	}
+
+	// Pitch: [ and ] step the hovered point (or the selected grid's transposition)
+	// by one scale degree; K/Shift+K move the key root, L cycles the scale
+	if d := g.step(actDegreeUp, actDegreeDown); d != 0 {
+		if !g.sel.empty() {
+			g.exec(g.transposeSelectionCmd(d))
+		} else if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.Degree += d
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else if g.selGrid < len(g.Grids) {
+			deg := g.Grids[g.selGrid].Degree
+			g.exec(gridEditCmd[int]{idx: g.selGrid, from: deg, to: deg + d,
+				set: func(gf *geom.GridFamily, v int) { gf.Degree = v }})
+		}
+	}
+	g.Key += g.step(actKeyUp, actKeyDown)
+	if g.pressed(actScale) {
+		for i, sc := range synth.Scales {
+			if sc.Name == g.Scale.Name {
+				g.Scale = synth.Scales[(i+1)%len(synth.Scales)]
+				break
+			}
+		}
+		if g.Scale.Name == "custom" {
+			g.Scale = synth.Scales[0]
+		}
+	}
+
+	// Ctrl+S saves the scene
+	if g.pressed(actSave) {
+		if err := g.SaveScene(g.scenePath); err != nil {
+			log.Printf("save scene: %v", err)
+		}
+	}
+
+	// Ctrl+D duplicates the selected grid family (see templates.go)
+	if g.pressed(actDuplicateGrid) {
+		g.duplicateGrid()
+	}
+
+	// H switches the selected hex family between full lattice and honeycomb
+	if g.pressed(actHexTiling) && g.selGrid < len(g.Grids) && g.Grids[g.selGrid].Kind == geom.GridHex {
+		tiling := g.Grids[g.selGrid].HexTiling
+		g.exec(gridEditCmd[bool]{idx: g.selGrid, from: tiling, to: !tiling,
+			set: func(gf *geom.GridFamily, v bool) { gf.HexTiling = v }})
+	}
+
+	// E cycles the envelope of the hovered point (or the selected grid)
+	if g.pressed(actEnvelope) {
+		if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.Env = synth.NextEnvelope(before.Env, true)
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else if g.selGrid < len(g.Grids) {
+			env := g.Grids[g.selGrid].Env
+			g.exec(gridEditCmd[*synth.Envelope]{idx: g.selGrid, from: env, to: synth.NextEnvelope(env, false),
+				set: func(gf *geom.GridFamily, v *synth.Envelope) { gf.Env = v }})
+		}
+	}
+
+	// Shift+E cycles the pitch glide of the selected grid
+	if g.pressed(actGridGlide) && g.selGrid < len(g.Grids) {
+		gl := g.Grids[g.selGrid].Glide
+		g.exec(gridEditCmd[*synth.Glide]{idx: g.selGrid, from: gl, to: synth.NextGlide(gl),
+			set: func(gf *geom.GridFamily, v *synth.Glide) { gf.Glide = v }})
+	}
+
+	// F cycles the filter of the selected grid
+	if g.pressed(actGridFilter) && g.selGrid < len(g.Grids) {
+		f := g.Grids[g.selGrid].Filter
+		g.exec(gridEditCmd[*synth.Filter]{idx: g.selGrid, from: f, to: synth.NextFilter(f),
+			set: func(gf *geom.GridFamily, v *synth.Filter) { gf.Filter = v }})
+	}
+
+	// C cycles the trigger chance of the hovered point (or the selected grid)
+	if g.pressed(actChance) {
+		if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.Chance = nextChance(before.Chance)
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else if g.selGrid < len(g.Grids) {
+			c := g.Grids[g.selGrid].Chance
+			g.exec(gridEditCmd[float64]{idx: g.selGrid, from: c, to: nextChance(c),
+				set: func(gf *geom.GridFamily, v float64) { gf.Chance = v }})
+		}
+	}
+
+	// Alt+C cycles the retrigger guard of the hovered point (or the selected grid)
+	if g.pressed(actCooldown) {
+		if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.Cooldown = nextCooldown(before.Cooldown)
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else if g.selGrid < len(g.Grids) {
+			c := g.Grids[g.selGrid].Cooldown
+			g.exec(gridEditCmd[float64]{idx: g.selGrid, from: c, to: nextCooldown(c),
+				set: func(gf *geom.GridFamily, v float64) { gf.Cooldown = v }})
+		}
+	}
+
+	// Shift+X cycles the symmetry points are placed with
+	if g.pressed(actSymmetry) {
+		g.symmetry = nextSymmetry(g.symmetry)
+	}
+
+	// Shift+L cycles the lifespan of the hovered point, or else the one new
+	// points are placed with
+	if g.pressed(actLifespan) {
+		if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.Life = nextLife(before.Life)
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else {
+			g.newLife = nextLife(g.newLife)
+		}
+	}
+
+	// Z cycles the note value triggers are quantized to (Ctrl+Z is undo)
+	if g.pressed(actQuantize) {
+		g.Quantize = engine.NextQuantize(g.Quantize)
+	}
+	// and Alt+Z the swing of every second subdivision
+	if g.pressed(actSwing) {
+		g.Swing = engine.NextSwing(g.Swing)
+	}
+
+	// Shift+Z cycles the arpeggio rate of points crossed together and ;
+	// its direction
+	if g.pressed(actArpeggio) {
+		g.Arp.Rate = engine.NextArpRate(g.Arp.Rate)
+	}
+	if g.pressed(actArpeggioDirection) {
+		g.Arp.Dir = g.Arp.Dir.Next()
+	}
+
+	// V cycles the accent pattern of the selected grid
+	if g.pressed(actGridAccent) && g.selGrid < len(g.Grids) {
+		a := g.Grids[g.selGrid].Accent
+		g.exec(gridEditCmd[[]float64]{idx: g.selGrid, from: a, to: nextAccent(a),
+			set: func(gf *geom.GridFamily, v []float64) { gf.Accent = v }})
+	}
+
+	// Shift+V cycles the width animation of the selected grid
+	if g.pressed(actGridAnimation) && g.selGrid < len(g.Grids) {
+		a := g.Grids[g.selGrid].Anim
+		g.exec(gridEditCmd[*geom.Animator]{idx: g.selGrid, from: a, to: geom.NextAnimator(a),
+			set: func(gf *geom.GridFamily, v *geom.Animator) { gf.Anim = v }})
+	}
+
+	// N cycles the note length of the hovered point (or the selected grid);
+	// Shift+N cycles whether a retrigger cuts or overlaps the sounding note
+	if g.pressed(actNoteLength) || g.pressed(actRetrigger) {
+		shift := g.pressed(actRetrigger)
+		if g.hoverIdx >= 0 {
+			before := g.Points[g.hoverIdx]
+			after := before
+			if shift {
+				after.Retrigger = before.Retrigger.Next(true)
+			} else {
+				after.Length = nextNoteLength(before.Length)
+			}
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else if g.selGrid < len(g.Grids) && shift {
+			r := g.Grids[g.selGrid].Retrigger
+			g.exec(gridEditCmd[synth.Retrigger]{idx: g.selGrid, from: r, to: r.Next(false),
+				set: func(gf *geom.GridFamily, v synth.Retrigger) { gf.Retrigger = v }})
+		} else if g.selGrid < len(g.Grids) {
+			l := g.Grids[g.selGrid].Length
+			g.exec(gridEditCmd[float64]{idx: g.selGrid, from: l, to: nextNoteLength(l),
+				set: func(gf *geom.GridFamily, v float64) { gf.Length = v }})
+		}
+	}
+
+	// Groups: G moves the hovered point to the next group (Shift+G selects the
+	// next group instead); M and S mute and solo the hovered point's group, or
+	// the selected group when no point is hovered, or else the selected grid
+	if g.pressed(actPointGroup) || g.pressed(actSelectGroup) {
+		if len(g.sel.points) > 0 && g.pressed(actPointGroup) {
+			g.exec(g.groupSelectionCmd())
+		} else if g.hoverIdx >= 0 && g.pressed(actPointGroup) {
+			before := g.Points[g.hoverIdx]
+			after := before
+			after.Group = g.NextGroup(before.Group)
+			g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+		} else {
+			g.selGroup = g.NextGroup(g.selGroup)
+		}
+	}
+	group := g.selGroup
+	if g.hoverIdx >= 0 {
+		group = g.Points[g.hoverIdx].Group
+	}
+	if group == "" && g.selGrid < len(g.Grids) {
+		gf := &g.Grids[g.selGrid]
+		if g.pressed(actMute) {
+			g.exec(gridEditCmd[bool]{idx: g.selGrid, from: gf.Mute, to: !gf.Mute,
+				set: func(gf *geom.GridFamily, v bool) { gf.Mute = v }})
+		}
+		if g.pressed(actSolo) {
+			g.exec(gridEditCmd[bool]{idx: g.selGrid, from: gf.Solo, to: !gf.Solo,
+				set: func(gf *geom.GridFamily, v bool) { gf.Solo = v }})
+		}
+	} else {
+		if g.pressed(actMute) {
+			g.ToggleMute(group)
+		}
+		if g.pressed(actSolo) {
+			g.ToggleSolo(group)
+		}
+	}
+
+	// X makes the hovered point ignore (or respond to again) the selected grid
+	if g.pressed(actIgnoreGrid) && g.hoverIdx >= 0 && g.selGrid < len(g.Grids) && g.selGrid < engine.MaxFilterGrids {
+		before := g.Points[g.hoverIdx]
+		after := before
+		after.Ignore ^= 1 << uint(g.selGrid)
+		g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+	}
+
+	// P cycles deriving point pitches from their positions (see pitchmap.go);
+	// Shift+P cycles the chord the hovered point plays
+	if g.pressed(actPitchMap) {
+		g.cyclePitchMap()
+	}
+	if g.pressed(actChord) && g.hoverIdx >= 0 {
+		before := g.Points[g.hoverIdx]
+		after := before
+		after.Chord = synth.NextChord(before.Chord)
+		g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+	}
+
+	// I cycles the intersection trigger mode
+	if g.pressed(actIntersections) {
+		g.IntersectMode = g.IntersectMode.Next()
+	}
+
+	// U switches loop mode, starting the loop at the current pattern
+	if g.pressed(actLoop) {
+		g.Loop.Enabled = !g.Loop.Enabled
+	}
+
+	// Q cycles the point brushes
+	if g.pressed(actBrush) {
+		g.brush.tool = (g.brush.tool + 1) % brushToolCount
+	}
+
+	// F3 toggles trails
+	if g.pressed(actTrails) {
+		g.trails = !g.trails
+	}
+
+	// F7 toggles the tiling
+	if g.pressed(actTiling) {
+		g.tiling = !g.tiling
+		g.cells = nil
+	}
+
+	// F12 exports the next frame with the scene it shows
+	if g.pressed(actSnapshot) {
+		g.snapshotDue = true
+	}
+
+	// F5 switches to the next color theme
+	if g.pressed(actTheme) {
+		g.setTheme(nextTheme(g.theme), g.theme)
+	}
+
+	// F11 switches between the window and fullscreen
+	if g.pressed(actFullscreen) {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	}
+
+	// F2 toggles the trigger timeline strip
+	if g.pressed(actTimeline) {
+		g.showTimeline = !g.showTimeline
+	}
+
+	// F6 toggles the trigger statistics overlay; Shift+F6 starts the counts over
+	if g.pressed(actStats) {
+		g.showStats = !g.showStats
+	}
+	if g.pressed(actStatsReset) {
+		g.ResetStats()
+	}
+
+	// ? shows and hides the key help
+	if g.pressed(actHelp) {
+		g.showHelp = !g.showHelp
+	}
+
+	// F1 toggles the debug overlay; Shift+F1 docks it in the next corner
+	if g.pressed(actProfile) {
+		g.prof.show = !g.prof.show
+	}
+	if g.pressed(actProfileDock) {
+		g.prof.dock = (g.prof.dock + 1) % dockCount
+	}
+
+	// R starts/stops recording the canvas, starting after the count-in
+	if g.pressed(actRecord) {
+		if g.recorder == nil {
+			g.startCounted("Recording", g.toggleRecording)
+		} else {
+			g.toggleRecording()
+		}
+	}
+
+	// Shift+R starts/stops capturing the notes to a MIDI file, starting after
+	// the count-in
+	if g.pressed(actCapture) {
+		if g.capture == nil {
+			g.startCounted("MIDI capture", g.toggleCapture)
+		} else {
+			g.toggleCapture()
+		}
+	}
+
+	// B starts/stops bouncing the audio output to WAV, starting after the
+	// count-in; it also ends by itself (see Update)
+	if g.pressed(actBounce) && g.bounce == nil {
+		g.startCounted("Bounce", g.toggleBounce)
+	} else if g.pressed(actBounce) {
+		g.toggleBounce()
+	}
+
+	// Shift+M switches the metronome
+	if g.pressed(actMetronome) {
+		g.metronome = !g.metronome
+	}
+
+	// Ctrl+- and Ctrl+= change the master gain, Ctrl+L switches the limiter
+	g.outputKeys()
+
+	// Shift+B switches the selected grid between painting its lines over the
+	// canvas and adding their light to it
+	if g.pressed(actGridBlend) && g.selGrid < len(g.Grids) {
+		b := g.Grids[g.selGrid].Blend
+		g.exec(gridEditCmd[geom.BlendMode]{idx: g.selGrid, from: b, to: b.Next(),
+			set: func(gf *geom.GridFamily, v geom.BlendMode) { gf.Blend = v }})
+	}
+	// Alt+E cycles when the selected grid's lines trigger the points they
+	// pass: as they enter a line, leave it, pass its middle or both edges
+	if g.pressed(actGridEdge) && g.selGrid < len(g.Grids) {
+		e := g.Grids[g.selGrid].Edge
+		g.exec(gridEditCmd[geom.TriggerEdge]{idx: g.selGrid, from: e, to: e.Next(),
+			set: func(gf *geom.GridFamily, v geom.TriggerEdge) { gf.Edge = v }})
+	}
+	// Alt+D switches the gap sounds of the selected grid (see gap.go)
+	if g.pressed(actGridGaps) && g.selGrid < len(g.Grids) {
+		on := g.Grids[g.selGrid].GapTrigger
+		g.exec(gridEditCmd[bool]{idx: g.selGrid, from: on, to: !on,
+			set: func(gf *geom.GridFamily, v bool) { gf.GapTrigger = v }})
+	}
+
+	// Number keys 1-9 load a preset slot; Ctrl+number saves the scene into it
+	for i := 0; i < 9; i++ {
+		var err error
+		switch {
+		case g.pressed(actSaveSlot1 + action(i)):
+			err = g.SaveSlot(i + 1)
+		case g.pressed(actSlot1 + action(i)):
+			err = g.LoadSlot(i + 1)
+		}
+		if err != nil {
+			log.Printf("slot %d: %v", i+1, err)
+		}
+	}
+
+	// PageUp/PageDown switch to the previous or next scene of the setlist
+	if d := g.step(actSetlistNext, actSetlistPrevious); d != 0 {
+		g.stepSetlist(d)
+	}
+
+	// Undo/redo (Ctrl+Z / Ctrl+Shift+Z); ignored while a point is held or a
+	// brush stroke is dragged so the history never refers to a half-finished drag
+	if d := g.step(actRedo, actUndo); d != 0 && g.dragIdx < 0 && !g.brush.active {
+		if d > 0 {
+			g.Redo()
+		} else {
+			g.Undo()
+		}
+		g.hoverIdx = -1
+	}
+
+	// J cycles the path the hovered point travels along; Shift+J its speed
+	if (g.pressed(actPath) || g.pressed(actPathSpeed)) && g.hoverIdx >= 0 {
+		before := g.Points[g.hoverIdx]
+		after := before
+		if g.pressed(actPathSpeed) {
+			after.Path.Speed = nextPathSpeed(before.Path.Speed)
+		} else {
+			after.Path = nextPath(before.Pos, before.Path)
+		}
+		g.exec(editPointCmd{idx: g.hoverIdx, before: before, after: after})
+	}
+
+	// Editor: O gives the selected grid its own motion (starting from the
+	// global one) or returns it to the shared motion
+	if g.pressed(actGridMotion) && g.selGrid < len(g.Grids) {
+		from := g.Grids[g.selGrid].Motion
+		var to *geom.Motion
+		if from == nil {
+			to = &geom.Motion{Dir: g.MoveDir, Speed: g.Speed}
+		}
+		g.exec(gridEditCmd[*geom.Motion]{idx: g.selGrid, from: from, to: to,
+			set: func(gf *geom.GridFamily, v *geom.Motion) { gf.Motion = v }})
+	}
+
+	// Transport of the selected grid: Space pauses or resumes its motion, /
+	// reverses it and - and = halve and double its speed
+	if g.selGrid < len(g.Grids) {
+		gf := &g.Grids[g.selGrid]
+		if g.pressed(actGridPause) {
+			g.exec(gridEditCmd[bool]{idx: g.selGrid, from: gf.Paused, to: !gf.Paused,
+				set: func(gf *geom.GridFamily, v bool) { gf.Paused = v }})
+		}
+		rate := gf.Rate
+		switch {
+		case g.pressed(actGridReverse):
+			rate = -geom.ScaleRate(rate, 1)
+		case g.pressed(actGridSlower):
+			rate = geom.ScaleRate(rate, 0.5)
+		case g.pressed(actGridFaster):
+			rate = geom.ScaleRate(rate, 2)
+		}
+		if rate == 1 {
+			rate = 0
+		}
+		if rate != gf.Rate {
+			g.exec(gridEditCmd[float64]{idx: g.selGrid, from: gf.Rate, to: rate,
+				set: func(gf *geom.GridFamily, v float64) { gf.Rate = v }})
+		}
+	}
+
+	// Shift+O cycles the opacity of the selected grid's lines
+	if g.pressed(actGridOpacity) && g.selGrid < len(g.Grids) {
+		a := g.Grids[g.selGrid].Alpha
+		g.exec(gridEditCmd[float64]{idx: g.selGrid, from: a, to: geom.NextAlpha(a),
+			set: func(gf *geom.GridFamily, v float64) { gf.Alpha = v }})
+	}
+
+	// Alt+B cycles the selected grid's drawn width and Alt+T shows its
+	// trigger band; neither changes what plays
+	if g.pressed(actGridWidth) && g.selGrid < len(g.Grids) {
+		w := g.Grids[g.selGrid].Width
+		g.exec(gridEditCmd[float64]{idx: g.selGrid, from: w, to: geom.NextWidth(w),
+			set: func(gf *geom.GridFamily, v float64) { gf.Width = v }})
+	}
+	if g.pressed(actGridBand) && g.selGrid < len(g.Grids) {
+		on := g.Grids[g.selGrid].ShowBand
+		g.exec(gridEditCmd[bool]{idx: g.selGrid, from: on, to: !on,
+			set: func(gf *geom.GridFamily, v bool) { gf.ShowBand = v }})
+	}
+
+	// ' and Shift+' nudge the selected grid's phase, \ picks by how much and
+	// Shift+\ resets it
+	g.phaseKeys()
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	defer g.prof.since(profDraw, time.Now())
+	// Draw the pattern where it is now, between the last two ticks
+	grids := g.Grids
+	g.Grids = g.interpolatedGrids()
+	defer func() { g.Grids = grids }()
+
+	// Fill background
+	screen.Fill(g.theme.Background)
+
+	// Moving parts go to the trail image in trails mode
+	layer := screen
+	if g.trails {
+		layer = g.trailLayer()
+	}
+	center := g.Center()
+	if g.tiling {
+		g.drawTiling(layer)
+	}
+	anySolo := geom.AnySolo(g.Grids)
+	for i := range g.Grids {
+		if gf := &g.Grids[i]; !gf.Sounds(anySolo) {
+			// Silenced grids keep moving, drawn faintly (these are drawn copies)
+			a := gf.Alpha
+			if a == 0 {
+				a = 1
+			}
+			gf.Alpha = geom.SilencedAlpha * a
+		}
+		g.Grids[i].Draw(layer, &g.cam, center)
+	}
+
+	g.particles.Draw(layer, &g.cam)
+	if g.trails {
+		screen.DrawImage(g.trail, nil)
+	}
+
+	// Draw the routes of moving points, then visual cues and points where
+	// they are between the last two ticks
+	points := g.Points
+	g.Points = g.interpolatedPoints()
+	defer func() { g.Points = points }()
+	for _, p := range g.Points {
+		p.Path.Draw(screen, &g.cam, g.theme.Path)
+	}
+	// Glyphs keep their size in window pixels on high-DPI displays
+	d := g.cam.Device()
+	for i, p := range g.Points {
+		sp := g.cam.ToScreen(p.Pos)
+		// visual cue ring if active
+		t := 0.0
+		if i < len(g.Cues) {
+			t = g.Cues[i]
+		}
+		// points of silenced groups are drawn dimmed, and points whose
+		// lifespan has run out fade away
+		dim := !g.Audible(p.Group)
+		level := p.Life.Level()
+		if t > 0 {
+			r := 8.0 + (1.0-t)*24.0
+			col := g.theme.Cue
+			col.A = uint8(float64(col.A) * t)
+			if dim {
+				col.A /= 3
+			}
+			vector.StrokeCircle(screen, float32(sp.X), float32(sp.Y), float32(r*d), float32(2*d), fade(col, level), true)
+		}
+
+		// point glyph, sized by its velocity
+		s := d * glyphScale(p)
+		switch {
+		case i == g.hoverIdx:
+			// highlighted point
+			drawCross(screen, sp, 8*s, fade(g.theme.PointHover, level))
+		case dim:
+			drawCross(screen, sp, 6*s, fade(g.theme.PointDim, level))
+		default:
+			drawCross(screen, sp, 6*s, fade(g.theme.Point, level))
+		}
+	}
+	g.drawSelection(screen)
+
+	g.drawPadCursor(screen)
+	g.drawBrush(screen)
+	g.drawSymmetry(screen)
+	g.drawTonic(screen)
+	g.drawGhost(screen)
+
+	if g.showTimeline {
+		g.drawTimeline(screen)
+	}
+	g.drawPhases(screen)
+
+	// The previous scene of the setlist fades out over the new one
+	g.drawCrossfade(screen)
+
+	// Record and export the canvas before the HUD is drawn over it
+	if g.snapshotDue {
+		g.snapshotDue = false
+		g.captureSnapshot(screen)
+	}
+	if g.recorder != nil {
+		g.recorder.Capture(screen)
+		vector.DrawFilledCircle(screen, float32(g.W-16), 16, 6, g.theme.Record, true)
+	}
+
+	g.drawPalette(screen)
+	if g.showStats {
+		g.drawStats(screen)
+	}
+	g.drawMorph(screen)
+	if g.prof.show {
+		g.drawProfile(screen)
+	}
+
+	// HUD text: the status, after the key help while it is shown
+	msg := "?: keys  "
+	if g.showHelp {
+		msg = helpText()
+	}
+	if g.setlist != nil {
+		msg += "Scene " + g.setlist.label() + "  "
+	}
+	msg += fmt.Sprintf("Key: %s %s  ", synth.KeyName(g.Key), g.Scale.Name)
+	switch {
+	case g.PitchMap.Enabled && g.PitchMap.Distance:
+		msg += "(pitch from distance to the tonal center)  "
+	case g.PitchMap.Enabled:
+		msg += "(pitch from position)  "
+	}
+	msg += fmt.Sprintf("Speed: %.1f px/s  Dir:(%.2f, %.2f) %.1f°", g.Speed, g.MoveDir.X, g.MoveDir.Y, dirAngle(g.MoveDir))
+	msg += "  Audio: " + g.audioLabel()
+	if g.IntersectMode != engine.IntersectOff {
+		msg += fmt.Sprintf("  Intersections: %s", g.IntersectMode)
+	}
+	if g.Arp.Rate > 0 {
+		msg += fmt.Sprintf("  Arpeggio: 1/%d %s", g.Arp.Rate, g.Arp.Dir)
+	}
+	if g.Quantize > 0 {
+		msg += fmt.Sprintf("  Quantize: 1/%d at %.0f BPM", g.Quantize, g.Clock.BPM)
+	}
+	if g.Swing > engine.MinSwing {
+		msg += fmt.Sprintf("  Swing: %g%% of 1/%d pairs", g.Swing, g.SwingNoteValue())
+	}
+	if g.brush.tool != brushPoint {
+		msg += "  Brush: " + g.brush.label()
+	}
+	if g.symmetry.Kind != geom.SymmetryOff {
+		msg += "  Symmetry: " + g.symmetry.String()
+	}
+	if g.newLife.Mortal() {
+		msg += "  New points live: " + g.newLife.String()
+	}
+	if g.Loop.Enabled {
+		msg += fmt.Sprintf("  Loop: %.1f/%g %s", g.LoopPosition(), g.Loop.Length, g.Loop.Unit())
+	}
+	if g.bounce != nil {
+		msg += fmt.Sprintf("  Bouncing WAV %.1f/%.0fs", g.bounce.Elapsed(g.bounceSeconds), g.bounceSeconds)
+	}
+	if g.capture != nil {
+		msg += fmt.Sprintf("  Capturing MIDI: %d notes", g.capture.Len())
+	}
+	if g.TempoMode {
+		bar, beat := g.Clock.Position()
+		msg += fmt.Sprintf("  BPM: %.0f  Bar %d Beat %.2f", g.Clock.BPM, bar, beat)
+	}
+	msg += g.metronomeLabel()
+	msg += g.outputLabel()
+	if g.hoverIdx >= 0 {
+		p := g.Points[g.hoverIdx]
+		note := g.Scale.Note(g.Key, p.Degree)
+		msg += fmt.Sprintf("  Degree %d: %s (%.1f Hz) Wave: %s Env: %s Group: %s Chance: %s Velocity: %.0f%% Length: %s Retrigger: %s Path: %s Chord: %s Life: %s FM: %s", p.Degree, synth.NoteName(note), synth.MIDIToFreq(note), p.Wave, envLabel(p.Env), g.groupLabel(p.Group), chanceLabel(p.Chance), p.VelocityScale()*100, pointLengthLabel(p.Length), p.Retrigger, pathLabel(p.Path), synth.ChordName(p.Chord), lifeLabel(p.Life), fmLabel(p.FM))
+		if p.Cooldown > 0 {
+			msg += "  Cooldown: " + cooldownLabel(p.Cooldown)
+		}
+		if p.Ignore != 0 {
+			msg += "  Ignores:"
+			for _, gi := range p.IgnoredGrids() {
+				msg += fmt.Sprintf(" G%d", gi+1)
+			}
+		}
+	} else if g.selGroup != "" {
+		msg += fmt.Sprintf("  Group: %s", g.groupLabel(g.selGroup))
+	}
+	msg += g.selectionLabel()
+	if g.touch.pinchSpeed {
+		msg += "  Pinch: speed"
+	}
+	if g.selGrid < len(g.Grids) {
+		gf := &g.Grids[g.selGrid]
+		msg += fmt.Sprintf("\nGrid %d (%s) Wave: %s Env: %s Transpose: %+d Chance: %s Length: %s Retrigger: %s Edge: %s Accent: %s Opacity: %s Blend: %s Transport: %s Mix: %s Anim: %s", g.selGrid+1, gf.KindName(), synth.ResolveWave(gf.Wave, synth.WaveInherit), synth.EnvelopeName(synth.ResolveEnvelope(gf.Env, nil)), gf.Degree, chanceLabel(gf.Chance), lengthLabel(gf.Length), synth.ResolveRetrigger(gf.Retrigger, synth.RetriggerInherit), gf.Edge, accentLabel(gf.Accent), alphaLabel(gf.Alpha), gf.Blend, gf.RateLabel(), gf.MixLabel(), geom.AnimatorName(gf.Anim))
+		if gf.Layer > 0 {
+			msg += "  Layer: " + g.layerLabel()
+		}
+		if gf.GapTrigger {
+			msg += "  Gaps: sound"
+		}
+		if gf.Cooldown > 0 {
+			msg += "  Cooldown: " + cooldownLabel(gf.Cooldown)
+		}
+		msg += fmt.Sprintf("  Width: %g Band: %g", gf.LineWidth(), 2*gf.Thickness)
+		if gf.ShowBand {
+			msg += " (shown)"
+		}
+		if gf.Drum != synth.DrumNone {
+			msg += fmt.Sprintf("  Drum: %s", gf.Drum)
+		}
+		if gf.FM != nil && gf.FM.Enabled() {
+			msg += fmt.Sprintf("  FM: %s %g:1 index %g", synth.FMName(*gf.FM), gf.FM.Ratio, gf.FM.Index)
+		}
+		if gf.Glide != nil {
+			msg += fmt.Sprintf("  Glide: %s %.2f→%.2f", synth.GlideName(gf.Glide), gf.Glide.Start, gf.Glide.End)
+		}
+		if gf.Filter != nil {
+			msg += fmt.Sprintf("  Filter: %s %s %.0f Hz", synth.FilterName(gf.Filter), gf.Filter.Type, gf.Filter.Cutoff)
+		}
+		if a, ok := gridAngle(gf); ok {
+			msg += fmt.Sprintf("  Angle: %.1f°", a)
+		}
+		if gf.Kind != geom.GridRay {
+			msg += fmt.Sprintf("  Spacing: %.1f px", gf.Spacing)
+		}
+		msg += fmt.Sprintf("  Phase: %.3f (nudge %s)", gf.PhaseFraction(), g.phaseLabel())
+		if m := gf.Motion; m != nil {
+			msg += fmt.Sprintf("  Own motion: %.1f px/s Dir:(%.2f, %.2f) [arrows steer this grid]", m.Speed, m.Dir.X, m.Dir.Y)
+		}
+	}
+	if g.TempoMode {
+		// Show how each family subdivides the beat at the current direction
+		msg += "\nBeats per line:"
+		for i, gf := range g.Grids {
+			dir, speed := g.MoveDir, g.Speed
+			if gf.Motion != nil {
+				dir, speed = gf.Motion.Dir, gf.Motion.Speed
+			}
+			msg += fmt.Sprintf("  G%d %.2f", i+1, g.Clock.BeatsPerLine(gf, dir, speed))
+		}
+	}
+	if g.entry.field != entryNone {
+		msg += "\n" + g.entry.label()
+	}
+	if l := g.recoveryLabel(); l != "" {
+		msg += "\n" + l
+	}
+	if l := g.midiLearnLabel(); l != "" {
+		msg += "\n" + l
+	}
+	if l := g.watchLabel(); l != "" {
+		msg += "\n" + l
+	}
+	if keys := g.keys.changed(); len(keys) > 0 {
+		msg += "\nKeys: " + strings.Join(keys, "  ")
+	}
+	g.printAt(screen, wrapHUD(msg, g.W/hudCharWidth), 0, 0)
+}
+
+// toggleRecording starts a new recording or finishes the current one.
+func (g *Game) toggleRecording() {
+	if g.recorder == nil {
+		r, err := StartRecorder(g.recordDir, g.recordFormat)
+		if err != nil {
+			log.Printf("record: %v", err)
+			return
+		}
+		g.recorder = r
+		return
+	}
+	r := g.recorder
+	g.recorder = nil
+	// Finish encoding in the background so the app keeps running smoothly
+	go func() {
+		out, err := r.Stop()
+		if err != nil {
+			log.Printf("record: %v", err)
+			return
+		}
+		log.Printf("recording saved to %s", out)
+	}()
+}
+
+// toggleBounce starts a WAV bounce into the recording directory or finishes the current one.
+func (g *Game) toggleBounce() {
+	if g.bounce == nil {
+		b, err := StartBounce(g.recordDir, g.blipSampleRate, g.bounceSeconds)
+		if err != nil {
+			log.Printf("bounce: %v", err)
+			return
+		}
+		g.bounce = b
+		g.mixer.SetTap(b)
+		return
+	}
+	b := g.bounce
+	g.bounce = nil
+	g.mixer.SetTap(nil)
+	go func() {
+		out, err := b.Stop()
+		if err != nil {
+			log.Printf("bounce: %v", err)
+			return
+		}
+		log.Printf("audio saved to %s", out)
+	}()
+}
+
+// envLabel names an optional envelope for the HUD.
+func envLabel(e *synth.Envelope) string {
+	if e == nil {
+		return "inherit"
+	}
+	return synth.EnvelopeName(*e)
+}
+
+// fmLabel names an optional FM voice for the HUD.
+func fmLabel(f *synth.FM) string {
+	if f == nil {
+		return "inherit"
+	}
+	return synth.FMName(*f)
+}
+
+// selectedMotion returns the independent motion of the selected grid, or nil
+// when it follows the global motion.
+func (g *Game) selectedMotion() *geom.Motion {
+	if g.selGrid < len(g.Grids) {
+		return g.Grids[g.selGrid].Motion
+	}
+	return nil
+}
+
+// steer applies the steering keys to a motion: rotate-left/right turn the
+// direction at a fixed angular rate (with Shift, each press snaps to the next
+// multiple of snapDegrees) and, if adjustSpeed is set, speed-up/down change
+// the speed by a fixed amount per second.
+func (g *Game) steer(dir *geom.Vec2, speed *float64, dt float64, adjustSpeed bool) {
+	// Rotate movement direction by a fixed angular rate
+	rotSpeed := 90.0 * (math.Pi / 180.0) // radians per second
+	// Compute current angle from dir
+	angle := math.Atan2(dir.Y, dir.X)
+	if g.in.KeyPressed(ebiten.KeyShift) {
+		if d := g.step(actRotateRight, actRotateLeft); d != 0 {
+			*dir = angleDir(snapAngle(dirAngle(*dir), d))
+		}
+	} else {
+		if g.held(actRotateLeft) {
+			angle -= rotSpeed * dt
+		}
+		if g.held(actRotateRight) {
+			angle += rotSpeed * dt
+		}
+		*dir = geom.Vec2{X: math.Cos(angle), Y: math.Sin(angle)}
+	}
+
+	if !adjustSpeed {
+		return
+	}
+	// Adjust speed by a fixed amount per second
+	accel := 120.0 // px/s^2
+	if g.held(actSpeedUp) {
+		*speed += accel * dt
+	}
+	if g.held(actSpeedDown) {
+		*speed -= accel * dt
+	}
+	if *speed < 0 {
+		*speed = 0
+	}
+}
+
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	// The canvas always matches the window in device pixels, so lines stay
+	// crisp on high-DPI displays; the camera scales the world up to keep its
+	// size on screen, and the scene follows size changes. A replay keeps the
+	// recorded size and scale, scaled into the window.
+	if g.replay == nil {
+		s := ebiten.DeviceScaleFactor()
+		g.cam.DeviceScale = s
+		g.resize(int(math.Ceil(float64(outsideWidth)*s)), int(math.Ceil(float64(outsideHeight)*s)))
+	}
+	return g.W, g.H
+}
+
+// resolveGlide returns the glide a voice renders with; nil is automatic.
+func resolveGlide(gl *synth.Glide) synth.Glide {
+	if gl == nil {
+		return synth.Glide{}
+	}
+	return *gl
+}
+
+// resolveFilter returns the filter a voice renders with; nil is none.
+func resolveFilter(f *synth.Filter) synth.Filter {
+	if f == nil {
+		return synth.Filter{}
+	}
+	return *f
+}
+
+func drawCross(dst *ebiten.Image, p geom.Vec2, size float64, col color.Color) {
+	// Two lines crossing at p
+	vector.StrokeLine(dst, float32(p.X-size), float32(p.Y), float32(p.X+size), float32(p.Y), 1.5, col, true)
+	vector.StrokeLine(dst, float32(p.X), float32(p.Y-size), float32(p.X), float32(p.Y+size), 1.5, col, true)
+}
+
+func (g *Game) playBlip(v synth.Voice, velocity float64, sends synth.Sends, gate synth.NoteGate) {
+	// Render each voice once and reuse the samples on later triggers;
+	// velocity is applied as voice gain in the mixer
+	smp, ok := g.blips[v]
+	if !ok {
+		smp = synth.GenerateBlip(g.blipSampleRate, v)
+		g.blips[v] = smp
+	}
+	g.mixer.Play(smp, velocity, sends, gate)
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run reads the configuration, sets up the game and runs it until the window closes.
+func run() error {
+	cfg, err := LoadConfig(os.Args[1:])
+	if err != nil {
+		return err
+	}
+	if cfg.MIDIPort == listMIDIPort || cfg.MIDIIn == listMIDIPort {
+		return printMIDIPorts()
+	}
+	// A replayed session starts with the seed, tick rate and size it was recorded with
+	var replay *SessionPlayer
+	var session SessionHeader
+	if cfg.Replay != "" {
+		if replay, session, err = OpenSession(cfg.Replay); err != nil {
+			return err
+		}
+		session.apply(&cfg)
+	}
+
+	game, err := NewGame(cfg)
+	if err != nil {
+		return err
+	}
+	if game.Scale, err = synth.ParseScale(cfg.Scale); err != nil {
+		return err
+	}
+	game.sends = synth.Sends{Delay: cfg.DelaySend, Reverb: cfg.ReverbSend}
+	game.mixer.SetDelay(synth.NewDelay(game.blipSampleRate, cfg.DelayTime, math.Min(cfg.DelayFeedback, 0.95)))
+	if game.IntersectMode, err = engine.ParseIntersectMode(cfg.Intersections); err != nil {
+		return err
+	}
+	if game.keys, err = LoadKeymap(bindingsPath(cfg)); err != nil {
+		return err
+	}
+	game.scenePath = cfg.Scene
+	// Merges from disk are not part of a recorded session's input
+	game.watch.on = cfg.WatchScene && replay == nil && cfg.RecordSession == ""
+	if cfg.WatchScene && !game.watch.on {
+		log.Print("watch-scene: off while a session is recorded or replayed")
+	}
+	game.presetDir = cfg.PresetDir
+	game.rescalePoints = cfg.RescalePoints
+	game.recordDir = cfg.RecordDir
+	game.recordFormat = cfg.RecordFormat
+	game.bounceSeconds = cfg.BounceSeconds
+	game.metronome = cfg.Metronome
+	game.tiling = cfg.Tiling
+	if game.newLife, err = engine.ParseLifespan(cfg.PointLife); err != nil {
+		return err
+	}
+	if game.symmetry, err = geom.ParseSymmetry(cfg.Symmetry); err != nil {
+		return err
+	}
+	game.countInBars = cfg.CountIn
+	if cfg.Seed == 0 {
+		log.Printf("random seed %d (pass -seed %d to repeat this session)", game.Seed, game.Seed)
+	}
+	game.fade.length = cfg.Crossfade
+	if cfg.Setlist != "" {
+		// The setlist's first scene takes the place of -scene
+		sl, err := LoadSetlist(cfg.Setlist)
+		if err != nil {
+			return err
+		}
+		if err := game.startSetlist(sl); err != nil {
+			return fmt.Errorf("setlist %s: %w", cfg.Setlist, err)
+		}
+	} else if hasFileSystem {
+		if err := game.LoadScene(cfg.Scene); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if cfg.Morph != "" {
+		if err := game.loadMorph(cfg.Morph); err != nil {
+			return err
+		}
+	}
+	// A recovery offer takes keys a recorded session plays back elsewhere, and
+	// its restore is not part of the recording. The marker is left alone, so
+	// the next session that isn't recorded or replayed still offers it.
+	if dir := cfg.AutosaveDir; replay == nil && cfg.RecordSession == "" {
+		if dir == "" {
+			dir = defaultAutosaveDir()
+		}
+		if err := game.startAutosave(dir, cfg.Autosave, cfg.AutosaveKeep); err != nil {
+			return err
+		}
+	}
+	if err := game.openOutputs(cfg.MIDIPort, cfg.MIDIChannel, cfg.OSCHost, cfg.OSCPort); err != nil {
+		return err
+	}
+	if game.midi != nil {
+		game.midi.clock.enabled = cfg.MIDIClock
+		defer game.midi.Close()
+	}
+	if game.osc != nil {
+		defer game.osc.Close()
+	}
+	if err := game.openInputs(cfg.MIDIIn, cfg.Remote); err != nil {
+		return err
+	}
+	if game.midiIn != nil {
+		defer game.midiIn.Close()
+	}
+	if game.remote != nil {
+		defer game.remote.Close()
+	}
+	if cfg.Script != "" {
+		s, err := LoadScript(game, cfg.Script)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		game.script = s
+		game.Bus.OnTrigger(s.Trigger)
+	}
+	// The session starts here, after the scene and script have set it up
+	if replay != nil {
+		if err := game.startReplay(replay, session); err != nil {
+			return err
+		}
+	}
+	if cfg.RecordSession != "" {
+		if game.session, err = RecordSession(cfg.RecordSession, game.sessionHeader()); err != nil {
+			return err
+		}
+		defer func() {
+			if game.session != nil {
+				if err := game.session.Close(); err != nil {
+					log.Printf("record session: %v", err)
+				}
+			}
+		}()
+	}
+	// Basic window setup
+	ebiten.SetWindowSize(game.W, game.H)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowTitle("Grythm — Grid Rhythm Visualizer")
+	ebiten.SetFullscreen(cfg.Fullscreen)
+	placeWindow(cfg)
+	ebiten.SetVsyncEnabled(cfg.VSync)
+	ebiten.SetTPS(cfg.TPS)
+	if err := ebiten.RunGame(game); err != nil {
+		return err
+	}
+	game.stopAutosave()
+	// The next run opens the window where this one closed it
+	if path, _ := configPath(os.Args[1:]); path != "" && hasFileSystem && game.windowSeen {
+		if err := saveWindowState(path, game.window); err != nil {
+			log.Print(err)
+		}
+	}
+	return nil
+}
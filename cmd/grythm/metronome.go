@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"grythm/engine"
+	"grythm/synth"
+)
+
+// Metronome. A click on every beat of the clock (see engine.BeatPosition),
+// higher on the first beat of a bar, gives a reference pulse to hear the
+// pattern against. It is scheduled to the beat's exact time like a trigger.
+// Recording and bouncing can start after a count-in: clicks up to a downbeat
+// at least -count-in bars away, on which the recording starts, whether or
+// not the metronome is on.
+
+const (
+	metronomeFreq     = 1320.0 // Hz of the click on an ordinary beat
+	metronomeDownFreq = 1760.0 // Hz of the click on a downbeat
+	metronomeLength   = 0.05   // seconds a click lasts
+	metronomeGain     = 0.6
+)
+
+// countIn is a recording waiting for its count-in to end.
+type countIn struct {
+	start int    // beat it starts on
+	what  string // what starts, for the HUD
+	begin func()
+}
+
+// startCounted runs begin after the count-in, or at once without one.
+// Starting the same thing again while it counts in cancels the count-in;
+// something else takes its place.
+func (g *Game) startCounted(what string, begin func()) {
+	if g.countIn != nil && g.countIn.what == what {
+		g.countIn = nil
+		return
+	}
+	if g.countInBars <= 0 {
+		begin()
+		return
+	}
+	bpb := max(g.Clock.BeatsPerBar, 1)
+	next := int(math.Ceil(g.BeatPosition()))
+	start := (next + g.countInBars*bpb + bpb - 1) / bpb * bpb
+	g.countIn = &countIn{start: start, what: what, begin: begin}
+}
+
+// metronomeBeat clicks on a beat and starts a recording whose count-in ends
+// on it; it is subscribed to the engine's beats.
+func (g *Game) metronomeBeat(b engine.BeatEvent) {
+	counting := g.countIn != nil
+	if counting && b.Beat >= g.countIn.start {
+		c := g.countIn
+		g.countIn, counting = nil, false
+		c.begin()
+	}
+	if !g.metronome && !counting {
+		return
+	}
+	freq := metronomeFreq
+	if b.Downbeat {
+		freq = metronomeDownFreq
+	}
+	g.playBlip(synth.Voice{Freq: freq, Length: metronomeLength}, metronomeGain, synth.Sends{},
+		synth.NoteGate{At: g.soundFrame(b.Time, b.Time)})
+}
+
+// metronomeLabel describes the metronome and a count-in for the HUD; "" when
+// neither is running.
+func (g *Game) metronomeLabel() string {
+	var l string
+	if g.metronome {
+		l = "  Metronome"
+	}
+	if c := g.countIn; c != nil {
+		l += fmt.Sprintf("  %s in %d", c.what, c.start-int(math.Floor(g.BeatPosition())))
+	}
+	return l
+}
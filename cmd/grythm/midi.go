@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"grythm/engine"
+)
+
+// MIDIOut sends note messages to a raw MIDI port. The port is any writable
+// byte stream that accepts MIDI wire messages, such as an ALSA raw MIDI device
+// (/dev/snd/midiC1D0), a virmidi port or a named pipe. Messages are encoded
+// directly so no cgo driver is needed. The MIDI libraries that reach the
+// system's ports on macOS and Windows (CoreMIDI, WinMM) all go through cgo
+// drivers, which would make cgo a requirement of every build, so there a
+// port has to be a byte stream bridged to a device by another program.
+type MIDIOut struct {
+	f       *os.File
+	channel byte    // 0-based MIDI channel
+	noteLen float64 // seconds between note-on and the scheduled note-off
+
+	// notes currently sounding, with the remaining time until their note-off
+	pending map[byte]float64
+	// notes to start later (quantized triggers), in the order they were scheduled
+	scheduled []midiNote
+	// the tempo clock it sends (see midiclock.go)
+	clock midiClock
+	// the last write failed, and was logged
+	failing bool
+}
+
+// midiNote is a note-on waiting to be sent.
+type midiNote struct {
+	wait           float64 // seconds until it is sent
+	note, velocity int
+	length         float64
+}
+
+// midiPort is a raw MIDI device found on the system (see midiPorts).
+type midiPort struct {
+	Name string // the card's id, with the port number after a colon past port 0
+	Path string // the device file
+}
+
+// listMIDIPort is the port name that lists the ports instead of opening one.
+const listMIDIPort = "list"
+
+// printMIDIPorts writes the ports that can be given to -midi-port and -midi-in.
+func printMIDIPorts() error {
+	ports, err := midiPorts()
+	if err != nil {
+		return err
+	}
+	if len(ports) == 0 {
+		fmt.Println("no MIDI ports found")
+	}
+	for _, p := range ports {
+		fmt.Printf("%-20s %s\n", p.Name, p.Path)
+	}
+	return nil
+}
+
+// resolveMIDIPort returns the device file of port: a port name from
+// midiPorts, or else a path as it is.
+func resolveMIDIPort(port string) string {
+	if strings.ContainsRune(port, os.PathSeparator) {
+		return port
+	}
+	ports, _ := midiPorts()
+	for _, p := range ports {
+		if strings.EqualFold(p.Name, port) {
+			return p.Path
+		}
+	}
+	return port
+}
+
+// OpenMIDIOut opens port, a port name or the path of a device, for writing.
+// channel is 1-based (1..16) as shown on synths.
+func OpenMIDIOut(port string, channel int) (*MIDIOut, error) {
+	if channel < 1 || channel > 16 {
+		return nil, fmt.Errorf("midi channel %d out of range 1-16", channel)
+	}
+	f, err := os.OpenFile(resolveMIDIPort(port), os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open midi port: %w", err)
+	}
+	return &MIDIOut{
+		f:       f,
+		channel: byte(channel - 1),
+		noteLen: 0.1,
+		pending: make(map[byte]float64),
+	}, nil
+}
+
+// NoteOn starts a note and schedules its note-off after length seconds, or
+// after noteLen when length is 0. Retriggering a note that is still sounding
+// ends the previous one first so hardware doesn't see two overlapping note-ons
+// for the same key.
+func (m *MIDIOut) NoteOn(note, velocity int, length float64) {
+	n := byte(clampInt(note, 0, 127))
+	if _, ok := m.pending[n]; ok {
+		m.send(0x80|m.channel, n, 0)
+	}
+	m.send(0x90|m.channel, n, byte(clampInt(velocity, 1, 127)))
+	if length <= 0 {
+		length = m.noteLen
+	}
+	m.pending[n] = length
+}
+
+// NoteOnAfter starts a note like NoteOn, delay seconds from now.
+func (m *MIDIOut) NoteOnAfter(delay float64, note, velocity int, length float64) {
+	if delay <= 0 {
+		m.NoteOn(note, velocity, length)
+		return
+	}
+	m.scheduled = append(m.scheduled, midiNote{wait: delay, note: note, velocity: velocity, length: length})
+}
+
+// Update sends the scheduled notes that are due and advances the note-off
+// timers by dt seconds.
+func (m *MIDIOut) Update(dt float64) {
+	waiting := m.scheduled[:0]
+	for _, s := range m.scheduled {
+		s.wait -= dt
+		if s.wait <= 0 {
+			m.NoteOn(s.note, s.velocity, s.length)
+			continue
+		}
+		waiting = append(waiting, s)
+	}
+	m.scheduled = waiting
+	for n, left := range m.pending {
+		left -= dt
+		if left <= 0 {
+			m.send(0x80|m.channel, n, 0)
+			delete(m.pending, n)
+			continue
+		}
+		m.pending[n] = left
+	}
+}
+
+// Close releases all sounding notes, drops the scheduled ones, stops the
+// clock and closes the port.
+func (m *MIDIOut) Close() error {
+	m.Clock(0, false)
+	m.scheduled = nil
+	for n := range m.pending {
+		m.send(0x80|m.channel, n, 0)
+		delete(m.pending, n)
+	}
+	return m.f.Close()
+}
+
+func (m *MIDIOut) send(msg ...byte) {
+	// A failed write drops the message; the visualizer keeps running. Only the
+	// first of a run of failures is logged, as a port that went away fails
+	// every message after it.
+	if _, err := m.f.Write(msg); err != nil {
+		if !m.failing {
+			log.Printf("midi out: %v; dropping messages until a write succeeds", err)
+		}
+		m.failing = true
+		return
+	}
+	if m.failing {
+		log.Print("midi out: writing again")
+	}
+	m.failing = false
+}
+
+// midiTrigger sends the notes of a trigger that sounds; it is subscribed to
+// the engine's triggers when an output is open.
+func (g *Game) midiTrigger(t engine.TriggerEvent) {
+	if !t.Audible {
+		return
+	}
+	notes, length := g.triggerNotes(t)
+	for _, n := range notes {
+		g.midi.NoteOnAfter(t.At-t.Time, n, midiVelocity(t.Velocity), length)
+	}
+}
+
+// midiIntersection sends the note of an intersection.
+func (g *Game) midiIntersection(x engine.IntersectionEvent) {
+	g.midi.NoteOnAfter(x.At-x.Time, g.intersectionNote(x), midiVelocity(x.Velocity), 0)
+}
+
+// midiVelocity maps a trigger velocity in [0, 1] to a MIDI velocity in [1, 127].
+func midiVelocity(v float64) int {
+	return clampInt(int(math.Round(1+v*126)), 1, 127)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
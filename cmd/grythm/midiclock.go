@@ -0,0 +1,63 @@
+package main
+
+import "math"
+
+// MIDI clock. With -midi-clock the MIDI output also sends the tempo clock so
+// hardware sequencers and drum machines can chase grythm's transport:
+// switching tempo mode on sends the song position of the clock and Start (or
+// Continue when it is past the start), then 24 clock pulses per beat follow
+// the BPM, and switching it off sends Stop. The pulses go out on the tick
+// they fall in, so they jitter by up to a tick against the beat.
+
+// MIDI system real-time and common messages.
+const (
+	midiClockPulse    = 0xf8
+	midiStart         = 0xfa
+	midiContinue      = 0xfb
+	midiStop          = 0xfc
+	midiSongPosition  = 0xf2
+	midiPulsesPerBeat = 24
+	midiPulsesPerStep = 6 // song position counts sixteenth notes
+)
+
+// midiClock is the state of the clock a MIDIOut sends.
+type midiClock struct {
+	enabled bool
+	running bool // Start or Continue was sent and Stop wasn't since
+	pulse   int  // pulses sent since the start of the song
+}
+
+// Clock follows the tempo clock at beats since its start, running or not,
+// sending the messages that keep a receiver in step.
+func (m *MIDIOut) Clock(beats float64, running bool) {
+	c := &m.clock
+	if !c.enabled {
+		return
+	}
+	if !running {
+		if c.running {
+			m.send(midiStop)
+			c.running = false
+		}
+		return
+	}
+	to := int(math.Floor(beats * midiPulsesPerBeat))
+	if c.running && to < c.pulse {
+		// The clock went back; stop and pick it up again from there
+		m.send(midiStop)
+		c.running = false
+	}
+	if !c.running {
+		step := to / midiPulsesPerStep
+		m.send(midiSongPosition, byte(step&0x7f), byte(step>>7&0x7f))
+		if step == 0 {
+			m.send(midiStart)
+		} else {
+			m.send(midiContinue)
+		}
+		c.running, c.pulse = true, step*midiPulsesPerStep
+	}
+	for ; c.pulse < to; c.pulse++ {
+		m.send(midiClockPulse)
+	}
+}
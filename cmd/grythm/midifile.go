@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"grythm/engine"
+	"grythm/synth"
+)
+
+// MIDI capture. Shift+R (after the count-in, like R and B) collects every
+// note that sounds, timed in beats of the tempo clock, and writes a Standard
+// MIDI File to -record-dir when pressed again. Each grid family gets a track
+// and a channel of its own (drums on the General MIDI drum channel, 10), and
+// intersections one more, so a performed session can be arranged further in
+// a DAW. The file starts on the bar line before the first note and carries
+// the tempo, including changes made during the capture.
+
+const (
+	midiTicksPerBeat    = 480
+	midiDrumChannel     = 9   // 0-based; channel 10 as shown on synths
+	intersectionChannel = 15  // 0-based channel of intersection notes
+	intersectionTrack   = -1  // track key of intersection notes
+	captureNoteLen      = 0.1 // seconds held by notes without a length, as on the MIDI port
+)
+
+// capturedNote is a note collected by a MIDICapture.
+type capturedNote struct {
+	track          int     // grid family, or intersectionTrack
+	channel        byte    // 0-based
+	beat, beats    float64 // when it starts and how long it lasts
+	note, velocity byte
+}
+
+// tempoChange is the tempo from a beat on.
+type tempoChange struct {
+	beat, bpm float64
+}
+
+// MIDICapture collects notes until it is saved.
+type MIDICapture struct {
+	out         string
+	beatsPerBar int
+	notes       []capturedNote
+	tempos      []tempoChange
+}
+
+// StartMIDICapture begins collecting notes for a new MIDI file in dir.
+func StartMIDICapture(dir string, beatsPerBar int) (*MIDICapture, error) {
+	if !hasFileSystem {
+		return nil, errNoFileSystem
+	}
+	stamp := time.Now().Format("20060102-150405")
+	return &MIDICapture{
+		out:         filepath.Join(dir, "grythm-"+stamp+".mid"),
+		beatsPerBar: max(beatsPerBar, 1),
+	}, nil
+}
+
+// Note adds a note at beat lasting beats, and the tempo it was played at.
+func (c *MIDICapture) Note(track int, channel byte, note, velocity int, beat, beats, bpm float64) {
+	if n := len(c.tempos); n == 0 || c.tempos[n-1].bpm != bpm {
+		c.tempos = append(c.tempos, tempoChange{beat: beat, bpm: bpm})
+	}
+	c.notes = append(c.notes, capturedNote{
+		track:    track,
+		channel:  channel,
+		beat:     beat,
+		beats:    beats,
+		note:     byte(clampInt(note, 0, 127)),
+		velocity: byte(clampInt(velocity, 1, 127)),
+	})
+}
+
+// Len returns the number of notes collected.
+func (c *MIDICapture) Len() int {
+	return len(c.notes)
+}
+
+// Save writes the notes to the file and returns its path.
+func (c *MIDICapture) Save() (string, error) {
+	f, err := os.Create(c.out)
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	err = c.write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return c.out, err
+}
+
+// midiEvent is one event of a track, at an absolute tick.
+type midiEvent struct {
+	tick int
+	data []byte
+}
+
+// write writes the capture as a format 1 file: a tempo track, then a track
+// per grid family in order and the intersections last.
+func (c *MIDICapture) write(w *bufio.Writer) error {
+	// Start on the bar line before the first note (or the first tempo)
+	start := 0.0
+	if len(c.tempos) > 0 {
+		start = c.tempos[0].beat
+	}
+	for _, n := range c.notes {
+		start = math.Min(start, n.beat)
+	}
+	bpb := float64(c.beatsPerBar)
+	start = math.Floor(start/bpb) * bpb
+	tick := func(beat float64) int {
+		return int(math.Round((beat - start) * midiTicksPerBeat))
+	}
+
+	var tracks [][]midiEvent
+	// Time signature: beatsPerBar quarter notes
+	meta := []midiEvent{{0, []byte{0xff, 0x58, 0x04, byte(min(c.beatsPerBar, 255)), 2, 24, 8}}}
+	for i, t := range c.tempos {
+		at := tick(t.beat)
+		if i == 0 {
+			at = 0
+		}
+		us := int(math.Round(60e6 / t.bpm))
+		meta = append(meta, midiEvent{at, []byte{0xff, 0x51, 0x03, byte(us >> 16), byte(us >> 8), byte(us)}})
+	}
+	tracks = append(tracks, meta)
+
+	keys := []int{}
+	for _, n := range c.notes {
+		if !slices.Contains(keys, n.track) {
+			keys = append(keys, n.track)
+		}
+	}
+	// Grids in order, the intersections after them
+	slices.SortFunc(keys, func(a, b int) int {
+		if a == intersectionTrack || b == intersectionTrack {
+			return cmp.Compare(b, a)
+		}
+		return cmp.Compare(a, b)
+	})
+	for _, k := range keys {
+		name := "Intersections"
+		if k != intersectionTrack {
+			name = fmt.Sprintf("Grid %d", k+1)
+		}
+		evs := []midiEvent{{0, append([]byte{0xff, 0x03, byte(len(name))}, name...)}}
+		for _, n := range c.notes {
+			if n.track != k {
+				continue
+			}
+			on := tick(n.beat)
+			off := max(tick(n.beat+n.beats), on+1)
+			evs = append(evs,
+				midiEvent{on, []byte{0x90 | n.channel, n.note, n.velocity}},
+				midiEvent{off, []byte{0x80 | n.channel, n.note, 0}})
+		}
+		tracks = append(tracks, evs)
+	}
+
+	hdr := []byte("MThd\x00\x00\x00\x06")
+	hdr = binary.BigEndian.AppendUint16(hdr, 1)
+	hdr = binary.BigEndian.AppendUint16(hdr, uint16(len(tracks)))
+	hdr = binary.BigEndian.AppendUint16(hdr, midiTicksPerBeat)
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	for _, evs := range tracks {
+		if _, err := w.Write(encodeTrack(evs)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeTrack encodes a track chunk of evs, sorted by tick with note-offs
+// before note-ons at the same tick, so a repeated note ends before it
+// starts again.
+func encodeTrack(evs []midiEvent) []byte {
+	slices.SortStableFunc(evs, func(a, b midiEvent) int {
+		if a.tick != b.tick {
+			return cmp.Compare(a.tick, b.tick)
+		}
+		return cmp.Compare(noteOn(a), noteOn(b))
+	})
+	var body bytes.Buffer
+	last := 0
+	for _, e := range evs {
+		body.Write(varLen(e.tick - last))
+		body.Write(e.data)
+		last = e.tick
+	}
+	body.Write([]byte{0, 0xff, 0x2f, 0}) // end of track
+	chunk := []byte("MTrk")
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(body.Len()))
+	return append(chunk, body.Bytes()...)
+}
+
+// noteOn returns 1 for a note-on event, 0 for others.
+func noteOn(e midiEvent) int {
+	if e.data[0]&0xf0 == 0x90 {
+		return 1
+	}
+	return 0
+}
+
+// varLen encodes v as a MIDI variable-length quantity.
+func varLen(v int) []byte {
+	out := []byte{byte(v & 0x7f)}
+	for v >>= 7; v > 0; v >>= 7 {
+		out = append([]byte{byte(v&0x7f) | 0x80}, out...)
+	}
+	return out
+}
+
+// gridChannel returns the 0-based MIDI channel of grid family gi's notes,
+// skipping the drum channel.
+func gridChannel(gi int) byte {
+	ch := gi % 15
+	if ch >= midiDrumChannel {
+		ch++
+	}
+	return byte(ch)
+}
+
+// captureTrigger adds the notes of a trigger that sounds to the capture.
+func (g *Game) captureTrigger(t engine.TriggerEvent) {
+	if g.capture == nil || !t.Audible {
+		return
+	}
+	notes, length := g.triggerNotes(t)
+	ch := gridChannel(t.Grid)
+	if g.Grids[t.Grid].Drum != synth.DrumNone {
+		ch = midiDrumChannel
+	}
+	for _, n := range notes {
+		g.captureNote(t.Grid, ch, n, t.Velocity, t.At, length)
+	}
+}
+
+// captureIntersection adds the note of an intersection to the capture.
+func (g *Game) captureIntersection(x engine.IntersectionEvent) {
+	if g.capture == nil {
+		return
+	}
+	g.captureNote(intersectionTrack, intersectionChannel, g.intersectionNote(x), x.Velocity, x.At, 0)
+}
+
+// captureNote adds a note sounding at simulation time at for length seconds.
+func (g *Game) captureNote(track int, ch byte, note int, velocity, at, length float64) {
+	if length <= 0 {
+		length = captureNoteLen
+	}
+	bpm := g.Clock.BPM
+	g.capture.Note(track, ch, note, midiVelocity(velocity), g.BeatAt(at), length*bpm/60, bpm)
+}
+
+// toggleCapture starts a MIDI capture or saves the current one.
+func (g *Game) toggleCapture() {
+	if g.capture == nil {
+		c, err := StartMIDICapture(g.recordDir, g.Clock.BeatsPerBar)
+		if err != nil {
+			log.Printf("midi capture: %v", err)
+			return
+		}
+		g.capture = c
+		return
+	}
+	c := g.capture
+	g.capture = nil
+	go func() {
+		out, err := c.Save()
+		if err != nil {
+			log.Printf("midi capture: %v", err)
+			return
+		}
+		log.Printf("midi saved to %s (%d notes)", out, c.Len())
+	}()
+}
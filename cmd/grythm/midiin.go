@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// MIDIIn reads note and control change messages from a raw MIDI port, the
+// same kind of byte stream MIDIOut writes to. A goroutine parses the stream
+// and hands complete messages to the game, which applies them in Update.
+type MIDIIn struct {
+	f    *os.File
+	msgs chan MIDIMessage
+}
+
+// MIDIMessage is a channel voice message. Status includes the channel.
+type MIDIMessage struct {
+	Status, Data1, Data2 byte
+}
+
+// OpenMIDIIn opens port, a port name or the path of a device (see
+// OpenMIDIOut), for reading and starts parsing it.
+func OpenMIDIIn(port string) (*MIDIIn, error) {
+	f, err := os.Open(resolveMIDIPort(port))
+	if err != nil {
+		return nil, fmt.Errorf("open midi input: %w", err)
+	}
+	m := &MIDIIn{f: f, msgs: make(chan MIDIMessage, 256)}
+	go m.read()
+	return m, nil
+}
+
+// read parses the byte stream until the port is closed. Running status is
+// supported; system messages are skipped.
+func (m *MIDIIn) read() {
+	defer close(m.msgs)
+	buf := make([]byte, 256)
+	var status byte
+	var data []byte
+	for {
+		n, err := m.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, b := range buf[:n] {
+			switch {
+			case b >= 0xF8:
+				// Real-time messages may appear anywhere and don't affect running status
+				continue
+			case b >= 0xF0:
+				// System common and SysEx: ignore until the next status byte
+				status = 0
+				data = data[:0]
+				continue
+			case b&0x80 != 0:
+				status = b
+				data = data[:0]
+				continue
+			case status == 0:
+				continue
+			}
+			data = append(data, b)
+			if len(data) < midiDataLen(status) {
+				continue
+			}
+			msg := MIDIMessage{Status: status, Data1: data[0]}
+			if len(data) > 1 {
+				msg.Data2 = data[1]
+			}
+			data = data[:0]
+			select {
+			case m.msgs <- msg:
+			default:
+				// The game is not keeping up; drop the message
+			}
+		}
+	}
+}
+
+// midiDataLen returns the number of data bytes that follow a channel status byte.
+func midiDataLen(status byte) int {
+	switch status & 0xF0 {
+	case 0xC0, 0xD0:
+		return 1
+	}
+	return 2
+}
+
+// Close stops reading and closes the port.
+func (m *MIDIIn) Close() error {
+	return m.f.Close()
+}
+
+// MIDITarget is a parameter a MIDI controller can drive.
+type MIDITarget int
+
+const (
+	MIDISpeed     MIDITarget = iota // pattern speed, or BPM in tempo mode
+	MIDIDirection                   // movement direction, 0-360°
+	MIDISpacing                     // spacing of the selected grid
+	MIDIDashPhase                   // dash phase of the selected grid
+	MIDIMorph                       // morph between scenes (see morph.go)
+	midiTargetCount
+)
+
+var midiTargetNames = []string{"speed", "direction", "spacing", "dash phase", "morph"}
+
+func (t MIDITarget) String() string {
+	if t < 0 || int(t) >= len(midiTargetNames) {
+		return "unknown"
+	}
+	return midiTargetNames[t]
+}
+
+const (
+	// midiMaxSpeed is the speed at controller value 127.
+	midiMaxSpeed = 600
+	// midiMinSpacing and midiMaxSpacing bound the spacing a controller sets.
+	midiMinSpacing = 10
+	midiMaxSpacing = 250
+	// midiNoCC disables a binding.
+	midiNoCC = -1
+)
+
+// updateMIDIIn applies the MIDI messages received since the last frame and
+// handles the learn key: F4 starts learning the first target, further presses
+// move on to the next one and finally leave learn mode.
+func (g *Game) updateMIDIIn() {
+	if g.pressed(actMIDILearn) {
+		g.midiLearn++
+		if g.midiLearn > int(midiTargetCount) {
+			g.midiLearn = 0
+		}
+	}
+	for _, msg := range g.in.MIDI {
+		g.midiMessage(msg)
+	}
+}
+
+// pollMIDIIn returns the messages that arrived since the last frame, for the
+// frame's Input.
+func (g *Game) pollMIDIIn() []MIDIMessage {
+	if g.midiIn == nil {
+		return nil
+	}
+	var msgs []MIDIMessage
+	for {
+		select {
+		case msg, ok := <-g.midiIn.msgs:
+			if !ok {
+				log.Print("midi input closed")
+				g.midiIn = nil
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+// learning returns the target being learned, if any.
+func (g *Game) learning() (MIDITarget, bool) {
+	return MIDITarget(g.midiLearn - 1), g.midiLearn > 0
+}
+
+// midiMessage applies a single message.
+func (g *Game) midiMessage(msg MIDIMessage) {
+	switch msg.Status & 0xF0 {
+	case 0x90:
+		if msg.Data2 > 0 {
+			g.midiNote(int(msg.Data1))
+		}
+	case 0xB0:
+		cc := int(msg.Data1)
+		if t, ok := g.learning(); ok {
+			g.bindCC(t, cc)
+			log.Printf("midi learn: CC %d controls %s", cc, t)
+			g.midiLearn = (g.midiLearn + 1) % (int(midiTargetCount) + 1)
+			return
+		}
+		for t, bound := range g.midiCC {
+			if bound == cc {
+				g.midiControl(MIDITarget(t), float64(msg.Data2)/127)
+			}
+		}
+	}
+}
+
+// bindCC makes cc control t, taking it away from any other target.
+func (g *Game) bindCC(t MIDITarget, cc int) {
+	for i := range g.midiCC {
+		if g.midiCC[i] == cc {
+			g.midiCC[i] = midiNoCC
+		}
+	}
+	g.midiCC[t] = cc
+}
+
+// midiControl sets target t from a controller value v in [0, 1].
+func (g *Game) midiControl(t MIDITarget, v float64) {
+	switch t {
+	case MIDISpeed:
+		if own := g.selectedMotion(); own != nil {
+			own.Speed = v * midiMaxSpeed
+		} else if g.TempoMode {
+			g.Clock.SetBPM(engine.MinBPM + v*(engine.MaxBPM-engine.MinBPM))
+		} else {
+			g.Speed = v * midiMaxSpeed
+		}
+	case MIDIDirection:
+		dir := angleDir(v * 360)
+		if own := g.selectedMotion(); own != nil {
+			own.Dir = dir
+		} else {
+			g.MoveDir = dir
+		}
+	case MIDISpacing:
+		if g.selGrid < len(g.Grids) {
+			setGridSpacing(&g.Grids[g.selGrid], midiMinSpacing+v*(midiMaxSpacing-midiMinSpacing))
+		}
+	case MIDIDashPhase:
+		if g.selGrid < len(g.Grids) {
+			gf := &g.Grids[g.selGrid]
+			gf.DashOffset = v * geom.PatternPeriod(gf.DashSegments())
+		}
+	case MIDIMorph:
+		g.setMorph(v)
+	}
+}
+
+// midiNote places a point sounding note where the pitch map would give it that
+// pitch, or removes the point already there. Notes outside the scale take the
+// nearest degree below.
+func (g *Game) midiNote(note int) {
+	deg := g.Scale.Degree(g.Key, note)
+	pos := g.PitchPos(deg)
+	for i, p := range g.Points {
+		if p.Pos == pos {
+			g.exec(removePointCmd{idx: i, p: p})
+			return
+		}
+	}
+	g.exec(addPointCmd{idx: len(g.Points), p: g.newPoint(pos, deg)})
+}
+
+// midiLearnLabel describes learn mode for the HUD.
+func (g *Game) midiLearnLabel() string {
+	t, ok := g.learning()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("MIDI learn: move a control for %s (F4: next)", t)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// midiPorts returns the ALSA raw MIDI devices, one per port of every card
+// (/dev/snd/midiC1D0 is port 0 of card 1), named after their card.
+func midiPorts() ([]midiPort, error) {
+	paths, err := filepath.Glob("/dev/snd/midiC*D*")
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]midiPort, 0, len(paths))
+	for _, path := range paths {
+		var card, dev int
+		if _, err := fmt.Sscanf(filepath.Base(path), "midiC%dD%d", &card, &dev); err != nil {
+			continue
+		}
+		name := fmt.Sprintf("card %d", card)
+		if id, err := os.ReadFile(fmt.Sprintf("/proc/asound/card%d/id", card)); err == nil {
+			name = strings.TrimSpace(string(id))
+		}
+		if dev > 0 {
+			name = fmt.Sprintf("%s:%d", name, dev)
+		}
+		ports = append(ports, midiPort{Name: name, Path: path})
+	}
+	return ports, nil
+}
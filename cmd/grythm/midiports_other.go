@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// midiPorts reports that ports can't be listed: there are no raw MIDI device
+// files to find here, so a port is given as the path of a byte stream.
+func midiPorts() ([]midiPort, error) {
+	return nil, errors.New("MIDI ports can only be listed on Linux; give the path of a raw MIDI device or named pipe")
+}
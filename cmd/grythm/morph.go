@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Morphing. -morph other.json loads a second scene to morph the current one
+// into: a slider at the bottom of the window, Alt+- and Alt+= or a MIDI
+// controller set how far, and the grid families take on the blend of their
+// spacings, angles and origins (see geom.GridFamily.Morph) while their lines
+// slide over to the other scene's offsets as the slider moves. Points glide
+// between their places in the two scenes, shifted by the part of the way the
+// slider moved, so a point dragged meanwhile keeps where it was dragged to.
+// Families and points are paired by id (see scenemerge.go), so adding,
+// removing or reordering them leaves the others' partners as they are; those
+// without a partner of the same kind stay as they are. Loading another scene (a preset or the setlist) makes it the
+// start of the morph, with the slider back at 0. Morphing is a performance
+// control, like the MIDI controllers, and is not undone.
+
+const (
+	morphStep   = 0.05  // how far Alt+- and Alt+= move the slider
+	morphW      = 240.0 // width of the slider
+	morphH      = 14.0  // height of the slider
+	morphMargin = 40.0  // distance of the slider from the bottom of the window
+)
+
+// morph holds the two scenes being morphed between and how far along it is.
+type morph struct {
+	a, b     map[string]geom.GridFamily // the families at 0 and at 1, by id
+	pa, pb   map[string]geom.Vec2       // the point positions at 0 and at 1, by id
+	amount   float64
+	dragging bool // the slider is held
+}
+
+// loaded reports whether there is a scene to morph into.
+func (m *morph) loaded() bool {
+	return m.b != nil
+}
+
+// loadMorph makes the scene at path the end of the morph, starting from the
+// current one.
+func (g *Game) loadMorph(path string) error {
+	if !hasFileSystem {
+		return errNoFileSystem
+	}
+	sn, err := readScene(path)
+	if err != nil {
+		return err
+	}
+	grids, err := sceneGrids(sn.Scene)
+	if err != nil {
+		return fmt.Errorf("morph %s: %w", path, err)
+	}
+	points, err := scenePoints(sn.Scene)
+	if err != nil {
+		return fmt.Errorf("morph %s: %w", path, err)
+	}
+	g.morph.b = gridsByID(grids)
+	g.morph.pb = pointPositions(points)
+	g.rebaseMorph()
+	return nil
+}
+
+// rebaseMorph makes the current scene the start of the morph.
+func (g *Game) rebaseMorph() {
+	m := &g.morph
+	if !m.loaded() {
+		return
+	}
+	// Named the way a saved scene would be, so a scene that was never saved
+	// pairs up by position with a morph scene written without ids
+	g.nameAll()
+	m.a = gridsByID(g.Grids)
+	m.pa = pointPositions(g.Points)
+	m.amount, m.dragging = 0, false
+}
+
+// gridsByID returns copies of families by their id.
+func gridsByID(grids []geom.GridFamily) map[string]geom.GridFamily {
+	out := make(map[string]geom.GridFamily, len(grids))
+	for i := range grids {
+		if id := grids[i].ID; id != "" {
+			out[id] = grids[i].Clone()
+		}
+	}
+	return out
+}
+
+// pointPositions returns where points are by their id.
+func pointPositions(points []engine.Point) map[string]geom.Vec2 {
+	out := make(map[string]geom.Vec2, len(points))
+	for _, p := range points {
+		if p.ID != "" {
+			out[p.ID] = p.Pos
+		}
+	}
+	return out
+}
+
+// setMorph moves the morph to amount v (0-1).
+func (g *Game) setMorph(v float64) {
+	m := &g.morph
+	v = math.Max(0, math.Min(1, v))
+	if !m.loaded() || v == m.amount {
+		return
+	}
+	for i := range g.Grids {
+		gf := &g.Grids[i]
+		a, okA := m.a[gf.ID]
+		b, okB := m.b[gf.ID]
+		if okA && okB && g.gridIndex(gf.ID) == i && gf.CanMorph(&a, &b) {
+			gf.Morph(&a, &b, m.amount, v)
+		}
+	}
+	for i := range g.Points {
+		q := &g.Points[i]
+		a, okA := m.pa[q.ID]
+		b, okB := m.pb[q.ID]
+		if !okA || !okB || g.pointIndex(q.ID) != i {
+			// No partner, or a copy sharing its id
+			continue
+		}
+		step := b.Sub(a).Mul(v - m.amount)
+		if q.Path.Moves() {
+			q.Path.Translate(step)
+		}
+		q.Pos = q.Pos.Add(step)
+		if g.PitchMap.Enabled {
+			q.Degree = g.DegreeAt(q.Pos)
+		}
+	}
+	g.Index.Invalidate()
+	m.amount = v
+}
+
+// morphSlider returns the slider's place in the window.
+func (g *Game) morphSlider() (x, y, w, h float64) {
+	return (float64(g.W) - morphW) / 2, float64(g.H) - morphMargin, morphW, morphH
+}
+
+// grabMorph starts dragging the slider when screen position p is on it,
+// reporting whether it did.
+func (g *Game) grabMorph(p geom.Vec2) bool {
+	if !g.morph.loaded() {
+		return false
+	}
+	x, y, w, h := g.morphSlider()
+	if p.X < x || p.X >= x+w || p.Y < y || p.Y >= y+h {
+		return false
+	}
+	g.morph.dragging = true
+	return true
+}
+
+// updateMorph follows the held slider with the cursor and handles the keys
+// that step it.
+func (g *Game) updateMorph(cursor geom.Vec2, ptr pointer) {
+	m := &g.morph
+	if m.dragging {
+		x, _, w, _ := g.morphSlider()
+		g.setMorph((cursor.X - x) / w)
+		if ptr.JustReleased {
+			m.dragging = false
+		}
+	}
+	if g.pressed(actMorphBack) {
+		g.setMorph(m.amount - morphStep)
+	}
+	if g.pressed(actMorphForward) {
+		g.setMorph(m.amount + morphStep)
+	}
+}
+
+// drawMorph draws the slider while there is a scene to morph into.
+func (g *Game) drawMorph(dst *ebiten.Image) {
+	if !g.morph.loaded() {
+		return
+	}
+	x, y, w, h := g.morphSlider()
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(w), float32(h), g.theme.Panel, false)
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(w*g.morph.amount), float32(h), g.theme.PanelOn, false)
+	g.printAt(dst, fmt.Sprintf("Morph %.0f%%", g.morph.amount*100), int(x+w)+8, int(y)-2)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "fmt"
+
+// noteLengthSteps are the lengths N cycles through; 0 uses the envelope's.
+var noteLengthSteps = []float64{0, 0.03, 0.12, 0.25, 0.5, 1}
+
+// nextNoteLength returns the step after l.
+func nextNoteLength(l float64) float64 {
+	for i, s := range noteLengthSteps {
+		if s == l {
+			return noteLengthSteps[(i+1)%len(noteLengthSteps)]
+		}
+	}
+	return 0
+}
+
+// lengthLabel formats a note length for the HUD.
+func lengthLabel(l float64) string {
+	if l <= 0 {
+		return "env"
+	}
+	return fmt.Sprintf("%.0fms", l*1000)
+}
+
+// pointLengthLabel formats a point's note length, where 0 defers to the grid.
+func pointLengthLabel(l float64) string {
+	if l <= 0 {
+		return "inherit"
+	}
+	return lengthLabel(l)
+}
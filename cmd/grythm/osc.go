@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+
+	"grythm/engine"
+)
+
+// OSCOut sends Open Sound Control messages over UDP, e.g. to SuperCollider,
+// Max or TouchDesigner. Only the small subset of OSC 1.0 needed for trigger
+// events is encoded (int32, float32 and string arguments).
+type OSCOut struct {
+	conn *net.UDPConn
+	buf  []byte // reused encoding buffer
+}
+
+// DialOSC connects to an OSC server at host:port.
+func DialOSC(host string, port int) (*OSCOut, error) {
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, fmt.Sprint(port)))
+	if err != nil {
+		return nil, fmt.Errorf("resolve osc address: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial osc: %w", err)
+	}
+	return &OSCOut{conn: conn}, nil
+}
+
+// Trigger sends /grythm/trigger with the grid index, point index, point
+// position and trigger velocity (0..1) of a trigger that sounds.
+func (o *OSCOut) Trigger(t engine.TriggerEvent) {
+	if !t.Audible {
+		return
+	}
+	o.Send("/grythm/trigger", int32(t.Grid), int32(t.Point), float32(t.Pos.X), float32(t.Pos.Y), float32(t.Velocity))
+}
+
+// Intersection sends /grythm/intersection with the indices of both grids and
+// the point (-1 for none), the position and the velocity.
+func (o *OSCOut) Intersection(x engine.IntersectionEvent) {
+	o.Send("/grythm/intersection", int32(x.A), int32(x.B), int32(x.Point), float32(x.Pos.X), float32(x.Pos.Y), float32(x.Velocity))
+}
+
+// Send encodes and sends a message. Arguments must be int32, float32 or string.
+// Send errors are ignored: a missing receiver must not disturb the visualizer.
+func (o *OSCOut) Send(address string, args ...any) {
+	b := appendOSCString(o.buf[:0], address)
+	tags := []byte{','}
+	for _, a := range args {
+		switch a.(type) {
+		case int32:
+			tags = append(tags, 'i')
+		case float32:
+			tags = append(tags, 'f')
+		case string:
+			tags = append(tags, 's')
+		}
+	}
+	b = appendOSCString(b, string(tags))
+	for _, a := range args {
+		switch v := a.(type) {
+		case int32:
+			b = binary.BigEndian.AppendUint32(b, uint32(v))
+		case float32:
+			b = binary.BigEndian.AppendUint32(b, math.Float32bits(v))
+		case string:
+			b = appendOSCString(b, v)
+		}
+	}
+	o.buf = b
+	_, _ = o.conn.Write(b)
+}
+
+// Close closes the UDP socket.
+func (o *OSCOut) Close() error {
+	return o.conn.Close()
+}
+
+// appendOSCString appends s null-terminated and padded to a multiple of 4 bytes.
+func appendOSCString(b []byte, s string) []byte {
+	b = append(b, s...)
+	pad := 4 - len(s)%4
+	for i := 0; i < pad; i++ {
+		b = append(b, 0)
+	}
+	return b
+}
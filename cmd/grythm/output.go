@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"grythm/synth"
+)
+
+// Output level. Ctrl+- and Ctrl+= turn the master gain (-volume at startup)
+// down and up in steps of gainStepDB, and Ctrl+L switches the limiter that
+// keeps the mixed output from clipping when many blips sound at once. The HUD
+// shows the gain, the output peak and how far the limiter turns it down, and
+// flags CLIP for a moment whenever the output went past full scale with the
+// limiter off.
+
+const (
+	maxGain         = 4.0  // master gain limit, about +12 dB
+	minGainDB       = -40  // lowest gain Ctrl+- steps down to before muting
+	gainStepDB      = 1.5  // step of Ctrl+- and Ctrl+=
+	meterFallDB     = 20.0 // decibels per second the peak and limiter readings fall back
+	clipHoldSeconds = 1.5  // how long CLIP stays up after the output clipped
+)
+
+// outputMeter is the HUD's reading of the mixer's meter, held so that it can
+// be read.
+type outputMeter struct {
+	peak     float64 // output peak, falling back at meterFallDB
+	limit    float64 // limiter gain, 1 when not limiting, returning at meterFallDB
+	clipHold float64 // seconds CLIP stays up
+}
+
+// stepGain returns gain moved by steps of gainStepDB, muted below minGainDB.
+func stepGain(gain float64, steps int) float64 {
+	db := minGainDB - gainStepDB
+	if gain > 0 {
+		db = synth.Decibels(gain)
+	}
+	db = math.Round((db+float64(steps)*gainStepDB)/gainStepDB) * gainStepDB
+	if db < minGainDB {
+		return 0
+	}
+	return math.Min(math.Pow(10, db/20), maxGain)
+}
+
+// outputKeys handles the master gain and limiter keys.
+func (g *Game) outputKeys() {
+	if s := g.step(actGainUp, actGainDown); s != 0 {
+		g.mixer.SetGain(stepGain(g.mixer.Gain(), s))
+	}
+	if g.pressed(actLimiter) {
+		g.mixer.SetLimiter(!g.mixer.Limiting())
+	}
+}
+
+// updateMeter reads the mixer's meter into the HUD's over dt seconds.
+func (g *Game) updateMeter(dt float64) {
+	mt := g.mixer.Meter()
+	fall := math.Pow(10, -meterFallDB*dt/20)
+	om := &g.outMeter
+	om.peak = math.Max(mt.Peak, om.peak*fall)
+	om.limit = math.Min(mt.Limit, math.Min(1, om.limit/fall))
+	om.clipHold = math.Max(0, om.clipHold-dt)
+	if mt.Clipped > 0 && !g.mixer.Limiting() {
+		om.clipHold = clipHoldSeconds
+	}
+}
+
+// outputLabel formats the output level for the HUD.
+func (g *Game) outputLabel() string {
+	gain := "muted"
+	if v := g.mixer.Gain(); v > 0 {
+		gain = fmt.Sprintf("%+.1f dB", synth.Decibels(v))
+	}
+	l := "  Gain: " + gain
+	if om := g.outMeter; om.peak > 0 {
+		l += fmt.Sprintf("  Peak: %.1f dB", synth.Decibels(om.peak))
+	}
+	switch {
+	case !g.mixer.Limiting():
+		l += "  Limiter off"
+	case g.outMeter.limit < 1:
+		l += fmt.Sprintf("  Limiting %.1f dB", synth.Decibels(g.outMeter.limit))
+	}
+	if g.outMeter.clipHold > 0 {
+		l += "  CLIP"
+	}
+	return l
+}
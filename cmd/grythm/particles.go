@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/geom"
+)
+
+// Particle is a short-lived spark spawned when a point is triggered.
+type Particle struct {
+	Pos, Vel geom.Vec2
+	Life     float64 // seconds remaining
+	MaxLife  float64
+	Color    color.RGBA
+}
+
+// Particles is a small pool of live particles, updated and drawn by Game.
+type Particles struct {
+	list []Particle
+	rng  *rand.Rand // seeded, so a replayed session bursts alike
+}
+
+// maxParticles caps the pool so dense scenes can't grow it unboundedly.
+const maxParticles = 2048
+
+// Burst spawns n particles at p flying outward in random directions, tinted with col.
+func (ps *Particles) Burst(p geom.Vec2, col color.Color, n int) {
+	c := toRGBA(col)
+	for i := 0; i < n && len(ps.list) < maxParticles; i++ {
+		a := ps.rng.Float64() * 2 * math.Pi
+		sp := 40 + ps.rng.Float64()*80 // px/s
+		life := 0.3 + ps.rng.Float64()*0.3
+		ps.list = append(ps.list, Particle{
+			Pos:     p,
+			Vel:     geom.Vec2{X: math.Cos(a), Y: math.Sin(a)}.Mul(sp),
+			Life:    life,
+			MaxLife: life,
+			Color:   c,
+		})
+	}
+}
+
+// Update moves particles and drops the ones that expired.
+func (ps *Particles) Update(dt float64) {
+	// Exponential drag so bursts slow down and hang briefly before fading
+	drag := math.Exp(-4 * dt)
+	live := ps.list[:0]
+	for _, pt := range ps.list {
+		pt.Life -= dt
+		if pt.Life <= 0 {
+			continue
+		}
+		pt.Pos = pt.Pos.Add(pt.Vel.Mul(dt))
+		pt.Vel = pt.Vel.Mul(drag)
+		live = append(live, pt)
+	}
+	ps.list = live
+}
+
+// Draw renders particles as small dots fading out with their remaining life.
+func (ps *Particles) Draw(dst *ebiten.Image, cam *geom.Camera) {
+	for _, pt := range ps.list {
+		t := pt.Life / pt.MaxLife
+		c := pt.Color
+		c.A = uint8(float64(c.A) * t)
+		sp := cam.ToScreen(pt.Pos)
+		vector.DrawFilledCircle(dst, float32(sp.X), float32(sp.Y), float32((1+1.5*t)*cam.Device()), c, true)
+	}
+}
+
+// toRGBA converts any color to non-premultiplied 8-bit RGBA.
+func toRGBA(c color.Color) color.RGBA {
+	if c == nil {
+		return color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return color.RGBA{n.R, n.G, n.B, n.A}
+}
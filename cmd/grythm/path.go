@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Sizes of the paths J gives a point, in world pixels.
+const (
+	pathLineLength = 160
+	pathRadius     = 60
+	pathLissajousW = 90
+	pathLissajousH = 60
+)
+
+// defaultPathSpeed is the speed, in rounds per second, of a newly given path.
+const defaultPathSpeed = 0.25
+
+// pathSpeeds are the speeds Shift+J cycles through.
+var pathSpeeds = []float64{0.125, 0.25, 0.5, 1, -0.25}
+
+// nextPath returns the path after cur in the order none, circle, Lissajous,
+// line. New paths start at pos, where the point is, so it doesn't jump.
+func nextPath(pos geom.Vec2, cur geom.Path) geom.Path {
+	speed := cur.Speed
+	if speed == 0 {
+		speed = defaultPathSpeed
+	}
+	switch cur.Kind {
+	case geom.PathNone:
+		return geom.Path{Kind: geom.PathCircle, Center: pos.Sub(geom.Vec2{X: pathRadius}), Radius: pathRadius, Speed: speed}
+	case geom.PathCircle:
+		// A figure of eight through the point
+		return geom.Path{Kind: geom.PathLissajous, Center: pos, Size: geom.Vec2{X: pathLissajousW, Y: pathLissajousH}, Freq: geom.Vec2{X: 1, Y: 2}, Speed: speed}
+	case geom.PathLissajous:
+		return geom.Path{Kind: geom.PathLine, Line: []geom.Vec2{pos, pos.Add(geom.Vec2{X: pathLineLength})}, Speed: speed}
+	}
+	return geom.Path{}
+}
+
+// nextPathSpeed returns the speed step after s.
+func nextPathSpeed(s float64) float64 {
+	for i, v := range pathSpeeds {
+		if v == s {
+			return pathSpeeds[(i+1)%len(pathSpeeds)]
+		}
+	}
+	return pathSpeeds[0]
+}
+
+// pathLabel formats a point's path for the HUD.
+func pathLabel(p geom.Path) string {
+	if !p.Moves() {
+		return "none"
+	}
+	return fmt.Sprintf("%s %g/s", p.Kind, p.Speed)
+}
+
+// interpolatedPoints returns copies of the points where they are at the
+// current time, between the last two ticks, like interpolatedGrids.
+func (g *Game) interpolatedPoints() []engine.Point {
+	back := (1 - g.timestep.Alpha()) * g.timestep.Step()
+	g.drawPoints = g.RewindPoints(g.drawPoints, back)
+	return g.drawPoints
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/geom"
+)
+
+// Phase nudging. ' moves the selected grid's pattern forward by an exact
+// fraction of its spacing (Shift+' moves it back) and \ picks the fraction,
+// 1/2, 1/3 or 1/4, so families of the same spacing can be set off against
+// each other by a clean subdivision instead of by eye. Shift+\ puts the
+// pattern back at its origin. While phases are edited a panel shows where
+// each family sits within its spacing, relative to the selected one.
+
+// phaseSteps are the fractions of a spacing \ cycles through.
+var phaseSteps = []int{2, 3, 4}
+
+// phaseShowSeconds is how long the phase panel stays up after an edit.
+const phaseShowSeconds = 3.0
+
+// nudgePhaseCmd moves the pattern of the grid family at idx by frac of its
+// spacing.
+type nudgePhaseCmd struct {
+	idx  int
+	frac float64
+}
+
+func (c nudgePhaseCmd) Do(g *Game)   { g.Grids[c.idx].NudgePhase(c.frac) }
+func (c nudgePhaseCmd) Undo(g *Game) { g.Grids[c.idx].NudgePhase(-c.frac) }
+
+// phaseKeys handles the phase keys of the selected grid.
+func (g *Game) phaseKeys() {
+	if g.selGrid >= len(g.Grids) {
+		return
+	}
+	if g.pressed(actPhaseFraction) {
+		g.phaseStep = (g.phaseStep + 1) % len(phaseSteps)
+		g.phaseShow = phaseShowSeconds
+	}
+	frac := 1 / float64(phaseSteps[g.phaseStep])
+	switch {
+	case g.pressed(actPhaseForward):
+		g.exec(nudgePhaseCmd{idx: g.selGrid, frac: frac})
+		g.phaseShow = phaseShowSeconds
+	case g.pressed(actPhaseBack):
+		g.exec(nudgePhaseCmd{idx: g.selGrid, frac: -frac})
+		g.phaseShow = phaseShowSeconds
+	case g.pressed(actPhaseReset):
+		g.exec(gridEditCmd[geom.Phase]{idx: g.selGrid, from: g.Grids[g.selGrid].Phase(), to: geom.Phase{},
+			set: func(gf *geom.GridFamily, v geom.Phase) { gf.SetPhase(v) }})
+		g.phaseShow = phaseShowSeconds
+	}
+}
+
+// phaseLabel formats the nudge fraction for the HUD.
+func (g *Game) phaseLabel() string {
+	return fmt.Sprintf("1/%d", phaseSteps[g.phaseStep])
+}
+
+// drawPhases draws the phase panel at the bottom left, above the timeline
+// when it is shown: a lane per family with a marker where its pattern sits
+// within one spacing, ticks at the nudge fraction and, beside it, the
+// difference from the selected family in spacings.
+func (g *Game) drawPhases(dst *ebiten.Image) {
+	if g.phaseShow <= 0 || len(g.Grids) == 0 {
+		return
+	}
+	const (
+		laneW = 120.0
+		textW = 130.0
+	)
+	rows := min(len(g.Grids), statsMaxRows)
+	h := float64(statsLineH*(rows+1) + 4)
+	bottom := float64(g.H) - 8
+	if g.showTimeline {
+		bottom -= 10*float64(len(g.Grids)) + 8
+	}
+	x, y := 8.0, bottom-h
+	vector.DrawFilledRect(dst, float32(x), float32(y), laneW+textW+12, float32(h), g.theme.Panel, false)
+
+	sel := g.Grids[min(g.selGrid, len(g.Grids)-1)].PhaseFraction()
+	n := phaseSteps[g.phaseStep]
+	var b strings.Builder
+	fmt.Fprintf(&b, "Phase (nudge %s)\n", g.phaseLabel())
+	for gi := 0; gi < rows; gi++ {
+		gf := &g.Grids[gi]
+		f := gf.PhaseFraction()
+		d := f - sel
+		// The shorter way round, so a family just behind reads as behind
+		switch {
+		case d > 0.5:
+			d--
+		case d <= -0.5:
+			d++
+		}
+		mark := " "
+		if gi == g.selGrid {
+			mark = ">"
+		}
+		fmt.Fprintf(&b, "%sG%d %.3f %+.3f\n", mark, gi+1, f, d)
+
+		ly := y + float64(statsLineH*(gi+1)) + 4
+		lx := x + textW + 4
+		vector.StrokeLine(dst, float32(lx), float32(ly+statsLineH/2), float32(lx+laneW), float32(ly+statsLineH/2), 1, g.theme.Guide, false)
+		for k := 0; k <= n; k++ {
+			tx := lx + laneW*float64(k)/float64(n)
+			vector.StrokeLine(dst, float32(tx), float32(ly+4), float32(tx), float32(ly+statsLineH-4), 1, g.theme.Guide, false)
+		}
+		w := float32(2)
+		if gi == g.selGrid {
+			w = 4
+		}
+		mx := lx + laneW*f
+		vector.StrokeLine(dst, float32(mx), float32(ly+1), float32(mx), float32(ly+statsLineH-1), w, gf.Color, false)
+	}
+	g.printAt(dst, b.String(), int(x)+4, int(y))
+}
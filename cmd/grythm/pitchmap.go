@@ -0,0 +1,144 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Pitch from position. P cycles the pitch map off, on (degree across, octave
+// up) and by distance to the tonal center, which is drawn as a marker ringed
+// at every octave. Dragging the marker re-pitches the points as it goes, so
+// moving it sweeps the harmony; the drag is one undo step.
+
+// tonicGrab is the on-screen radius (window pixels) within which a press
+// grabs the tonal center marker.
+const tonicGrab = 12.0
+
+// maxTonicRings bounds the octave rings drawn; zoomed far out they would
+// only fill the view.
+const maxTonicRings = 64
+
+// tonicDrag is a drag of the tonal center in progress.
+type tonicDrag struct {
+	active bool
+	grab   geom.Vec2      // offset from the cursor to the marker
+	from   geom.Vec2      // the marker's place at press, relative to the canvas center
+	before []engine.Point // the points as they were at press, for undo
+}
+
+// tonicCmd moves the tonal center.
+type tonicCmd struct {
+	from, to geom.Vec2
+}
+
+func (c tonicCmd) Do(g *Game)   { g.PitchMap.Tonic = c.to }
+func (c tonicCmd) Undo(g *Game) { g.PitchMap.Tonic = c.from }
+
+// cyclePitchMap steps the mapping from off to position to distance and back
+// to off. Switching to a mapping re-derives every point's degree from its
+// position, as one undoable step.
+func (g *Game) cyclePitchMap() {
+	pm := &g.PitchMap
+	switch {
+	case !pm.Enabled:
+		pm.Enabled = true
+	case !pm.Distance:
+		pm.Distance = true
+	default:
+		pm.Enabled, pm.Distance = false, false
+		return
+	}
+	if cmds := g.repitchCmds(); len(cmds) > 0 {
+		g.exec(cmds)
+	}
+}
+
+// repitchCmds returns the edits that give every point the degree the pitch
+// map has for its position.
+func (g *Game) repitchCmds() batchCmd {
+	var cmds batchCmd
+	for i, p := range g.Points {
+		if deg := g.DegreeAt(p.Pos); deg != p.Degree {
+			after := p
+			after.Degree = deg
+			cmds = append(cmds, editPointCmd{idx: i, before: p, after: after})
+		}
+	}
+	return cmds
+}
+
+// grabTonic starts dragging the tonal center when the distance mapping is on
+// and screen position p is on its marker, reporting whether it did.
+func (g *Game) grabTonic(p geom.Vec2) bool {
+	if !g.PitchMap.Enabled || !g.PitchMap.Distance {
+		return false
+	}
+	at := g.TonicPos()
+	if g.cam.ToScreen(at).Sub(p).Len() > tonicGrab*g.cam.Device() {
+		return false
+	}
+	g.tonic = tonicDrag{
+		active: true,
+		grab:   at.Sub(g.cam.ToWorld(p)),
+		from:   g.PitchMap.Tonic,
+		before: append([]engine.Point(nil), g.Points...),
+	}
+	return true
+}
+
+// updateTonic moves the grabbed tonal center with the mouse, re-pitching the
+// points, and records the drag on release.
+func (g *Game) updateTonic(mouse geom.Vec2, ptr pointer) {
+	td := &g.tonic
+	if !td.active {
+		return
+	}
+	g.PitchMap.Tonic = g.snapPoint(mouse.Add(td.grab)).Sub(g.Center())
+	for i := range g.Points {
+		g.Points[i].Degree = g.DegreeAt(g.Points[i].Pos)
+	}
+	if !ptr.JustReleased {
+		return
+	}
+	td.active = false
+	if g.PitchMap.Tonic == td.from {
+		return
+	}
+	batch := batchCmd{tonicCmd{from: td.from, to: g.PitchMap.Tonic}}
+	// Points added or removed during the drag leave the old ones unmatched;
+	// the move is then kept without their pitches
+	if len(td.before) == len(g.Points) {
+		for i, p := range td.before {
+			if p.Degree != g.Points[i].Degree {
+				batch = append(batch, editPointCmd{idx: i, before: p, after: g.Points[i]})
+			}
+		}
+	}
+	g.record(batch)
+}
+
+// drawTonic draws the tonal center marker and a ring at every octave out from
+// it while the distance mapping is on.
+func (g *Game) drawTonic(dst *ebiten.Image) {
+	if !g.PitchMap.Enabled || !g.PitchMap.Distance {
+		return
+	}
+	d := g.cam.Device()
+	at := g.TonicPos()
+	sp := g.cam.ToScreen(at)
+	octave := g.PitchMap.RingStep() * float64(len(g.Scale.Steps))
+	reach := g.cam.ViewRadius() + g.cam.Center.Sub(at).Len()
+	if reach/octave < maxTonicRings {
+		for r := octave; r < reach; r += octave {
+			vector.StrokeCircle(dst, float32(sp.X), float32(sp.Y), float32(r*g.cam.Scale()), float32(d), g.theme.Guide, true)
+		}
+	}
+	col := g.theme.Point
+	if g.tonic.active {
+		col = g.theme.PointHover
+	}
+	vector.StrokeCircle(dst, float32(sp.X), float32(sp.Y), float32(0.6*tonicGrab*d), float32(1.5*d), col, true)
+	drawCross(dst, sp, tonicGrab*d, col)
+}
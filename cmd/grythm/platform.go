@@ -0,0 +1,49 @@
+//go:build !js
+
+package main
+
+// hasFileSystem reports whether scenes, slots and recordings can be written to disk.
+const hasFileSystem = true
+
+// openOutputs connects the MIDI and OSC outputs that were configured.
+func (g *Game) openOutputs(midiPort string, midiChannel int, oscHost string, oscPort int) error {
+	if midiPort != "" {
+		m, err := OpenMIDIOut(midiPort, midiChannel)
+		if err != nil {
+			return err
+		}
+		g.midi = m
+		g.Bus.OnTrigger(g.midiTrigger)
+		g.Bus.OnIntersection(g.midiIntersection)
+	}
+	if oscPort != 0 {
+		o, err := DialOSC(oscHost, oscPort)
+		if err != nil {
+			return err
+		}
+		g.osc = o
+		g.Bus.OnTrigger(o.Trigger)
+		g.Bus.OnIntersection(o.Intersection)
+	}
+	return nil
+}
+
+// openInputs connects the MIDI input and starts the remote control server if
+// they were configured.
+func (g *Game) openInputs(midiPort, remoteAddr string) error {
+	if midiPort != "" {
+		m, err := OpenMIDIIn(midiPort)
+		if err != nil {
+			return err
+		}
+		g.midiIn = m
+	}
+	if remoteAddr != "" {
+		r, err := StartRemote(remoteAddr)
+		if err != nil {
+			return err
+		}
+		g.remote = r
+	}
+	return nil
+}
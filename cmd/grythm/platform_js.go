@@ -0,0 +1,30 @@
+//go:build js
+
+package main
+
+import "log"
+
+// hasFileSystem reports whether scenes, slots and recordings can be written to
+// disk; the browser build has no file system, so only built-in presets load.
+const hasFileSystem = false
+
+// openOutputs is a no-op in the browser: raw MIDI devices and UDP sockets are
+// not available there.
+func (g *Game) openOutputs(midiPort string, midiChannel int, oscHost string, oscPort int) error {
+	if midiPort != "" || oscPort != 0 {
+		log.Print("MIDI and OSC output are not available in the browser")
+	}
+	return nil
+}
+
+// openInputs is a no-op in the browser for the same reason; a page can't
+// serve the remote control API either.
+func (g *Game) openInputs(midiPort, remoteAddr string) error {
+	if midiPort != "" {
+		log.Print("MIDI input is not available in the browser")
+	}
+	if remoteAddr != "" {
+		log.Print("the remote control server is not available in the browser")
+	}
+	return nil
+}
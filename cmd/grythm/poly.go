@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"grythm/geom"
+)
+
+// Polyrhythms. Y types a ratio such as 3:4:5 that sets the spacings of the
+// selected grid and the ones after it: the first keeps its spacing and the
+// others get the spacing that makes them cross as often as their term says
+// in the time the first takes for its own, so 3:4:5 gives three, four and
+// five lines over the same stretch of travel. All of them are moved to have a
+// line through the origin, the hovered point or else the middle of the view,
+// so the rhythm starts there in phase.
+
+// maxRatioTerms bounds the terms of a ratio.
+const maxRatioTerms = 16
+
+// parseRatio parses colon-separated positive numbers.
+func parseRatio(s string) ([]float64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > maxRatioTerms {
+		return nil, fmt.Errorf("want 2 to %d terms like 3:4", maxRatioTerms)
+	}
+	terms := make([]float64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("term %q is not a positive number", p)
+		}
+		terms[i] = v
+	}
+	return terms, nil
+}
+
+// polySpacing is the spacing and phase a polyrhythm gives a family.
+type polySpacing struct {
+	spacing float64
+	phase   geom.Phase
+}
+
+// setPolySpacing sets a family's spacing and moves its pattern to the given phase.
+func setPolySpacing(gf *geom.GridFamily, v polySpacing) {
+	setGridSpacing(gf, v.spacing)
+	gf.SetPhase(v.phase)
+}
+
+// applyRatio spaces the families from the selected grid on by ratio and
+// aligns them at origin, as one undo step. Ray families have no spacing and
+// are skipped without using up a term.
+func (g *Game) applyRatio(ratio []float64, origin geom.Vec2) error {
+	if g.selGrid >= len(g.Grids) {
+		return fmt.Errorf("no grid selected")
+	}
+	base := g.Grids[g.selGrid].Spacing * ratio[0]
+	center := g.Center()
+	var batch batchCmd
+	term := 0
+	for gi := g.selGrid; gi < len(g.Grids) && term < len(ratio); gi++ {
+		gf := g.Grids[gi]
+		if gf.Kind == geom.GridRay {
+			continue
+		}
+		from := polySpacing{gf.Spacing, gf.Phase()}
+		setGridSpacing(&gf, base/ratio[term])
+		gf.AlignAt(origin, center)
+		to := polySpacing{gf.Spacing, gf.Phase()}
+		batch = append(batch, gridEditCmd[polySpacing]{idx: gi, from: from, to: to, set: setPolySpacing})
+		term++
+	}
+	if term < len(ratio) {
+		return fmt.Errorf("%d terms but only %d grids from the selected one", len(ratio), term)
+	}
+	g.exec(batch)
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Preset is a built-in scene, selectable with the number keys.
+type Preset struct {
+	Name  string
+	Scene func(w, h float64) Scene // builds the scene for a w x h window
+}
+
+// Presets is the built-in preset registry. Slot n (1-9) loads Presets[n-1]
+// unless the user saved their own scene into that slot.
+var Presets = []Preset{
+	{"polyrhythm 3:4", presetPolyrhythm},
+	{"euclidean 3/8 + 5/8", presetEuclidean},
+	{"diagonal moire", presetMoire},
+	{"honeycomb ripples", presetHoneycomb},
+	{"radar", presetRadar},
+}
+
+// presetPolyrhythm: two vertical families with spacings 4:3 sweep one row of
+// points, so the blue lines play 3 beats against the green lines' 4.
+func presetPolyrhythm(w, h float64) Scene {
+	sc := baseScene()
+	sc.MoveDir = geom.Vec2{X: 1, Y: 0}
+	sc.Speed = 120
+	sc.Grids = []SceneGrid{
+		{Normal: geom.Vec2{X: 1, Y: 0}, Spacing: 80, Color: "#6666FF", Thickness: 2, Wave: synth.WaveTriangle},
+		{Normal: geom.Vec2{X: 1, Y: 0}, Spacing: 60, Color: "#66FF66", Thickness: 2, Degree: 4},
+	}
+	sc.Points = []ScenePoint{
+		{Pos: geom.Vec2{X: w * 0.5, Y: h * 0.5}},
+	}
+	return sc
+}
+
+// presetEuclidean: points placed on the steps of Euclidean rhythms, swept by
+// one line per bar, so each row plays its pattern.
+func presetEuclidean(w, h float64) Scene {
+	sc := baseScene()
+	sc.MoveDir = geom.Vec2{X: 1, Y: 0}
+	sc.Speed = 120
+	const step = 40.0
+	bar := 8 * step
+	sc.Grids = []SceneGrid{
+		{Normal: geom.Vec2{X: 1, Y: 0}, Spacing: bar, Color: "#FFAA44", Thickness: 2},
+	}
+	left := w*0.5 - bar/2
+	for row, k := range []int{3, 5} {
+		y := h*0.4 + float64(row)*h*0.2
+		for i, on := range geom.EuclidRhythm(k, 8) {
+			if on {
+				sc.Points = append(sc.Points, ScenePoint{Pos: geom.Vec2{X: left + float64(i)*step, Y: y}, Degree: row * 2})
+			}
+		}
+	}
+	return sc
+}
+
+// presetMoire: nearly parallel diagonal families interfere into slow beating patterns.
+func presetMoire(w, h float64) Scene {
+	sc := baseScene()
+	sc.MoveDir = geom.Vec2{X: 1, Y: 0.2}.Norm()
+	sc.Speed = 90
+	for i, col := range []string{"#FF66CC", "#66CCFF", "#CCFF66"} {
+		a := math.Pi/4 + float64(i)*0.06
+		sc.Grids = append(sc.Grids, SceneGrid{
+			Normal:    geom.Vec2{X: math.Cos(a), Y: math.Sin(a)},
+			Spacing:   36 + 2*float64(i),
+			Color:     col,
+			Thickness: 1.5,
+			Degree:    2 * i,
+		})
+	}
+	for i := 0; i < 5; i++ {
+		sc.Points = append(sc.Points, ScenePoint{Pos: geom.Vec2{X: w * (0.2 + 0.15*float64(i)), Y: h * 0.5}, Degree: i})
+	}
+	return sc
+}
+
+// presetHoneycomb: a drifting honeycomb with ripples spreading from the middle.
+func presetHoneycomb(w, h float64) Scene {
+	sc := baseScene()
+	sc.MoveDir = geom.Vec2{X: 0.3, Y: 1}.Norm()
+	sc.Speed = 60
+	sc.Grids = []SceneGrid{
+		{Kind: geom.GridHex, Normal: geom.Vec2{X: 1, Y: 0}, Spacing: 50, Color: "#AA88FF", Thickness: 2, HexTiling: true, Wave: synth.WaveSine},
+		{Kind: geom.GridRadial, Origin: geom.Vec2{X: w * 0.5, Y: h * 0.5}, Spacing: 160, Color: "#FF8844", Thickness: 2, Wave: synth.WaveSaw, Degree: -7},
+	}
+	for i := 0; i < 6; i++ {
+		a := float64(i) * math.Pi / 3
+		sc.Points = append(sc.Points, ScenePoint{Pos: geom.Vec2{X: w*0.5 + 150*math.Cos(a), Y: h*0.5 + 150*math.Sin(a)}, Degree: i})
+	}
+	return sc
+}
+
+// presetRadar: a clock hand sweeps a spiral of points once every two bars while
+// slow horizontal lines drift over them.
+func presetRadar(w, h float64) Scene {
+	sc := baseScene()
+	sc.MoveDir = geom.Vec2{X: 0, Y: 1}
+	sc.Speed = 20
+	sc.Grids = []SceneGrid{
+		{Kind: geom.GridRay, Origin: geom.Vec2{X: w * 0.5, Y: h * 0.5}, Rays: 1, AngularSpeed: 90, Color: "#66FFAA", Thickness: 2, Wave: synth.WaveTriangle},
+		{Normal: geom.Vec2{X: 0, Y: 1}, Spacing: 120, Color: "#445566", Thickness: 2, Degree: -7},
+	}
+	for i := 0; i < 8; i++ {
+		a := float64(i) * math.Pi / 4
+		r := 60 + 25*float64(i)
+		sc.Points = append(sc.Points, ScenePoint{Pos: geom.Vec2{X: w*0.5 + r*math.Cos(a), Y: h*0.5 + r*math.Sin(a)}, Degree: i})
+	}
+	return sc
+}
+
+// baseScene holds the settings shared by all presets.
+func baseScene() Scene {
+	return Scene{Key: 81, Scale: synth.Scales[0], BPM: 120}
+}
+
+// slotPath returns the file a user slot is stored in.
+func (g *Game) slotPath(slot int) string {
+	return filepath.Join(g.presetDir, fmt.Sprintf("slot-%d.json", slot))
+}
+
+// LoadSlot switches to slot (1-9): the user's saved scene if there is one,
+// otherwise the built-in preset with that number.
+func (g *Game) LoadSlot(slot int) error {
+	if hasFileSystem {
+		err := g.LoadScene(g.slotPath(slot))
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if slot < 1 || slot > len(Presets) {
+		return fmt.Errorf("slot %d is empty", slot)
+	}
+	return g.ApplyScene(Presets[slot-1].Scene(float64(g.W), float64(g.H)))
+}
+
+// SaveSlot stores the current scene in user slot (1-9).
+func (g *Game) SaveSlot(slot int) error {
+	if !hasFileSystem {
+		return errNoFileSystem
+	}
+	if err := os.MkdirAll(g.presetDir, 0o755); err != nil {
+		return err
+	}
+	return g.SaveScene(g.slotPath(slot))
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package main
+
+// processAlive reports every process as gone where processes can't be
+// looked up; there is no file system to autosave to there either.
+func processAlive(pid int) bool {
+	return false
+}
@@ -0,0 +1,14 @@
+package main
+
+import "os"
+
+// processAlive reports whether a process with the given id is running;
+// finding one that has exited fails here.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
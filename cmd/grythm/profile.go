@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// The debug overlay (F1, Shift+F1 docks it in the next corner of the window)
+// shows what a frame costs, to find what slows down a large scene: the
+// ticks and frames per second, the voices sounding, the scene's size, the
+// memory allocated, the line batches drawn and how long each part of a frame
+// takes. The times are averages over about profSmooth seconds, with the
+// worst frame of the last profWindow seconds next to them.
+
+const (
+	profSmooth = 0.5   // seconds the averages are smoothed over
+	profWindow = 1.0   // seconds the worst frame is kept and the memory read
+	profW      = 260.0 // width of the overlay
+)
+
+// profSection is a timed part of a frame.
+type profSection int
+
+const (
+	profUpdate  profSection = iota // the whole of Update, the ticks included
+	profTick                       // the engine's ticks and the per-tick work around them
+	profMotion                     // moving the pattern and the points (engine.TickProfile)
+	profDetect                     // finding the points lines touch
+	profPublish                    // sounding and showing the triggers
+	profDraw                       // Draw
+	profSectionCount
+)
+
+var profSectionNames = [profSectionCount]string{"update", "tick", "motion", "detect", "publish", "draw"}
+
+// profDock is the corner the overlay is docked in.
+type profDock int
+
+const (
+	dockBottomRight profDock = iota
+	dockBottomLeft
+	dockTopLeft
+	dockTopRight
+	dockCount
+)
+
+// profiler times the parts of every frame and keeps the statistics the
+// overlay shows.
+type profiler struct {
+	show bool
+	dock profDock
+
+	frame [profSectionCount]time.Duration // this frame's times, summed over its ticks
+	avg   [profSectionCount]float64       // smoothed milliseconds per frame
+	worst [profSectionCount]float64       // highest milliseconds of the window
+	peak  [profSectionCount]float64       // highest milliseconds so far in this window
+
+	ticks     int     // ticks this frame
+	tickAvg   float64 // smoothed ticks per frame
+	drawCalls float64 // smoothed line batches per frame
+
+	window  float64 // seconds into the current window
+	mallocs uint64  // allocations counted at the start of the window
+	allocs  float64 // allocations per second over the last window
+	heap    uint64  // bytes in use on the heap at the last reading
+	gcs     uint32  // garbage collections so far
+}
+
+// add counts d against section s of this frame.
+func (p *profiler) add(s profSection, d time.Duration) {
+	p.frame[s] += d
+}
+
+// since counts the time since start against section s of this frame.
+func (p *profiler) since(s profSection, start time.Time) {
+	p.add(s, time.Since(start))
+}
+
+// addTick counts the parts of an engine tick.
+func (p *profiler) addTick(tp engine.TickProfile) {
+	p.add(profMotion, tp.Motion)
+	p.add(profDetect, tp.Detect)
+	p.add(profPublish, tp.Publish)
+	p.ticks++
+}
+
+// endFrame folds the last frame, of dt seconds, into the statistics and
+// starts a new one. Memory is read once a window, and only while the overlay
+// shows, since reading it briefly stops the world.
+func (p *profiler) endFrame(dt float64) {
+	k := 1.0
+	if dt < profSmooth {
+		k = dt / profSmooth
+	}
+	for s := range p.frame {
+		ms := p.frame[s].Seconds() * 1000
+		p.avg[s] += (ms - p.avg[s]) * k
+		p.peak[s] = max(p.peak[s], ms)
+		p.frame[s] = 0
+	}
+	p.tickAvg += (float64(p.ticks) - p.tickAvg) * k
+	p.ticks = 0
+	p.drawCalls += (float64(geom.DrawCalls()) - p.drawCalls) * k
+	p.window += dt
+	if p.window < profWindow {
+		return
+	}
+	p.worst, p.peak = p.peak, [profSectionCount]float64{}
+	if p.show {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		if p.mallocs > 0 {
+			p.allocs = float64(ms.Mallocs-p.mallocs) / p.window
+		}
+		p.mallocs, p.heap, p.gcs = ms.Mallocs, ms.HeapAlloc, ms.NumGC
+	} else {
+		p.mallocs = 0
+	}
+	p.window = 0
+}
+
+// drawProfile renders the debug overlay in its corner.
+func (g *Game) drawProfile(dst *ebiten.Image) {
+	p := &g.prof
+	var b strings.Builder
+	fmt.Fprintf(&b, "TPS %.0f  FPS %.0f  ticks/frame %.1f\n", ebiten.ActualTPS(), ebiten.ActualFPS(), p.tickAvg)
+	fmt.Fprintf(&b, "Voices %d  grids %d  points %d\n", g.mixer.ActiveVoices(), len(g.Grids), len(g.Points))
+	fmt.Fprintf(&b, "Allocs %.0f/s  heap %.1f MB  GCs %d\n", p.allocs, float64(p.heap)/(1<<20), p.gcs)
+	fmt.Fprintf(&b, "Line batches %.1f/frame\n", p.drawCalls)
+	fmt.Fprintf(&b, "%-10s %6s %7s\n", "ms/frame", "avg", "worst")
+	for s, name := range profSectionNames {
+		fmt.Fprintf(&b, "%-10s %6.2f %7.2f\n", name, p.avg[s], p.worst[s])
+	}
+	lines := strings.Count(b.String(), "\n")
+
+	w, h := profW, float64(statsLineH*lines+4)
+	x, y := 8.0, 8.0
+	if p.dock == dockBottomRight || p.dock == dockTopRight {
+		x = float64(g.W) - w - 8
+	}
+	if p.dock == dockBottomRight || p.dock == dockBottomLeft {
+		y = float64(g.H) - h - 8
+	}
+	vector.DrawFilledRect(dst, float32(x), float32(y), float32(w), float32(h), g.theme.Panel, false)
+	g.printAt(dst, b.String(), int(x)+4, int(y))
+}
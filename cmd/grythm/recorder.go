@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Recorder captures frames from the Draw loop and writes them to disk as an
+// animated GIF or a numbered PNG sequence. Encoding happens on a background
+// goroutine; when it falls behind, frames are dropped rather than stalling Draw.
+type Recorder struct {
+	format string // "gif" or "png"
+	every  int    // capture every Nth drawn frame
+	tick   int
+
+	frames chan *image.RGBA
+	done   chan error
+	out    string // file (gif) or directory (png) being written
+}
+
+// recordFPS is the capture rate; Draw runs at 60 FPS so every third frame is kept.
+const recordFPS = 20
+
+// maxGIFFrames bounds the in-memory GIF so a forgotten recording can't exhaust memory.
+const maxGIFFrames = 60 * recordFPS
+
+// StartRecorder begins a recording in dir using format "gif" or "png".
+func StartRecorder(dir, format string) (*Recorder, error) {
+	if !hasFileSystem {
+		return nil, errNoFileSystem
+	}
+	stamp := time.Now().Format("20060102-150405")
+	r := &Recorder{
+		format: format,
+		every:  60 / recordFPS,
+		frames: make(chan *image.RGBA, 16),
+		done:   make(chan error, 1),
+	}
+	switch format {
+	case "gif":
+		r.out = filepath.Join(dir, "grythm-"+stamp+".gif")
+		go r.encodeGIF()
+	case "png":
+		r.out = filepath.Join(dir, "grythm-"+stamp)
+		if err := os.MkdirAll(r.out, 0o755); err != nil {
+			return nil, err
+		}
+		go r.encodePNG()
+	default:
+		return nil, fmt.Errorf("unknown record format %q (want gif or png)", format)
+	}
+	return r, nil
+}
+
+// Capture grabs the current screen contents if this frame is due.
+func (r *Recorder) Capture(screen *ebiten.Image) {
+	r.tick++
+	if r.tick%r.every != 0 {
+		return
+	}
+	img := image.NewRGBA(screen.Bounds())
+	screen.ReadPixels(img.Pix)
+	select {
+	case r.frames <- img:
+	default:
+		// Encoder is behind; skip this frame
+	}
+}
+
+// Stop ends the recording, waits for the encoder and returns the output path.
+func (r *Recorder) Stop() (string, error) {
+	close(r.frames)
+	return r.out, <-r.done
+}
+
+func (r *Recorder) encodeGIF() {
+	anim := &gif.GIF{}
+	delay := 100 / recordFPS // GIF delays are in 1/100s
+	for img := range r.frames {
+		if len(anim.Image) >= maxGIFFrames {
+			continue
+		}
+		pal := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(pal, img.Bounds(), img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, pal)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	f, err := os.Create(r.out)
+	if err != nil {
+		r.done <- err
+		return
+	}
+	err = gif.EncodeAll(f, anim)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	r.done <- err
+}
+
+func (r *Recorder) encodePNG() {
+	var firstErr error
+	n := 0
+	for img := range r.frames {
+		if firstErr != nil {
+			continue // keep draining so Capture never blocks
+		}
+		n++
+		firstErr = writePNG(filepath.Join(r.out, fmt.Sprintf("frame-%05d.png", n)), img)
+	}
+	r.done <- firstErr
+}
+
+// writePNG encodes img to a new file at path.
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
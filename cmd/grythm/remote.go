@@ -0,0 +1,329 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"grythm/geom"
+)
+
+// The remote control server lets a phone or another machine drive the
+// visualizer over HTTP and WebSocket, e.g. for VJing:
+//
+//	GET  /          a control page for phones (remote.html)
+//	GET  /state     the scene as JSON: a snapshot with the canvas size
+//	POST /command   one RemoteCommand as JSON
+//	GET  /ws        a WebSocket that pushes the state as it changes and
+//	                takes commands; errors come back as {"error": "..."}
+//
+// The server runs on its own goroutines. Commands are handed to the game like
+// MIDI input (they are part of the frame's Input, so recorded sessions replay
+// them), and the game publishes its state a few times per second.
+//
+// Any web page open in a browser on the network could reach the server, so
+// commands are only taken from the control page itself or from programs:
+// WebSocket upgrades and commands that come with an Origin other than the
+// server's are refused, and a POSTed command must say it is JSON, which a
+// page elsewhere can't send without the browser asking first.
+
+//go:embed remote.html
+var remotePage []byte
+
+// remotePublishInterval is how often (seconds) the game publishes its state.
+const remotePublishInterval = 0.1
+
+// RemoteCommand is a command sent to the remote control API.
+type RemoteCommand struct {
+	Cmd    string    `json:"cmd"`              // speed, bpm, rotate, direction, addPoint, mute or solo
+	Value  float64   `json:"value,omitempty"`  // speed in px/s, BPM, or degrees (rotate is relative, clockwise)
+	Pos    geom.Vec2 `json:"pos"`              // addPoint: world position
+	Degree *int      `json:"degree,omitempty"` // addPoint: scale degree; omitted picks one as a click does
+	Group  string    `json:"group,omitempty"`  // mute and solo: the point group
+	On     *bool     `json:"on,omitempty"`     // mute and solo: the switch; omitted toggles it
+}
+
+// validate reports a command the game can't apply.
+func (c *RemoteCommand) validate() error {
+	if math.IsNaN(c.Value) || math.IsInf(c.Value, 0) || math.IsNaN(c.Pos.X) || math.IsNaN(c.Pos.Y) {
+		return fmt.Errorf("values must be finite numbers")
+	}
+	switch c.Cmd {
+	case "speed":
+		if c.Value < 0 {
+			return fmt.Errorf("speed must not be negative")
+		}
+	case "bpm", "rotate", "direction", "addPoint":
+	case "mute", "solo":
+		if c.Group == "" {
+			return fmt.Errorf("%s needs a group", c.Cmd)
+		}
+	default:
+		return fmt.Errorf("unknown command %q", c.Cmd)
+	}
+	return nil
+}
+
+// RemoteState is what the API reports: the scene with its animated state and
+// the view, and the size of the canvas the view fills.
+type RemoteState struct {
+	Snapshot
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Remote is a running remote control server.
+type Remote struct {
+	srv  *http.Server
+	cmds chan RemoteCommand
+
+	mu      sync.Mutex
+	state   []byte        // latest published state
+	changed chan struct{} // closed when a new state is published
+}
+
+// StartRemote serves the remote control API on addr (host:port).
+func StartRemote(addr string) (*Remote, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("remote: %w", err)
+	}
+	r := &Remote{cmds: make(chan RemoteCommand, 256), changed: make(chan struct{})}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", r.serveIndex)
+	mux.HandleFunc("/state", r.serveState)
+	mux.HandleFunc("/command", r.serveCommand)
+	mux.HandleFunc("/ws", r.serveWebSocket)
+	r.srv = &http.Server{Handler: mux}
+	go func() {
+		if err := r.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("remote: %v", err)
+		}
+	}()
+	log.Printf("remote control on http://%s/", ln.Addr())
+	return r, nil
+}
+
+// Close stops the server.
+func (r *Remote) Close() error {
+	return r.srv.Close()
+}
+
+// publish replaces the state reported to clients.
+func (r *Remote) publish(state []byte) {
+	r.mu.Lock()
+	r.state = state
+	close(r.changed)
+	r.changed = make(chan struct{})
+	r.mu.Unlock()
+}
+
+// latest returns the published state and a channel closed when it changes.
+func (r *Remote) latest() ([]byte, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state, r.changed
+}
+
+// command parses a command and queues it for the game.
+func (r *Remote) command(data []byte) error {
+	var c RemoteCommand
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("parse command: %w", err)
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+	select {
+	case r.cmds <- c:
+		return nil
+	default:
+		return fmt.Errorf("too many commands")
+	}
+}
+
+func (r *Remote) serveIndex(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(remotePage)
+}
+
+func (r *Remote) serveState(w http.ResponseWriter, req *http.Request) {
+	state, _ := r.latest()
+	if state == nil {
+		http.Error(w, "no state yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(state)
+}
+
+// sameOrigin reports whether req comes from a page served here, or from a
+// program, which sends no Origin.
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, req.Host)
+}
+
+func (r *Remote) serveCommand(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "commands are POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(req) {
+		http.Error(w, "commands from other sites are refused", http.StatusForbidden)
+		return
+	}
+	if t, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err != nil || t != "application/json" {
+		http.Error(w, "commands are sent as application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(req.Body, wsMaxMessage))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := r.command(data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// serveWebSocket pushes every new state to the client while reading its commands.
+func (r *Remote) serveWebSocket(w http.ResponseWriter, req *http.Request) {
+	if !sameOrigin(req) {
+		http.Error(w, "connections from other sites are refused", http.StatusForbidden)
+		log.Printf("remote: refused a WebSocket from %s", req.Header.Get("Origin"))
+		return
+	}
+	c, err := upgradeWebSocket(w, req)
+	if err != nil {
+		log.Printf("remote: %v", err)
+		return
+	}
+	defer c.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := r.command(msg); err != nil {
+				reply, _ := json.Marshal(map[string]string{"error": err.Error()})
+				if c.WriteText(reply) != nil {
+					return
+				}
+			}
+		}
+	}()
+	for {
+		state, changed := r.latest()
+		if state != nil {
+			if err := c.WriteText(state); err != nil {
+				return
+			}
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return
+		}
+	}
+}
+
+// pollRemote returns the commands that arrived since the last frame, for the
+// frame's Input.
+func (g *Game) pollRemote() []RemoteCommand {
+	if g.remote == nil {
+		return nil
+	}
+	var cmds []RemoteCommand
+	for {
+		select {
+		case c := <-g.remote.cmds:
+			cmds = append(cmds, c)
+		default:
+			return cmds
+		}
+	}
+}
+
+// updateRemote applies the frame's remote commands and publishes the state
+// every remotePublishInterval.
+func (g *Game) updateRemote(dt float64) {
+	for _, c := range g.in.Remote {
+		g.remoteCommand(c)
+	}
+	if g.remote == nil {
+		return
+	}
+	g.remoteWait -= dt
+	if g.remoteWait > 0 {
+		return
+	}
+	g.remoteWait = remotePublishInterval
+	data, err := json.Marshal(RemoteState{Snapshot: g.snapshot(), Width: g.W, Height: g.H})
+	if err != nil {
+		log.Printf("remote: %v", err)
+		return
+	}
+	g.remote.publish(data)
+}
+
+// remoteCommand applies a single command. Speed and direction act on the
+// global motion; added points can be undone like clicked ones.
+func (g *Game) remoteCommand(c RemoteCommand) {
+	switch c.Cmd {
+	case "speed":
+		if g.TempoMode {
+			g.Clock.SetSpeed(c.Value)
+		} else {
+			g.Speed = c.Value
+		}
+	case "bpm":
+		g.Clock.SetBPM(c.Value)
+	case "rotate":
+		g.MoveDir = angleDir(dirAngle(g.MoveDir) + c.Value)
+	case "direction":
+		g.MoveDir = angleDir(c.Value)
+	case "addPoint":
+		deg := g.NewPointDegree(c.Pos)
+		if c.Degree != nil {
+			deg = *c.Degree
+		}
+		g.exec(addPointCmd{idx: len(g.Points), p: g.newPoint(c.Pos, deg)})
+	case "mute", "solo":
+		st := g.Groups[c.Group]
+		sw := &st.Mute
+		if c.Cmd == "solo" {
+			sw = &st.Solo
+		}
+		if c.On != nil {
+			*sw = *c.On
+		} else {
+			*sw = !*sw
+		}
+		g.SetGroup(c.Group, st)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"grythm/engine"
+)
+
+// A session file holds everything needed to play a session again: a header
+// with the starting scene (as a snapshot, so its animated state and the view
+// come along), the trigger seed and the options that shape the simulation,
+// followed by the Input of every frame, one JSON object per line. The game
+// takes all its input from Input and all its randomness from the seed, and the
+// simulation runs in fixed ticks, so feeding the frames back with their
+// recorded lengths reproduces the session: the same edits, the same pattern
+// and the same triggers.
+
+// SessionHeader is the first line of a session file.
+type SessionHeader struct {
+	Start         Snapshot `json:"start"`
+	Width         int      `json:"width"` // canvas size at the start
+	Height        int      `json:"height"`
+	TickRate      int      `json:"tickRate"`
+	Intersections string   `json:"intersections"`
+	RescalePoints bool     `json:"rescalePoints,omitempty"`
+	Setlist       *Setlist `json:"setlist,omitempty"` // the scenes PageUp/PageDown switch between
+	Crossfade     float64  `json:"crossfade,omitempty"`
+}
+
+// SessionRecorder writes the frames of a session as they are played.
+type SessionRecorder struct {
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// RecordSession creates the session file at path and writes its header.
+func RecordSession(path string, h SessionHeader) (*SessionRecorder, error) {
+	if !hasFileSystem {
+		return nil, errNoFileSystem
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("record session: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	r := &SessionRecorder{f: f, w: w, enc: json.NewEncoder(w)}
+	if err := r.enc.Encode(h); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("record session: %w", err)
+	}
+	return r, nil
+}
+
+// Write appends the input of a frame.
+func (r *SessionRecorder) Write(in *Input) error {
+	return r.enc.Encode(in)
+}
+
+// Close flushes and closes the file.
+func (r *SessionRecorder) Close() error {
+	if err := r.w.Flush(); err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+// SessionPlayer reads back the frames of a recorded session.
+type SessionPlayer struct {
+	f     *os.File
+	dec   *json.Decoder
+	frame int
+}
+
+// OpenSession opens the session file at path and reads its header.
+func OpenSession(path string) (*SessionPlayer, SessionHeader, error) {
+	var h SessionHeader
+	if !hasFileSystem {
+		return nil, h, errNoFileSystem
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, h, fmt.Errorf("replay: %w", err)
+	}
+	p := &SessionPlayer{f: f, dec: json.NewDecoder(bufio.NewReader(f))}
+	if err := p.dec.Decode(&h); err != nil {
+		f.Close()
+		return nil, h, fmt.Errorf("replay %s: header: %w", path, err)
+	}
+	if h.TickRate <= 0 || h.Width <= 0 || h.Height <= 0 {
+		f.Close()
+		return nil, h, fmt.Errorf("replay %s: tick rate and size must be positive", path)
+	}
+	return p, h, nil
+}
+
+// Next returns the input of the next frame, or false at the end of the session.
+func (p *SessionPlayer) Next() (*Input, bool) {
+	var in Input
+	if err := p.dec.Decode(&in); err != nil {
+		if !errors.Is(err, io.EOF) {
+			log.Printf("replay: frame %d: %v", p.frame+1, err)
+		}
+		return nil, false
+	}
+	p.frame++
+	return &in, true
+}
+
+// Close closes the file.
+func (p *SessionPlayer) Close() error {
+	return p.f.Close()
+}
+
+// apply sets the options of cfg the session was recorded with.
+func (h SessionHeader) apply(cfg *Config) {
+	cfg.Seed = h.Start.Seed
+	cfg.TickRate = h.TickRate
+	cfg.Width, cfg.Height = h.Width, h.Height
+}
+
+// sessionHeader describes the game as it is now, to start a recording.
+func (g *Game) sessionHeader() SessionHeader {
+	return SessionHeader{
+		Start:         g.snapshot(),
+		Width:         g.W,
+		Height:        g.H,
+		TickRate:      int(1/g.timestep.Step() + 0.5),
+		Intersections: g.IntersectMode.String(),
+		RescalePoints: g.rescalePoints,
+		Setlist:       g.setlist,
+		Crossfade:     g.fade.length,
+	}
+}
+
+// startReplay puts the game in the state a session started from. The game
+// must have been created with the session's seed, tick rate and size (see
+// SessionHeader.apply).
+func (g *Game) startReplay(p *SessionPlayer, h SessionHeader) error {
+	if err := g.ApplyScene(h.Start.Scene); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	g.applySnapshot(h.Start)
+	mode, err := engine.ParseIntersectMode(h.Intersections)
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	g.IntersectMode = mode
+	g.rescalePoints = h.RescalePoints
+	g.setlist = h.Setlist
+	g.fade.length = h.Crossfade
+	if g.setlist != nil && (len(g.setlist.Entries) == 0 || g.setlist.Pos < 0 || g.setlist.Pos >= len(g.setlist.Entries)) {
+		return fmt.Errorf("replay: setlist position %d out of range", g.setlist.Pos)
+	}
+	g.replay = p
+	return nil
+}
+
+// readInput returns the input of this frame: the next recorded frame while a
+// session is replayed, otherwise what the player does. Live frames are
+// written to the session being recorded, if any. When the replay runs out the
+// player takes over from where it ended.
+func (g *Game) readInput() *Input {
+	if g.replay != nil {
+		if in, ok := g.replay.Next(); ok {
+			g.timestep.FrameOf(in.DT)
+			// Live MIDI and remote commands are not applied during a replay,
+			// but they are drained
+			g.pollMIDIIn()
+			g.pollRemote()
+			g.cam.DeviceScale = in.Scale
+			g.resize(in.W, in.H)
+			return in
+		}
+		log.Printf("replay finished after %d frames", g.replay.frame)
+		g.replay.Close()
+		g.replay = nil
+	}
+	in := g.captureInput(g.timestep.Frame())
+	if g.session != nil {
+		if err := g.session.Write(in); err != nil {
+			log.Printf("record session: %v (recording stopped)", err)
+			g.session.Close()
+			g.session = nil
+		}
+	}
+	return in
+}
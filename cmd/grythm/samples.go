@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log"
+
+	"grythm/synth"
+)
+
+// sample returns the decoded sample at path, decoding it on first use. It
+// returns nil for an empty path or a file that failed to decode; the failure is
+// logged once and the caller falls back to the synth blip.
+func (g *Game) sample(path string) []float32 {
+	if path == "" {
+		return nil
+	}
+	if smp, ok := g.samples[path]; ok {
+		return smp
+	}
+	smp, err := synth.LoadSample(path, g.blipSampleRate)
+	if err != nil {
+		log.Printf("sample: %v (falling back to synth)", err)
+	}
+	g.samples[path] = smp
+	return smp
+}
@@ -0,0 +1,698 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"grythm/engine"
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Scene is the on-disk (JSON) description of an arrangement: grid families,
+// points and the global motion and pitch settings. Animated state such as the
+// dash phase is not stored.
+type Scene struct {
+	Key       int                          `json:"key"`
+	Scale     synth.Scale                  `json:"scale"`
+	MoveDir   geom.Vec2                    `json:"moveDir"`
+	Speed     float64                      `json:"speed"`
+	TempoMode bool                         `json:"tempoMode,omitempty"`
+	BPM       float64                      `json:"bpm,omitempty"`
+	Grids     []SceneGrid                  `json:"grids"`
+	Points    []ScenePoint                 `json:"points"`
+	Groups    map[string]engine.GroupState `json:"groups,omitempty"`
+	PitchMap  *engine.PitchMap             `json:"pitchMap,omitempty"`
+	Loop      *engine.Loop                 `json:"loop,omitempty"`
+	Trails    bool                         `json:"trails,omitempty"`
+	TrailFade float64                      `json:"trailFade,omitempty"` // 0 uses the default
+	Theme     string                       `json:"theme,omitempty"`     // the grid colors come from this theme's palette; "" is the default theme's
+	Quantize  int                          `json:"quantize,omitempty"`  // note value triggers are held back to; 0 is off
+	Swing     float64                      `json:"swing,omitempty"`     // percent of a pair of subdivisions the second starts at; 0 is straight
+	Arp       *engine.Arpeggio             `json:"arp,omitempty"`       // spreading of chords on one line; nil plays them at once
+	Layers    []engine.Layer               `json:"layers,omitempty"`    // transforms of the grid layers, from layer 1; missing ones are untouched
+}
+
+// SceneGrid is the stored form of a GridFamily.
+type SceneGrid struct {
+	ID           string           `json:"id,omitempty"` // name a merged scene refers to it by (see scenemerge.go)
+	Kind         geom.GridKind    `json:"kind"`
+	Normal       geom.Vec2        `json:"normal,omitempty"`
+	Origin       geom.Vec2        `json:"origin,omitempty"`
+	Spacing      float64          `json:"spacing"`
+	Offset       float64          `json:"offset,omitempty"`
+	Color        string           `json:"color"`
+	Thickness    float64          `json:"thickness"`
+	DashLength   float64          `json:"dashLength,omitempty"`
+	GapLength    float64          `json:"gapLength,omitempty"`
+	DashOffset   float64          `json:"dashOffset,omitempty"`
+	Wave         synth.Waveform   `json:"wave,omitempty"`
+	Degree       int              `json:"degree,omitempty"`
+	Motion       *geom.Motion     `json:"motion,omitempty"`
+	Sample       string           `json:"sample,omitempty"`
+	Env          *synth.Envelope  `json:"env,omitempty"`
+	HexTiling    bool             `json:"hexTiling,omitempty"`
+	Sends        *synth.Sends     `json:"sends,omitempty"`
+	Filter       *synth.Filter    `json:"filter,omitempty"`
+	Glide        *synth.Glide     `json:"glide,omitempty"`
+	FM           *synth.FM        `json:"fm,omitempty"`
+	Drum         synth.Drum       `json:"drum,omitempty"`
+	DashPattern  []float64        `json:"dashPattern,omitempty"`
+	Euclid       *geom.EuclidSpec `json:"euclid,omitempty"`
+	Amplitude    float64          `json:"amplitude,omitempty"`
+	Wavelength   float64          `json:"wavelength,omitempty"`
+	CurvePhase   float64          `json:"curvePhase,omitempty"`
+	Bezier       []geom.Vec2      `json:"bezier,omitempty"`
+	Rays         int              `json:"rays,omitempty"`
+	AngularSpeed float64          `json:"angularSpeed,omitempty"`
+	Angle        float64          `json:"angle,omitempty"` // ray families: starting angle in degrees
+	Chance       float64          `json:"chance,omitempty"`
+	Cooldown     float64          `json:"cooldown,omitempty"` // least milliseconds between triggers of a point
+	Length       float64          `json:"length,omitempty"`
+	Retrigger    synth.Retrigger  `json:"retrigger,omitempty"`
+	Accent       []float64        `json:"accent,omitempty"`
+	Alpha        float64          `json:"alpha,omitempty"` // opacity 0-1; 0 is opaque
+	Width        float64          `json:"width,omitempty"` // drawn width in window pixels; 0 is the default
+	ShowBand     bool             `json:"showBand,omitempty"`
+	Blend        geom.BlendMode   `json:"blend,omitempty"`
+	Edge         geom.TriggerEdge `json:"edge,omitempty"` // when its lines trigger points; enter when left out
+	GapTrigger   bool             `json:"gapTrigger,omitempty"`
+	Paused       bool             `json:"paused,omitempty"`
+	Rate         float64          `json:"rate,omitempty"` // motion multiplier; negative runs backwards, 0 is 1
+	Mute         bool             `json:"mute,omitempty"`
+	Solo         bool             `json:"solo,omitempty"`
+	Volume       float64          `json:"volume,omitempty"` // level of its notes, 0-2; 0 is 1
+	Layer        int              `json:"layer,omitempty"`  // layer it belongs to, from 1; 0 is none
+	Anim         *geom.Animator   `json:"anim,omitempty"`
+}
+
+// ScenePoint is the stored form of a Point.
+type ScenePoint struct {
+	ID        string          `json:"id,omitempty"` // name a merged scene refers to it by (see scenemerge.go)
+	Pos       geom.Vec2       `json:"pos"`
+	Degree    int             `json:"degree,omitempty"`
+	Wave      synth.Waveform  `json:"wave,omitempty"`
+	Sample    string          `json:"sample,omitempty"`
+	Env       *synth.Envelope `json:"env,omitempty"`
+	FM        *synth.FM       `json:"fm,omitempty"`
+	Group     string          `json:"group,omitempty"`
+	Ignore    []int           `json:"ignoreGrids,omitempty"` // indices of grid families the point does not respond to
+	Chance    float64         `json:"chance,omitempty"`
+	Cooldown  float64         `json:"cooldown,omitempty"` // least milliseconds between its triggers
+	Velocity  float64         `json:"velocity,omitempty"` // factor its notes' velocity is scaled by; 0 is 1
+	Length    float64         `json:"length,omitempty"`
+	Retrigger synth.Retrigger `json:"retrigger,omitempty"`
+	Path      *geom.Path      `json:"path,omitempty"`   // route the point travels; pos is where it is on it
+	Chord     []int           `json:"chord,omitempty"`  // semitones above the point's note played with it
+	Life      string          `json:"life,omitempty"`   // lifespan, e.g. "8" triggers or "4s" (see engine.ParseLifespan)
+	Mirror    *SceneMirror    `json:"mirror,omitempty"` // set of mirror copies it belongs to
+}
+
+// SceneMirror links a point to its mirror copies (see symmetry.go).
+type SceneMirror struct {
+	Set      int           `json:"set"`
+	Image    int           `json:"image"`
+	Symmetry geom.Symmetry `json:"symmetry"`
+}
+
+// Scene captures the current arrangement.
+func (g *Game) Scene() Scene {
+	sc := Scene{
+		Key:       g.Key,
+		Scale:     g.Scale,
+		MoveDir:   g.MoveDir,
+		Speed:     g.Speed,
+		TempoMode: g.TempoMode,
+		BPM:       g.Clock.BPM,
+		Trails:    g.trails,
+		Theme:     g.theme.Name,
+		Quantize:  g.Quantize,
+		Swing:     g.Swing,
+	}
+	if g.trailFade != defaultTrailFade {
+		sc.TrailFade = g.trailFade
+	}
+	for _, gf := range g.Grids {
+		sg := SceneGrid{
+			ID:           gf.ID,
+			Kind:         gf.Kind,
+			Normal:       gf.Normal,
+			Origin:       gf.Origin,
+			Spacing:      gf.Spacing,
+			Offset:       gf.Offset,
+			Color:        formatHexColor(gf.Color),
+			Thickness:    gf.Thickness,
+			DashLength:   gf.DashLength,
+			GapLength:    gf.GapLength,
+			DashOffset:   gf.DashOffset,
+			Wave:         gf.Wave,
+			Degree:       gf.Degree,
+			Sample:       gf.Sample,
+			Env:          copyEnvelope(gf.Env),
+			HexTiling:    gf.HexTiling,
+			Amplitude:    gf.Amplitude,
+			Wavelength:   gf.Wavelength,
+			CurvePhase:   gf.CurvePhase,
+			Bezier:       slices.Clone(gf.Bezier),
+			Rays:         gf.Rays,
+			AngularSpeed: gf.AngularSpeed,
+			Angle:        gf.Angle * 180 / math.Pi,
+			Chance:       gf.Chance,
+			Cooldown:     gf.Cooldown,
+			Length:       gf.Length,
+			Retrigger:    gf.Retrigger,
+			Drum:         gf.Drum,
+			Accent:       slices.Clone(gf.Accent),
+			Alpha:        gf.Alpha,
+			Width:        gf.Width,
+			ShowBand:     gf.ShowBand,
+			Blend:        gf.Blend,
+			Edge:         gf.Edge,
+			GapTrigger:   gf.GapTrigger,
+			Paused:       gf.Paused,
+			Rate:         gf.Rate,
+			Mute:         gf.Mute,
+			Solo:         gf.Solo,
+			Volume:       gf.Volume,
+			Layer:        gf.Layer,
+		}
+		if gf.Sends != nil {
+			s := *gf.Sends
+			sg.Sends = &s
+		}
+		if gf.Filter != nil {
+			f := *gf.Filter
+			sg.Filter = &f
+		}
+		if gf.Glide != nil {
+			gl := *gf.Glide
+			sg.Glide = &gl
+		}
+		sg.FM = copyFM(gf.FM)
+		if gf.Anim != nil {
+			a := *gf.Anim
+			sg.Anim = &a
+		}
+		// A Euclidean spec is stored instead of the pattern it generates
+		if gf.Euclid != nil {
+			e := *gf.Euclid
+			sg.Euclid = &e
+		} else {
+			sg.DashPattern = append([]float64(nil), gf.DashPattern...)
+		}
+		if gf.Motion != nil {
+			m := *gf.Motion
+			sg.Motion = &m
+		}
+		sc.Grids = append(sc.Grids, sg)
+	}
+	for _, p := range g.Points {
+		sp := ScenePoint{ID: p.ID, Pos: p.Pos, Degree: p.Degree, Wave: p.Wave, Sample: p.Sample, Env: copyEnvelope(p.Env), FM: copyFM(p.FM), Group: p.Group, Ignore: p.IgnoredGrids(), Chance: p.Chance, Cooldown: p.Cooldown, Velocity: p.Velocity, Length: p.Length, Retrigger: p.Retrigger, Chord: append([]int(nil), p.Chord...), Life: p.Life.String()}
+		if m := p.Mirror; m.Set != 0 {
+			sp.Mirror = &SceneMirror{Set: m.Set, Image: m.Image, Symmetry: m.Sym}
+		}
+		if p.Path.Moves() {
+			path := p.Path
+			path.Line = append([]geom.Vec2(nil), p.Path.Line...)
+			sp.Path = &path
+		}
+		sc.Points = append(sc.Points, sp)
+	}
+	if g.PitchMap != engine.DefaultPitchMap {
+		pm := g.PitchMap
+		sc.PitchMap = &pm
+	}
+	if g.Arp != (engine.Arpeggio{}) {
+		a := g.Arp
+		sc.Arp = &a
+	}
+	if g.Loop != engine.DefaultLoop {
+		l := g.Loop
+		sc.Loop = &l
+	}
+	// Trailing untouched layers are left out
+	for n := len(g.Layers); n > 0; n-- {
+		if g.Layers[n-1] != (engine.Layer{}) {
+			sc.Layers = append([]engine.Layer(nil), g.Layers[:n]...)
+			break
+		}
+	}
+	for name, st := range g.Groups {
+		if sc.Groups == nil {
+			sc.Groups = make(map[string]engine.GroupState)
+		}
+		sc.Groups[name] = st
+	}
+	return sc
+}
+
+// ApplyScene replaces the arrangement with sc. Undo history is cleared since
+// it refers to the previous arrangement.
+func (g *Game) ApplyScene(sc Scene) error {
+	grids, err := sceneGrids(sc)
+	if err != nil {
+		return err
+	}
+	points, err := scenePoints(sc)
+	if err != nil {
+		return err
+	}
+	theme, from, err := g.sceneSettings(sc)
+	if err != nil {
+		return err
+	}
+
+	g.Grids = grids
+	g.Points = points
+	g.applySettings(sc, theme, from)
+	g.ResetPointState()
+	g.history = History{}
+	g.selGrid = 0
+	g.hoverIdx = -1
+	g.dragIdx = -1
+	g.sel, g.band = selection{}, rubberBand{}
+	g.tonic = tonicDrag{}
+	g.rebaseMorph()
+	// Decode referenced samples up front so missing files are reported right away
+	for _, gf := range g.Grids {
+		g.sample(gf.Sample)
+	}
+	for _, p := range g.Points {
+		g.sample(p.Sample)
+	}
+	return nil
+}
+
+// sceneGrids builds the grid families of sc, checking them as it goes.
+// Unnamed families are named after their position (see nameItems).
+func sceneGrids(sc Scene) ([]geom.GridFamily, error) {
+	ids, err := sceneGridIDs(sc)
+	if err != nil {
+		return nil, err
+	}
+	grids := make([]geom.GridFamily, 0, len(sc.Grids))
+	for i, sg := range sc.Grids {
+		if sg.Spacing <= 0 {
+			return nil, fmt.Errorf("grid %d: spacing must be positive", i+1)
+		}
+		if sg.Kind == geom.GridWavy && sg.Wavelength <= 0 {
+			return nil, fmt.Errorf("grid %d: wavelength must be positive", i+1)
+		}
+		if sg.Kind == geom.GridCurve {
+			if err := geom.ValidateBezier(sg.Bezier); err != nil {
+				return nil, fmt.Errorf("grid %d: %w", i+1, err)
+			}
+		}
+		if len(sg.DashPattern)%2 != 0 {
+			return nil, fmt.Errorf("grid %d: dash pattern needs pairs of dash and gap lengths", i+1)
+		}
+		for _, l := range sg.DashPattern {
+			if l <= 0 {
+				return nil, fmt.Errorf("grid %d: dash pattern lengths must be positive", i+1)
+			}
+		}
+		if e := sg.Euclid; e != nil && (e.Steps <= 0 || e.Pulses < 0 || e.Pulses > e.Steps) {
+			return nil, fmt.Errorf("grid %d: euclid needs 0 <= pulses <= steps", i+1)
+		}
+		col, err := parseHexColor(sg.Color)
+		if err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if sg.Env != nil {
+			if err := sg.Env.Validate(); err != nil {
+				return nil, fmt.Errorf("grid %d: %w", i+1, err)
+			}
+		}
+		if sg.Filter != nil {
+			if err := sg.Filter.Validate(); err != nil {
+				return nil, fmt.Errorf("grid %d: %w", i+1, err)
+			}
+		}
+		if sg.Glide != nil {
+			if err := sg.Glide.Validate(); err != nil {
+				return nil, fmt.Errorf("grid %d: %w", i+1, err)
+			}
+		}
+		if sg.FM != nil {
+			if err := sg.FM.Validate(); err != nil {
+				return nil, fmt.Errorf("grid %d: %w", i+1, err)
+			}
+		}
+		if sg.Anim != nil {
+			if err := sg.Anim.Validate(); err != nil {
+				return nil, fmt.Errorf("grid %d: %w", i+1, err)
+			}
+		}
+		if err := engine.ValidateChance(sg.Chance); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := engine.ValidateCooldown(sg.Cooldown); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := synth.ValidateNoteLength(sg.Length); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := geom.ValidateAccent(sg.Accent); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := geom.ValidateWidth(sg.Width); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := geom.ValidateAlpha(sg.Alpha); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := geom.ValidateRate(sg.Rate); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := geom.ValidateVolume(sg.Volume); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		if err := engine.ValidateLayerIndex(sg.Layer); err != nil {
+			return nil, fmt.Errorf("grid %d: %w", i+1, err)
+		}
+		gf := geom.GridFamily{
+			ID:           ids[i],
+			Kind:         sg.Kind,
+			Normal:       sg.Normal.Norm(),
+			Origin:       sg.Origin,
+			Spacing:      sg.Spacing,
+			Offset:       sg.Offset,
+			Color:        col,
+			Thickness:    sg.Thickness,
+			DashLength:   sg.DashLength,
+			GapLength:    sg.GapLength,
+			DashOffset:   sg.DashOffset,
+			Wave:         sg.Wave,
+			Degree:       sg.Degree,
+			Sample:       sg.Sample,
+			Env:          copyEnvelope(sg.Env),
+			HexTiling:    sg.HexTiling,
+			Amplitude:    sg.Amplitude,
+			Wavelength:   sg.Wavelength,
+			CurvePhase:   sg.CurvePhase,
+			Bezier:       slices.Clone(sg.Bezier),
+			Rays:         sg.Rays,
+			AngularSpeed: sg.AngularSpeed,
+			Angle:        sg.Angle * math.Pi / 180,
+			Chance:       sg.Chance,
+			Cooldown:     sg.Cooldown,
+			Length:       sg.Length,
+			Retrigger:    sg.Retrigger,
+			Drum:         sg.Drum,
+			Accent:       slices.Clone(sg.Accent),
+			Alpha:        sg.Alpha,
+			Width:        sg.Width,
+			ShowBand:     sg.ShowBand,
+			Blend:        sg.Blend,
+			Edge:         sg.Edge,
+			GapTrigger:   sg.GapTrigger,
+			Paused:       sg.Paused,
+			Rate:         sg.Rate,
+			Mute:         sg.Mute,
+			Solo:         sg.Solo,
+			Volume:       sg.Volume,
+			Layer:        sg.Layer,
+		}
+		if sg.Sends != nil {
+			s := *sg.Sends
+			gf.Sends = &s
+		}
+		if sg.Filter != nil {
+			f := *sg.Filter
+			gf.Filter = &f
+		}
+		if sg.Glide != nil {
+			gl := *sg.Glide
+			gf.Glide = &gl
+		}
+		gf.FM = copyFM(sg.FM)
+		if sg.Anim != nil {
+			a := *sg.Anim
+			gf.Anim = &a
+		}
+		gf.DashPattern = append([]float64(nil), sg.DashPattern...)
+		if sg.Euclid != nil {
+			e := *sg.Euclid
+			gf.Euclid = &e
+			gf.ApplyEuclid()
+		}
+		if sg.Motion != nil {
+			m := *sg.Motion
+			m.Dir = m.Dir.Norm()
+			gf.Motion = &m
+		}
+		grids = append(grids, gf)
+	}
+	return grids, nil
+}
+
+// scenePoints builds the points of sc, checking them as it goes. Unnamed
+// points are named after their position (see nameItems).
+func scenePoints(sc Scene) ([]engine.Point, error) {
+	ids, err := scenePointIDs(sc)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]engine.Point, 0, len(sc.Points))
+	for i, sp := range sc.Points {
+		if sp.Env != nil {
+			if err := sp.Env.Validate(); err != nil {
+				return nil, fmt.Errorf("point %d: %w", i+1, err)
+			}
+		}
+		if sp.FM != nil {
+			if err := sp.FM.Validate(); err != nil {
+				return nil, fmt.Errorf("point %d: %w", i+1, err)
+			}
+		}
+		ignore, err := engine.IgnoreMask(sp.Ignore)
+		if err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		if err := engine.ValidateChance(sp.Chance); err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		if err := engine.ValidateCooldown(sp.Cooldown); err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		if err := engine.ValidatePointVelocity(sp.Velocity); err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		if err := synth.ValidateNoteLength(sp.Length); err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		if err := synth.ValidateChord(sp.Chord); err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		life, err := engine.ParseLifespan(sp.Life)
+		if err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+		p := engine.Point{ID: ids[i], Pos: sp.Pos, Degree: sp.Degree, Wave: sp.Wave, Sample: sp.Sample, Env: copyEnvelope(sp.Env), FM: copyFM(sp.FM), Group: sp.Group, Ignore: ignore, Chance: sp.Chance, Cooldown: sp.Cooldown, Velocity: sp.Velocity, Length: sp.Length, Retrigger: sp.Retrigger, Chord: append([]int(nil), sp.Chord...), Life: life}
+		if m := sp.Mirror; m != nil {
+			if m.Set <= 0 || m.Image < 0 || m.Image >= m.Symmetry.Count() {
+				return nil, fmt.Errorf("point %d: mirror image %d of set %d out of range for %s", i+1, m.Image, m.Set, m.Symmetry)
+			}
+			p.Mirror = engine.Mirror{Set: m.Set, Image: m.Image, Sym: m.Symmetry}
+		}
+		if sp.Path != nil {
+			if err := sp.Path.Validate(); err != nil {
+				return nil, fmt.Errorf("point %d: %w", i+1, err)
+			}
+			p.Path = *sp.Path
+			p.Path.Line = append([]geom.Vec2(nil), sp.Path.Line...)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// sceneSettings checks the global settings of sc and returns the theme it
+// asks for and the one its grid colors are taken from.
+func (g *Game) sceneSettings(sc Scene) (theme, from *Theme, err error) {
+	if len(sc.Scale.Steps) == 0 {
+		return nil, nil, fmt.Errorf("scale %q has no steps", sc.Scale.Name)
+	}
+	if sc.Loop != nil && sc.Loop.Length <= 0 {
+		return nil, nil, fmt.Errorf("loop length must be positive")
+	}
+	if err := engine.ValidateQuantize(sc.Quantize); err != nil {
+		return nil, nil, err
+	}
+	if err := engine.ValidateSwing(sc.Swing); err != nil {
+		return nil, nil, err
+	}
+	if sc.Arp != nil {
+		if err := sc.Arp.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(sc.Layers) > engine.MaxLayers {
+		return nil, nil, fmt.Errorf("%d layers, at most %d", len(sc.Layers), engine.MaxLayers)
+	}
+	for i, l := range sc.Layers {
+		if err := l.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("layer %d: %w", i+1, err)
+		}
+	}
+	// A scene with a theme brings it along; one without (like the presets)
+	// keeps the current theme, its colors taken from the default palette
+	theme, from = g.theme, &Themes[0]
+	if sc.Theme != "" {
+		t, err := ThemeNamed(sc.Theme)
+		if err != nil {
+			return nil, nil, err
+		}
+		theme, from = t, t
+	}
+	return theme, from, nil
+}
+
+// applySettings takes over the global settings of sc, which sceneSettings
+// has checked, moving the grid colors from the palette of from to theme.
+func (g *Game) applySettings(sc Scene, theme, from *Theme) {
+	g.setTheme(theme, from)
+	g.Key = sc.Key
+	g.Scale = sc.Scale
+	if d := sc.MoveDir.Norm(); d.Len() > 0 {
+		g.MoveDir = d
+	}
+	g.Speed = sc.Speed
+	g.TempoMode = sc.TempoMode
+	g.Quantize = sc.Quantize
+	g.Swing = sc.Swing
+	g.Arp = engine.Arpeggio{}
+	if sc.Arp != nil {
+		g.Arp = *sc.Arp
+	}
+	if sc.BPM > 0 {
+		g.Clock.SetBPM(sc.BPM)
+	}
+	g.Layers = [engine.MaxLayers]engine.Layer{}
+	copy(g.Layers[:], sc.Layers)
+	g.Groups = make(map[string]engine.GroupState)
+	for name, st := range sc.Groups {
+		g.SetGroup(name, st)
+	}
+	g.selGroup = ""
+	g.PitchMap = engine.DefaultPitchMap
+	g.trails = sc.Trails
+	g.trailFade = defaultTrailFade
+	if sc.TrailFade > 0 {
+		g.trailFade = sc.TrailFade
+	}
+	if sc.PitchMap != nil {
+		g.PitchMap = *sc.PitchMap
+	}
+	g.Loop = engine.DefaultLoop
+	if sc.Loop != nil {
+		g.Loop = *sc.Loop
+	}
+}
+
+// copyEnvelope returns a copy of an optional envelope so scene and game never share one.
+func copyEnvelope(e *synth.Envelope) *synth.Envelope {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// copyFM returns a copy of an optional FM voice.
+func copyFM(f *synth.FM) *synth.FM {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	return &c
+}
+
+// errNoFileSystem is returned for file operations in the browser build.
+var errNoFileSystem = errors.New("no file system available")
+
+// LoadScene reads a scene file (or a snapshot sidecar) and applies it.
+func (g *Game) LoadScene(path string) error {
+	if !hasFileSystem {
+		return errNoFileSystem
+	}
+	sn, err := readScene(path)
+	if err != nil {
+		return err
+	}
+	if err := g.ApplyScene(sn.Scene); err != nil {
+		return fmt.Errorf("scene %s: %w", path, err)
+	}
+	g.applySnapshot(sn)
+	g.watch.seen(path, sn.Scene)
+	return nil
+}
+
+// readScene reads a scene file; snapshot sidecars are scenes too (see
+// snapshot.go).
+func readScene(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var sn Snapshot
+	if err := json.Unmarshal(data, &sn); err != nil {
+		return Snapshot{}, fmt.Errorf("parse scene %s: %w", path, err)
+	}
+	return sn, nil
+}
+
+// SaveScene writes the current arrangement to path, naming the grid
+// families and points that have no id yet.
+func (g *Game) SaveScene(path string) error {
+	if !hasFileSystem {
+		return errNoFileSystem
+	}
+	// Everything gets a name, so the file can be merged back in
+	g.nameAll()
+	sc := g.Scene()
+	data, err := json.MarshalIndent(sc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	g.watch.seen(path, sc)
+	return nil
+}
+
+// formatHexColor formats c as #RRGGBB, or #RRGGBBAA when not opaque.
+func formatHexColor(c color.Color) string {
+	n := toRGBA(c)
+	if n.A == 0xFF {
+		return fmt.Sprintf("#%02X%02X%02X", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("#%02X%02X%02X%02X", n.R, n.G, n.B, n.A)
+}
+
+// parseHexColor parses #RRGGBB or #RRGGBBAA.
+func parseHexColor(s string) (color.Color, error) {
+	var c color.NRGBA
+	c.A = 0xFF
+	h := strings.TrimPrefix(s, "#")
+	var err error
+	switch len(h) {
+	case 6:
+		_, err = fmt.Sscanf(h, "%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 8:
+		_, err = fmt.Sscanf(h, "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("bad length")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return c, nil
+}
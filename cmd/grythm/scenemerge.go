@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"time"
+
+	"grythm/engine"
+)
+
+// Live editing. A scene can be merged into the running one instead of
+// replacing it: grid families and points are matched by their id, so those
+// the file changed are updated, new ones are added and the rest play on
+// untouched. With -watch-scene the scene file is merged in whenever it
+// changes on disk (see filewatch.go), so it can be edited in a text
+// editor while it plays.
+// Only what changed in the file since it was last loaded, saved or merged is
+// taken over; edits made in the game to anything else are kept. Nothing is
+// removed by a merge.
+
+const (
+	sceneWatchInterval = 0.5 // seconds between looks at the file where it can't be watched
+	sceneSettle        = 0.1 // seconds a change is given to be written in full before it is read
+)
+
+// sceneWatch follows the scene file on disk.
+type sceneWatch struct {
+	on      bool
+	path    string          // file the rest refers to
+	changes <-chan struct{} // notifications of the file changing; nil polls its modification time
+	stop    func()          // ends the notifications
+	wait    float64         // seconds until the file is looked at
+	due     bool            // a change was notified and is read when wait has run out
+	mod     time.Time       // modification time of the version seen last
+	last    *Scene          // that version, to tell what a change touched; nil if it did not parse
+	err     string          // why the latest version was not merged; "" when it was
+}
+
+// seen records the version of the file at path just loaded or saved, so it
+// is not merged back in.
+func (w *sceneWatch) seen(path string, sc Scene) {
+	if path != w.path {
+		return
+	}
+	if info, err := os.Stat(path); err == nil {
+		w.mod = info.ModTime()
+	}
+	w.last = &sc
+	w.err = ""
+}
+
+// follow starts watching path, the version on disk now being the one later
+// changes are told from.
+func (w *sceneWatch) follow(path string) {
+	if w.stop != nil {
+		w.stop()
+	}
+	w.path, w.changes, w.stop, w.due, w.last, w.err = path, nil, nil, false, nil, ""
+	changes, stop, err := watchFile(path)
+	if err != nil {
+		log.Printf("%v; checking the scene file every %gs instead", err, sceneWatchInterval)
+	} else {
+		w.changes, w.stop = changes, stop
+	}
+	if info, err := os.Stat(path); err == nil {
+		w.mod = info.ModTime()
+	}
+	if sn, err := readScene(path); err == nil {
+		w.last = &sn.Scene
+	}
+}
+
+// watchScene merges the scene file into the arrangement when it has changed.
+func (g *Game) watchScene(dt float64) {
+	w := &g.watch
+	if !w.on || !hasFileSystem || g.scenePath == "" {
+		return
+	}
+	if g.scenePath != w.path {
+		// A new file, e.g. the next scene of a setlist
+		w.follow(g.scenePath)
+		return
+	}
+	if w.changes != nil {
+		select {
+		case <-w.changes:
+			w.due, w.wait = true, sceneSettle
+		default:
+		}
+		if !w.due {
+			return
+		}
+	}
+	if w.wait -= dt; w.wait > 0 {
+		return
+	}
+	w.due = false
+	if w.changes == nil {
+		w.wait = sceneWatchInterval
+	}
+	info, err := os.Stat(w.path)
+	if err != nil || info.ModTime().Equal(w.mod) {
+		// Gone for the moment, or a save of our own
+		return
+	}
+	w.mod = info.ModTime()
+	// A version that doesn't parse or check out leaves the scene as it is;
+	// the next save is told from the last good one
+	sn, err := readScene(w.path)
+	if err == nil {
+		err = g.MergeScene(sn.Scene, w.last)
+	}
+	if err != nil {
+		w.err = err.Error()
+		log.Printf("reload scene %s: %v", w.path, err)
+		return
+	}
+	w.last, w.err = &sn.Scene, ""
+}
+
+// watchLabel describes a failed reload for the HUD; "" when there is none.
+func (g *Game) watchLabel() string {
+	if g.watch.err == "" {
+		return ""
+	}
+	return "Scene not reloaded: " + g.watch.err
+}
+
+// MergeScene merges sc into the arrangement. prev is an earlier version of
+// sc; the entries and settings it holds unchanged are left as they are in
+// the game. With no prev everything in sc is taken over. Undo history is
+// cleared when anything changes.
+func (g *Game) MergeScene(sc Scene, prev *Scene) error {
+	grids, err := sceneGrids(sc)
+	if err != nil {
+		return err
+	}
+	points, err := scenePoints(sc)
+	if err != nil {
+		return err
+	}
+	theme, from, err := g.sceneSettings(sc)
+	if err != nil {
+		return err
+	}
+	var prevGrids map[string]SceneGrid
+	var prevPoints map[string]ScenePoint
+	if prev != nil {
+		prevGrids, prevPoints = sceneEntries(*prev)
+	}
+
+	changed := false
+	if prev == nil || !reflect.DeepEqual(sceneGlobals(sc), sceneGlobals(*prev)) {
+		// The grids in the game are in the palette of the current theme
+		g.applySettings(sc, theme, g.theme)
+		changed = true
+	}
+	at := make([]int, len(grids)) // index in the game of each family of sc, or -1
+	for i, gf := range grids {
+		at[i] = g.gridIndex(gf.ID)
+		pg, known := prevGrids[gf.ID]
+		if known && reflect.DeepEqual(pg, sc.Grids[i]) {
+			continue
+		}
+		if k, ok := from.slot(gf.Color); ok {
+			gf.Color = g.theme.Grids[k]
+		}
+		g.sample(gf.Sample)
+		changed = true
+		if j := at[i]; j >= 0 {
+			// A family that was not moved in the file goes on from where it is
+			old := &g.Grids[j]
+			if known && old.Kind == gf.Kind && pg.Offset == sc.Grids[i].Offset && pg.Angle == sc.Grids[i].Angle {
+				gf.SetPhase(old.Phase())
+			}
+			g.Grids[j] = gf
+			g.Index.Invalidate()
+			continue
+		}
+		g.AppendGrid(gf)
+		at[i] = len(g.Grids) - 1
+	}
+	for i, p := range points {
+		if pp, known := prevPoints[p.ID]; known && reflect.DeepEqual(pp, sc.Points[i]) {
+			continue
+		}
+		// Ignored grids are numbered as in the file
+		p.Ignore = remapIgnore(p.Ignore, at)
+		g.sample(p.Sample)
+		changed = true
+		if j := g.pointIndex(p.ID); j >= 0 {
+			g.SetPoint(j, p)
+			continue
+		}
+		g.InsertPoint(len(g.Points), p)
+	}
+	if changed {
+		g.history = History{}
+		g.hoverIdx = -1
+		g.dragIdx = -1
+	}
+	return nil
+}
+
+// sceneGlobals returns sc without its grids and points, the settings a merge
+// compares.
+func sceneGlobals(sc Scene) Scene {
+	sc.Grids, sc.Points = nil, nil
+	return sc
+}
+
+// sceneEntries returns the grid families and points of sc by id.
+func sceneEntries(sc Scene) (map[string]SceneGrid, map[string]ScenePoint) {
+	grids := make(map[string]SceneGrid, len(sc.Grids))
+	if ids, err := sceneGridIDs(sc); err == nil {
+		for i, id := range ids {
+			grids[id] = sc.Grids[i]
+		}
+	}
+	points := make(map[string]ScenePoint, len(sc.Points))
+	if ids, err := scenePointIDs(sc); err == nil {
+		for i, id := range ids {
+			points[id] = sc.Points[i]
+		}
+	}
+	return grids, points
+}
+
+// gridIndex returns the index of the family named id, or -1.
+func (g *Game) gridIndex(id string) int {
+	for i := range g.Grids {
+		if g.Grids[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// pointIndex returns the index of the point named id, or -1.
+func (g *Game) pointIndex(id string) int {
+	for i := range g.Points {
+		if g.Points[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// remapIgnore renumbers an ignore mask from the families of a scene to the
+// game's, at holding the game's index of each; unmatched ones are dropped.
+func remapIgnore(mask uint64, at []int) uint64 {
+	var out uint64
+	for gi, j := range at {
+		if gi < engine.MaxFilterGrids && mask&(1<<uint(gi)) != 0 && j >= 0 && j < engine.MaxFilterGrids {
+			out |= 1 << uint(j)
+		}
+	}
+	return out
+}
+
+// sceneGridIDs returns the id of every family of sc (see nameItems).
+func sceneGridIDs(sc Scene) ([]string, error) {
+	ids := make([]string, len(sc.Grids))
+	for i, sg := range sc.Grids {
+		ids[i] = sg.ID
+	}
+	if dup := nameItems(ids, "grid"); dup != "" {
+		return nil, fmt.Errorf("grid id %q is used twice", dup)
+	}
+	return ids, nil
+}
+
+// scenePointIDs returns the id of every point of sc (see nameItems).
+func scenePointIDs(sc Scene) ([]string, error) {
+	ids := make([]string, len(sc.Points))
+	for i, sp := range sc.Points {
+		ids[i] = sp.ID
+	}
+	if dup := nameItems(ids, "point"); dup != "" {
+		return nil, fmt.Errorf("point id %q is used twice", dup)
+	}
+	return ids, nil
+}
+
+// nameAll names the families and points of the game that have no id, and
+// renames copies that share one.
+func (g *Game) nameAll() {
+	ids := make([]string, len(g.Grids))
+	for i := range g.Grids {
+		ids[i] = g.Grids[i].ID
+	}
+	nameItems(ids, "grid")
+	for i := range g.Grids {
+		g.Grids[i].ID = ids[i]
+	}
+	ids = make([]string, len(g.Points))
+	for i := range g.Points {
+		ids[i] = g.Points[i].ID
+	}
+	nameItems(ids, "point")
+	for i := range g.Points {
+		g.Points[i].ID = ids[i]
+	}
+}
+
+// nameItems fills in ids so every one is unique: an empty id, or one already
+// used earlier in the list, becomes prefix-N after its position (counting
+// from 1), or the next N that is free. It returns the first id that was used
+// twice, if any.
+func nameItems(ids []string, prefix string) (dup string) {
+	used := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		used[id] = true
+	}
+	seen := make(map[string]bool, len(ids))
+	for i, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			continue
+		}
+		if id != "" && dup == "" {
+			dup = id
+		}
+		n := i + 1
+		for used[fmt.Sprintf("%s-%d", prefix, n)] {
+			n++
+		}
+		ids[i] = fmt.Sprintf("%s-%d", prefix, n)
+		used[ids[i]], seen[ids[i]] = true, true
+	}
+	return dup
+}
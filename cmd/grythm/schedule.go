@@ -0,0 +1,89 @@
+package main
+
+import "fmt"
+
+// Sub-tick trigger timing. A crossing is detected at the end of the tick in
+// which it happened; the engine works out when within the tick the line
+// reached the point (Trigger.Time), and the voice is scheduled at that moment
+// on the mixer's sample clock. Game time maps to mixer frames with a fixed offset chosen so that
+// events are scheduled scheduleAhead seconds after the mixer's current
+// position, which absorbs the jitter between Update and audio buffer reads.
+
+// scheduleAhead is the least headroom in seconds between the mixer's
+// position and the newest scheduled trigger. It needs to cover one Update
+// interval, and the audio buffer too: the mixer is read a buffer at a time,
+// so with a larger buffer its position jumps further between Updates and
+// notes scheduled closer than that would land in the past and stutter.
+const scheduleAhead = 0.025
+
+// audioSchedule maps game time to mixer frames.
+type audioSchedule struct {
+	offset float64 // mixer frame at game time 0
+	synced bool
+	ahead  float64 // headroom in seconds, at least scheduleAhead
+
+	latency float64 // smoothed seconds from a trigger to it being heard; 0 until measured
+}
+
+// newAudioSchedule returns the schedule for an audio buffer of the given seconds.
+func newAudioSchedule(buffer float64) audioSchedule {
+	return audioSchedule{ahead: max(scheduleAhead, buffer)}
+}
+
+// audioFrame returns the mixer frame at which an event at game time t should
+// sound. The mapping is re-anchored when an event would land in the past or
+// too far ahead, which happens at start-up, after a stall and as the audio and
+// system clocks drift apart.
+func (g *Game) audioFrame(t float64) int64 {
+	now := float64(g.mixer.Now())
+	rate := float64(g.blipSampleRate)
+	s := &g.audioSched
+	ahead := s.ahead * rate
+	target := t*rate + s.offset
+	if !s.synced || target < now || target > now+4*ahead {
+		s.offset = now + ahead - t*rate
+		s.synced = true
+		target = now + ahead
+	}
+	return int64(target)
+}
+
+// soundFrame returns the mixer frame for an event detected at game time t
+// that sounds at game time at, no earlier than t (see engine.QuantizeTime).
+// The mapping is anchored on t, so a quantized note is scheduled up to a
+// subdivision further ahead than the usual headroom.
+func (g *Game) soundFrame(t, at float64) int64 {
+	return g.audioFrame(t) + int64((at-t)*float64(g.blipSampleRate))
+}
+
+// latencySmoothing is the weight of a new latency measurement in the average.
+const latencySmoothing = 0.05
+
+// measureLatency updates the audio latency: the headroom a trigger is
+// scheduled with plus the audio the player has buffered but not yet played,
+// which is how far the mixer's clock runs ahead of what is heard.
+func (g *Game) measureLatency() {
+	if g.player == nil || !g.player.IsPlaying() {
+		return
+	}
+	buffered := float64(g.mixer.Now())/float64(g.blipSampleRate) - g.player.Position().Seconds()
+	if buffered < 0 {
+		return
+	}
+	s := &g.audioSched
+	m := s.ahead + buffered
+	if s.latency == 0 {
+		s.latency = m
+		return
+	}
+	s.latency += latencySmoothing * (m - s.latency)
+}
+
+// audioLabel shows the audio settings and the measured latency for the HUD.
+func (g *Game) audioLabel() string {
+	l := fmt.Sprintf("%d Hz, buffer %.0f ms", g.blipSampleRate, g.audioBuffer*1000)
+	if lat := g.audioSched.latency; lat > 0 {
+		l += fmt.Sprintf(", latency %.0f ms", lat*1000)
+	}
+	return l
+}
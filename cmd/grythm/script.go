@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+
+	lua "github.com/yuin/gopher-lua"
+	"grythm/engine"
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Script runs a Lua file with hooks into the visualizer. The script may
+// define these global functions, all optional:
+//
+//	onTrigger(grid, point, velocity)  after a line crosses a point
+//	onBeat(bar, beat)                 on every beat while tempo mode is on
+//	onUpdate(dt)                      once per frame
+//
+// and can change the scene through the grythm table (see scriptAPI). Grid and
+// point indices start at 1 as usual in Lua. Script edits bypass undo.
+type Script struct {
+	L        *lua.LState
+	pending  []scriptEvent // triggers queued during the touch loop
+	lastBeat int
+	failed   map[string]bool // hooks disabled after an error
+}
+
+// scriptEvent is a queued onTrigger call.
+type scriptEvent struct {
+	grid, point int
+	velocity    float64
+}
+
+// LoadScript runs the script at path against g.
+func LoadScript(g *Game, path string) (*Script, error) {
+	L := lua.NewState()
+	s := &Script{L: L, lastBeat: -1, failed: make(map[string]bool)}
+	L.SetGlobal("grythm", L.SetFuncs(L.NewTable(), g.scriptAPI()))
+	// math.random draws from the session's seed, so a replay repeats it
+	rng := rand.New(rand.NewSource(g.Seed))
+	if m, ok := L.GetGlobal("math").(*lua.LTable); ok {
+		L.SetFuncs(m, map[string]lua.LGFunction{
+			"random": func(L *lua.LState) int { return luaRandom(L, rng) },
+			"randomseed": func(L *lua.LState) int {
+				rng.Seed(int64(L.CheckNumber(1)))
+				return 0
+			},
+		})
+	}
+	if err := L.DoFile(path); err != nil {
+		L.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// luaRandom implements Lua's math.random with rng: random() is a float in
+// [0, 1), random(m) an integer in [1, m] and random(m, n) one in [m, n].
+func luaRandom(L *lua.LState, rng *rand.Rand) int {
+	switch L.GetTop() {
+	case 0:
+		L.Push(lua.LNumber(rng.Float64()))
+	case 1:
+		n := L.CheckInt(1)
+		if n < 1 {
+			L.ArgError(1, "interval is empty")
+		}
+		L.Push(lua.LNumber(1 + rng.Intn(n)))
+	default:
+		lo, hi := L.CheckInt(1), L.CheckInt(2)
+		if lo > hi {
+			L.ArgError(2, "interval is empty")
+		}
+		L.Push(lua.LNumber(lo + rng.Intn(hi-lo+1)))
+	}
+	return 1
+}
+
+// Close releases the Lua state.
+func (s *Script) Close() {
+	s.L.Close()
+}
+
+// call invokes the global function name if the script defines it. A hook that
+// raises an error is logged and disabled so it doesn't flood the log every frame.
+func (s *Script) call(name string, args ...lua.LValue) {
+	if s.failed[name] {
+		return
+	}
+	fn, ok := s.L.GetGlobal(name).(*lua.LFunction)
+	if !ok {
+		return
+	}
+	if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...); err != nil {
+		log.Printf("script: %s: %v (hook disabled)", name, err)
+		s.failed[name] = true
+	}
+}
+
+// Trigger queues an onTrigger call; it is subscribed to the engine's
+// triggers. Hooks run after the touch loop so a script can't change points
+// while they are being iterated.
+func (s *Script) Trigger(t engine.TriggerEvent) {
+	s.pending = append(s.pending, scriptEvent{t.Grid, t.Point, t.Velocity})
+}
+
+// Update runs the queued and per-frame hooks.
+func (s *Script) Update(g *Game, dt float64) {
+	events := s.pending
+	s.pending = nil
+	for _, e := range events {
+		s.call("onTrigger", lua.LNumber(e.grid+1), lua.LNumber(e.point+1), lua.LNumber(e.velocity))
+	}
+	if g.TempoMode {
+		if beat := int(math.Floor(g.Clock.Beats)); beat != s.lastBeat {
+			s.lastBeat = beat
+			bar, b := g.Clock.Position()
+			s.call("onBeat", lua.LNumber(bar), lua.LNumber(math.Floor(b)))
+		}
+	}
+	s.call("onUpdate", lua.LNumber(dt))
+}
+
+// scriptAPI returns the functions of the grythm table.
+func (g *Game) scriptAPI() map[string]lua.LGFunction {
+	// pointArg and gridArg read a 1-based index argument
+	pointArg := func(L *lua.LState, n int) int {
+		i := L.CheckInt(n) - 1
+		if i < 0 || i >= len(g.Points) {
+			L.ArgError(n, "no such point")
+		}
+		return i
+	}
+	gridArg := func(L *lua.LState, n int) int {
+		i := L.CheckInt(n) - 1
+		if i < 0 || i >= len(g.Grids) {
+			L.ArgError(n, "no such grid")
+		}
+		return i
+	}
+	return map[string]lua.LGFunction{
+		"time":       func(L *lua.LState) int { L.Push(lua.LNumber(g.Time)); return 1 },
+		"width":      func(L *lua.LState) int { L.Push(lua.LNumber(g.W)); return 1 },
+		"height":     func(L *lua.LState) int { L.Push(lua.LNumber(g.H)); return 1 },
+		"pointCount": func(L *lua.LState) int { L.Push(lua.LNumber(len(g.Points))); return 1 },
+		"gridCount":  func(L *lua.LState) int { L.Push(lua.LNumber(len(g.Grids))); return 1 },
+		// addPoint(x, y [, degree]) -> index
+		"addPoint": func(L *lua.LState) int {
+			pos := geom.Vec2{X: float64(L.CheckNumber(1)), Y: float64(L.CheckNumber(2))}
+			deg := L.OptInt(3, g.NewPointDegree(pos))
+			g.InsertPoint(len(g.Points), engine.Point{Pos: pos, Degree: deg})
+			L.Push(lua.LNumber(len(g.Points)))
+			return 1
+		},
+		"removePoint": func(L *lua.LState) int {
+			g.RemovePoint(pointArg(L, 1))
+			g.hoverIdx, g.dragIdx = -1, -1
+			return 0
+		},
+		// point(i) -> {x, y, degree, wave, group, chance, cooldown, length}
+		"point": func(L *lua.LState) int {
+			p := g.Points[pointArg(L, 1)]
+			t := L.NewTable()
+			t.RawSetString("x", lua.LNumber(p.Pos.X))
+			t.RawSetString("y", lua.LNumber(p.Pos.Y))
+			t.RawSetString("degree", lua.LNumber(p.Degree))
+			t.RawSetString("wave", lua.LString(p.Wave.String()))
+			t.RawSetString("group", lua.LString(p.Group))
+			t.RawSetString("chance", lua.LNumber(engine.EffectiveChance(p.Chance)))
+			t.RawSetString("cooldown", lua.LNumber(p.Cooldown))
+			t.RawSetString("velocity", lua.LNumber(p.VelocityScale()))
+			t.RawSetString("length", lua.LNumber(p.Length))
+			L.Push(t)
+			return 1
+		},
+		// setPoint(i, {x=, y=, degree=, wave=, group=, chance=, cooldown=, velocity=, length=}); missing fields are kept
+		"setPoint": func(L *lua.LState) int {
+			i := pointArg(L, 1)
+			t := L.CheckTable(2)
+			p := g.Points[i]
+			p.Pos.X = tableNumber(t, "x", p.Pos.X)
+			p.Pos.Y = tableNumber(t, "y", p.Pos.Y)
+			p.Degree = int(tableNumber(t, "degree", float64(p.Degree)))
+			p.Group = tableString(t, "group", p.Group)
+			p.Chance = tableNumber(t, "chance", p.Chance)
+			p.Cooldown = tableNumber(t, "cooldown", p.Cooldown)
+			p.Velocity = tableNumber(t, "velocity", p.Velocity)
+			p.Length = tableNumber(t, "length", p.Length)
+			if err := engine.ValidateChance(p.Chance); err != nil {
+				L.ArgError(2, err.Error())
+			}
+			if err := engine.ValidateCooldown(p.Cooldown); err != nil {
+				L.ArgError(2, err.Error())
+			}
+			if err := engine.ValidatePointVelocity(p.Velocity); err != nil {
+				L.ArgError(2, err.Error())
+			}
+			if err := synth.ValidateNoteLength(p.Length); err != nil {
+				L.ArgError(2, err.Error())
+			}
+			if w := tableString(t, "wave", ""); w != "" {
+				if err := p.Wave.UnmarshalText([]byte(w)); err != nil {
+					L.ArgError(2, err.Error())
+				}
+			}
+			g.SetPoint(i, p)
+			return 0
+		},
+		// grid(i) -> {kind, angle, spacing, offset, degree, wave, thickness, width, chance, cooldown, length}
+		"grid": func(L *lua.LState) int {
+			gf := &g.Grids[gridArg(L, 1)]
+			t := L.NewTable()
+			t.RawSetString("kind", lua.LString(gf.KindName()))
+			t.RawSetString("angle", lua.LNumber(math.Atan2(gf.Normal.Y, gf.Normal.X)*180/math.Pi))
+			t.RawSetString("spacing", lua.LNumber(gf.Spacing))
+			t.RawSetString("offset", lua.LNumber(gf.Offset))
+			t.RawSetString("degree", lua.LNumber(gf.Degree))
+			t.RawSetString("wave", lua.LString(gf.Wave.String()))
+			t.RawSetString("thickness", lua.LNumber(gf.Thickness))
+			t.RawSetString("width", lua.LNumber(gf.LineWidth()))
+			t.RawSetString("chance", lua.LNumber(engine.EffectiveChance(gf.Chance)))
+			t.RawSetString("cooldown", lua.LNumber(gf.Cooldown))
+			t.RawSetString("length", lua.LNumber(gf.Length))
+			t.RawSetString("alpha", lua.LNumber(gf.Alpha))
+			t.RawSetString("blend", lua.LString(gf.Blend.String()))
+			t.RawSetString("edge", lua.LString(gf.Edge.String()))
+			L.Push(t)
+			return 1
+		},
+		// setGrid(i, {angle=, spacing=, degree=, wave=, thickness=, width=, chance=, cooldown=, length=}); missing fields are kept
+		"setGrid": func(L *lua.LState) int {
+			gf := &g.Grids[gridArg(L, 1)]
+			if err := applyGridTable(gf, L.CheckTable(2)); err != nil {
+				L.ArgError(2, err.Error())
+			}
+			return 0
+		},
+		// addGrid({angle=, spacing=, color=, ...}) -> index; a linear family
+		"addGrid": func(L *lua.LState) int {
+			gf := geom.GridFamily{
+				Normal:    geom.Vec2{X: 1, Y: 0},
+				Spacing:   60,
+				Color:     g.theme.GridColor(len(g.Grids)),
+				Thickness: 2,
+			}
+			if err := applyGridTable(&gf, L.OptTable(1, L.NewTable())); err != nil {
+				L.ArgError(1, err.Error())
+			}
+			g.AppendGrid(gf)
+			L.Push(lua.LNumber(len(g.Grids)))
+			return 1
+		},
+		// addGridSegment(x1, y1, x2, y2 [, fields]) adds a linear family with
+		// a line through each end of the segment (see geom.FamilyFromSegment)
+		"addGridSegment": func(L *lua.LState) int {
+			a := geom.Vec2{X: float64(L.CheckNumber(1)), Y: float64(L.CheckNumber(2))}
+			b := geom.Vec2{X: float64(L.CheckNumber(3)), Y: float64(L.CheckNumber(4))}
+			gf, ok := geom.FamilyFromSegment(a, b, g.Center())
+			if !ok {
+				L.ArgError(3, fmt.Sprintf("segment shorter than %gpx", geom.MinSegmentSpacing))
+			}
+			gf.Color = g.theme.GridColor(len(g.Grids))
+			if err := applyGridTable(&gf, L.OptTable(5, L.NewTable())); err != nil {
+				L.ArgError(5, err.Error())
+			}
+			g.AppendGrid(gf)
+			L.Push(lua.LNumber(len(g.Grids)))
+			return 1
+		},
+		// setMotion(angleDegrees, speed)
+		"setMotion": func(L *lua.LState) int {
+			a := float64(L.CheckNumber(1)) * math.Pi / 180
+			g.MoveDir = geom.Vec2{X: math.Cos(a), Y: math.Sin(a)}
+			g.Speed = math.Max(0, float64(L.OptNumber(2, lua.LNumber(g.Speed))))
+			if g.TempoMode {
+				g.Clock.SetSpeed(g.Speed)
+			}
+			return 0
+		},
+		"setBPM": func(L *lua.LState) int { g.Clock.SetBPM(float64(L.CheckNumber(1))); return 0 },
+		"setKey": func(L *lua.LState) int { g.Key = L.CheckInt(1); return 0 },
+	}
+}
+
+// applyGridTable sets the grid fields present in t.
+func applyGridTable(gf *geom.GridFamily, t *lua.LTable) error {
+	if v, ok := t.RawGetString("angle").(lua.LNumber); ok {
+		a := float64(v) * math.Pi / 180
+		gf.Normal = geom.Vec2{X: math.Cos(a), Y: math.Sin(a)}
+	}
+	if sp := tableNumber(t, "spacing", gf.Spacing); sp > 0 {
+		gf.Spacing = sp
+	} else {
+		return fmt.Errorf("spacing must be positive")
+	}
+	gf.Degree = int(tableNumber(t, "degree", float64(gf.Degree)))
+	gf.Thickness = tableNumber(t, "thickness", gf.Thickness)
+	gf.Width = tableNumber(t, "width", gf.Width)
+	if err := geom.ValidateWidth(gf.Width); err != nil {
+		return err
+	}
+	gf.Chance = tableNumber(t, "chance", gf.Chance)
+	if err := engine.ValidateChance(gf.Chance); err != nil {
+		return err
+	}
+	gf.Cooldown = tableNumber(t, "cooldown", gf.Cooldown)
+	if err := engine.ValidateCooldown(gf.Cooldown); err != nil {
+		return err
+	}
+	gf.Length = tableNumber(t, "length", gf.Length)
+	if err := synth.ValidateNoteLength(gf.Length); err != nil {
+		return err
+	}
+	if w := tableString(t, "wave", ""); w != "" {
+		if err := gf.Wave.UnmarshalText([]byte(w)); err != nil {
+			return err
+		}
+	}
+	if c := tableString(t, "color", ""); c != "" {
+		col, err := parseHexColor(c)
+		if err != nil {
+			return err
+		}
+		gf.Color = col
+	}
+	gf.Alpha = tableNumber(t, "alpha", gf.Alpha)
+	if err := geom.ValidateAlpha(gf.Alpha); err != nil {
+		return err
+	}
+	if b := tableString(t, "blend", ""); b != "" {
+		if err := gf.Blend.UnmarshalText([]byte(b)); err != nil {
+			return err
+		}
+	}
+	if e := tableString(t, "edge", ""); e != "" {
+		if err := gf.Edge.UnmarshalText([]byte(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableNumber returns the number field key of t, or def if it is not a number.
+func tableNumber(t *lua.LTable, key string, def float64) float64 {
+	if v, ok := t.RawGetString(key).(lua.LNumber); ok {
+		return float64(v)
+	}
+	return def
+}
+
+// tableString returns the string field key of t, or def if it is not a string.
+func tableString(t *lua.LTable, key, def string) string {
+	if v, ok := t.RawGetString(key).(lua.LString); ok {
+		return string(v)
+	}
+	return def
+}
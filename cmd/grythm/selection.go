@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Multi-selection. Shift+drag on the empty canvas spans a rubber band that
+// selects the points inside it, Shift+click on a point adds it to the
+// selection or takes it out, Ctrl+A selects every point and Escape clears the
+// selection. Shift+Tab adds the selected grid family to it (or takes it out).
+// While there is a selection the editing keys act on all of it, as one undo
+// step: dragging a selected point moves the others along, Delete removes the
+// points, [ ] transpose the points and families and G moves the points to the
+// next group. Ctrl+C copies the selection to the clipboard and Ctrl+X cuts
+// its points; Ctrl+V pastes at the cursor and Ctrl+Shift+V where it was
+// copied, moved on by pasteOffset for every paste. What was pasted becomes
+// the selection.
+
+// pasteOffset is how far (world pixels, down and to the right) each
+// Ctrl+Shift+V pastes from the one before.
+const pasteOffset = 20.0
+
+// selection holds the indices of the selected points and grid families, in
+// ascending order. The point indices follow insertions and removals (see
+// Game.InsertPoint); families are only appended and truncated, so indices
+// past the end are skipped (see selectedGrids).
+type selection struct {
+	points []int
+	grids  []int
+}
+
+// empty reports whether nothing is selected.
+func (s *selection) empty() bool {
+	return len(s.points) == 0 && len(s.grids) == 0
+}
+
+// hasPoint reports whether point i is selected.
+func (s *selection) hasPoint(i int) bool {
+	_, ok := slices.BinarySearch(s.points, i)
+	return ok
+}
+
+// toggle adds i to the sorted indices, or takes it out when it is there.
+func toggle(indices []int, i int) []int {
+	at, ok := slices.BinarySearch(indices, i)
+	if ok {
+		return slices.Delete(indices, at, at+1)
+	}
+	return slices.Insert(indices, at, i)
+}
+
+// insertPoint shifts the selection past a point inserted at idx.
+func (s *selection) insertPoint(idx int) {
+	for k := range s.points {
+		if s.points[k] >= idx {
+			s.points[k]++
+		}
+	}
+}
+
+// removePoint drops point idx from the selection and shifts those after it.
+func (s *selection) removePoint(idx int) {
+	out := s.points[:0]
+	for _, i := range s.points {
+		switch {
+		case i < idx:
+			out = append(out, i)
+		case i > idx:
+			out = append(out, i-1)
+		}
+	}
+	s.points = out
+}
+
+// clipboard holds what Ctrl+C copied: points relative to their mean position
+// where they were copied, and grid families.
+type clipboard struct {
+	points []engine.Point
+	grids  []geom.GridFamily
+	origin geom.Vec2 // mean position of the points when copied
+	pastes int       // Ctrl+Shift+V pastes since the copy
+}
+
+// rubberBand is a selection rectangle being dragged, in world coordinates.
+type rubberBand struct {
+	active   bool
+	from, to geom.Vec2
+}
+
+// InsertPoint inserts p at idx (see engine.Engine.InsertPoint), keeping the
+// selection on the points it was on.
+func (g *Game) InsertPoint(idx int, p engine.Point) {
+	g.Engine.InsertPoint(idx, p)
+	g.sel.insertPoint(idx)
+}
+
+// RemovePoint removes the point at idx (see engine.Engine.RemovePoint) and
+// from the selection.
+func (g *Game) RemovePoint(idx int) {
+	g.Engine.RemovePoint(idx)
+	g.sel.removePoint(idx)
+}
+
+// selectedGrids returns the selected grid families that still exist.
+func (g *Game) selectedGrids() []int {
+	var out []int
+	for _, gi := range g.sel.grids {
+		if gi < len(g.Grids) {
+			out = append(out, gi)
+		}
+	}
+	return out
+}
+
+// selectPress handles a Shift+press: on point i it adds the point to the
+// selection or takes it out, on the empty canvas (i < 0) it starts a rubber
+// band at mouse.
+func (g *Game) selectPress(i int, mouse geom.Vec2) {
+	if i >= 0 {
+		g.sel.points = toggle(g.sel.points, i)
+		return
+	}
+	g.band = rubberBand{active: true, from: mouse, to: mouse}
+}
+
+// updateBand follows the rubber band with the mouse and selects the points
+// inside it on release.
+func (g *Game) updateBand(mouse geom.Vec2, ptr pointer, pinching bool) {
+	b := &g.band
+	if !b.active {
+		return
+	}
+	b.to = mouse
+	if pinching {
+		b.active = false
+		return
+	}
+	if !ptr.JustReleased {
+		return
+	}
+	b.active = false
+	lo := geom.Vec2{X: min(b.from.X, b.to.X), Y: min(b.from.Y, b.to.Y)}
+	hi := geom.Vec2{X: max(b.from.X, b.to.X), Y: max(b.from.Y, b.to.Y)}
+	g.sel.points = g.sel.points[:0]
+	for i, p := range g.Points {
+		if p.Pos.X >= lo.X && p.Pos.X <= hi.X && p.Pos.Y >= lo.Y && p.Pos.Y <= hi.Y {
+			g.sel.points = append(g.sel.points, i)
+		}
+	}
+}
+
+// dragsSelection reports whether dragging point i moves the rest of the
+// selection along, rather than its mirror copies.
+func (g *Game) dragsSelection(i int) bool {
+	return len(g.sel.points) > 1 && g.sel.hasPoint(i)
+}
+
+// dragCompanions returns the points that move along when point i is dragged:
+// the rest of the selection, or its mirror copies.
+func (g *Game) dragCompanions(i int) []int {
+	if !g.dragsSelection(i) {
+		return g.mirrorsOf(i)
+	}
+	var out []int
+	for _, j := range g.sel.points {
+		if j != i {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// movePoints moves the points at idx by d, with their paths.
+func (g *Game) movePoints(idx []int, d geom.Vec2) {
+	for _, i := range idx {
+		q := &g.Points[i]
+		if q.Path.Moves() {
+			q.Path.Translate(d)
+		}
+		q.Pos = q.Pos.Add(d)
+		if g.PitchMap.Enabled {
+			q.Degree = g.DegreeAt(q.Pos)
+		}
+	}
+	g.Index.Invalidate()
+}
+
+// selectionKeys handles the keys that select, remove, copy and paste.
+func (g *Game) selectionKeys(mouse geom.Vec2) {
+	if g.pressed(actSelectAll) {
+		g.sel.points = g.sel.points[:0]
+		for i := range g.Points {
+			g.sel.points = append(g.sel.points, i)
+		}
+	}
+	if g.pressed(actSelectNone) {
+		g.sel = selection{}
+	}
+	if g.pressed(actSelectFamily) && g.selGrid < len(g.Grids) {
+		g.sel.grids = toggle(g.sel.grids, g.selGrid)
+	}
+	if g.pressed(actCopy) || g.pressed(actCut) {
+		g.copySelection()
+	}
+	if (g.pressed(actDelete) || g.pressed(actCut)) && len(g.sel.points) > 0 {
+		g.exec(g.removeSelectionCmd())
+	}
+	if g.pressed(actPaste) {
+		g.paste(mouse)
+	}
+	if g.pressed(actPasteOffset) {
+		g.clip.pastes++
+		g.paste(g.clip.origin.Add(geom.Vec2{X: 1, Y: 1}.Mul(pasteOffset * float64(g.clip.pastes))))
+	}
+}
+
+// copySelection puts the selected points and families on the clipboard.
+func (g *Game) copySelection() {
+	if g.sel.empty() {
+		return
+	}
+	cb := clipboard{}
+	for _, i := range g.sel.points {
+		cb.origin = cb.origin.Add(g.Points[i].Pos)
+	}
+	if len(g.sel.points) > 0 {
+		cb.origin = cb.origin.Mul(1 / float64(len(g.sel.points)))
+	}
+	for _, i := range g.sel.points {
+		// A copy is a point of its own: not linked to mirrors, not named and
+		// with its whole lifespan ahead
+		p := g.Points[i]
+		if p.Path.Moves() {
+			p.Path.Translate(cb.origin.Mul(-1))
+		}
+		p.Pos = p.Pos.Sub(cb.origin)
+		p.Chord = slices.Clone(p.Chord)
+		p.Life = p.Life.Renewed()
+		p.Mirror = engine.Mirror{}
+		p.ID = ""
+		cb.points = append(cb.points, p)
+	}
+	for _, gi := range g.selectedGrids() {
+		cb.grids = append(cb.grids, g.Grids[gi].Clone())
+	}
+	g.clip = cb
+}
+
+// removeSelectionCmd returns the command that removes the selected points.
+func (g *Game) removeSelectionCmd() Command {
+	// From the back, so the indices of those still to go don't shift
+	var batch batchCmd
+	for k := len(g.sel.points) - 1; k >= 0; k-- {
+		i := g.sel.points[k]
+		batch = append(batch, removePointCmd{idx: i, p: g.Points[i]})
+	}
+	return batch
+}
+
+// paste adds the clipboard with its points around at, as one undo step, and
+// selects what it added.
+func (g *Game) paste(at geom.Vec2) {
+	cb := &g.clip
+	if len(cb.points) == 0 && len(cb.grids) == 0 {
+		return
+	}
+	var batch batchCmd
+	next := selection{}
+	for k, p := range cb.points {
+		if p.Path.Moves() {
+			p.Path.Translate(at)
+		}
+		p.Pos = p.Pos.Add(at)
+		if g.PitchMap.Enabled {
+			p.Degree = g.DegreeAt(p.Pos)
+		}
+		p.Chord = slices.Clone(p.Chord)
+		batch = append(batch, addPointCmd{idx: len(g.Points) + k, p: p})
+		next.points = append(next.points, len(g.Points)+k)
+	}
+	if len(cb.grids) > 0 {
+		batch = append(batch, addGridsCmd{at: len(g.Grids), sel: g.selGrid, grids: cb.grids})
+		for k := range cb.grids {
+			next.grids = append(next.grids, len(g.Grids)+k)
+		}
+	}
+	g.exec(batch)
+	g.sel = next
+}
+
+// transposeSelectionCmd returns the command that moves the selected points
+// and families d scale degrees.
+func (g *Game) transposeSelectionCmd(d int) Command {
+	var batch batchCmd
+	for _, i := range g.sel.points {
+		before := g.Points[i]
+		after := before
+		after.Degree += d
+		batch = append(batch, editPointCmd{idx: i, before: before, after: after})
+	}
+	for _, gi := range g.selectedGrids() {
+		deg := g.Grids[gi].Degree
+		batch = append(batch, gridEditCmd[int]{idx: gi, from: deg, to: deg + d,
+			set: func(gf *geom.GridFamily, v int) { gf.Degree = v }})
+	}
+	return batch
+}
+
+// groupSelectionCmd returns the command that moves the selected points to the
+// group after the first one's, all to the same group.
+func (g *Game) groupSelectionCmd() Command {
+	group := g.NextGroup(g.Points[g.sel.points[0]].Group)
+	var batch batchCmd
+	for _, i := range g.sel.points {
+		before := g.Points[i]
+		after := before
+		after.Group = group
+		batch = append(batch, editPointCmd{idx: i, before: before, after: after})
+	}
+	return batch
+}
+
+// selectionLabel describes the selection for the HUD.
+func (g *Game) selectionLabel() string {
+	grids := g.selectedGrids()
+	if len(g.sel.points) == 0 && len(grids) == 0 {
+		return ""
+	}
+	l := fmt.Sprintf("  Selected: %d points", len(g.sel.points))
+	if len(grids) > 0 {
+		l += ", grids"
+		for _, gi := range grids {
+			l += fmt.Sprintf(" G%d", gi+1)
+		}
+	}
+	return l
+}
+
+// drawSelection rings the selected points and outlines the rubber band.
+func (g *Game) drawSelection(dst *ebiten.Image) {
+	d := float32(g.cam.Device())
+	for _, i := range g.sel.points {
+		if i >= len(g.Points) {
+			continue
+		}
+		sp := g.cam.ToScreen(g.Points[i].Pos)
+		vector.StrokeCircle(dst, float32(sp.X), float32(sp.Y), 11*d, d, g.theme.Guide, true)
+	}
+	if b := g.band; b.active {
+		a, c := g.cam.ToScreen(b.from), g.cam.ToScreen(b.to)
+		x, y := min(a.X, c.X), min(a.Y, c.Y)
+		w, h := max(a.X, c.X)-x, max(a.Y, c.Y)-y
+		vector.StrokeRect(dst, float32(x), float32(y), float32(w), float32(h), d, g.theme.Guide, false)
+	}
+}
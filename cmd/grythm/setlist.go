@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// A setlist is a list of scenes to perform one after the other. All of them
+// are read at startup, so switching (PageUp/PageDown) never waits for the
+// disk. A switch crossfades from the last frame of the old scene to the new
+// one, and the notes still sounding fade out over the same time while the
+// new scene starts playing. A scene keeps its edits when it is left, and
+// starts over from them when it comes round again.
+
+// Setlist holds the scenes of a performance and which one is playing.
+type Setlist struct {
+	Entries []SetlistEntry `json:"entries"`
+	Pos     int            `json:"pos"` // index of the scene playing
+}
+
+// SetlistEntry is one scene of a setlist.
+type SetlistEntry struct {
+	Path  string   `json:"path"`  // scene file, saved to with Ctrl+S while it plays
+	Start Snapshot `json:"start"` // the scene as it starts when switched to
+}
+
+// LoadSetlist reads a setlist file: one scene file per line, relative to the
+// setlist, with blank lines and lines starting with # ignored. Each scene is
+// read right away.
+func LoadSetlist(path string) (*Setlist, error) {
+	if !hasFileSystem {
+		return nil, errNoFileSystem
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("setlist: %w", err)
+	}
+	defer f.Close()
+	sl := &Setlist{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(path), line)
+		}
+		data, err := os.ReadFile(line)
+		if err != nil {
+			return nil, fmt.Errorf("setlist %s: %w", path, err)
+		}
+		e := SetlistEntry{Path: line}
+		if err := json.Unmarshal(data, &e.Start); err != nil {
+			return nil, fmt.Errorf("setlist %s: parse scene %s: %w", path, line, err)
+		}
+		sl.Entries = append(sl.Entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("setlist %s: %w", path, err)
+	}
+	if len(sl.Entries) == 0 {
+		return nil, fmt.Errorf("setlist %s: no scenes", path)
+	}
+	return sl, nil
+}
+
+// label names the playing scene for the HUD.
+func (sl *Setlist) label() string {
+	name := strings.TrimSuffix(filepath.Base(sl.Entries[sl.Pos].Path), ".json")
+	return fmt.Sprintf("%d/%d %s", sl.Pos+1, len(sl.Entries), name)
+}
+
+// startSetlist plays the scene at the setlist's position, without a crossfade.
+func (g *Game) startSetlist(sl *Setlist) error {
+	g.setlist = sl
+	e := &sl.Entries[sl.Pos]
+	if err := g.ApplyScene(e.Start.Scene); err != nil {
+		return fmt.Errorf("scene %s: %w", e.Path, err)
+	}
+	g.applySnapshot(e.Start)
+	g.scenePath = e.Path
+	return nil
+}
+
+// stepSetlist switches to the scene step places further along the setlist,
+// wrapping around, and starts the crossfade. The scene being left keeps its
+// edits for when it is played again.
+func (g *Game) stepSetlist(step int) {
+	sl := g.setlist
+	if sl == nil || g.dragIdx >= 0 || g.brush.active {
+		return
+	}
+	from := sl.Pos
+	sl.Entries[from].Start = Snapshot{Scene: g.Scene()}
+	sl.Pos = ((sl.Pos+step)%len(sl.Entries) + len(sl.Entries)) % len(sl.Entries)
+	if err := g.startSetlist(sl); err != nil {
+		log.Printf("setlist: %v", err)
+		sl.Pos = from
+		return
+	}
+	g.mixer.Release(int(g.fade.length * float64(g.blipSampleRate)))
+	g.fade.start()
+}
+
+// crossfade fades the last frame of the previous scene out over the new one.
+type crossfade struct {
+	length float64       // seconds; 0 cuts straight to the new scene
+	left   float64       // seconds of the fade to go
+	last   *ebiten.Image // the canvas of the previous frame, kept while a setlist plays
+	from   *ebiten.Image // the frame being faded out
+}
+
+// start freezes the last frame and fades it out from now on.
+func (c *crossfade) start() {
+	if c.length <= 0 || c.last == nil {
+		return
+	}
+	c.last, c.from = c.from, c.last
+	c.left = c.length
+}
+
+// update advances the fade by dt seconds.
+func (c *crossfade) update(dt float64) {
+	c.left = max(0, c.left-dt)
+}
+
+// drawCrossfade draws the fading frame over the canvas and keeps a copy of
+// the result for the next switch. It is called from Draw before the HUD.
+func (g *Game) drawCrossfade(screen *ebiten.Image) {
+	c := &g.fade
+	if g.setlist == nil || c.length <= 0 {
+		return
+	}
+	if c.left > 0 && c.from != nil {
+		op := &ebiten.DrawImageOptions{}
+		op.ColorScale.ScaleAlpha(float32(c.left / c.length))
+		screen.DrawImage(c.from, op)
+	}
+	if c.last != nil {
+		if b := c.last.Bounds(); b.Dx() != g.W || b.Dy() != g.H {
+			c.last.Dispose()
+			c.last = nil
+		}
+	}
+	if c.last == nil {
+		c.last = ebiten.NewImage(g.W, g.H)
+	}
+	c.last.Clear()
+	c.last.DrawImage(screen, nil)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"grythm/geom"
+)
+
+// Snapshot is the sidecar written next to an exported frame (F12): the scene
+// together with the animated state it was drawn in. It is a scene file
+// itself, so -scene loads it, and the extra fields then put the pattern and
+// the view back exactly as pictured.
+type Snapshot struct {
+	Scene
+	Phases []geom.Phase `json:"phases"`          // animated state of each grid as drawn
+	View   geom.Vec2    `json:"view"`            // camera center (world pixels)
+	Zoom   float64      `json:"zoom"`            // camera zoom
+	Time   float64      `json:"time"`            // simulation time in seconds
+	Beats  float64      `json:"beats,omitempty"` // clock position in tempo mode
+	Seed   int64        `json:"seed"`            // trigger dice seed of the session (-seed)
+}
+
+// captureSnapshot writes the canvas and its sidecar into the recording
+// directory. It is called from Draw, where the grids are the interpolated
+// ones on screen.
+func (g *Game) captureSnapshot(screen *ebiten.Image) {
+	if !hasFileSystem {
+		log.Printf("snapshot: %v", errNoFileSystem)
+		return
+	}
+	img := image.NewRGBA(screen.Bounds())
+	screen.ReadPixels(img.Pix)
+	sn := g.snapshot()
+	base := filepath.Join(g.recordDir, "grythm-"+time.Now().Format("20060102-150405.000"))
+	// Encode in the background so the frame isn't held up
+	go func() {
+		if err := writeSnapshot(base, img, sn); err != nil {
+			log.Printf("snapshot: %v", err)
+			return
+		}
+		log.Printf("snapshot saved to %s.png", base)
+	}()
+}
+
+// snapshot captures the scene with its animated state and the view.
+func (g *Game) snapshot() Snapshot {
+	sn := Snapshot{
+		Scene: g.Scene(),
+		View:  g.cam.Center,
+		Zoom:  g.cam.Zoom,
+		Time:  g.Time,
+		Beats: g.Clock.Beats,
+		Seed:  g.Seed,
+	}
+	for i := range g.Grids {
+		sn.Phases = append(sn.Phases, g.Grids[i].Phase())
+	}
+	return sn
+}
+
+// writeSnapshot writes base.png and base.json.
+func writeSnapshot(base string, img image.Image, sn Snapshot) error {
+	data, err := json.MarshalIndent(sn, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writePNG(base+".png", img); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".json", append(data, '\n'), 0o644)
+}
+
+// applySnapshot restores the animated state and view of a loaded snapshot,
+// after its scene has been applied. Plain scene files have no phases and are
+// left as they are.
+func (g *Game) applySnapshot(sn Snapshot) {
+	if len(sn.Phases) != len(g.Grids) {
+		return
+	}
+	for i := range g.Grids {
+		g.Grids[i].SetPhase(sn.Phases[i])
+	}
+	if sn.Zoom > 0 {
+		g.cam.Center = sn.View
+		g.cam.Zoom = sn.Zoom
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// The stats overlay (F6, Shift+F6 resets the counts) shows the engine's
+// trigger statistics: the total and the current triggers per second, the
+// count of every grid, the busiest points and the hovered one, and the
+// histogram of intervals between successive triggers of the same point.
+// Triggers in its first bin, closer together than a line could plausibly
+// cross a point twice, are counted as likely double triggers.
+
+const (
+	statsW       = 250.0 // width of the overlay
+	statsLineH   = 16    // height of a line of debug text
+	statsMaxRows = 8     // grids listed before the rest are summed up
+	statsBusiest = 3     // points listed as the busiest
+)
+
+// intervalLabels name the bins of engine.IntervalBins and the one after them.
+var intervalLabels = []string{"<16ms", "<31ms", "<63ms", "<125ms", "<250ms", "<500ms", "<1s", "<2s", "<4s", ">=4s"}
+
+// drawStats renders the overlay below the brush palette.
+func (g *Game) drawStats(dst *ebiten.Image) {
+	st := &g.Stats
+	var b strings.Builder
+	fmt.Fprintf(&b, "Triggers: %d (%.1f/s)\n", st.Total, st.Rate(g.Time))
+	for gi := range g.Grids {
+		if gi == statsMaxRows {
+			fmt.Fprintf(&b, "Grids %d-%d: %d\n", gi+1, len(g.Grids), sumFrom(st.Grid, gi))
+			break
+		}
+		fmt.Fprintf(&b, "Grid %d: %d\n", gi+1, countAt(st.Grid, gi))
+	}
+	b.WriteString("Busiest points:")
+	for _, pi := range busiest(st.Point, statsBusiest) {
+		fmt.Fprintf(&b, " #%d %d", pi+1, st.Point[pi])
+	}
+	b.WriteByte('\n')
+	if g.hoverIdx >= 0 {
+		fmt.Fprintf(&b, "Hovered point: %d\n", countAt(st.Point, g.hoverIdx))
+	}
+	fmt.Fprintf(&b, "Intervals of a point (likely doubles: %d)\n", countAt(st.Intervals, 0))
+	head := strings.Count(b.String(), "\n")
+	for _, l := range intervalLabels {
+		b.WriteString(l + "\n")
+	}
+
+	x := float64(g.W) - statsW - 8
+	y := paletteTop + float64(brushToolCount)*paletteRowH + 8
+	h := float64(statsLineH*(head+len(intervalLabels)) + 4)
+	vector.DrawFilledRect(dst, float32(x), float32(y), statsW, float32(h), g.theme.Panel, false)
+	g.printAt(dst, b.String(), int(x)+4, int(y))
+
+	// Bars of the interval histogram next to their labels
+	most := slices.Max(append([]int{1}, st.Intervals...))
+	barX, barW := x+56, statsW-64
+	for i := range intervalLabels {
+		n := countAt(st.Intervals, i)
+		if n == 0 {
+			continue
+		}
+		by := y + float64(statsLineH*(head+i)) + 4
+		vector.DrawFilledRect(dst, float32(barX), float32(by), float32(barW*float64(n)/float64(most)), statsLineH-6, g.theme.Beat, false)
+	}
+}
+
+// countAt returns counts[i], or 0 when the slice doesn't reach i.
+func countAt(counts []int, i int) int {
+	if i < len(counts) {
+		return counts[i]
+	}
+	return 0
+}
+
+// sumFrom adds up the counts from index i on.
+func sumFrom(counts []int, i int) int {
+	n := 0
+	for ; i < len(counts); i++ {
+		n += counts[i]
+	}
+	return n
+}
+
+// busiest returns the indices of up to n of the highest counts, highest first.
+func busiest(counts []int, n int) []int {
+	idx := make([]int, 0, len(counts))
+	for i, c := range counts {
+		if c > 0 {
+			idx = append(idx, i)
+		}
+	}
+	slices.SortStableFunc(idx, func(a, b int) int { return counts[b] - counts[a] })
+	return idx[:min(n, len(idx))]
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"grythm/engine"
+	"grythm/geom"
+)
+
+// Symmetry editing. With a symmetry on (Shift+X cycles them, -symmetry sets
+// one at startup) a point placed by hand comes with its mirror images about
+// the center of the canvas, the anchor of the linear families. The copies
+// stay linked (see engine.Mirror): dragging one moves the others to match,
+// and removing one removes them all, as one undo step each. The set keeps the
+// symmetry it was made with when the tool is switched to another.
+
+// symmetrySteps are the symmetries Shift+X cycles through; off comes first.
+var symmetrySteps = []geom.Symmetry{
+	{},
+	{Kind: geom.SymmetryVertical},
+	{Kind: geom.SymmetryHorizontal},
+	{Kind: geom.SymmetryBoth},
+	{Kind: geom.SymmetryRadial, Fold: 3},
+	{Kind: geom.SymmetryRadial, Fold: 4},
+	{Kind: geom.SymmetryRadial, Fold: 6},
+	{Kind: geom.SymmetryRadial, Fold: 8},
+}
+
+// nextSymmetry returns the step after s.
+func nextSymmetry(s geom.Symmetry) geom.Symmetry {
+	for i, st := range symmetrySteps {
+		if st == s {
+			return symmetrySteps[(i+1)%len(symmetrySteps)]
+		}
+	}
+	return symmetrySteps[0]
+}
+
+// placeCmd returns the command that places a point by hand at pos, along with
+// its mirror images when a symmetry is on.
+func (g *Game) placeCmd(pos geom.Vec2) Command {
+	p := g.newPoint(pos, g.NewPointDegree(pos))
+	n := g.symmetry.Count()
+	if n == 1 {
+		return addPointCmd{idx: len(g.Points), p: p}
+	}
+	set := 1
+	for _, q := range g.Points {
+		set = max(set, q.Mirror.Set+1)
+	}
+	batch := make(batchCmd, n)
+	for i := range batch {
+		c := p
+		c.Pos = g.symmetry.Image(pos, g.Center(), i)
+		if g.PitchMap.Enabled {
+			c.Degree = g.DegreeAt(c.Pos)
+		}
+		c.Mirror = engine.Mirror{Set: set, Image: i, Sym: g.symmetry}
+		batch[i] = addPointCmd{idx: len(g.Points) + i, p: c}
+	}
+	return batch
+}
+
+// removeCmd returns the command that removes point i with its mirror copies.
+func (g *Game) removeCmd(i int) Command {
+	p := g.Points[i]
+	if p.Mirror.Set == 0 {
+		return removePointCmd{idx: i, p: p}
+	}
+	// From the back, so the indices of those still to go don't shift
+	var batch batchCmd
+	for j := len(g.Points) - 1; j >= 0; j-- {
+		if j == i || g.Points[j].Mirrors(p) {
+			batch = append(batch, removePointCmd{idx: j, p: g.Points[j]})
+		}
+	}
+	return batch
+}
+
+// mirrorsOf returns the indices of the mirror copies of point i.
+func (g *Game) mirrorsOf(i int) []int {
+	var out []int
+	for j := range g.Points {
+		if j != i && g.Points[j].Mirrors(g.Points[i]) {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// moveMirrors puts the mirror copies of point i where its symmetry has them
+// after it was moved.
+func (g *Game) moveMirrors(i int) {
+	p := g.Points[i]
+	m := p.Mirror
+	if m.Set == 0 {
+		return
+	}
+	center := g.Center()
+	src := m.Sym.Source(p.Pos, center, m.Image)
+	for _, j := range g.mirrorsOf(i) {
+		q := &g.Points[j]
+		to := m.Sym.Image(src, center, q.Mirror.Image)
+		if q.Path.Moves() {
+			q.Path.Translate(to.Sub(q.Pos))
+		}
+		q.Pos = to
+		if g.PitchMap.Enabled {
+			q.Degree = g.DegreeAt(to)
+		}
+	}
+	g.Index.Invalidate()
+}
+
+// drawSymmetry draws the mirror axes, or the spokes of a radial symmetry,
+// while a symmetry is on.
+func (g *Game) drawSymmetry(dst *ebiten.Image) {
+	s := g.symmetry
+	if s.Kind == geom.SymmetryOff {
+		return
+	}
+	var angles []float64
+	switch s.Kind {
+	case geom.SymmetryVertical:
+		angles = []float64{math.Pi / 2, -math.Pi / 2}
+	case geom.SymmetryHorizontal:
+		angles = []float64{0, math.Pi}
+	case geom.SymmetryBoth:
+		angles = []float64{0, math.Pi / 2, math.Pi, -math.Pi / 2}
+	case geom.SymmetryRadial:
+		for i := 0; i < s.Count(); i++ {
+			angles = append(angles, -math.Pi/2+2*math.Pi*float64(i)/float64(s.Count()))
+		}
+	}
+	// Long enough to leave the window from anywhere in view
+	reach := g.cam.ViewRadius() + g.cam.Center.Sub(g.Center()).Len()
+	c := g.cam.ToScreen(g.Center())
+	for _, a := range angles {
+		end := g.cam.ToScreen(g.Center().Add(geom.Vec2{X: math.Cos(a), Y: math.Sin(a)}.Mul(reach)))
+		vector.StrokeLine(dst, float32(c.X), float32(c.Y), float32(end.X), float32(end.Y), float32(g.cam.Device()), g.theme.Guide, true)
+	}
+}
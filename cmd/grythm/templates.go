@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"grythm/geom"
+)
+
+// Grid templates build layouts of several families from the selected one,
+// so star and moiré patterns need no setup family by family. Ctrl+D
+// duplicates the selected family; the copy is selected, ready to be turned a
+// little for a moiré. Shift+D types a count N and adds copies so that N
+// families share the selected one's spacing and settings, turned evenly over
+// 180°. All of them are moved to have a line through the hovered point or
+// else the middle of the view, so the star is centered there. New families
+// are appended after the existing ones, and each template is one undo step.
+
+// maxStarFamilies bounds the families of a star.
+const maxStarFamilies = 24
+
+// duplicateGrid appends a copy of the selected family.
+func (g *Game) duplicateGrid() {
+	if g.selGrid >= len(g.Grids) {
+		return
+	}
+	g.exec(addGridsCmd{at: len(g.Grids), sel: g.selGrid, grids: []geom.GridFamily{g.Grids[g.selGrid].Clone()}})
+}
+
+// applyStar turns the selected family and n-1 copies of it evenly over 180°
+// and aligns them at origin, as one undo step.
+func (g *Game) applyStar(n int, origin geom.Vec2) error {
+	if g.selGrid >= len(g.Grids) {
+		return fmt.Errorf("no grid selected")
+	}
+	if n < 2 || n > maxStarFamilies {
+		return fmt.Errorf("between 2 and %d families", maxStarFamilies)
+	}
+	src := &g.Grids[g.selGrid]
+	base, ok := gridAngle(src)
+	if !ok {
+		return fmt.Errorf("radial grids have no angle")
+	}
+	center := g.Center()
+	first := src.Clone()
+	alignStarArm(&first, origin, center)
+	batch := batchCmd{gridEditCmd[geom.Phase]{idx: g.selGrid, from: src.Phase(), to: first.Phase(),
+		set: func(gf *geom.GridFamily, v geom.Phase) { gf.SetPhase(v) }}}
+	arms := make([]geom.GridFamily, 0, n-1)
+	for k := 1; k < n; k++ {
+		gf := src.Clone()
+		turnGrid(&gf, base+180*float64(k)/float64(n))
+		alignStarArm(&gf, origin, center)
+		arms = append(arms, gf)
+	}
+	batch = append(batch, addGridsCmd{at: len(g.Grids), sel: g.selGrid, grids: arms})
+	g.exec(batch)
+	return nil
+}
+
+// alignStarArm moves a family of a star to have a line through origin. Rays
+// keep their angle, as it is what sets them apart.
+func alignStarArm(gf *geom.GridFamily, origin, center geom.Vec2) {
+	if gf.Kind != geom.GridRay {
+		gf.AlignAt(origin, center)
+	}
+}
+
+// turnGrid sets the orientation of a linear, hex, wavy, curve or ray family to deg degrees.
+func turnGrid(gf *geom.GridFamily, deg float64) {
+	if gf.Kind == geom.GridRay {
+		gf.Angle = deg * math.Pi / 180
+	} else {
+		gf.Normal = angleDir(deg)
+	}
+}
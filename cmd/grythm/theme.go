@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Theme is the palette everything is drawn in. Grid families keep their own
+// colors, which are scene content, but new families take theirs from Grids,
+// and switching themes recolors the families that use a color of the old
+// palette with the color in the same slot of the new one.
+type Theme struct {
+	Name       string
+	Background color.RGBA
+	Grids      []color.RGBA // palette for grid families, in order
+	Point      color.RGBA   // point glyphs
+	PointHover color.RGBA   // the hovered point
+	PointDim   color.RGBA   // points of silenced groups
+	Cue        color.RGBA   // trigger ring; its alpha fades with the cue
+	Path       color.RGBA   // routes of moving points
+	Intersect  color.RGBA   // particles of intersection triggers
+	Text       color.RGBA   // HUD and labels
+	Panel      color.RGBA   // backdrop of the timeline and the tool palette
+	PanelOn    color.RGBA   // the active tool in the palette
+	Beat       color.RGBA   // beat lines in the timeline
+	Guide      color.RGBA   // brush outlines and previews
+	Cursor     color.RGBA   // gamepad edit cursor
+	Record     color.RGBA   // recording indicator
+}
+
+// Themes are the built-in themes; the first is the default.
+var Themes = []Theme{
+	{
+		Name:       "dark",
+		Background: color.RGBA{0x0D, 0x0D, 0x10, 0xFF},
+		Grids: []color.RGBA{
+			{0x66, 0x66, 0xFF, 0xFF}, {0x66, 0xFF, 0x66, 0xFF}, {0xFF, 0x88, 0x44, 0xFF}, {0xAA, 0x88, 0xFF, 0xFF},
+			{0xFF, 0xAA, 0x44, 0xFF}, {0x66, 0xFF, 0xAA, 0xFF}, {0xFF, 0x66, 0xCC, 0xFF}, {0x66, 0xCC, 0xFF, 0xFF},
+			{0xCC, 0xFF, 0x66, 0xFF}, {0x44, 0x55, 0x66, 0xFF},
+		},
+		Point:      color.RGBA{0xFF, 0xEE, 0xAA, 0xFF},
+		PointHover: color.RGBA{0xFF, 0xFF, 0x66, 0xFF},
+		PointDim:   color.RGBA{0x55, 0x50, 0x3A, 0xFF},
+		Cue:        color.RGBA{0xFF, 0xFF, 0x99, 0xC8},
+		Path:       color.RGBA{0x66, 0x60, 0x44, 0x80},
+		Intersect:  color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		Text:       color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		Panel:      color.RGBA{0x00, 0x00, 0x00, 0xB0},
+		PanelOn:    color.RGBA{0x55, 0x55, 0x88, 0xE0},
+		Beat:       color.RGBA{0x44, 0x44, 0x55, 0xFF},
+		Guide:      color.RGBA{0xCC, 0xCC, 0xFF, 0xB0},
+		Cursor:     color.RGBA{0x66, 0xDD, 0xFF, 0xFF},
+		Record:     color.RGBA{0xFF, 0x33, 0x33, 0xFF},
+	},
+	{
+		Name:       "light",
+		Background: color.RGBA{0xF4, 0xF1, 0xEA, 0xFF},
+		Grids: []color.RGBA{
+			{0x33, 0x44, 0xCC, 0xFF}, {0x22, 0x88, 0x33, 0xFF}, {0xD2, 0x60, 0x1E, 0xFF}, {0x77, 0x55, 0xCC, 0xFF},
+			{0xC9, 0x8A, 0x1E, 0xFF}, {0x1E, 0x9E, 0x6E, 0xFF}, {0xC2, 0x3A, 0x8E, 0xFF}, {0x2A, 0x88, 0xB8, 0xFF},
+			{0x7A, 0x9A, 0x1E, 0xFF}, {0x88, 0x99, 0xAA, 0xFF},
+		},
+		Point:      color.RGBA{0x33, 0x2A, 0x22, 0xFF},
+		PointHover: color.RGBA{0xCC, 0x33, 0x00, 0xFF},
+		PointDim:   color.RGBA{0xBB, 0xB3, 0xA6, 0xFF},
+		Cue:        color.RGBA{0xCC, 0x66, 0x00, 0xC8},
+		Path:       color.RGBA{0xAA, 0x99, 0x77, 0x80},
+		Intersect:  color.RGBA{0x22, 0x22, 0x22, 0xFF},
+		Text:       color.RGBA{0x22, 0x22, 0x2A, 0xFF},
+		Panel:      color.RGBA{0xFF, 0xFF, 0xFF, 0xC0},
+		PanelOn:    color.RGBA{0xBB, 0xBB, 0xEE, 0xE0},
+		Beat:       color.RGBA{0xCC, 0xC6, 0xBB, 0xFF},
+		Guide:      color.RGBA{0x44, 0x44, 0x99, 0xB0},
+		Cursor:     color.RGBA{0x00, 0x77, 0xCC, 0xFF},
+		Record:     color.RGBA{0xDD, 0x11, 0x11, 0xFF},
+	},
+	{
+		Name:       "neon",
+		Background: color.RGBA{0x05, 0x00, 0x10, 0xFF},
+		Grids: []color.RGBA{
+			{0x00, 0xF0, 0xFF, 0xFF}, {0x39, 0xFF, 0x14, 0xFF}, {0xFF, 0x31, 0x31, 0xFF}, {0xBC, 0x13, 0xFE, 0xFF},
+			{0xFF, 0xF0, 0x1F, 0xFF}, {0x00, 0xFF, 0xA3, 0xFF}, {0xFF, 0x10, 0xF0, 0xFF}, {0x1F, 0x51, 0xFF, 0xFF},
+			{0xCC, 0xFF, 0x00, 0xFF}, {0x5E, 0x6A, 0x7A, 0xFF},
+		},
+		Point:      color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		PointHover: color.RGBA{0xFF, 0xF0, 0x1F, 0xFF},
+		PointDim:   color.RGBA{0x44, 0x33, 0x55, 0xFF},
+		Cue:        color.RGBA{0xFF, 0x10, 0xF0, 0xC8},
+		Path:       color.RGBA{0x88, 0x22, 0xAA, 0x80},
+		Intersect:  color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		Text:       color.RGBA{0x00, 0xF0, 0xFF, 0xFF},
+		Panel:      color.RGBA{0x10, 0x00, 0x20, 0xC0},
+		PanelOn:    color.RGBA{0x66, 0x00, 0x99, 0xE0},
+		Beat:       color.RGBA{0x44, 0x11, 0x66, 0xFF},
+		Guide:      color.RGBA{0x00, 0xF0, 0xFF, 0xB0},
+		Cursor:     color.RGBA{0x39, 0xFF, 0x14, 0xFF},
+		Record:     color.RGBA{0xFF, 0x31, 0x31, 0xFF},
+	},
+	{
+		Name:       "monochrome",
+		Background: color.RGBA{0x00, 0x00, 0x00, 0xFF},
+		Grids: []color.RGBA{
+			{0xEE, 0xEE, 0xEE, 0xFF}, {0xBB, 0xBB, 0xBB, 0xFF}, {0x99, 0x99, 0x99, 0xFF}, {0xDD, 0xDD, 0xDD, 0xFF},
+			{0xAA, 0xAA, 0xAA, 0xFF}, {0xCC, 0xCC, 0xCC, 0xFF}, {0x88, 0x88, 0x88, 0xFF}, {0xE6, 0xE6, 0xE6, 0xFF},
+			{0xC4, 0xC4, 0xC4, 0xFF}, {0x66, 0x66, 0x66, 0xFF},
+		},
+		Point:      color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		PointHover: color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		PointDim:   color.RGBA{0x44, 0x44, 0x44, 0xFF},
+		Cue:        color.RGBA{0xFF, 0xFF, 0xFF, 0xC8},
+		Path:       color.RGBA{0x55, 0x55, 0x55, 0x80},
+		Intersect:  color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		Text:       color.RGBA{0xDD, 0xDD, 0xDD, 0xFF},
+		Panel:      color.RGBA{0x11, 0x11, 0x11, 0xC0},
+		PanelOn:    color.RGBA{0x55, 0x55, 0x55, 0xE0},
+		Beat:       color.RGBA{0x33, 0x33, 0x33, 0xFF},
+		Guide:      color.RGBA{0xAA, 0xAA, 0xAA, 0xB0},
+		Cursor:     color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		Record:     color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+	},
+}
+
+// ThemeNamed returns the built-in theme called name.
+func ThemeNamed(name string) (*Theme, error) {
+	for i := range Themes {
+		if Themes[i].Name == name {
+			return &Themes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown theme %q", name)
+}
+
+// nextTheme returns the built-in theme after t, wrapping around.
+func nextTheme(t *Theme) *Theme {
+	for i := range Themes {
+		if &Themes[i] == t {
+			return &Themes[(i+1)%len(Themes)]
+		}
+	}
+	return &Themes[0]
+}
+
+// GridColor returns palette slot i, cycling through the palette.
+func (t *Theme) GridColor(i int) color.RGBA {
+	return t.Grids[i%len(t.Grids)]
+}
+
+// slot returns the palette slot holding c, if any.
+func (t *Theme) slot(c color.Color) (int, bool) {
+	n := toRGBA(c)
+	for i, pc := range t.Grids {
+		if pc == n {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// setTheme switches to theme t, moving the grid families colored from the
+// current palette (or from, for a scene made in another theme) to the same
+// slots of the new one.
+func (g *Game) setTheme(t, from *Theme) {
+	for i := range g.Grids {
+		if k, ok := from.slot(g.Grids[i].Color); ok {
+			g.Grids[i].Color = t.Grids[k]
+		}
+	}
+	g.theme = t
+	// The trail image is refilled with the new background
+	if g.trail != nil {
+		g.trail.Dispose()
+		g.trail = nil
+	}
+}
+
+// printAt draws s at (x, y) in the theme's text color. The debug font is
+// white, so the text is printed onto a scratch layer that is drawn tinted.
+func (g *Game) printAt(dst *ebiten.Image, s string, x, y int) {
+	if g.textLayer != nil {
+		if b := g.textLayer.Bounds(); b.Dx() != g.W || b.Dy() != g.H {
+			g.textLayer.Dispose()
+			g.textLayer = nil
+		}
+	}
+	if g.textLayer == nil {
+		g.textLayer = ebiten.NewImage(g.W, g.H)
+	}
+	g.textLayer.Clear()
+	ebitenutil.DebugPrintAt(g.textLayer, s, x, y)
+	op := &ebiten.DrawImageOptions{}
+	op.ColorScale.ScaleWithColor(g.theme.Text)
+	dst.DrawImage(g.textLayer, op)
+}
+
+// alphaLabel shows a grid's opacity for the HUD.
+func alphaLabel(a float64) string {
+	if a == 0 {
+		a = 1
+	}
+	return fmt.Sprintf("%.0f%%", a*100)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"grythm/geom"
+)
+
+// Tiling mode. F7 (or -tiling) fills the cells the straight lines of the
+// first two or three grids cut the canvas into (see geom.TilingLines) with
+// faint translucent polygons, alternating in shade like a checkerboard. A
+// trigger lights the cell holding the point in the color of the grid that
+// crossed it, and the light fades, so the pattern reads as a reactive tiling
+// as well as lines.
+
+const (
+	cellSeconds = 0.8  // how long a lit cell takes to fade
+	cellBase    = 0.05 // opacity of the unlit cells, doubled on every other one
+	cellLit     = 0.5  // opacity a trigger lights a cell to
+)
+
+// litCell is a cell lit by a trigger.
+type litCell struct {
+	key   geom.CellKey
+	level float64 // 0-1, fading
+	col   color.RGBA
+}
+
+// lightCell lights the cell of the tiling that holds p in the color of grid
+// gi, brighter for a louder trigger.
+func (g *Game) lightCell(gi int, p geom.Vec2, velocity float64) {
+	if !g.tiling {
+		return
+	}
+	lines, _ := geom.TilingLines(g.Grids)
+	if len(lines) < 2 {
+		return
+	}
+	key := geom.CellAt(lines, p, g.Center())
+	level := 0.4 + 0.6*velocity
+	col := color.RGBAModel.Convert(g.Grids[gi].Color).(color.RGBA)
+	for i := range g.cells {
+		if g.cells[i].key == key {
+			g.cells[i].level = max(g.cells[i].level, level)
+			g.cells[i].col = col
+			return
+		}
+	}
+	g.cells = append(g.cells, litCell{key: key, level: level, col: col})
+}
+
+// updateCells fades the lit cells by dt seconds and drops dark ones.
+func (g *Game) updateCells(dt float64) {
+	live := g.cells[:0]
+	for _, c := range g.cells {
+		if c.level -= dt / cellSeconds; c.level > 0 {
+			live = append(live, c)
+		}
+	}
+	g.cells = live
+}
+
+// drawTiling fills the cells of the tiling under the grid lines.
+func (g *Game) drawTiling(dst *ebiten.Image) {
+	lines, owners := geom.TilingLines(g.Grids)
+	if len(lines) < 2 {
+		return
+	}
+	// Unlit cells take the mean color of the grids that make them
+	var r, gr, b int
+	for _, gi := range owners {
+		c := color.RGBAModel.Convert(g.Grids[gi].Color).(color.RGBA)
+		r, gr, b = r+int(c.R), gr+int(c.G), b+int(c.B)
+	}
+	n := len(owners)
+	base := color.RGBA{uint8(r / n), uint8(gr / n), uint8(b / n), 0xff}
+	geom.DrawTiling(dst, &g.cam, g.Center(), lines, func(k geom.CellKey) color.Color {
+		for _, c := range g.cells {
+			if c.key == k {
+				return fade(c.col, max(cellBase, cellLit*c.level))
+			}
+		}
+		if (k[0]+k[1]+k[2])&1 != 0 {
+			return fade(base, 2*cellBase)
+		}
+		return fade(base, cellBase)
+	})
+}
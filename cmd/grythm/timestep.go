@@ -0,0 +1,21 @@
+package main
+
+import "grythm/geom"
+
+// interpolatedGrids returns copies of the grids where they are at the current
+// time, between the last two ticks (see engine.Timestep), with their
+// flashes, ripples and the swell of their width animation.
+func (g *Game) interpolatedGrids() []geom.GridFamily {
+	back := (1 - g.timestep.Alpha()) * g.timestep.Step()
+	g.drawGrids = g.Rewind(g.drawGrids, back)
+	for i := range g.drawGrids {
+		if i < len(g.flashes) {
+			g.drawGrids[i].Flash = g.flashes[i]
+		}
+		if i < len(g.ripples) {
+			g.drawGrids[i].Ripples = g.ripples[i]
+		}
+		g.drawGrids[i].Swell = g.swell(i)
+	}
+	return g.drawGrids
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// defaultTrailFade is how much of the previous frame is covered each frame.
+const defaultTrailFade = 0.12
+
+// trailLayer returns the offscreen image that grids and particles are drawn
+// onto in trails mode, after fading what it held by the trail fade. Instead of
+// clearing it, a translucent background layer is drawn over the last frame so
+// moving lines and particles leave fading afterimages.
+func (g *Game) trailLayer() *ebiten.Image {
+	if g.trail != nil {
+		if b := g.trail.Bounds(); b.Dx() != g.W || b.Dy() != g.H {
+			g.trail.Dispose()
+			g.trail = nil
+		}
+	}
+	if g.trail == nil {
+		g.trail = ebiten.NewImage(g.W, g.H)
+		g.trail.Fill(g.theme.Background)
+	}
+	c := g.theme.Background
+	fade := color.NRGBA{c.R, c.G, c.B, uint8(255 * clamp01(g.trailFade))}
+	vector.DrawFilledRect(g.trail, 0, 0, float32(g.W), float32(g.H), fade, false)
+	return g.trail
+}
+
+// clamp01 limits v to [0, 1].
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
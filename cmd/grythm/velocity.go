@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+
+	"grythm/engine"
+)
+
+// velocityStep is how much a notch of the mouse wheel changes the velocity
+// of the hovered point.
+const velocityStep = 0.1
+
+// wheelVelocity changes the velocity of the hovered point (of every selected
+// point, when it is one of them) by wy notches of the wheel, as one undo
+// step. It reports whether a point was hovered, so the wheel doesn't zoom.
+func (g *Game) wheelVelocity(wy float64) bool {
+	i := g.hoverIdx
+	if i < 0 || g.dragIdx >= 0 {
+		return false
+	}
+	idx := []int{i}
+	if g.dragsSelection(i) {
+		idx = g.sel.points
+	}
+	var batch batchCmd
+	for _, j := range idx {
+		before := g.Points[j]
+		after := before
+		v := math.Max(engine.MinPointVelocity, math.Min(engine.MaxPointVelocity, before.VelocityScale()+wy*velocityStep))
+		if math.Abs(v-1) < 1e-9 {
+			// Back to where it started: store it as unset
+			v = 0
+		}
+		after.Velocity = v
+		if after.Velocity != before.Velocity {
+			batch = append(batch, editPointCmd{idx: j, before: before, after: after})
+		}
+	}
+	if len(batch) > 0 {
+		g.exec(batch)
+	}
+	return true
+}
+
+// glyphScale returns the factor a point is drawn larger or smaller by for
+// its velocity.
+func glyphScale(p engine.Point) float64 {
+	return 0.5 + 0.5*p.VelocityScale()
+}
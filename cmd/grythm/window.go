@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Window state. The size, position and monitor of the window and whether it
+// was fullscreen are written back to the config file when the game quits, so
+// the next run opens where this one closed. Only those keys are rewritten;
+// the rest of the file, comments included, is kept as it is.
+
+// windowState is where the window is, in window (not device) pixels.
+type windowState struct {
+	Width, Height int
+	X, Y          int // position on the monitor
+	Monitor       string
+	Fullscreen    bool
+}
+
+// trackWindow notes the window's state, to save it when the game quits.
+// Fullscreen leaves the window's own size and position alone.
+func (g *Game) trackWindow() {
+	w, h := ebiten.WindowSize()
+	if w <= 0 || h <= 0 {
+		// Not a desktop window
+		return
+	}
+	g.window.Width, g.window.Height = w, h
+	g.window.X, g.window.Y = ebiten.WindowPosition()
+	if m := ebiten.Monitor(); m != nil {
+		g.window.Monitor = m.Name()
+	}
+	g.window.Fullscreen = ebiten.IsFullscreen()
+	g.windowSeen = true
+}
+
+// placeWindow opens the window on the configured monitor and position.
+func placeWindow(cfg Config) {
+	if cfg.Monitor != "" {
+		found := false
+		for _, m := range ebiten.AppendMonitors(nil) {
+			if m.Name() == cfg.Monitor {
+				ebiten.SetMonitor(m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Printf("monitor %q not found, using the primary one", cfg.Monitor)
+		}
+	}
+	if x, y, ok, _ := parseWindowPosition(cfg.WindowPosition); ok {
+		ebiten.SetWindowPosition(x, y)
+	}
+}
+
+// parseWindowPosition reads an "x,y" window position; ok is false for "".
+func parseWindowPosition(s string) (x, y int, ok bool, err error) {
+	if s == "" {
+		return 0, 0, false, nil
+	}
+	xs, ys, found := strings.Cut(s, ",")
+	if found {
+		x, err = strconv.Atoi(strings.TrimSpace(xs))
+		if err == nil {
+			y, err = strconv.Atoi(strings.TrimSpace(ys))
+		}
+	}
+	if !found || err != nil {
+		return 0, 0, false, fmt.Errorf("window position %q is not x,y", s)
+	}
+	return x, y, true, nil
+}
+
+// saveWindowState writes ws to the config file at path, creating it if
+// needed. Keys already in the file are replaced where they are; new ones go
+// before the first table, where top-level keys belong.
+func saveWindowState(path string, ws windowState) error {
+	vals := []struct{ key, val string }{
+		{"width", strconv.Itoa(ws.Width)},
+		{"height", strconv.Itoa(ws.Height)},
+		{"window-position", strconv.Quote(fmt.Sprintf("%d,%d", ws.X, ws.Y))},
+		{"monitor", strconv.Quote(ws.Monitor)},
+		{"fullscreen", strconv.FormatBool(ws.Fullscreen)},
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("save window state: %w", err)
+	}
+	var lines []string
+	if text := strings.TrimRight(string(data), "\n"); text != "" {
+		lines = strings.Split(text, "\n")
+	}
+	done := make([]bool, len(vals))
+	tableAt := len(lines)
+	for i, l := range lines {
+		t := strings.TrimSpace(l)
+		if strings.HasPrefix(t, "[") {
+			tableAt = i
+			break
+		}
+		key, _, ok := strings.Cut(t, "=")
+		if !ok {
+			continue
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		for j, v := range vals {
+			if v.key == key {
+				lines[i] = v.key + " = " + v.val
+				done[j] = true
+			}
+		}
+	}
+	var add []string
+	for j, v := range vals {
+		if !done[j] {
+			add = append(add, v.key+" = "+v.val)
+		}
+	}
+	lines = append(lines[:tableAt], append(add, lines[tableAt:]...)...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("save window state: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("save window state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"grythm/geom"
+)
+
+// Arpeggiation. When one line crosses several points in the same tick their
+// notes would sound as a chord; with an arpeggio rate set they are spread out
+// instead, one every subdivision of the clock's beat in the chosen order,
+// starting when the first of them would sound. The crossings themselves (and
+// their cues) still happen at once; only TriggerEvent.At moves, so the host
+// schedules the notes ahead like quantized ones. Silenced points keep their
+// own time and don't take a step.
+
+// ArpDir is the order the notes of an arpeggio are played in.
+type ArpDir int
+
+const (
+	ArpUp     ArpDir = iota // lowest scale degree first
+	ArpDown                 // highest scale degree first
+	ArpAlong                // in the order the points lie along the line
+	ArpRandom               // shuffled with the trigger dice, so replays match
+)
+
+var arpDirNames = []string{"up", "down", "along", "random"}
+
+func (d ArpDir) String() string {
+	if d < 0 || int(d) >= len(arpDirNames) {
+		return "unknown"
+	}
+	return arpDirNames[d]
+}
+
+// Next returns the following direction, wrapping around.
+func (d ArpDir) Next() ArpDir {
+	return (d + 1) % ArpDir(len(arpDirNames))
+}
+
+// MarshalText stores a direction by name.
+func (d ArpDir) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a direction name.
+func (d *ArpDir) UnmarshalText(b []byte) error {
+	for i, n := range arpDirNames {
+		if n == string(b) {
+			*d = ArpDir(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown arpeggio direction %q", b)
+}
+
+// Arpeggio spreads the notes of the points a line crosses at once.
+type Arpeggio struct {
+	Rate int    `json:"rate"`          // note value between the notes, e.g. 16; 0 plays chords
+	Dir  ArpDir `json:"dir,omitempty"` // order of the notes
+}
+
+// ArpRates are the note values an editor cycles through; 0 is off.
+var ArpRates = []int{0, 8, 16, 32}
+
+// NextArpRate returns the rate after r.
+func NextArpRate(r int) int {
+	for i, s := range ArpRates {
+		if s == r {
+			return ArpRates[(i+1)%len(ArpRates)]
+		}
+	}
+	return 0
+}
+
+// Validate reports an arpeggio the engine can't play.
+func (a Arpeggio) Validate() error {
+	if a.Rate < 0 {
+		return fmt.Errorf("arpeggio note value must not be negative")
+	}
+	if a.Dir < 0 || int(a.Dir) >= len(arpDirNames) {
+		return fmt.Errorf("unknown arpeggio direction")
+	}
+	return nil
+}
+
+// step returns the seconds between the notes of an arpeggio, or 0 when off.
+func (a Arpeggio) step(bpm float64) float64 {
+	if a.Rate <= 0 || bpm <= 0 {
+		return 0
+	}
+	return 60 / bpm * 4 / float64(a.Rate)
+}
+
+// arpKey identifies the line a trigger came from.
+type arpKey struct {
+	grid int
+	line geom.LineKey
+}
+
+// flushTriggers publishes the triggers of the tick, spreading those that
+// share a line into arpeggios.
+func (e *Engine) flushTriggers(center geom.Vec2) {
+	pending := e.pending
+	e.pending = e.pending[:0]
+	if len(e.Bus.triggers) == 0 {
+		return
+	}
+	if step := e.Arp.step(e.Clock.BPM); step > 0 {
+		e.arpeggiate(pending, center, step)
+	}
+	for _, t := range pending {
+		e.Bus.PublishTrigger(t)
+	}
+}
+
+// arpeggiate moves the sounding times of the audible triggers that share a
+// line step seconds apart.
+func (e *Engine) arpeggiate(ts []TriggerEvent, center geom.Vec2, step float64) {
+	groups := map[arpKey][]int{}
+	var order []arpKey
+	for i, t := range ts {
+		if !t.Audible {
+			continue
+		}
+		gf := &e.Grids[t.Grid]
+		line, ok := gf.LineAt(e.Points[t.Point].Pos, center)
+		if !ok {
+			continue
+		}
+		k := arpKey{t.Grid, line}
+		if _, seen := groups[k]; !seen {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+	// Groups are visited in the order they were found, so the dice are rolled alike on replay
+	for _, k := range order {
+		idx := groups[k]
+		if len(idx) < 2 {
+			continue
+		}
+		start := ts[idx[0]].At
+		for _, i := range idx[1:] {
+			start = min(start, ts[i].At)
+		}
+		e.arpOrder(ts, idx, center)
+		for n, i := range idx {
+			ts[i].At = start + float64(n)*step
+		}
+	}
+}
+
+// arpOrder sorts the trigger indices of one line into the arpeggio's order.
+func (e *Engine) arpOrder(ts []TriggerEvent, idx []int, center geom.Vec2) {
+	degree := func(i int) int { return e.Points[ts[i].Point].Degree }
+	switch e.Arp.Dir {
+	case ArpUp:
+		slices.SortStableFunc(idx, func(a, b int) int { return cmp.Compare(degree(a), degree(b)) })
+	case ArpDown:
+		slices.SortStableFunc(idx, func(a, b int) int { return cmp.Compare(degree(b), degree(a)) })
+	case ArpAlong:
+		gf := &e.Grids[ts[idx[0]].Grid]
+		along := func(i int) float64 { return gf.Along(e.Points[ts[i].Point].Pos, center) }
+		slices.SortStableFunc(idx, func(a, b int) int { return cmp.Compare(along(a), along(b)) })
+	case ArpRandom:
+		e.rng.Shuffle(len(idx), func(a, b int) { idx[a], idx[b] = idx[b], idx[a] })
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import "grythm/geom"
+
+// Event bus. Crossing detection publishes what sounds as events on the
+// engine's Bus and knows nothing of what becomes of them: sound, MIDI, OSC,
+// the visual feedback and logging each subscribe on their own, so an output
+// is added without touching the detection. Events are delivered during Tick,
+// to the subscribers in the order they subscribed.
+
+// TriggerEvent is a line of grid family Grid crossing point Point.
+type TriggerEvent struct {
+	Grid, Point int
+	Pos         geom.Vec2 // where the point was
+	Note        int       // MIDI note of the point's degree transposed by the grid's
+	Velocity    float64   // 0..1, from how fast the line swept across the point, times the grid's volume
+	Time        float64   // simulation time of the crossing, within the last tick
+	At          float64   // simulation time it sounds: Time, or later when quantized or arpeggiated
+	Audible     bool      // false when the point's group is silenced
+	Gap         bool      // the point came into a gap of a dashed line, not a dash (see gap.go)
+}
+
+// IntersectionEvent is a crossing of the lines of grid families A and B
+// over Point, or the lines of two parallel families coinciding (Point is -1).
+type IntersectionEvent struct {
+	A, B, Point int
+	Pos         geom.Vec2
+	Velocity    float64
+	Time        float64
+	At          float64 // when it sounds, as for TriggerEvent
+}
+
+// BeatEvent is the clock passing a beat (see BeatPosition).
+type BeatEvent struct {
+	Beat     int     // beats since the start, from 0
+	Downbeat bool    // the first beat of a bar
+	Time     float64 // simulation time of the beat, within the last tick
+}
+
+// Bus delivers the engine's events to its subscribers.
+type Bus struct {
+	triggers      []func(TriggerEvent)
+	intersections []func(IntersectionEvent)
+	beats         []func(BeatEvent)
+}
+
+// OnTrigger subscribes f to the triggers.
+func (b *Bus) OnTrigger(f func(TriggerEvent)) {
+	b.triggers = append(b.triggers, f)
+}
+
+// OnIntersection subscribes f to the intersections.
+func (b *Bus) OnIntersection(f func(IntersectionEvent)) {
+	b.intersections = append(b.intersections, f)
+}
+
+// OnBeat subscribes f to the beats.
+func (b *Bus) OnBeat(f func(BeatEvent)) {
+	b.beats = append(b.beats, f)
+}
+
+// PublishTrigger delivers ev to every trigger subscriber.
+func (b *Bus) PublishTrigger(ev TriggerEvent) {
+	for _, f := range b.triggers {
+		f(ev)
+	}
+}
+
+// PublishIntersection delivers ev to every intersection subscriber.
+func (b *Bus) PublishIntersection(ev IntersectionEvent) {
+	for _, f := range b.intersections {
+		f(ev)
+	}
+}
+
+// PublishBeat delivers ev to every beat subscriber.
+func (b *Bus) PublishBeat(ev BeatEvent) {
+	for _, f := range b.beats {
+		f(ev)
+	}
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Trigger probability. Points and grid families carry a Chance in (0, 1]: a
+// crossing only triggers when a roll of the engine's RNG passes the chance of
+// both the point and the grid. 0 is the zero value and means always, like 1.
+
+// EffectiveChance maps the zero value to 1.
+func EffectiveChance(c float64) float64 {
+	if c <= 0 {
+		return 1
+	}
+	return c
+}
+
+// ValidateChance checks a stored chance.
+func ValidateChance(c float64) error {
+	if c < 0 || c > 1 {
+		return fmt.Errorf("chance %g out of range 0-1", c)
+	}
+	return nil
+}
+
+// newRNG returns the trigger RNG for seed; 0 picks a seed from the clock.
+// The seed in use is returned so that a session can be reproduced.
+func newRNG(seed int64) (*rand.Rand, int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed)), seed
+}
+
+// fires rolls against the product of the given chances. The RNG is only
+// consulted when a roll is needed, so scenes without probabilities leave its
+// sequence untouched.
+func (e *Engine) fires(chances ...float64) bool {
+	p := 1.0
+	for _, c := range chances {
+		p *= EffectiveChance(c)
+	}
+	if p >= 1 {
+		return true
+	}
+	return e.rng.Float64() < p
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+// Retrigger guard. A slow line lingering at the edge of its band, or the end
+// of a dash fluttering back and forth over a point, can trigger the point
+// again and again within a few milliseconds. A point's Cooldown is the least
+// time between its triggers by any family, and a family's Cooldown the least
+// time between its triggers of the same point; a crossing that comes sooner
+// is dropped without a sound or a cue, and doesn't roll the dice either.
+// Only triggers count: a crossing the dice drop (see chance.go) starts no
+// cooldown. Both are in milliseconds, 0 meaning no guard.
+
+// MaxCooldown bounds a cooldown, in milliseconds.
+const MaxCooldown = 2000.0
+
+// ValidateCooldown checks a stored cooldown.
+func ValidateCooldown(ms float64) error {
+	if ms < 0 || ms > MaxCooldown {
+		return fmt.Errorf("cooldown %g out of range 0-%g ms", ms, MaxCooldown)
+	}
+	return nil
+}
+
+// newHitRow returns the last trigger times of n points that were never
+// triggered.
+func newHitRow(n int) []float64 {
+	row := make([]float64, n)
+	for i := range row {
+		row[i] = math.Inf(-1)
+	}
+	return row
+}
+
+// cooling reports whether a crossing of point pi by family gi at time at
+// comes too close to another trigger. Crossings within a tick are found
+// family by family, so the other may be later in the tick.
+func (e *Engine) cooling(gi, pi int, at float64) bool {
+	if math.Abs(at-e.lastHit[gi][pi]) < e.Grids[gi].Cooldown/1000 {
+		return true
+	}
+	if wait := e.Points[pi].Cooldown / 1000; wait > 0 {
+		for _, row := range e.lastHit {
+			if math.Abs(at-row[pi]) < wait {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"math"
+
+	"grythm/geom"
+)
+
+// Trigger edges. A family triggers a point as it enters the thickness band of
+// a line by default; its Edge can make that the moment the point leaves the
+// band, both moments, or the moment the middle of the line passes it. The
+// last is told by the sign of the point's distance from the middle (see
+// geom.GridFamily.SignedDistance), which is kept from tick to tick while the
+// point is in the band.
+
+// contact is what a family's lines were to a point at the last tick.
+type contact struct {
+	inside bool    // the point was in the thickness band of a line
+	gap    bool    // it was in the band, but in a gap between dashes (see gap.go)
+	dist   float64 // its signed distance from the middle of that line; only kept for EdgeCenter families
+}
+
+// crossTest reports whether a point at p is past the moment a crossing is
+// looked for, with gf where the family was then.
+type crossTest func(gf *geom.GridFamily, p geom.Vec2) bool
+
+// entered handles point pi, found in the band of family gi this tick after
+// being as was at the last, and returns its contact now.
+func (e *Engine) entered(gi, pi int, was contact, center geom.Vec2, dt float64) contact {
+	gf := &e.Grids[gi]
+	now := contact{inside: true}
+	switch {
+	case gf.Edge.Entering() && !was.inside:
+		e.cross(gi, pi, center, dt, func(gf *geom.GridFamily, p geom.Vec2) bool {
+			return gf.Touches(p, center)
+		})
+	case gf.Edge == geom.EdgeCenter:
+		now.dist = gf.SignedDistance(e.Points[pi].Pos, center)
+		before := was.dist
+		if !was.inside {
+			// It came into the band this tick, perhaps past the middle already
+			before = e.distBefore(gi, pi, center, dt)
+		}
+		if crossedCenter(before, now.dist) {
+			e.cross(gi, pi, center, dt, sideOf(now.dist, center))
+		}
+	}
+	return now
+}
+
+// exits handles the points in the band of family gi at the last tick, as
+// row has them, that have left it.
+func (e *Engine) exits(gi int, row []contact, center geom.Vec2, dt float64) {
+	gf := &e.Grids[gi]
+	leave := func(pi int) {
+		was := row[pi]
+		if !was.inside || gf.Touches(e.Points[pi].Pos, center) {
+			return
+		}
+		switch {
+		case gf.Edge.Leaving():
+			e.cross(gi, pi, center, dt, func(gf *geom.GridFamily, p geom.Vec2) bool {
+				return !gf.Touches(p, center)
+			})
+		case gf.Edge == geom.EdgeCenter:
+			// It went through the rest of the band in one tick
+			if d := gf.SignedDistance(e.Points[pi].Pos, center); crossedCenter(was.dist, d) {
+				e.cross(gi, pi, center, dt, sideOf(d, center))
+			}
+		}
+	}
+	if prev, ok := e.Index.insideOf(gi); ok {
+		for _, pi := range prev {
+			leave(pi)
+		}
+		return
+	}
+	for pi := range row {
+		leave(pi)
+	}
+}
+
+// crossedCenter reports whether a point went from signed distance before to
+// after past the middle of a line.
+func crossedCenter(before, after float64) bool {
+	return (before < 0) != (after < 0)
+}
+
+// sideOf returns a test of whether a point is on the side of the middle of
+// its nearest line that signed distance d is.
+func sideOf(d float64, center geom.Vec2) crossTest {
+	return func(gf *geom.GridFamily, p geom.Vec2) bool {
+		return (gf.SignedDistance(p, center) < 0) == (d < 0)
+	}
+}
+
+// distBefore returns the signed distance of point pi from the middle of the
+// nearest line of family gi at the start of the last tick, of length dt.
+func (e *Engine) distBefore(gi, pi int, center geom.Vec2, dt float64) float64 {
+	gf := e.Grids[gi]
+	e.rewind(&gf, gi, dt)
+	return gf.SignedDistance(e.pointBefore(pi, dt), center)
+}
+
+// cross triggers point pi, if it listens, by a line of family gi at the
+// moment of the last tick that test became true.
+func (e *Engine) cross(gi, pi int, center geom.Vec2, dt float64, test crossTest) {
+	if velocity, at, ok := e.crossing(gi, pi, center, dt, test); ok {
+		e.trigger(gi, pi, velocity, at, false)
+	}
+}
+
+// crossing returns how hard and when a line of family gi crossed point pi in
+// the last tick, at the moment test became true, or false if the point
+// doesn't listen to the family or is held.
+func (e *Engine) crossing(gi, pi int, center geom.Vec2, dt float64, test crossTest) (velocity, at float64, ok bool) {
+	p := e.Points[pi]
+	if e.held(pi) || !p.Listens(gi) {
+		return 0, 0, false
+	}
+	gf := &e.Grids[gi]
+	at = e.Time - e.crossingTime(gi, pi, dt, test)
+	rel := e.GridVelocity(gi).Sub(e.PointVelocity(pi))
+	speed := gf.CrossingSpeed(p.Pos, center, rel)
+	if gf.Kind == geom.GridRay {
+		// Rays sweep at their own speed, which the layer scales too
+		speed *= math.Abs(e.layerRate(gi))
+	}
+	return velocityFromSpeed(speed) * gf.AccentGain(p.Pos, center), at, true
+}
@@ -0,0 +1,358 @@
+// Package engine runs the grid-rhythm simulation: families of grid lines move
+// across a set of points, and every time a line reaches a point the engine
+// reports a trigger. Sound, drawing and input are left to the host, so the
+// engine can be embedded in any Ebiten game; cmd/grythm is the reference host.
+package engine
+
+import (
+	"math/rand"
+	"time"
+
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Engine holds the simulated scene. Fields may be changed between ticks;
+// after replacing Grids or Points wholesale call ResetPointState, and after
+// moving points call Index.Invalidate.
+type Engine struct {
+	W, H int // canvas size; its center is the anchor of linear families
+
+	Grids   []geom.GridFamily
+	Points  []Point
+	MoveDir geom.Vec2 // direction of the moving tiled pattern
+	Speed   float64   // pixels per second magnitude
+
+	// pitch: points and grids address pitches as degrees of scale in key
+	Scale    synth.Scale
+	Key      int      // MIDI note of scale degree 0
+	PitchMap PitchMap // optional degree-from-position mapping
+
+	// tempo mode: speed is derived from the clock's BPM instead of set directly
+	TempoMode bool
+	Clock     Clock
+	// note value triggers are held back to, e.g. 16 for sixteenths; 0 sounds
+	// them as they happen (see quantize.go)
+	Quantize int
+	// percent of a pair of subdivisions the second one starts at; 0 (or 50)
+	// plays them straight (see swing.go)
+	Swing float64
+	// spreading of the points one line crosses at once (see arp.go)
+	Arp Arpeggio
+
+	// intersection triggers between pairs of linear families
+	IntersectMode IntersectMode
+
+	// loop mode: the pattern jumps back to where it was every Loop length (see loop.go)
+	Loop Loop
+
+	// point groups: mute/solo switches by name
+	Groups map[string]GroupState
+	// grid layers: transforms shared by the families in them (see layer.go)
+	Layers [MaxLayers]Layer
+
+	// visual cues per point (1.0 just triggered -> 0.0 faded)
+	Cues []float64
+
+	// trigger counts and intervals (see stats.go)
+	Stats Stats
+
+	// simulation time in seconds
+	Time float64
+
+	// how long the parts of the last tick took, for profiling
+	Profile TickProfile
+
+	// Held is a point being moved by the host, or -1; its mirror copies
+	// (see Point.Mirror) are held with it. A held point only tracks its
+	// state so that sweeping it through lines (or dropping it on one) doesn't
+	// fire a burst of triggers.
+	Held int
+	// View is the world position the host looks at; aligned lines are
+	// reported there.
+	View geom.Vec2
+
+	// Bus carries the triggers to whoever subscribed (see bus.go).
+	Bus Bus
+
+	// Seed reproduces the trigger dice (see chance.go).
+	Seed int64
+	rng  *rand.Rand
+
+	Index SpatialIndex
+
+	lastContact [][]contact // [gridIdx][pointIdx] whether point was inside thickness band last tick, and where (see edge.go)
+	lastHit     [][]float64 // [gridIdx][pointIdx] simulation time the grid last triggered the point (see cooldown.go)
+	lastCross   [][]bool    // [pairIdx][pointIdx] whether point was on the pair's crossing last tick
+	lastAlign   []bool      // [pairIdx] whether the pair's lines coincided last tick
+
+	loopStart []geom.Phase // [gridIdx] pattern at the start of the loop; nil when not looping
+	loopPaths []float64    // [pointIdx] path phases at the start of the loop
+	loopPos   float64      // pixels travelled since the start of the loop
+
+	pending []TriggerEvent // triggers of the tick, published once all grids are done
+}
+
+// New creates an engine for a w x h canvas with no grids or points. The
+// trigger dice are seeded with seed; 0 picks a seed, which is kept in Seed.
+func New(w, h int, seed int64) *Engine {
+	e := &Engine{
+		W: w, H: h,
+		MoveDir:  geom.Vec2{X: 1, Y: 0.3}.Norm(),
+		Speed:    120, // px/sec
+		Clock:    Clock{BPM: 120, BeatsPerBar: 4, BeatPixels: 60},
+		Scale:    synth.Scales[0],
+		Key:      81, // A5, the original 880Hz blip
+		PitchMap: DefaultPitchMap,
+		Loop:     DefaultLoop,
+		Groups:   make(map[string]GroupState),
+		Held:     -1,
+	}
+	e.rng, e.Seed = newRNG(seed)
+	e.ResetPointState()
+	return e
+}
+
+// Center returns the world anchor of linear families, the middle of the canvas.
+func (e *Engine) Center() geom.Vec2 {
+	return geom.Vec2{X: float64(e.W) / 2, Y: float64(e.H) / 2}
+}
+
+// TickProfile is how long the parts of a tick took.
+type TickProfile struct {
+	Motion  time.Duration // moving the pattern and the points
+	Detect  time.Duration // finding the points lines and crossings touch
+	Publish time.Duration // the subscribers handling the triggers and beats
+}
+
+// Tick advances the simulation by dt seconds: the pattern moves and points
+// it crosses are triggered.
+func (e *Engine) Tick(dt float64) {
+	start := time.Now()
+	beats := e.BeatPosition()
+	e.Time += dt
+	if e.TempoMode {
+		e.Clock.Advance(dt)
+	}
+	e.publishBeats(beats, dt)
+	beaten := time.Now()
+
+	// Advance offsets based on projection of movement onto grid normals
+	for i := range e.Grids {
+		e.advance(&e.Grids[i], i, dt)
+	}
+	e.movePoints(dt)
+	e.agePoints(dt)
+	e.updateLoop(dt)
+	moved := time.Now()
+
+	// Touch detection. Only points near a family's lines are tested (see
+	// SpatialIndex); everything else is outside.
+	center := e.Center()
+	var inside []int
+	var now []contact
+	for gi := range e.Grids {
+		gf := &e.Grids[gi]
+		row := e.lastContact[gi]
+		inside, now = inside[:0], now[:0]
+		// inside collects the points in the band of a line, on a dash or,
+		// for families sounding their gaps, in a gap
+		e.Index.candidates(gf, e.Points, center, func(pi int) {
+			switch p := e.Points[pi].Pos; {
+			case gf.Touches(p, center):
+				inside = append(inside, pi)
+				now = append(now, e.entered(gi, pi, row[pi], center, dt))
+			case gf.GapTrigger && gf.InGap(p, center):
+				inside = append(inside, pi)
+				now = append(now, e.enteredGap(gi, pi, row[pi], center, dt))
+			}
+		})
+		if gf.Edge != geom.EdgeEnter {
+			e.exits(gi, row, center, dt)
+		}
+		if prev, ok := e.Index.insideOf(gi); ok {
+			for _, pi := range prev {
+				row[pi] = contact{}
+			}
+		} else {
+			for pi := range row {
+				row[pi] = contact{}
+			}
+		}
+		for k, pi := range inside {
+			row[pi] = now[k]
+		}
+		e.Index.setInside(gi, inside, len(e.Grids))
+	}
+	detected := time.Now()
+	e.flushTriggers(center)
+	flushed := time.Now()
+
+	if e.IntersectMode != IntersectOff {
+		e.updateIntersections(center)
+	}
+
+	// Decay visual cue timers
+	decay := 0.4 // seconds to fade out
+	for i := range e.Cues {
+		if e.Cues[i] > 0 {
+			e.Cues[i] -= dt / decay
+			if e.Cues[i] < 0 {
+				e.Cues[i] = 0
+			}
+		}
+	}
+	// Intersections are published as they are found, so their subscribers
+	// count as detection
+	e.Profile = TickProfile{
+		Motion:  moved.Sub(beaten),
+		Detect:  detected.Sub(moved) + time.Since(flushed),
+		Publish: beaten.Sub(start) + flushed.Sub(detected),
+	}
+}
+
+// trigger rolls the dice for point pi being crossed by a line of grid gi at
+// time at, or coming into a gap of it, and queues the crossing to be
+// published.
+func (e *Engine) trigger(gi, pi int, velocity, at float64, gap bool) {
+	p := e.Points[pi]
+	if p.Life.Expired() || e.cooling(gi, pi, at) || !e.fires(p.Chance, e.Grids[gi].Chance) {
+		return
+	}
+	e.lastHit[gi][pi] = at
+	e.Points[pi].Life.Hits++
+	// Silenced groups keep their visual cue so the pattern stays readable
+	e.Cues[pi] = 1.0
+	e.Stats.record(gi, pi, at)
+	e.pending = append(e.pending, TriggerEvent{
+		Grid: gi, Point: pi, Pos: p.Pos, Note: e.Scale.Note(e.Key, p.Degree+e.Grids[gi].Degree),
+		Velocity: velocity * e.Grids[gi].Level() * p.VelocityScale(), Time: at, At: e.soundTime(at),
+		Audible: e.Audible(p.Group) && e.GridAudible(gi), Gap: gap,
+	})
+}
+
+// GridVelocity returns the velocity (pixels per second) of grid family i: its
+// own motion if it has one, otherwise the global motion, scaled by its rate
+// and its layer's.
+func (e *Engine) GridVelocity(i int) geom.Vec2 {
+	gf := &e.Grids[i]
+	rate := gf.MotionRate() * e.layerRate(i)
+	if m := gf.Motion; m != nil {
+		return m.Dir.Mul(m.Speed * rate)
+	}
+	return e.MoveDir.Mul(e.Speed * rate)
+}
+
+// Rewind returns copies of the grids (reusing dst) moved back by the given
+// number of seconds. Motion is linear within a tick, so rewinding part of the
+// last tick gives the pattern between ticks, e.g. for drawing.
+func (e *Engine) Rewind(dst []geom.GridFamily, seconds float64) []geom.GridFamily {
+	dst = append(dst[:0], e.Grids...)
+	for i := range dst {
+		e.rewind(&dst[i], i, seconds)
+	}
+	return dst
+}
+
+// advance moves gf, grid family i or a copy of it, on by the given number of seconds.
+func (e *Engine) advance(gf *geom.GridFamily, i int, seconds float64) {
+	gf.Advance(e.GridVelocity(i).Mul(seconds))
+	if gf.Kind == geom.GridRay {
+		gf.Rotate(seconds * e.layerRate(i))
+	}
+}
+
+// rewind moves gf, a copy of grid family i, back by the given number of seconds.
+func (e *Engine) rewind(gf *geom.GridFamily, i int, seconds float64) {
+	e.advance(gf, i, -seconds)
+}
+
+// crossingBisections is the number of halvings used to locate a crossing
+// within a tick; 8 resolves a 120Hz tick to about 30µs.
+const crossingBisections = 8
+
+// crossingTime returns how many seconds before the end of the last tick (of
+// length dt) a line of family gi crossed point pi, test telling whether it
+// had. The family is rewound on a copy, and a point on a path along it, and
+// the moment test became true (the point entered the line, left it or passed
+// its middle, see edge.go) is found by bisection, so every kind of geometry
+// is handled alike. A point for which test was already true at the start of
+// the tick (e.g. just placed) reports dt.
+func (e *Engine) crossingTime(gi, pi int, dt float64, test crossTest) float64 {
+	at := func(back float64) bool {
+		gf := e.Grids[gi]
+		e.rewind(&gf, gi, back)
+		return test(&gf, e.pointBefore(pi, back))
+	}
+	// test is always true at lo and false at hi
+	lo, hi := 0.0, dt
+	if at(hi) {
+		return dt
+	}
+	for i := 0; i < crossingBisections; i++ {
+		mid := (lo + hi) / 2
+		if at(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// ResetPointState rebuilds the per-point state after grids or points were
+// replaced wholesale, and puts points with a path on it. A running loop
+// starts again and the trigger statistics start over.
+func (e *Engine) ResetPointState() {
+	e.movePoints(0)
+	e.lastContact = make([][]contact, len(e.Grids))
+	for i := range e.lastContact {
+		e.lastContact[i] = make([]contact, len(e.Points))
+	}
+	e.lastHit = make([][]float64, len(e.Grids))
+	for i := range e.lastHit {
+		e.lastHit[i] = newHitRow(len(e.Points))
+	}
+	e.Cues = make([]float64, len(e.Points))
+	e.resetPairState()
+	e.Index.Invalidate()
+	e.loopStart = nil
+	e.ResetStats()
+}
+
+// AppendGrid adds a grid family, giving it fresh trigger state without
+// disturbing that of the existing families.
+func (e *Engine) AppendGrid(gf geom.GridFamily) {
+	e.Grids = append(e.Grids, gf)
+	e.lastContact = append(e.lastContact, make([]contact, len(e.Points)))
+	e.lastHit = append(e.lastHit, newHitRow(len(e.Points)))
+	if e.loopStart != nil && len(e.loopStart) == len(e.Grids)-1 {
+		e.loopStart = append(e.loopStart, gf.Phase())
+	}
+	e.resetPairState()
+	e.Index.Invalidate()
+}
+
+// TruncateGrids removes the families from index n on with their trigger
+// state, undoing AppendGrid.
+func (e *Engine) TruncateGrids(n int) {
+	e.Grids = e.Grids[:n]
+	e.lastContact = e.lastContact[:n]
+	e.lastHit = e.lastHit[:n]
+	if len(e.loopStart) > n {
+		e.loopStart = e.loopStart[:n]
+	}
+	e.Stats.truncateGrids(n)
+	e.resetPairState()
+	e.Index.Invalidate()
+}
+
+// resetPairState clears the intersection state of every pair of families.
+func (e *Engine) resetPairState() {
+	pairs := gridPairs(len(e.Grids))
+	e.lastCross = make([][]bool, pairs)
+	for i := range e.lastCross {
+		e.lastCross[i] = make([]bool, len(e.Points))
+	}
+	e.lastAlign = make([]bool, pairs)
+}
@@ -0,0 +1,27 @@
+package engine
+
+import "grythm/geom"
+
+// Gap sounds. A dashed family with GapTrigger set also triggers the points
+// that come into its gaps, at GapLevel of the velocity a dash would have;
+// such triggers are marked Gap, and the host gives them a voice of their
+// own, so one dashed line plays two interleaved parts. A point in a gap
+// sounds as it comes into the band there or as a gap reaches it along the
+// line, whatever the family's edge.
+
+// GapLevel is the velocity of a gap trigger relative to a dash's.
+const GapLevel = 0.5
+
+// enteredGap handles point pi, found in a gap of family gi this tick after
+// being as was at the last, and returns its contact now.
+func (e *Engine) enteredGap(gi, pi int, was contact, center geom.Vec2, dt float64) contact {
+	if !was.gap {
+		velocity, at, ok := e.crossing(gi, pi, center, dt, func(gf *geom.GridFamily, p geom.Vec2) bool {
+			return gf.InGap(p, center)
+		})
+		if ok {
+			e.trigger(gi, pi, velocity*GapLevel, at, true)
+		}
+	}
+	return contact{gap: true}
+}
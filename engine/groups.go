@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"sort"
+
+	"grythm/geom"
+)
+
+// GroupState holds the mixing switches of a named point group.
+type GroupState struct {
+	Mute bool `json:"mute,omitempty"`
+	Solo bool `json:"solo,omitempty"`
+}
+
+// defaultGroups are offered when cycling a point's group (see NextGroup), in
+// addition to any names already used by the scene.
+var defaultGroups = []string{"A", "B", "C", "D"}
+
+// groupNames lists the groups to cycle through: ungrouped ("") first, then the
+// default and used names in sorted order.
+func (e *Engine) groupNames() []string {
+	seen := map[string]bool{"": true}
+	var names []string
+	add := func(n string) {
+		if !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
+	}
+	for _, n := range defaultGroups {
+		add(n)
+	}
+	for _, p := range e.Points {
+		add(p.Group)
+	}
+	for n := range e.Groups {
+		add(n)
+	}
+	sort.Strings(names)
+	return append([]string{""}, names...)
+}
+
+// NextGroup returns the group following cur in groupNames (wrapping around).
+func (e *Engine) NextGroup(cur string) string {
+	names := e.groupNames()
+	for i, n := range names {
+		if n == cur {
+			return names[(i+1)%len(names)]
+		}
+	}
+	return names[0]
+}
+
+// Audible reports whether points of group name should sound. While any group is
+// soloed only soloed groups play; otherwise all but muted groups do. Ungrouped
+// points cannot be muted but are silenced by a solo.
+func (e *Engine) Audible(name string) bool {
+	for _, st := range e.Groups {
+		if st.Solo {
+			return e.Groups[name].Solo && name != ""
+		}
+	}
+	return name == "" || !e.Groups[name].Mute
+}
+
+// GridAudible reports whether the notes of grid family gi should sound: while
+// any family is soloed only soloed ones do, otherwise all but muted ones.
+func (e *Engine) GridAudible(gi int) bool {
+	return e.Grids[gi].Sounds(geom.AnySolo(e.Grids))
+}
+
+// ToggleMute flips the mute switch of a group; the ungrouped points have none.
+func (e *Engine) ToggleMute(name string) {
+	if name == "" {
+		return
+	}
+	st := e.Groups[name]
+	st.Mute = !st.Mute
+	e.SetGroup(name, st)
+}
+
+// ToggleSolo flips the solo switch of a group.
+func (e *Engine) ToggleSolo(name string) {
+	if name == "" {
+		return
+	}
+	st := e.Groups[name]
+	st.Solo = !st.Solo
+	e.SetGroup(name, st)
+}
+
+// SetGroup stores st for name, dropping groups with no switches set.
+func (e *Engine) SetGroup(name string, st GroupState) {
+	if st == (GroupState{}) {
+		delete(e.Groups, name)
+		return
+	}
+	e.Groups[name] = st
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+
+	"grythm/geom"
+)
+
+// IntersectMode selects whether crossings of two linear families trigger
+// sounds of their own, in addition to the regular line triggers.
+type IntersectMode int
+
+const (
+	// IntersectOff disables intersection triggers.
+	IntersectOff IntersectMode = iota
+	// IntersectPoints fires when an intersection of two families' lines passes over a point.
+	IntersectPoints
+	// IntersectAlign fires when the lines of two parallel families coincide.
+	IntersectAlign
+)
+
+var intersectModeNames = []string{"off", "points", "align"}
+
+func (m IntersectMode) String() string {
+	if m < 0 || int(m) >= len(intersectModeNames) {
+		return "unknown"
+	}
+	return intersectModeNames[m]
+}
+
+// Next returns the following mode, wrapping around.
+func (m IntersectMode) Next() IntersectMode {
+	return (m + 1) % IntersectMode(len(intersectModeNames))
+}
+
+// ParseIntersectMode parses an intersection mode name.
+func ParseIntersectMode(s string) (IntersectMode, error) {
+	for i, n := range intersectModeNames {
+		if n == s {
+			return IntersectMode(i), nil
+		}
+	}
+	return IntersectOff, fmt.Errorf("unknown intersection mode %q", s)
+}
+
+// gridPairs returns the number of unordered pairs of n grid families.
+func gridPairs(n int) int {
+	return n * (n - 1) / 2
+}
+
+// pairIndex returns the index of the unordered pair a < b among n families.
+func pairIndex(a, b, n int) int {
+	return a*n - a*(a+1)/2 + (b - a - 1)
+}
+
+// updateIntersections fires intersection triggers for every pair of linear
+// families according to the current mode.
+func (e *Engine) updateIntersections(center geom.Vec2) {
+	n := len(e.Grids)
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			ga, gb := &e.Grids[a], &e.Grids[b]
+			pair := pairIndex(a, b, n)
+			if ga.Kind != geom.GridLinear || gb.Kind != geom.GridLinear {
+				continue
+			}
+			va, vb := e.GridVelocity(a), e.GridVelocity(b)
+			if e.IntersectMode == IntersectAlign {
+				d, on := geom.Aligned(ga, gb, center, e.View)
+				if on && !e.lastAlign[pair] {
+					pos := center.Add(ga.Normal.Mul(d))
+					// The lines meet at the speed they close in on each other
+					speed := math.Abs(ga.Normal.Dot(va) - ga.Normal.Dot(vb))
+					e.intersection(a, b, -1, pos, velocityFromSpeed(speed))
+				}
+				e.lastAlign[pair] = on
+				continue
+			}
+			for pi, p := range e.Points {
+				// A point sits on an intersection when it is on a drawn part of both lines
+				inside := ga.Touches(p.Pos, center) && gb.Touches(p.Pos, center)
+				if inside && !e.lastCross[pair][pi] && !e.held(pi) && p.Listens(a) && p.Listens(b) {
+					if x, vel, ok := geom.LineIntersection(ga, gb, p.Pos, center, va, vb); ok {
+						e.intersection(a, b, pi, x, velocityFromSpeed(vel.Len()))
+					}
+				}
+				e.lastCross[pair][pi] = inside
+			}
+		}
+	}
+}
+
+// intersection reports the crossing of grids a and b at pos, over point pi
+// (or -1 for aligned lines), if the dice and the point's group allow it.
+func (e *Engine) intersection(a, b, pi int, pos geom.Vec2, velocity float64) {
+	ga, gb := &e.Grids[a], &e.Grids[b]
+	if !e.GridAudible(a) || !e.GridAudible(b) {
+		return
+	}
+	velocity *= ga.Level() * gb.Level()
+	if pi >= 0 {
+		p := e.Points[pi]
+		if !e.fires(p.Chance, ga.Chance, gb.Chance) || !e.Audible(p.Group) {
+			return
+		}
+		e.Cues[pi] = 1.0
+		velocity *= p.VelocityScale()
+	} else if !e.fires(ga.Chance, gb.Chance) {
+		return
+	}
+	e.Bus.PublishIntersection(IntersectionEvent{A: a, B: b, Point: pi, Pos: pos, Velocity: velocity, Time: e.Time, At: e.soundTime(e.Time)})
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+
+	"grythm/geom"
+)
+
+// Layers gather grid families (see geom.GridFamily.Layer) into units with a
+// transform shared on top of their own settings, so a lattice built from
+// several families can be handled as one: turning or moving a layer turns or
+// moves all its families, and its rate scales their motion along with their
+// own rates. A layer's transform is its Angle about the canvas center
+// followed by its Shift, so it turns about the center as far as it has been
+// moved. The families hold their settings with the transform applied, and
+// one joining or leaving a layer is given or relieved of it (see SetLayer).
+
+// MaxLayers is the number of layers.
+const MaxLayers = 4
+
+// Layer is the shared transform of the families in it.
+type Layer struct {
+	Angle float64   `json:"angle,omitempty"` // degrees it has been turned by, clockwise on screen
+	Shift geom.Vec2 `json:"shift"`           // how far it has been moved (world pixels)
+	Rate  float64   `json:"rate,omitempty"`  // multiplier of its families' motion; negative runs them backwards, 0 means 1
+}
+
+// Validate reports a layer an editor could not have made.
+func (l Layer) Validate() error {
+	return geom.ValidateRate(l.Rate)
+}
+
+// ValidateLayerIndex reports a family's layer that doesn't exist.
+func ValidateLayerIndex(n int) error {
+	if n < 0 || n > MaxLayers {
+		return fmt.Errorf("layer %d out of range 1-%d", n, MaxLayers)
+	}
+	return nil
+}
+
+// LayerOf returns the layer grid family gi is in, or nil.
+func (e *Engine) LayerOf(gi int) *Layer {
+	n := e.Grids[gi].Layer
+	if n < 1 || n > MaxLayers {
+		return nil
+	}
+	return &e.Layers[n-1]
+}
+
+// layerRate returns the factor the layer of family gi scales its motion by.
+func (e *Engine) layerRate(gi int) float64 {
+	if l := e.LayerOf(gi); l != nil && l.Rate != 0 {
+		return l.Rate
+	}
+	return 1
+}
+
+// TurnLayer turns layer n (from 1) with its families by deg degrees.
+func (e *Engine) TurnLayer(n int, deg float64) {
+	l := &e.Layers[n-1]
+	l.Angle += deg
+	a := deg * math.Pi / 180
+	for i := range e.Grids {
+		if gf := &e.Grids[i]; gf.Layer == n {
+			// About the center where the layer's move took it
+			gf.Move(l.Shift.Mul(-1))
+			gf.Turn(a, e.Center())
+			gf.Move(l.Shift)
+		}
+	}
+}
+
+// MoveLayer moves layer n (from 1) with its families by d.
+func (e *Engine) MoveLayer(n int, d geom.Vec2) {
+	l := &e.Layers[n-1]
+	l.Shift = l.Shift.Add(d)
+	for i := range e.Grids {
+		if e.Grids[i].Layer == n {
+			e.Grids[i].Move(d)
+		}
+	}
+}
+
+// SetLayer puts grid family gi in layer n (from 1; 0 for none). It is taken
+// out of the transform of the layer it was in and given that of n, so it
+// lines up with the families already there.
+func (e *Engine) SetLayer(gi, n int) {
+	gf := &e.Grids[gi]
+	if l := e.LayerOf(gi); l != nil {
+		gf.Move(l.Shift.Mul(-1))
+		gf.Turn(-l.Angle*math.Pi/180, e.Center())
+	}
+	gf.Layer = n
+	if l := e.LayerOf(gi); l != nil {
+		gf.Turn(l.Angle*math.Pi/180, e.Center())
+		gf.Move(l.Shift)
+	}
+}
+
+// LayerLabel describes layer n (from 1) for display.
+func (e *Engine) LayerLabel(n int) string {
+	l := e.Layers[n-1]
+	r := l.Rate
+	if r == 0 {
+		r = 1
+	}
+	return fmt.Sprintf("%d (%+.0f°, moved %.0f,%.0f, x%g)", n, l.Angle, l.Shift.X, l.Shift.Y, r)
+}
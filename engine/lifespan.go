@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Ephemeral points. A point can be given a lifespan, a number of triggers or
+// of seconds (or both, whichever runs out first); after that it no longer
+// sounds and fades out over LifespanFade seconds. The engine only keeps
+// count: the host removes points once their lifespan is Over, so it can keep
+// its own references to points in step.
+
+// LifespanFade is how many seconds a point takes to fade out once its
+// lifespan has run out.
+const LifespanFade = 0.6
+
+// Lifespan limits how long a point lives. The zero Lifespan lives forever.
+type Lifespan struct {
+	Triggers int     // crossings it sounds before it goes; 0 is no limit
+	Seconds  float64 // seconds it lives; 0 is no limit
+
+	Hits   int     // crossings it has sounded
+	Age    float64 // seconds since it was placed
+	Fading float64 // seconds since its lifespan ran out
+}
+
+// Mortal reports whether the lifespan is limited.
+func (l Lifespan) Mortal() bool {
+	return l.Triggers > 0 || l.Seconds > 0
+}
+
+// Expired reports whether the lifespan has run out.
+func (l Lifespan) Expired() bool {
+	return (l.Triggers > 0 && l.Hits >= l.Triggers) || (l.Seconds > 0 && l.Age >= l.Seconds)
+}
+
+// Level returns how visible the point is: 1 while it lives, falling to 0 as
+// it fades out.
+func (l Lifespan) Level() float64 {
+	if !l.Expired() {
+		return 1
+	}
+	return max(0, 1-l.Fading/LifespanFade)
+}
+
+// Over reports whether the point has faded out and should be removed.
+func (l Lifespan) Over() bool {
+	return l.Expired() && l.Fading >= LifespanFade
+}
+
+// Renewed returns the lifespan as it was when the point was placed.
+func (l Lifespan) Renewed() Lifespan {
+	return Lifespan{Triggers: l.Triggers, Seconds: l.Seconds}
+}
+
+// String formats the lifespan as ParseLifespan reads it: "8" for 8
+// triggers, "4s" for 4 seconds, "8,4s" for both; "" lives forever.
+func (l Lifespan) String() string {
+	var parts []string
+	if l.Triggers > 0 {
+		parts = append(parts, strconv.Itoa(l.Triggers))
+	}
+	if l.Seconds > 0 {
+		parts = append(parts, strconv.FormatFloat(l.Seconds, 'g', -1, 64)+"s")
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseLifespan reads a lifespan formatted by Lifespan.String.
+func ParseLifespan(s string) (Lifespan, error) {
+	var l Lifespan
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if secs, ok := strings.CutSuffix(part, "s"); ok {
+			v, err := strconv.ParseFloat(secs, 64)
+			if err != nil || v < 0 {
+				return Lifespan{}, fmt.Errorf("lifespan %q: bad number of seconds %q", s, secs)
+			}
+			l.Seconds = v
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Lifespan{}, fmt.Errorf("lifespan %q: bad number of triggers %q", s, part)
+		}
+		l.Triggers = n
+	}
+	return l, nil
+}
+
+// agePoints counts the time of the points with a lifespan.
+func (e *Engine) agePoints(dt float64) {
+	for i := range e.Points {
+		l := &e.Points[i].Life
+		if !l.Mortal() {
+			continue
+		}
+		if l.Expired() {
+			l.Fading += dt
+		} else {
+			l.Age += dt
+		}
+	}
+}
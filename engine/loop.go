@@ -0,0 +1,104 @@
+package engine
+
+import "math"
+
+// Loop mode turns the endless scroll into a repeating clip. The pattern
+// remembers where every family was when the loop started, and once the
+// global motion has travelled the loop length all families jump back there,
+// so the rhythm repeats exactly however their spacings and speeds relate.
+// Families with their own motion, rotating rays and points on paths jump
+// back too.
+
+// Loop is the length of the repeating pattern. It is measured in travel of
+// the global motion: Length pixels, or Length beats of the clock's
+// BeatPixels each.
+type Loop struct {
+	Enabled bool    `json:"enabled"`
+	Length  float64 `json:"length"`
+	Beats   bool    `json:"beats,omitempty"` // Length counts beats instead of pixels
+}
+
+// DefaultLoop is four bars of four beats.
+var DefaultLoop = Loop{Length: 16, Beats: true}
+
+// Unit returns the unit Length is measured in.
+func (l Loop) Unit() string {
+	if l.Beats {
+		return "beats"
+	}
+	return "px"
+}
+
+// pixels returns the loop length in pixels of travel.
+func (l Loop) pixels(c Clock) float64 {
+	if l.Beats {
+		return l.Length * c.BeatPixels
+	}
+	return l.Length
+}
+
+// RestartLoop starts the loop afresh from the current pattern.
+func (e *Engine) RestartLoop() {
+	e.loopStart = e.loopStart[:0]
+	for i := range e.Grids {
+		e.loopStart = append(e.loopStart, e.Grids[i].Phase())
+	}
+	e.loopPaths = e.loopPaths[:0]
+	for i := range e.Points {
+		e.loopPaths = append(e.loopPaths, e.Points[i].Path.Phase)
+	}
+	e.loopPos = 0
+}
+
+// LoopPosition returns how far the pattern is into the loop, in the unit of
+// its length.
+func (e *Engine) LoopPosition() float64 {
+	if e.Loop.Beats && e.Clock.BeatPixels > 0 {
+		return e.loopPos / e.Clock.BeatPixels
+	}
+	return e.loopPos
+}
+
+// updateLoop counts the travel of the last tick (of length dt) and jumps the
+// pattern back to the start of the loop when it passes the end, playing on
+// from there for the rest of the tick.
+func (e *Engine) updateLoop(dt float64) {
+	if !e.Loop.Enabled {
+		e.loopStart = nil
+		return
+	}
+	// A loop starts when switched on, and again whenever families were
+	// replaced wholesale
+	if e.loopStart == nil || len(e.loopStart) != len(e.Grids) {
+		e.RestartLoop()
+		return
+	}
+	length := e.Loop.pixels(e.Clock)
+	if length <= 0 {
+		return
+	}
+	e.loopPos += e.Speed * dt
+	if e.loopPos < length {
+		return
+	}
+	e.loopPos = math.Mod(e.loopPos-length, length)
+	over := 0.0
+	if e.Speed > 0 {
+		over = e.loopPos / e.Speed
+	}
+	for i := range e.Grids {
+		e.Grids[i].SetPhase(e.loopStart[i])
+		e.advance(&e.Grids[i], i, over)
+	}
+	// Points added or removed since the loop started play on from where they are
+	if len(e.loopPaths) == len(e.Points) {
+		for pi := range e.Points {
+			if p := &e.Points[pi]; p.Path.Moves() && !e.held(pi) {
+				p.Path.Phase = e.loopPaths[pi]
+				p.Path.Advance(over)
+				p.Pos = p.Path.Pos()
+			}
+		}
+		e.Index.Invalidate()
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import "grythm/geom"
+
+// Points with a path (see geom.Path) travel along it every tick. Lines can
+// then reach a point that moves as well, so crossings are worked out against
+// where both were: the crossing time rewinds the point along its path
+// together with the family, and the velocity of a trigger is that of the line
+// relative to the point.
+
+// movePoints advances every point with a path by dt seconds and puts it
+// where its path now is. The held point stays under the host's control.
+func (e *Engine) movePoints(dt float64) {
+	moved := false
+	for pi := range e.Points {
+		p := &e.Points[pi]
+		if !p.Path.Moves() || e.held(pi) {
+			continue
+		}
+		p.Path.Advance(dt)
+		p.Pos = p.Path.Pos()
+		moved = true
+	}
+	if moved {
+		e.Index.Invalidate()
+	}
+}
+
+// pointBefore returns where point pi was the given number of seconds ago.
+func (e *Engine) pointBefore(pi int, seconds float64) geom.Vec2 {
+	p := &e.Points[pi]
+	if !p.Path.Moves() || e.held(pi) {
+		return p.Pos
+	}
+	return p.Path.PosBefore(seconds)
+}
+
+// PointVelocity returns the velocity (pixels per second) of point pi along
+// its path; standing and held points have none.
+func (e *Engine) PointVelocity(pi int) geom.Vec2 {
+	p := &e.Points[pi]
+	if !p.Path.Moves() || e.held(pi) {
+		return geom.Vec2{}
+	}
+	return p.Path.Velocity()
+}
+
+// RewindPoints returns copies of the points (reusing dst) moved back along
+// their paths by the given number of seconds, like Rewind does for grids.
+func (e *Engine) RewindPoints(dst []Point, seconds float64) []Point {
+	dst = append(dst[:0], e.Points...)
+	for pi := range dst {
+		if p := &dst[pi]; p.Path.Moves() && !e.held(pi) {
+			p.Path.Advance(-seconds)
+			p.Pos = p.Path.Pos()
+		}
+	}
+	return dst
+}
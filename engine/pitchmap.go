@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"math"
+
+	"grythm/geom"
+)
+
+// PitchMap derives a point's scale degree from where it sits: every
+// DegreeWidth pixels to the right of the center is one degree up, every
+// OctaveHeight pixels above it one octave up. With Distance the degree comes
+// from the distance to a tonal center instead: the tonic at the center, one
+// degree up every RingWidth pixels out, so points close to it sound low and
+// far ones high. Points get their degree when placed or dragged, so the
+// spatial arrangement composes the melody.
+type PitchMap struct {
+	Enabled      bool      `json:"enabled"`
+	DegreeWidth  float64   `json:"degreeWidth"`
+	OctaveHeight float64   `json:"octaveHeight"`
+	Distance     bool      `json:"distance,omitempty"`
+	Tonic        geom.Vec2 `json:"tonic,omitempty"`     // tonal center relative to the canvas center
+	RingWidth    float64   `json:"ringWidth,omitempty"` // pixels per degree from the tonal center; 0 is the default's
+}
+
+// DefaultPitchMap fits about a dozen degrees across and four octaves up the
+// default window, or two octaves out from the tonal center.
+var DefaultPitchMap = PitchMap{DegreeWidth: 60, OctaveHeight: 160, RingWidth: 24}
+
+// TonicPos returns the world position of the tonal center.
+func (e *Engine) TonicPos() geom.Vec2 {
+	return e.Center().Add(e.PitchMap.Tonic)
+}
+
+// RingStep returns the pixels per degree of the distance mapping.
+func (pm PitchMap) RingStep() float64 {
+	if pm.RingWidth <= 0 {
+		return DefaultPitchMap.RingWidth
+	}
+	return pm.RingWidth
+}
+
+// DegreeAt returns the scale degree for position pos.
+func (e *Engine) DegreeAt(pos geom.Vec2) int {
+	pm := e.PitchMap
+	if pm.Distance {
+		return int(pos.Sub(e.TonicPos()).Len() / pm.RingStep())
+	}
+	center := e.Center()
+	deg := 0
+	if pm.DegreeWidth > 0 {
+		deg = int(math.Floor((pos.X - center.X) / pm.DegreeWidth))
+	}
+	if pm.OctaveHeight > 0 {
+		// Screen Y grows downward; higher on screen is a higher octave
+		octave := int(math.Floor((center.Y - pos.Y) / pm.OctaveHeight))
+		deg += octave * len(e.Scale.Steps)
+	}
+	return deg
+}
+
+// NewPointDegree returns the degree for a point placed at pos: from the pitch
+// map when enabled, otherwise cycling through the scale.
+func (e *Engine) NewPointDegree(pos geom.Vec2) int {
+	if e.PitchMap.Enabled {
+		return e.DegreeAt(pos)
+	}
+	return len(e.Points) % newPointDegrees
+}
+
+// PitchPos returns the middle of the pitch map cell of scale degree deg, the
+// inverse of degreeAt. With Distance it lies to the right of the tonal
+// center; degrees below the tonic are put on the center itself.
+func (e *Engine) PitchPos(deg int) geom.Vec2 {
+	pm := e.PitchMap
+	if pm.Distance {
+		r := (float64(max(deg, 0)) + 0.5) * pm.RingStep()
+		return e.TonicPos().Add(geom.Vec2{X: r})
+	}
+	if pm.DegreeWidth <= 0 || pm.OctaveHeight <= 0 {
+		pm = DefaultPitchMap
+	}
+	n := len(e.Scale.Steps)
+	oct := int(math.Floor(float64(deg) / float64(n)))
+	step := deg - oct*n
+	center := e.Center()
+	return geom.Vec2{
+		X: center.X + (float64(step)+0.5)*pm.DegreeWidth,
+		Y: center.Y - (float64(oct)+0.5)*pm.OctaveHeight,
+	}
+}
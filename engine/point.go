@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"fmt"
+
+	"grythm/geom"
+	"grythm/synth"
+)
+
+// Point is a trigger location on the canvas. Each point carries its own pitch,
+// as a degree of the global scale, so that crossings at different points sound different.
+type Point struct {
+	Pos       geom.Vec2
+	Degree    int             // scale degree relative to the key root
+	Wave      synth.Waveform  // oscillator shape; WaveInherit uses the triggering grid's waveform
+	Sample    string          // optional WAV/OGG file played instead of the synth blip
+	Env       *synth.Envelope // amplitude envelope; nil uses the triggering grid's
+	FM        *synth.FM       // FM voice instead of Wave (see synth/fm.go); nil uses the triggering grid's
+	Group     string          // optional group name for mute/solo; "" is ungrouped
+	Ignore    uint64          // bit i set: the point does not respond to grid family i
+	Chance    float64         // probability (0-1) that a crossing sounds; 0 means always
+	Velocity  float64         // factor its notes' velocity is scaled by (see velocity.go); 0 means 1
+	Length    float64         // note length in seconds; 0 uses the grid's
+	Retrigger synth.Retrigger // what a new note does to this point's sounding note; inherit uses the grid's
+	Cooldown  float64         // least milliseconds between its triggers by any family (see cooldown.go); 0 is none
+	Path      geom.Path       // route the point travels along (see path.go); Pos follows it
+	Chord     []int           // semitones above the note played with it (see synth/chord.go); nil plays one note
+	Life      Lifespan        // how long it lives (see lifespan.go); the zero Lifespan is forever
+	Mirror    Mirror          // copies of it placed by symmetry
+	ID        string          // name a scene file refers to it by; "" until it is saved
+}
+
+// Mirror links a point to the copies made of it by a symmetry: each is one
+// image of the same source position, so moving one moves the others.
+type Mirror struct {
+	Set   int           // shared by the point and its copies; 0 is none
+	Image int           // which image of Sym the point is
+	Sym   geom.Symmetry // symmetry the set was made with
+}
+
+// Mirrors reports whether p and q are copies of each other.
+func (p Point) Mirrors(q Point) bool {
+	return p.Mirror.Set != 0 && p.Mirror.Set == q.Mirror.Set
+}
+
+// held reports whether point pi is being moved by the host (see Held).
+func (e *Engine) held(pi int) bool {
+	if e.Held < 0 || e.Held >= len(e.Points) {
+		return false
+	}
+	return pi == e.Held || e.Points[pi].Mirrors(e.Points[e.Held])
+}
+
+// newPointDegrees is the number of scale degrees newly placed points cycle through.
+const newPointDegrees = 7
+
+// MaxFilterGrids is the number of grid families a point can opt out of individually.
+const MaxFilterGrids = 64
+
+// Listens reports whether the point responds to grid family gi.
+func (p Point) Listens(gi int) bool {
+	return gi >= MaxFilterGrids || p.Ignore&(1<<uint(gi)) == 0
+}
+
+// IgnoredGrids lists the grid families the point ignores, for storage.
+func (p Point) IgnoredGrids() []int {
+	var out []int
+	for gi := 0; gi < MaxFilterGrids; gi++ {
+		if !p.Listens(gi) {
+			out = append(out, gi)
+		}
+	}
+	return out
+}
+
+// IgnoreMask builds the Ignore mask from a list of grid indices.
+func IgnoreMask(grids []int) (uint64, error) {
+	var mask uint64
+	for _, gi := range grids {
+		if gi < 0 || gi >= MaxFilterGrids {
+			return 0, fmt.Errorf("ignored grid %d out of range 0-%d", gi, MaxFilterGrids-1)
+		}
+		mask |= 1 << uint(gi)
+	}
+	return mask, nil
+}
+
+// InsertPoint inserts p at index idx, keeping the per-point state slices in step.
+func (e *Engine) InsertPoint(idx int, p Point) {
+	if p.Path.Moves() {
+		p.Pos = p.Path.Pos()
+	}
+	e.Points = append(e.Points[:idx], append([]Point{p}, e.Points[idx:]...)...)
+	e.Index.Invalidate()
+	for gi := range e.lastContact {
+		row := e.lastContact[gi]
+		e.lastContact[gi] = append(row[:idx], append([]contact{{}}, row[idx:]...)...)
+	}
+	for gi := range e.lastHit {
+		row := e.lastHit[gi]
+		e.lastHit[gi] = append(row[:idx], append(newHitRow(1), row[idx:]...)...)
+	}
+	for pi := range e.lastCross {
+		row := e.lastCross[pi]
+		e.lastCross[pi] = append(row[:idx], append([]bool{false}, row[idx:]...)...)
+	}
+	e.Cues = append(e.Cues[:idx], append([]float64{0}, e.Cues[idx:]...)...)
+	e.Stats.insertPoint(idx)
+}
+
+// RemovePoint removes the point at index idx along with its per-point state.
+func (e *Engine) RemovePoint(idx int) {
+	e.Points = append(e.Points[:idx], e.Points[idx+1:]...)
+	e.Index.Invalidate()
+	for gi := range e.lastContact {
+		row := e.lastContact[gi]
+		e.lastContact[gi] = append(row[:idx], row[idx+1:]...)
+	}
+	for gi := range e.lastHit {
+		row := e.lastHit[gi]
+		e.lastHit[gi] = append(row[:idx], row[idx+1:]...)
+	}
+	for pi := range e.lastCross {
+		row := e.lastCross[pi]
+		e.lastCross[pi] = append(row[:idx], row[idx+1:]...)
+	}
+	// remove corresponding cue timer
+	e.Cues = append(e.Cues[:idx], e.Cues[idx+1:]...)
+	e.Stats.removePoint(idx)
+}
+
+// SetPoint replaces the point at idx. Its trigger state is kept, so a point
+// that jumps onto a line (e.g. through undo) does not fire until it leaves and re-enters.
+// A point with a path is put where its path has it.
+func (e *Engine) SetPoint(idx int, p Point) {
+	if p.Path.Moves() {
+		p.Pos = p.Path.Pos()
+	}
+	e.Points[idx] = p
+	e.Index.Invalidate()
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quantization holds every trigger back to the next subdivision of the
+// clock's beat, so lines that are slightly off the beat still play tight
+// rhythms. Crossings are detected (and drawn) when they happen;
+// TriggerEvent.At is when they sound, which the host schedules ahead. The
+// beat is the clock's: in tempo mode its running position, otherwise counted
+// at its BPM from time 0.
+
+// QuantizeSteps are the note values triggers can be quantized to, a beat
+// being a quarter note; 0 is off.
+var QuantizeSteps = []int{0, 4, 8, 16, 32}
+
+// ValidateQuantize reports a note value triggers can't be quantized to.
+func ValidateQuantize(q int) error {
+	if q < 0 {
+		return fmt.Errorf("quantize note value must not be negative")
+	}
+	return nil
+}
+
+// NextQuantize returns the step after q.
+func NextQuantize(q int) int {
+	for i, s := range QuantizeSteps {
+		if s == q {
+			return QuantizeSteps[(i+1)%len(QuantizeSteps)]
+		}
+	}
+	return 0
+}
+
+// quantizeEpsilon keeps events that are on a subdivision (up to rounding)
+// from being held back a whole step.
+const quantizeEpsilon = 1e-6
+
+// QuantizeTime returns the first subdivision at or after game time t, or t
+// when quantization is off.
+func (e *Engine) QuantizeTime(t float64) float64 {
+	if e.Quantize <= 0 || e.Clock.BPM <= 0 {
+		return t
+	}
+	step := e.noteSeconds(e.Quantize)
+	pos := e.beatSeconds(t)
+	next := math.Ceil(pos/step-quantizeEpsilon) * step
+	return t + math.Max(0, next-pos)
+}
+
+// noteSeconds returns the length in seconds of note value n (4 is a beat) at
+// the clock's tempo.
+func (e *Engine) noteSeconds(n int) float64 {
+	return 60 / e.Clock.BPM * 4 / float64(n)
+}
+
+// beatSeconds returns the seconds since beat 0 at game time t.
+func (e *Engine) beatSeconds(t float64) float64 {
+	if e.TempoMode {
+		return e.Clock.Beats*60/e.Clock.BPM - (e.Time - t)
+	}
+	return t
+}
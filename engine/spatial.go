@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"math"
+	"sort"
+
+	"grythm/geom"
+)
+
+// SpatialIndex speeds up the per-frame touch and hover queries. Points are
+// kept sorted by their projection onto each grid direction (or by distance
+// from each ring origin), so a family only tests the points lying in the bands
+// around its lines instead of every point. Projections are rebuilt lazily
+// after points change, which is rare compared to frames.
+type SpatialIndex struct {
+	version int // bumped whenever points move, are added or removed
+	proj    map[projKey]*projection
+	inside  [][]int // [gridIdx] points inside the family last frame; nil row means unknown
+	mark    []int   // per point: stamp of the last query that reported it
+	stamp   int
+}
+
+// projKey identifies a projection: onto a direction, or distance from an origin.
+type projKey struct {
+	radial bool
+	v      geom.Vec2
+}
+
+// projection holds the points sorted by their projected value.
+type projection struct {
+	version int
+	vals    []float64 // sorted projected values
+	idx     []int     // point index for each value
+}
+
+// Invalidate marks all projections and inside lists stale after points changed.
+func (s *SpatialIndex) Invalidate() {
+	s.version++
+	s.inside = nil
+	// Drop projections of directions no longer in use after many scene changes
+	if len(s.proj) > 64 {
+		s.proj = nil
+	}
+}
+
+// get returns the projection for key, rebuilding it if points changed since.
+func (s *SpatialIndex) get(key projKey, points []Point, center geom.Vec2) *projection {
+	if s.proj == nil {
+		s.proj = make(map[projKey]*projection)
+	}
+	p := s.proj[key]
+	if p == nil {
+		p = &projection{version: -1}
+		s.proj[key] = p
+	}
+	if p.version == s.version && len(p.idx) == len(points) {
+		return p
+	}
+	p.version = s.version
+	p.idx = p.idx[:0]
+	vals := make([]float64, len(points))
+	for i, pt := range points {
+		p.idx = append(p.idx, i)
+		if key.radial {
+			vals[i] = pt.Pos.Sub(key.v).Len()
+		} else {
+			vals[i] = key.v.Dot(pt.Pos.Sub(center))
+		}
+	}
+	sort.Slice(p.idx, func(a, b int) bool { return vals[p.idx[a]] < vals[p.idx[b]] })
+	p.vals = p.vals[:0]
+	for _, i := range p.idx {
+		p.vals = append(p.vals, vals[i])
+	}
+	return p
+}
+
+// each calls fn for every point whose projected value lies in [lo, hi].
+func (p *projection) each(lo, hi float64, fn func(pi int)) {
+	i := sort.SearchFloat64s(p.vals, lo)
+	for ; i < len(p.vals) && p.vals[i] <= hi; i++ {
+		fn(p.idx[i])
+	}
+}
+
+// bands calls fn for the points within half-width w of the values
+// first + k*step (k >= kMin), the positions of a family's lines. When there are
+// more lines in range than points it is cheaper to visit every point.
+func (p *projection) bands(first, step, w float64, kMin float64, fn func(pi int)) {
+	if len(p.vals) == 0 {
+		return
+	}
+	lo, hi := p.vals[0]-w, p.vals[len(p.vals)-1]+w
+	k0 := math.Max(math.Ceil((lo-first)/step), kMin)
+	k1 := math.Floor((hi - first) / step)
+	if k1-k0+1 > float64(len(p.vals)) {
+		for _, pi := range p.idx {
+			fn(pi)
+		}
+		return
+	}
+	for k := k0; k <= k1; k++ {
+		d := first + k*step
+		p.each(d-w, d+w, fn)
+	}
+}
+
+// candidates calls fn once for every point that might touch grid family gf:
+// a superset of the points for which gf.Touches is true.
+func (s *SpatialIndex) candidates(gf *geom.GridFamily, points []Point, center geom.Vec2, fn func(pi int)) {
+	if len(s.mark) != len(points) {
+		s.mark = make([]int, len(points))
+	}
+	s.stamp++
+	visit := func(pi int) {
+		if s.mark[pi] != s.stamp {
+			s.mark[pi] = s.stamp
+			fn(pi)
+		}
+	}
+	s.linesOf(gf, points, center, visit)
+}
+
+func (s *SpatialIndex) linesOf(gf *geom.GridFamily, points []Point, center geom.Vec2, fn func(pi int)) {
+	if gf.Spacing <= 0 {
+		return
+	}
+	switch gf.Kind {
+	case geom.GridRay:
+		// Rays sweep every direction, so every point is a candidate
+		for pi := range points {
+			fn(pi)
+		}
+	case geom.GridRadial:
+		p := s.get(projKey{radial: true, v: gf.Origin}, points, center)
+		p.bands(gf.Offset, gf.Spacing, gf.Thickness, 0, fn)
+	case geom.GridHex:
+		for _, l := range gf.HexLines() {
+			s.linesOf(&l, points, center, fn)
+		}
+	default:
+		w := gf.Thickness
+		if gf.Kind == geom.GridWavy && gf.Wavy() {
+			w += math.Abs(gf.Amplitude)
+		}
+		if gf.Kind == geom.GridCurve && gf.Curved() {
+			w += gf.BezierReach()
+		}
+		p := s.get(projKey{v: gf.Normal}, points, center)
+		p.bands(gf.Offset, gf.Spacing, w, math.Inf(-1), fn)
+	}
+}
+
+// insideOf returns the points that were inside grid gi last frame, or false if
+// that is unknown (after points changed).
+func (s *SpatialIndex) insideOf(gi int) ([]int, bool) {
+	if gi >= len(s.inside) || s.inside[gi] == nil {
+		return nil, false
+	}
+	return s.inside[gi], true
+}
+
+// setInside records the points inside grid gi this frame.
+func (s *SpatialIndex) setInside(gi int, pts []int, grids int) {
+	if len(s.inside) != grids {
+		s.inside = make([][]int, grids)
+	}
+	s.inside[gi] = append(s.inside[gi][:0], pts...)
+	if s.inside[gi] == nil {
+		s.inside[gi] = []int{}
+	}
+}
+
+// Nearest returns the point closest to pos within radius r, or -1. It shares
+// the X-axis projection (relative to center) with vertical line families.
+func (s *SpatialIndex) Nearest(points []Point, pos, center geom.Vec2, r float64) int {
+	best, bestDist := -1, r
+	p := s.get(projKey{v: geom.Vec2{X: 1, Y: 0}}, points, center)
+	x := pos.X - center.X
+	p.each(x-r, x+r, func(pi int) {
+		if d := points[pi].Pos.Sub(pos).Len(); d <= bestDist {
+			best, bestDist = pi, d
+		}
+	})
+	return best
+}
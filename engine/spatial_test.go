@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"grythm/geom"
+)
+
+// spatialScene returns an engine on a 1920x1080 canvas with points scattered
+// over it and a mix of straight, dashed and ring families.
+func spatialScene(points, grids int) *Engine {
+	e := New(1920, 1080, 1)
+	r := rand.New(rand.NewSource(1))
+	for range points {
+		e.Points = append(e.Points, Point{Pos: geom.Vec2{X: r.Float64() * 1920, Y: r.Float64() * 1080}})
+	}
+	for i := range grids {
+		a := r.Float64() * math.Pi
+		gf := geom.GridFamily{
+			Normal:    geom.Vec2{X: math.Cos(a), Y: math.Sin(a)},
+			Spacing:   40 + r.Float64()*120,
+			Offset:    r.Float64() * 40,
+			Thickness: 2,
+		}
+		switch i % 4 {
+		case 1:
+			gf.DashLength, gf.GapLength = 60, 30
+		case 3:
+			gf.Kind = geom.GridRadial
+			gf.Origin = geom.Vec2{X: r.Float64() * 1920, Y: r.Float64() * 1080}
+		}
+		e.Grids = append(e.Grids, gf)
+	}
+	e.ResetPointState()
+	return e
+}
+
+var spatialSizes = []struct{ points, grids int }{
+	{1000, 12},
+	{4000, 24},
+	{10000, 48},
+}
+
+func TestCandidatesCoverTouches(t *testing.T) {
+	e := spatialScene(4000, 24)
+	center := e.Center()
+	for step := range 20 {
+		e.Tick(0.05)
+		for gi := range e.Grids {
+			gf := &e.Grids[gi]
+			found := make([]bool, len(e.Points))
+			e.Index.candidates(gf, e.Points, center, func(pi int) { found[pi] = true })
+			for pi, p := range e.Points {
+				if gf.Touches(p.Pos, center) && !found[pi] {
+					t.Fatalf("step %d: grid %d touches point %d at %v, not a candidate", step, gi, pi, p.Pos)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkCandidates(b *testing.B) {
+	for _, size := range spatialSizes {
+		b.Run(fmt.Sprintf("points=%d/grids=%d", size.points, size.grids), func(b *testing.B) {
+			e := spatialScene(size.points, size.grids)
+			center := e.Center()
+			touched := 0
+			for b.Loop() {
+				for gi := range e.Grids {
+					gf := &e.Grids[gi]
+					e.Index.candidates(gf, e.Points, center, func(pi int) {
+						if gf.Touches(e.Points[pi].Pos, center) {
+							touched++
+						}
+					})
+				}
+			}
+			b.ReportMetric(float64(touched)/float64(b.N), "touches/op")
+		})
+	}
+}
+
+// BenchmarkCandidatesBruteForce tests every point against every family, the
+// loop the index replaces.
+func BenchmarkCandidatesBruteForce(b *testing.B) {
+	for _, size := range spatialSizes {
+		b.Run(fmt.Sprintf("points=%d/grids=%d", size.points, size.grids), func(b *testing.B) {
+			e := spatialScene(size.points, size.grids)
+			center := e.Center()
+			touched := 0
+			for b.Loop() {
+				for gi := range e.Grids {
+					gf := &e.Grids[gi]
+					for pi := range e.Points {
+						if gf.Touches(e.Points[pi].Pos, center) {
+							touched++
+						}
+					}
+				}
+			}
+			b.ReportMetric(float64(touched)/float64(b.N), "touches/op")
+		})
+	}
+}
+
+func BenchmarkTick(b *testing.B) {
+	for _, size := range spatialSizes {
+		b.Run(fmt.Sprintf("points=%d/grids=%d", size.points, size.grids), func(b *testing.B) {
+			e := spatialScene(size.points, size.grids)
+			for b.Loop() {
+				e.Tick(1.0 / 60)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package engine
+
+import "slices"
+
+// Trigger statistics, for tuning how dense a pattern is and for spotting
+// double triggers: how often each grid family and each point fired, how many
+// triggers there were over the last RateWindow, and a histogram of the
+// intervals between successive triggers of the same point. An interval in
+// the first bin is almost always one crossing reported twice.
+
+// RateWindow is the span (seconds) triggers per second are measured over.
+const RateWindow = 2.0
+
+// IntervalBins are the upper edges (seconds) of the interval histogram's
+// bins, doubling from 1/64 s; the last bin holds everything longer.
+var IntervalBins = []float64{1.0 / 64, 1.0 / 32, 1.0 / 16, 1.0 / 8, 0.25, 0.5, 1, 2, 4}
+
+// Stats counts the triggers since the scene was set up or the stats reset.
+// Slices are indexed like Grids and Points and may be shorter than them when
+// the last families or points have not fired.
+type Stats struct {
+	Total     int
+	Grid      []int // triggers per grid family
+	Point     []int // triggers per point
+	Intervals []int // histogram over IntervalBins, with one extra bin for longer intervals
+
+	recent []float64 // times of the triggers in the last RateWindow, oldest first
+	last   []float64 // [pointIdx] time of the point's last trigger; -1 before the first
+}
+
+// ResetStats clears the trigger statistics.
+func (e *Engine) ResetStats() {
+	e.Stats = Stats{}
+}
+
+// record counts a trigger of point pi by grid gi at time at.
+func (s *Stats) record(gi, pi int, at float64) {
+	s.Total++
+	s.Grid = grow(s.Grid, gi+1, 0)
+	s.Grid[gi]++
+	s.Point = grow(s.Point, pi+1, 0)
+	s.Point[pi]++
+	s.last = grow(s.last, pi+1, -1)
+	if prev := s.last[pi]; prev >= 0 && at >= prev {
+		if s.Intervals == nil {
+			s.Intervals = make([]int, len(IntervalBins)+1)
+		}
+		bin, _ := slices.BinarySearch(IntervalBins, at-prev)
+		s.Intervals[bin]++
+	}
+	s.last[pi] = at
+	s.recent = append(s.recent, at)
+	s.forget(at)
+}
+
+// Rate returns the triggers per second over the RateWindow up to now.
+func (s *Stats) Rate(now float64) float64 {
+	s.forget(now)
+	return float64(len(s.recent)) / RateWindow
+}
+
+// forget drops the recent triggers that are more than RateWindow before now.
+func (s *Stats) forget(now float64) {
+	i := 0
+	for i < len(s.recent) && s.recent[i] <= now-RateWindow {
+		i++
+	}
+	s.recent = s.recent[i:]
+}
+
+// insertPoint and removePoint keep the per-point counts in step with Points.
+func (s *Stats) insertPoint(idx int) {
+	if idx < len(s.Point) {
+		s.Point = slices.Insert(s.Point, idx, 0)
+	}
+	if idx < len(s.last) {
+		s.last = slices.Insert(s.last, idx, -1)
+	}
+}
+
+func (s *Stats) removePoint(idx int) {
+	if idx < len(s.Point) {
+		s.Point = slices.Delete(s.Point, idx, idx+1)
+	}
+	if idx < len(s.last) {
+		s.last = slices.Delete(s.last, idx, idx+1)
+	}
+}
+
+// truncateGrids drops the counts of families from index n on.
+func (s *Stats) truncateGrids(n int) {
+	if n < len(s.Grid) {
+		s.Grid = s.Grid[:n]
+	}
+}
+
+// grow extends s with fill values to at least length n.
+func grow[T any](s []T, n int, fill T) []T {
+	for len(s) < n {
+		s = append(s, fill)
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+// Swing delays every second subdivision of the beat, so even rhythms
+// shuffle. The subdivisions are the quantization's note value, or
+// swingNoteValue when triggers are not quantized, and are taken in pairs:
+// at a swing of 50% the second of a pair starts halfway, as played straight,
+// and at 66% it starts two thirds of the way in, a triplet shuffle. Time
+// within the pair is stretched to match, so a note never sounds earlier
+// than it would straight and unquantized notes keep their order.
+
+// MinSwing and MaxSwing bound the swing percentage; 0 means MinSwing.
+const (
+	MinSwing = 50.0
+	MaxSwing = 75.0
+)
+
+// SwingSteps are the swing percentages Alt+Z cycles through; 0 is straight.
+var SwingSteps = []float64{0, 54, 58, 62, 66, 71, 75}
+
+// swingNoteValue is the note value swung when triggers are not quantized.
+const swingNoteValue = 8
+
+// ValidateSwing reports a swing percentage out of range.
+func ValidateSwing(s float64) error {
+	if s == 0 {
+		return nil
+	}
+	if !(s >= MinSwing && s <= MaxSwing) {
+		return fmt.Errorf("swing %g%% out of range %g-%g%%", s, MinSwing, MaxSwing)
+	}
+	return nil
+}
+
+// NextSwing returns the step after s.
+func NextSwing(s float64) float64 {
+	for i, v := range SwingSteps {
+		if v == s {
+			return SwingSteps[(i+1)%len(SwingSteps)]
+		}
+	}
+	return 0
+}
+
+// SwingNoteValue returns the note value that is swung.
+func (e *Engine) SwingNoteValue() int {
+	if e.Quantize > 0 {
+		return e.Quantize
+	}
+	return swingNoteValue
+}
+
+// SwingTime returns when a note due at game time t sounds with the swing
+// applied, or t when it is off.
+func (e *Engine) SwingTime(t float64) float64 {
+	if e.Swing <= MinSwing || e.Clock.BPM <= 0 {
+		return t
+	}
+	step := e.noteSeconds(e.SwingNoteValue())
+	pos := e.beatSeconds(t)
+	pair := math.Floor(pos/(2*step)+quantizeEpsilon) * 2 * step
+	in := math.Max(0, pos-pair)
+	split := 2 * step * e.Swing / 100
+	var at float64
+	if in < step {
+		at = in * split / step
+	} else {
+		at = split + (in-step)*(2*step-split)/step
+	}
+	return t + math.Max(0, at-in)
+}
+
+// soundTime returns when a trigger detected at game time t sounds: quantized,
+// then swung.
+func (e *Engine) soundTime(t float64) float64 {
+	return e.SwingTime(e.QuantizeTime(t))
+}
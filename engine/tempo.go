@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"math"
+
+	"grythm/geom"
+)
+
+// Clock drives the pattern from a tempo instead of a raw speed. The pattern
+// travels BeatPixels along the movement direction every beat, so a family whose
+// normal is aligned with the movement and whose Spacing is BeatPixels/n is
+// crossed n times per beat.
+type Clock struct {
+	BPM         float64
+	BeatsPerBar int
+	BeatPixels  float64 // pattern travel per beat in pixels
+	Beats       float64 // elapsed beats since the clock started
+}
+
+const (
+	MinBPM = 20
+	MaxBPM = 400
+)
+
+// Speed returns the pattern speed in pixels per second for the current tempo.
+func (c *Clock) Speed() float64 {
+	return c.BPM / 60 * c.BeatPixels
+}
+
+// SetSpeed sets the tempo so that the pattern moves at speed pixels per second.
+func (c *Clock) SetSpeed(speed float64) {
+	c.SetBPM(speed / c.BeatPixels * 60)
+}
+
+// SetBPM sets the tempo, clamped to a playable range.
+func (c *Clock) SetBPM(bpm float64) {
+	c.BPM = math.Max(MinBPM, math.Min(MaxBPM, bpm))
+}
+
+// Advance moves the clock forward by dt seconds.
+func (c *Clock) Advance(dt float64) {
+	c.Beats += c.BPM / 60 * dt
+}
+
+// BeatPosition returns the beats since the start: the clock's in tempo mode,
+// otherwise the beats of the clock's BPM in the simulation time, the same
+// grid quantization holds triggers to.
+func (e *Engine) BeatPosition() float64 {
+	if e.TempoMode {
+		return e.Clock.Beats
+	}
+	return e.Time * e.Clock.BPM / 60
+}
+
+// BeatAt returns the beat position at simulation time t, near the current
+// time: the current position moved on at the clock's tempo.
+func (e *Engine) BeatAt(t float64) float64 {
+	return e.BeatPosition() + (t-e.Time)*e.Clock.BPM/60
+}
+
+// publishBeats publishes the beats the last tick, of dt seconds, passed;
+// from is the beat position it started at.
+func (e *Engine) publishBeats(from, dt float64) {
+	to := e.BeatPosition()
+	if len(e.Bus.beats) == 0 || to <= from {
+		return
+	}
+	bpb := max(e.Clock.BeatsPerBar, 1)
+	for n := math.Ceil(from); n < to; n++ {
+		// Beats are spread evenly over the tick, the tempo being fixed within it
+		beat := int(n)
+		e.Bus.PublishBeat(BeatEvent{
+			Beat:     beat,
+			Downbeat: beat%bpb == 0,
+			Time:     e.Time - (to-n)/(to-from)*dt,
+		})
+	}
+}
+
+// Position returns the 1-based bar number and the 1-based (fractional) beat within that bar.
+func (c *Clock) Position() (bar int, beat float64) {
+	bpb := float64(c.BeatsPerBar)
+	bar = int(math.Floor(c.Beats/bpb)) + 1
+	beat = math.Mod(c.Beats, bpb) + 1
+	return bar, beat
+}
+
+// BeatsPerLine returns how many beats pass between two consecutive line
+// crossings of gf when the family moves along dir at speed pixels per second.
+// It returns 0 if the family never crosses a fixed point with that motion.
+func (c *Clock) BeatsPerLine(gf geom.GridFamily, dir geom.Vec2, speed float64) float64 {
+	proj := math.Abs(gf.Normal.Dot(dir))
+	switch gf.Kind {
+	case geom.GridRay:
+		// Rays pass a point once per 1/Rays of a revolution, whatever the motion
+		if gf.AngularSpeed == 0 {
+			return 0
+		}
+		seconds := 360 / math.Abs(gf.AngularSpeed) / float64(gf.RayCount())
+		return seconds * c.BPM / 60
+	case geom.GridRadial:
+		// Rings expand at the full pattern speed
+		proj = 1
+	case geom.GridHex:
+		// Report the direction crossed most often
+		for _, l := range gf.HexLines() {
+			proj = math.Max(proj, math.Abs(l.Normal.Dot(dir)))
+		}
+	}
+	if proj == 0 || speed == 0 {
+		return 0
+	}
+	seconds := gf.Spacing / (proj * speed)
+	return seconds * c.BPM / 60
+}
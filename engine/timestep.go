@@ -0,0 +1,67 @@
+package engine
+
+import "time"
+
+// Timestep runs the simulation in fixed ticks, independent of how often Ebiten
+// calls Update. Each frame adds the real elapsed time to an accumulator that
+// is spent in whole ticks; Draw renders the pattern between the last two
+// ticks using the leftover fraction, so motion stays smooth and trigger timing
+// stays accurate when TPS changes or frames are dropped.
+type Timestep struct {
+	step float64   // seconds per tick
+	acc  float64   // elapsed time not yet simulated
+	last time.Time // wall time of the previous frame; zero before the first
+}
+
+// DefaultTickRate is the number of simulation ticks per second.
+const DefaultTickRate = 120
+
+// maxFrameSeconds caps the time a single frame can add, so a stall (a dragged
+// window, a breakpoint) doesn't trigger a long burst of catch-up ticks.
+const maxFrameSeconds = 0.25
+
+// NewTimestep creates a timestep running rate ticks per second.
+func NewTimestep(rate int) Timestep {
+	return Timestep{step: 1 / float64(rate)}
+}
+
+// Frame starts a new frame and returns the real time elapsed since the last one.
+func (t *Timestep) Frame() float64 {
+	now := time.Now()
+	dt := t.step
+	if !t.last.IsZero() {
+		dt = now.Sub(t.last).Seconds()
+	}
+	t.last = now
+	if dt > maxFrameSeconds {
+		dt = maxFrameSeconds
+	}
+	t.acc += dt
+	return dt
+}
+
+// FrameOf starts a new frame that lasted dt seconds, whatever the wall clock
+// says, e.g. to replay a recorded session tick for tick.
+func (t *Timestep) FrameOf(dt float64) {
+	t.last = time.Now()
+	t.acc += dt
+}
+
+// Next reports whether another tick is due, and consumes it.
+func (t *Timestep) Next() bool {
+	if t.acc < t.step {
+		return false
+	}
+	t.acc -= t.step
+	return true
+}
+
+// Step returns the length of a tick in seconds.
+func (t *Timestep) Step() float64 {
+	return t.step
+}
+
+// Alpha returns how far the current time lies between the last tick and the next (0-1).
+func (t *Timestep) Alpha() float64 {
+	return t.acc / t.step
+}
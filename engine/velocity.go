@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+)
+
+// Trigger velocity is derived from how fast a line sweeps across a point:
+// slow lines tap gently, fast lines hit hard.
+const (
+	// velocityRefSpeed is the crossing speed (px/s) that reaches full velocity.
+	velocityRefSpeed = 240.0
+	// minVelocity keeps very slow crossings audible.
+	minVelocity = 0.2
+)
+
+// velocityFromSpeed maps a crossing speed in pixels per second to a velocity in
+// [minVelocity, 1]. A square-root curve keeps mid speeds from sounding weak.
+func velocityFromSpeed(speed float64) float64 {
+	x := math.Min(1, math.Abs(speed)/velocityRefSpeed)
+	return minVelocity + (1-minVelocity)*math.Sqrt(x)
+}
+
+// Each point also has a velocity of its own that scales every note it plays,
+// so accents and soft notes can be placed in space: it reaches the synth
+// voices, MIDI and OSC through the trigger's velocity, and the host draws the
+// point larger or smaller by it.
+
+// MinPointVelocity and MaxPointVelocity bound a point's velocity; 1 leaves
+// its notes as the crossings make them.
+const (
+	MinPointVelocity = 0.1
+	MaxPointVelocity = 2.0
+)
+
+// ValidatePointVelocity reports a point velocity an editor could not have
+// set; 0 means 1.
+func ValidatePointVelocity(v float64) error {
+	if v != 0 && !(v >= MinPointVelocity && v <= MaxPointVelocity) {
+		return fmt.Errorf("point velocity %g out of range %g-%g", v, MinPointVelocity, MaxPointVelocity)
+	}
+	return nil
+}
+
+// VelocityScale returns the factor the point's notes are scaled by.
+func (p Point) VelocityScale() float64 {
+	if p.Velocity == 0 {
+		return 1
+	}
+	return p.Velocity
+}
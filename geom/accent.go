@@ -0,0 +1,56 @@
+package geom
+
+import "fmt"
+
+// Accents give a family a meter. Accent is a pattern of levels (0-1) that
+// runs over the family's lines in the order they are numbered (see
+// flash.go), e.g. [1, 0, 0] for strong, weak, weak: the lines of the strong
+// beats are drawn thicker and trigger at full velocity, the others softer.
+
+// accentWeakGain is the velocity factor of lines with accent level 0.
+const accentWeakGain = 0.55
+
+// accentWidth is how much wider a line of accent level 1 is drawn.
+const accentWidth = 1.5
+
+// ValidateAccent reports an accent pattern with levels outside 0-1.
+func ValidateAccent(pattern []float64) error {
+	for _, l := range pattern {
+		if l < 0 || l > 1 {
+			return fmt.Errorf("accent levels must be within 0-1")
+		}
+	}
+	return nil
+}
+
+// AccentEvery returns the pattern that accents every nth line; 0 is no accents.
+func AccentEvery(n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	pattern := make([]float64, n)
+	pattern[0] = 1
+	return pattern
+}
+
+// accentLevel returns the accent level of line key, or 0 without a pattern.
+func (gf *GridFamily) accentLevel(key LineKey) float64 {
+	n := len(gf.Accent)
+	if n == 0 {
+		return 0
+	}
+	return gf.Accent[(key.n%n+n)%n]
+}
+
+// AccentGain returns the velocity factor of the line touching p: 1 for a
+// fully accented line or a family without accents, less for weaker lines.
+func (gf *GridFamily) AccentGain(p, center Vec2) float64 {
+	if len(gf.Accent) == 0 {
+		return 1
+	}
+	key, ok := gf.LineAt(p, center)
+	if !ok {
+		return 1
+	}
+	return accentWeakGain + (1-accentWeakGain)*gf.accentLevel(key)
+}
@@ -0,0 +1,126 @@
+package geom
+
+import (
+	"fmt"
+	"math"
+)
+
+// Line width animation. A family's Animator makes its drawn lines swell and
+// shrink with the rhythm: an LFO breathes a number of times per beat, and a
+// decay swells the lines each time the family triggers and lets them settle.
+// Only the drawing is animated; the band points are triggered in (Thickness)
+// stays put, so the animation never changes what plays. The host works out
+// the level and sets it as Swell on the copies it draws.
+
+// AnimKind selects what drives an Animator.
+type AnimKind int
+
+const (
+	AnimLFO   AnimKind = iota // a sine at Rate cycles per beat
+	AnimDecay                 // a swell at every trigger that fades over Rate seconds
+)
+
+var animKindNames = []string{"lfo", "decay"}
+
+func (k AnimKind) String() string {
+	if k < 0 || int(k) >= len(animKindNames) {
+		return "unknown"
+	}
+	return animKindNames[k]
+}
+
+// MarshalText stores a kind by name.
+func (k AnimKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText parses a kind name.
+func (k *AnimKind) UnmarshalText(b []byte) error {
+	for i, n := range animKindNames {
+		if n == string(b) {
+			*k = AnimKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown animation %q", b)
+}
+
+// Animator animates the width of a family's lines.
+type Animator struct {
+	Kind  AnimKind `json:"kind"`
+	Depth float64  `json:"depth"` // extra width at the peak, in multiples of the normal width
+	Rate  float64  `json:"rate"`  // lfo: cycles per beat; decay: seconds to settle
+}
+
+// AnimatorPresets are the named animations, in the order an editor cycles through them.
+var AnimatorPresets = []struct {
+	Name string
+	Anim Animator
+}{
+	{"breathe", Animator{Kind: AnimLFO, Depth: 0.8, Rate: 0.25}},
+	{"throb", Animator{Kind: AnimLFO, Depth: 1, Rate: 1}},
+	{"pulse", Animator{Kind: AnimDecay, Depth: 1.5, Rate: 0.3}},
+	{"hit", Animator{Kind: AnimDecay, Depth: 3, Rate: 0.12}},
+}
+
+// AnimatorName returns the preset name of a, "off" for nil, or "custom".
+func AnimatorName(a *Animator) string {
+	if a == nil {
+		return "off"
+	}
+	for _, p := range AnimatorPresets {
+		if p.Anim == *a {
+			return p.Name
+		}
+	}
+	return "custom"
+}
+
+// NextAnimator returns the preset after a; the cycle ends with nil, no animation.
+func NextAnimator(a *Animator) *Animator {
+	idx := -1
+	if a != nil {
+		for i, p := range AnimatorPresets {
+			if p.Anim == *a {
+				idx = i
+				break
+			}
+		}
+	}
+	idx++
+	if idx >= len(AnimatorPresets) {
+		return nil
+	}
+	next := AnimatorPresets[idx].Anim
+	return &next
+}
+
+// Validate checks that the animation can be drawn.
+func (a Animator) Validate() error {
+	if a.Kind < 0 || int(a.Kind) >= len(animKindNames) {
+		return fmt.Errorf("unknown animation kind")
+	}
+	if a.Depth < 0 || a.Depth > 10 {
+		return fmt.Errorf("animation depth must be within 0-10")
+	}
+	if a.Rate <= 0 {
+		return fmt.Errorf("animation rate must be positive")
+	}
+	return nil
+}
+
+// Level returns the swell (0-1) at beat position beats, sinceHit seconds
+// after the family last triggered (negative if it never did).
+func (a Animator) Level(beats, sinceHit float64) float64 {
+	switch a.Kind {
+	case AnimLFO:
+		return 0.5 - 0.5*math.Cos(2*math.Pi*a.Rate*beats)
+	case AnimDecay:
+		if sinceHit < 0 || sinceHit >= a.Rate {
+			return 0
+		}
+		x := 1 - sinceHit/a.Rate
+		return x * x
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package geom
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Drawn width and trigger band. Thickness is half the width of the band a
+// family's lines trigger points in, in world pixels, so it grows and shrinks
+// with the zoom like the spacing does. Width is how wide its lines are drawn,
+// in window pixels, and changes nothing that plays: hairlines can trigger in
+// a wide band and bold lines in a narrow one. ShowBand draws the band as well,
+// as a translucent ribbon under the lines, to show where points will sound.
+
+const (
+	DefaultWidth = 1.5  // drawn width of lines whose Width is 0
+	MaxWidth     = 12.0 // widest lines a family can be drawn with
+	bandAlpha    = 0.2  // opacity of the band ribbon, relative to the family's
+)
+
+// WidthSteps are the widths an editor cycles through, 0 being DefaultWidth.
+var WidthSteps = []float64{0, 3, 6, 0.75}
+
+// NextWidth returns the step after width w; other values go back to the default.
+func NextWidth(w float64) float64 {
+	for i, s := range WidthSteps {
+		if s == w {
+			return WidthSteps[(i+1)%len(WidthSteps)]
+		}
+	}
+	return 0
+}
+
+// ValidateWidth reports a drawn width outside 0-MaxWidth.
+func ValidateWidth(w float64) error {
+	if w < 0 || w > MaxWidth {
+		return fmt.Errorf("width %g out of range 0-%g", w, MaxWidth)
+	}
+	return nil
+}
+
+// LineWidth returns the width its lines are drawn with before accents,
+// flashes and animation widen them.
+func (gf *GridFamily) LineWidth() float64 {
+	if gf.Width == 0 {
+		return DefaultWidth
+	}
+	return gf.Width
+}
+
+// drawBand draws the trigger bands of the lines visible through cam: the
+// lines again, as wide on screen as their band and faint.
+func (gf *GridFamily) drawBand(s *strokes, cam *Camera, center Vec2) {
+	if gf.Thickness <= 0 {
+		return
+	}
+	b := *gf
+	b.band = 2 * gf.Thickness * cam.Scale()
+	b.draw(s, cam, center)
+}
+
+// bandColor returns the color of the band ribbon: the family's, faded.
+func (gf *GridFamily) bandColor() color.Color {
+	alpha := bandAlpha
+	if gf.Alpha > 0 {
+		alpha *= gf.Alpha
+	}
+	r, g, b, a := gf.Color.RGBA()
+	scale := func(c uint32) uint8 { return uint8(float64(c) * alpha / 257) }
+	return color.RGBA{scale(r), scale(g), scale(b), scale(a)}
+}
@@ -0,0 +1,60 @@
+package geom
+
+import "math"
+
+// Camera maps world coordinates (where grids and points live) to the screen.
+// The default camera shows the world 1:1 with world (W/2, H/2) at the middle
+// of the window, which matches the original fixed layout.
+type Camera struct {
+	Center      Vec2    // world point shown at the middle of the screen
+	Zoom        float64 // window pixels per world pixel
+	Screen      Vec2    // screen size in device pixels
+	DeviceScale float64 // device pixels per window pixel, above 1 on high-DPI displays; 0 means 1
+}
+
+// Device returns the device pixels per window pixel.
+func (c *Camera) Device() float64 {
+	if c.DeviceScale <= 0 {
+		return 1
+	}
+	return c.DeviceScale
+}
+
+// Scale returns the device pixels per world pixel.
+func (c *Camera) Scale() float64 {
+	return c.Zoom * c.Device()
+}
+
+const (
+	minZoom = 0.1
+	maxZoom = 10
+)
+
+// ToScreen converts a world position to screen pixels.
+func (c *Camera) ToScreen(p Vec2) Vec2 {
+	return p.Sub(c.Center).Mul(c.Scale()).Add(c.Screen.Mul(0.5))
+}
+
+// ToWorld converts a screen position to world coordinates.
+func (c *Camera) ToWorld(p Vec2) Vec2 {
+	return p.Sub(c.Screen.Mul(0.5)).Mul(1 / c.Scale()).Add(c.Center)
+}
+
+// ViewRadius returns the radius (world pixels) of a circle around Center that covers the whole screen.
+func (c *Camera) ViewRadius() float64 {
+	return c.Screen.Len() / 2 / c.Scale()
+}
+
+// Pan moves the view by a screen-space delta, so the world follows the cursor.
+func (c *Camera) Pan(screenDelta Vec2) {
+	c.Center = c.Center.Sub(screenDelta.Mul(1 / c.Scale()))
+}
+
+// ZoomAt multiplies the zoom by factor, keeping the world point under the
+// screen position anchor fixed.
+func (c *Camera) ZoomAt(anchor Vec2, factor float64) {
+	before := c.ToWorld(anchor)
+	c.Zoom = math.Max(minZoom, math.Min(maxZoom, c.Zoom*factor))
+	after := c.ToWorld(anchor)
+	c.Center = c.Center.Add(before.Sub(after))
+}
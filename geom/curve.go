@@ -0,0 +1,283 @@
+package geom
+
+import (
+	"fmt"
+	"math"
+)
+
+// A curve family (GridCurve) is a linear family whose lines are chains of a
+// user-drawn cubic Bezier curve, the motif. Bezier holds its control points,
+// a start point and then two handles and an end point per segment, in the
+// frame of a line: X along it and Y across it, along Normal. The motif
+// repeats along each line every span of its end points in X, and the lines
+// repeat every Spacing as straight lines do. Motion along the normal slides
+// them; motion along the lines travels the motif along them (CurveShift),
+// like the wave of a wavy family. Hit testing finds the point of the curve
+// closest to a point numerically, and drawing flattens the curve finer the
+// further in the view is zoomed.
+
+const (
+	bezierSamples = 8    // coarse samples per segment the closest point is refined from
+	bezierRefine  = 4    // Newton steps refining it
+	flatTolerance = 0.25 // screen pixels a flattened piece may stray from the curve
+	maxFlatDepth  = 10   // halvings of a segment at most
+)
+
+// ValidateBezier reports control points that make no motif.
+func ValidateBezier(pts []Vec2) error {
+	if len(pts) < 4 || (len(pts)-1)%3 != 0 {
+		return fmt.Errorf("bezier needs a start point and 3 more per segment, not %d points", len(pts))
+	}
+	if pts[len(pts)-1].X <= pts[0].X {
+		return fmt.Errorf("bezier must end further along the line (x) than it starts")
+	}
+	return nil
+}
+
+// Curved reports whether the family has a usable motif; without one it
+// behaves like a linear family.
+func (gf *GridFamily) Curved() bool {
+	return ValidateBezier(gf.Bezier) == nil
+}
+
+// curvePeriod returns the distance along a line after which the motif repeats.
+func (gf *GridFamily) curvePeriod() float64 {
+	return gf.Bezier[len(gf.Bezier)-1].X - gf.Bezier[0].X
+}
+
+// bezierSegments returns the number of cubic segments of the motif.
+func (gf *GridFamily) bezierSegments() int {
+	return (len(gf.Bezier) - 1) / 3
+}
+
+// bezierAt returns the point at t (0-1) of motif segment i with its first
+// and second derivatives.
+func (gf *GridFamily) bezierAt(i int, t float64) (pos, d1, d2 Vec2) {
+	p := gf.Bezier[3*i : 3*i+4]
+	u := 1 - t
+	pos = p[0].Mul(u * u * u).Add(p[1].Mul(3 * u * u * t)).Add(p[2].Mul(3 * u * t * t)).Add(p[3].Mul(t * t * t))
+	d1 = p[1].Sub(p[0]).Mul(3 * u * u).Add(p[2].Sub(p[1]).Mul(6 * u * t)).Add(p[3].Sub(p[2]).Mul(3 * t * t))
+	d2 = p[2].Sub(p[1].Mul(2)).Add(p[0]).Mul(6 * u).Add(p[3].Sub(p[2].Mul(2)).Add(p[1]).Mul(6 * t))
+	return pos, d1, d2
+}
+
+// bezierBounds returns the corners of a box the motif stays in: that of its
+// control points.
+func (gf *GridFamily) bezierBounds() (lo, hi Vec2) {
+	lo, hi = gf.Bezier[0], gf.Bezier[0]
+	for _, p := range gf.Bezier[1:] {
+		lo = Vec2{math.Min(lo.X, p.X), math.Min(lo.Y, p.Y)}
+		hi = Vec2{math.Max(hi.X, p.X), math.Max(hi.Y, p.Y)}
+	}
+	return lo, hi
+}
+
+// BezierReach returns how far across a line its motif reaches to either side.
+func (gf *GridFamily) BezierReach() float64 {
+	lo, hi := gf.bezierBounds()
+	return math.Max(math.Abs(lo.Y), math.Abs(hi.Y))
+}
+
+// nearestBezier returns the point of the motif closest to q, both in the
+// frame of a line, the curve's direction there (not normalized) and the
+// distance. Each segment is sampled coarsely and the best sample refined
+// with Newton steps on the squared distance.
+func (gf *GridFamily) nearestBezier(q Vec2) (pos, dir Vec2, dist float64) {
+	dist = math.Inf(1)
+	for i := range gf.bezierSegments() {
+		t, best := 0.0, math.Inf(1)
+		for j := 0; j <= bezierSamples; j++ {
+			s := float64(j) / bezierSamples
+			if p, _, _ := gf.bezierAt(i, s); p.Sub(q).Len() < best {
+				t, best = s, p.Sub(q).Len()
+			}
+		}
+		for range bezierRefine {
+			p, d1, d2 := gf.bezierAt(i, t)
+			r := p.Sub(q)
+			// Gradient and curvature of half the squared distance
+			h, h1 := r.Dot(d1), d1.Dot(d1)+r.Dot(d2)
+			if h1 <= 0 {
+				break
+			}
+			t = math.Max(0, math.Min(1, t-h/h1))
+		}
+		if p, d1, _ := gf.bezierAt(i, t); p.Sub(q).Len() < dist {
+			pos, dir, dist = p, d1, p.Sub(q).Len()
+		}
+	}
+	return pos, dir, dist
+}
+
+// curveFoot is the point of a curve family's line closest to a point.
+type curveFoot struct {
+	k    float64 // the line, numbered as straight lines are
+	s    float64 // where the foot lies along the line, from the family's anchor
+	dist float64 // how far the point is from it
+	side float64 // the signed distance: positive on the Normal side of the curve
+	dir  Vec2    // unit direction of the curve at the foot, in the world
+}
+
+// curveFeet calls fn with the closest point of every repeat of the motif
+// that comes within reach of p, until fn returns true. center is the world
+// anchor of the family.
+func (gf *GridFamily) curveFeet(p, center Vec2, reach float64, fn func(f curveFoot) bool) {
+	n, t := gf.Normal, gf.Normal.Perp()
+	u, along := n.Dot(p.Sub(center)), t.Dot(p.Sub(center))-gf.CurveShift
+	lo, hi := gf.bezierBounds()
+	period := gf.curvePeriod()
+	for k := math.Floor((u - hi.Y - reach - gf.Offset) / gf.Spacing); k <= math.Ceil((u-lo.Y+reach-gf.Offset)/gf.Spacing); k++ {
+		d := k*gf.Spacing + gf.Offset
+		for j := math.Floor((along - hi.X - reach) / period); j <= math.Ceil((along-lo.X+reach)/period); j++ {
+			x0 := j * period
+			q := Vec2{along - x0, u - d}
+			pos, dir, dist := gf.nearestBezier(q)
+			if dist > reach {
+				continue
+			}
+			side := dist
+			if dir.Cross(q.Sub(pos)) < 0 {
+				side = -dist
+			}
+			f := curveFoot{k: k, s: pos.X + x0 + gf.CurveShift, dist: dist, side: side, dir: t.Mul(dir.X).Add(n.Mul(dir.Y)).Norm()}
+			if fn(f) {
+				return
+			}
+		}
+	}
+}
+
+// nearestFoot returns the closest point of the family's lines to p.
+func (gf *GridFamily) nearestFoot(p, center Vec2) curveFoot {
+	best := curveFoot{dist: math.Inf(1)}
+	// The nearest line is never further than a spacing and a repeat away
+	gf.curveFeet(p, center, gf.Spacing+gf.curvePeriod(), func(f curveFoot) bool {
+		if f.dist < best.dist {
+			best = f
+		}
+		return false
+	})
+	return best
+}
+
+// curveLine returns the index k of a drawn curved line touching p.
+func (gf *GridFamily) curveLine(p, center Vec2) (k float64, ok bool) {
+	gf.curveFeet(p, center, gf.Thickness, func(f curveFoot) bool {
+		// Dashes are laid out along the line, as for straight lines
+		if !gf.dashed() || gf.inDash(gf.patternPos(f.s)) {
+			k, ok = f.k, true
+		}
+		return ok
+	})
+	return k, ok
+}
+
+// touchesCurve reports whether p lies within the thickness band of a drawn
+// part of a curved line. center is the world anchor of the family.
+func (gf *GridFamily) touchesCurve(p, center Vec2) bool {
+	_, ok := gf.curveLine(p, center)
+	return ok
+}
+
+// curveCrossingSpeed is the speed at which the curved line nearest p passes
+// it: the component of vel across the curve there.
+func (gf *GridFamily) curveCrossingSpeed(p, center, vel Vec2) float64 {
+	return math.Abs(gf.nearestFoot(p, center).dir.Perp().Dot(vel))
+}
+
+// alignCurve slides the lines along the normal until the nearest repeat of
+// the curve passes through p, refining the offset with Newton steps on the
+// signed distance.
+func (gf *GridFamily) alignCurve(p, center Vec2) {
+	for range bezierRefine {
+		f := gf.nearestFoot(p, center)
+		// How much of a slide along the normal moves the curve across p
+		c := f.dir.Dot(gf.Normal.Perp())
+		if f.dist < 1e-9 || math.Abs(c) < 0.1 {
+			return
+		}
+		gf.Offset += f.side / c
+	}
+}
+
+// advanceBezier travels the motif along the lines by the tangential part of
+// step.
+func (gf *GridFamily) advanceBezier(step Vec2) {
+	if !gf.Curved() {
+		return
+	}
+	gf.CurveShift = math.Mod(gf.CurveShift+gf.Normal.Perp().Dot(step), gf.curvePeriod())
+}
+
+// drawCurve renders the repeats of the motif in view, each segment
+// flattened to within flatTolerance screen pixels.
+func (gf *GridFamily) drawCurve(s *strokes, cam *Camera, center Vec2) {
+	n, t := gf.Normal, gf.Normal.Perp()
+	view := cam.Center
+	R := cam.ViewRadius()
+	lo, hi := gf.bezierBounds()
+	period := gf.curvePeriod()
+	dView := n.Dot(view.Sub(center))
+	sView := t.Dot(view.Sub(center)) - gf.CurveShift
+	kMin := math.Floor((dView - R - hi.Y - gf.Offset) / gf.Spacing)
+	kMax := math.Ceil((dView + R - lo.Y - gf.Offset) / gf.Spacing)
+	jMin := math.Floor((sView - R - hi.X) / period)
+	jMax := math.Ceil((sView + R - lo.X) / period)
+	tol := flatTolerance / cam.Scale()
+	var pts []Vec2
+	for k := kMin; k <= kMax; k++ {
+		d := k*gf.Spacing + gf.Offset
+		width, col := gf.lineStyle(gf.lineKeyAt(d))
+		for j := jMin; j <= jMax; j++ {
+			at := center.Add(t.Mul(j*period + gf.CurveShift)).Add(n.Mul(d))
+			world := func(v Vec2) Vec2 { return at.Add(t.Mul(v.X)).Add(n.Mul(v.Y)) }
+			for i := range gf.bezierSegments() {
+				c := gf.Bezier[3*i : 3*i+4]
+				seg := [4]Vec2{world(c[0]), world(c[1]), world(c[2]), world(c[3])}
+				pts = flattenBezier(append(pts[:0], seg[0]), seg, tol, 0)
+				for m := 1; m < len(pts); m++ {
+					// Dashes are decided per piece so drawing matches the hit test
+					mid := pts[m-1].Add(pts[m]).Mul(0.5)
+					if !gf.dashed() || gf.inDash(gf.patternPos(t.Dot(mid.Sub(center)))) {
+						s.line(cam.ToScreen(pts[m-1]), cam.ToScreen(pts[m]), width, col)
+					}
+				}
+			}
+		}
+	}
+}
+
+// flattenBezier appends to out the points after the first of the cubic
+// Bezier segment p, halving it until every piece strays less than tol from
+// its chord.
+func flattenBezier(out []Vec2, p [4]Vec2, tol float64, depth int) []Vec2 {
+	if depth >= maxFlatDepth || bezierFlat(p, tol) {
+		return append(out, p[3])
+	}
+	a, b := splitBezier(p)
+	out = flattenBezier(out, a, tol, depth+1)
+	return flattenBezier(out, b, tol, depth+1)
+}
+
+// bezierFlat reports whether the handles of segment p lie within tol of its
+// chord, so the chord can stand in for it.
+func bezierFlat(p [4]Vec2, tol float64) bool {
+	c := p[3].Sub(p[0])
+	l := c.Len()
+	off := func(q Vec2) float64 {
+		if l == 0 {
+			return q.Sub(p[0]).Len()
+		}
+		return math.Abs(c.Cross(q.Sub(p[0]))) / l
+	}
+	return math.Max(off(p[1]), off(p[2])) <= tol
+}
+
+// splitBezier halves segment p (de Casteljau).
+func splitBezier(p [4]Vec2) (a, b [4]Vec2) {
+	mid := func(x, y Vec2) Vec2 { return x.Add(y).Mul(0.5) }
+	p01, p12, p23 := mid(p[0], p[1]), mid(p[1], p[2]), mid(p[2], p[3])
+	p012, p123 := mid(p01, p12), mid(p12, p23)
+	m := mid(p012, p123)
+	return [4]Vec2{p[0], p01, p012, m}, [4]Vec2{m, p123, p23, p[3]}
+}
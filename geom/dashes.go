@@ -0,0 +1,138 @@
+package geom
+
+import "math"
+
+// Dash patterns: a family's lines are either solid, dashed with one dash and
+// gap length (DashLength/GapLength), or follow a DashPattern of alternating
+// dash and gap lengths, e.g. generated from a Euclidean rhythm.
+
+// EuclidSpec describes a dash pattern of Pulses dashes spread as evenly as
+// possible over Steps steps, each step as long as the family's spacing.
+type EuclidSpec struct {
+	Pulses int     `json:"pulses"`
+	Steps  int     `json:"steps"`
+	Duty   float64 `json:"duty,omitempty"` // fraction of a step drawn per pulse; 0 means 0.5
+}
+
+// EuclideanDashes returns the dash pattern for k pulses over n steps of the
+// given length: each pulse is a dash of duty*step followed by a gap up to the
+// next pulse. It returns nil if there are no pulses.
+func EuclideanDashes(k, n int, step, duty float64) []float64 {
+	if duty <= 0 || duty >= 1 {
+		duty = 0.5
+	}
+	var pulses []int
+	for i, on := range EuclidRhythm(k, n) {
+		if on {
+			pulses = append(pulses, i)
+		}
+	}
+	if len(pulses) == 0 || step <= 0 {
+		return nil
+	}
+	pattern := make([]float64, 0, 2*len(pulses))
+	for i, p := range pulses {
+		next := n // wraps to the first pulse of the next cycle, which is step 0
+		if i+1 < len(pulses) {
+			next = pulses[i+1]
+		}
+		dash := duty * step
+		pattern = append(pattern, dash, float64(next-p)*step-dash)
+	}
+	return pattern
+}
+
+// ApplyEuclid regenerates the family's dash pattern from its Euclidean spec.
+func (gf *GridFamily) ApplyEuclid() {
+	if e := gf.Euclid; e != nil {
+		gf.DashPattern = EuclideanDashes(e.Pulses, e.Steps, gf.Spacing, e.Duty)
+	}
+}
+
+// DashSegments returns the family's dash pattern as alternating dash and gap
+// lengths, or nil for solid lines.
+func (gf *GridFamily) DashSegments() []float64 {
+	if len(gf.DashPattern) >= 2 {
+		return gf.DashPattern
+	}
+	if gf.DashLength > 0 && gf.GapLength > 0 {
+		return []float64{gf.DashLength, gf.GapLength}
+	}
+	return nil
+}
+
+// PatternPeriod returns the total length of a dash pattern.
+func PatternPeriod(pattern []float64) float64 {
+	var sum float64
+	for _, l := range pattern {
+		sum += l
+	}
+	return sum
+}
+
+// patternInDash reports whether pos falls on a dash of pattern.
+func patternInDash(pattern []float64, pos float64) bool {
+	period := PatternPeriod(pattern)
+	if period <= 0 {
+		return true
+	}
+	m := math.Mod(math.Mod(pos, period)+period, period)
+	for i, l := range pattern {
+		if m < l {
+			return i%2 == 0
+		}
+		m -= l
+	}
+	return false
+}
+
+// patternSpans calls fn for each dash of pattern overlapping [from, to), with
+// pattern position phase at from. Spans are clipped to the range and given
+// relative to from.
+func patternSpans(pattern []float64, phase, length float64, fn func(a, b float64)) {
+	period := PatternPeriod(pattern)
+	if period <= 0 {
+		return
+	}
+	// Start at the pattern cycle that contains (or precedes) from
+	pos := -math.Mod(math.Mod(phase, period)+period, period)
+	for pos < length {
+		for i, l := range pattern {
+			if i%2 == 0 {
+				a, b := math.Max(pos, 0), math.Min(pos+l, length)
+				if b > a {
+					fn(a, b)
+				}
+			}
+			pos += l
+		}
+	}
+}
+
+// scalePattern returns pattern with every length multiplied by z.
+func scalePattern(pattern []float64, z float64) []float64 {
+	if pattern == nil {
+		return nil
+	}
+	out := make([]float64, len(pattern))
+	for i, l := range pattern {
+		out[i] = l * z
+	}
+	return out
+}
+
+// EuclidRhythm distributes k pulses as evenly as possible over n steps (Bjorklund's
+// pattern, computed with the equivalent Bresenham formulation), starting on a pulse.
+func EuclidRhythm(k, n int) []bool {
+	out := make([]bool, n)
+	if n <= 0 || k <= 0 {
+		return out
+	}
+	if k > n {
+		k = n
+	}
+	for i := 0; i < n; i++ {
+		out[i] = (i*k)%n < k
+	}
+	return out
+}
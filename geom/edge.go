@@ -0,0 +1,98 @@
+package geom
+
+import (
+	"fmt"
+	"math"
+)
+
+// TriggerEdge is the moment a family's line triggers a point it passes.
+type TriggerEdge int
+
+const (
+	EdgeEnter  TriggerEdge = iota // the point enters the thickness band
+	EdgeExit                      // the point leaves the band
+	EdgeCenter                    // the middle of the line passes the point
+	EdgeBoth                      // the point enters the band and leaves it
+)
+
+var edgeNames = []string{"enter", "exit", "center", "both"}
+
+func (e TriggerEdge) String() string {
+	if e < 0 || int(e) >= len(edgeNames) {
+		return "unknown"
+	}
+	return edgeNames[e]
+}
+
+// Next returns the following edge, wrapping around.
+func (e TriggerEdge) Next() TriggerEdge {
+	return (e + 1) % TriggerEdge(len(edgeNames))
+}
+
+// Entering reports whether points trigger as they enter the band.
+func (e TriggerEdge) Entering() bool {
+	return e == EdgeEnter || e == EdgeBoth
+}
+
+// Leaving reports whether points trigger as they leave the band.
+func (e TriggerEdge) Leaving() bool {
+	return e == EdgeExit || e == EdgeBoth
+}
+
+// MarshalText stores an edge by name.
+func (e TriggerEdge) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText parses an edge name.
+func (e *TriggerEdge) UnmarshalText(text []byte) error {
+	for i, n := range edgeNames {
+		if n == string(text) {
+			*e = TriggerEdge(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown trigger edge %q", text)
+}
+
+// SignedDistance returns how far p is from the middle of the nearest line of
+// the family, positive on the side its normal points to: outward for rings,
+// clockwise for rays. Passing the middle of a line flips the sign. center is
+// the world anchor of linear families.
+func (gf *GridFamily) SignedDistance(p, center Vec2) float64 {
+	switch gf.Kind {
+	case GridRadial:
+		r := p.Sub(gf.Origin).Len()
+		k := math.Max(0, math.Round((r-gf.Offset)/gf.Spacing))
+		return r - (k*gf.Spacing + gf.Offset)
+	case GridRay:
+		rel := p.Sub(gf.Origin)
+		a := math.Atan2(rel.Y, rel.X)
+		diff := math.Remainder(a-gf.nearestRay(a), 2*math.Pi)
+		return rel.Len() * math.Sin(diff)
+	case GridHex:
+		best := math.Inf(1)
+		for _, l := range gf.HexLines() {
+			if d := l.SignedDistance(p, center); math.Abs(d) < math.Abs(best) {
+				best = d
+			}
+		}
+		return best
+	case GridWavy:
+		if gf.Wavy() {
+			n, t := gf.Normal, gf.Normal.Perp()
+			u, s0 := n.Dot(p.Sub(center)), t.Dot(p.Sub(center))
+			a := math.Abs(gf.Amplitude)
+			best := math.Inf(1)
+			for k := math.Floor((u - a - gf.Offset) / gf.Spacing); k <= math.Ceil((u+a-gf.Offset)/gf.Spacing); k++ {
+				d := k*gf.Spacing + gf.Offset
+				s, dist := gf.nearestCurve(s0, u, d)
+				if dist < math.Abs(best) {
+					best = math.Copysign(dist, u-d-gf.curve(s))
+				}
+			}
+			return best
+		}
+	}
+	return gf.Normal.Dot(p.Sub(center)) - gf.nearestLine(p, center)
+}
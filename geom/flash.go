@@ -0,0 +1,132 @@
+package geom
+
+import (
+	"image/color"
+	"math"
+)
+
+// Line identity for onset flashes. When a line touches a point, that line
+// (not the whole family) can be drawn brighter and thicker for a moment. Lines
+// are told apart by a LineKey that stays the same while the family moves:
+// Offset wraps back by one Spacing every time a line passes, so each family
+// counts its wraps and the key subtracts them from the line index k.
+
+// LineKey identifies one line of a family: set is the direction within a hex
+// family (0 otherwise), n the line number, stable under motion.
+type LineKey struct {
+	set, n int
+}
+
+// LineFlash is a recently triggered line and its brightness (0-1).
+type LineFlash struct {
+	Key   LineKey
+	Level float64
+}
+
+// lineKeyAt returns the key of the straight line, ring or wavy line at offset d
+// (k*Spacing + Offset).
+func (gf *GridFamily) lineKeyAt(d float64) LineKey {
+	if gf.hexDir > 0 {
+		// A line of a hex family, numbered on the lattice spacing so both
+		// honeycomb parities share one numbering
+		unit := gf.Spacing
+		if gf.HexTiling {
+			unit /= 2
+		}
+		return LineKey{gf.hexDir - 1, int(math.Round((d - gf.hexBase) / unit))}
+	}
+	return LineKey{0, int(math.Round((d-gf.Offset)/gf.Spacing)) - gf.Wraps}
+}
+
+// LineAt returns the key of the line touching p. It assumes Touches(p) holds.
+func (gf *GridFamily) LineAt(p, center Vec2) (LineKey, bool) {
+	switch gf.Kind {
+	case GridRay:
+		rel := p.Sub(gf.Origin)
+		if rel.Len() <= gf.Thickness {
+			// At the origin all rays touch; there is no single one to flash
+			return LineKey{}, false
+		}
+		n := gf.RayCount()
+		i := int(math.Round((gf.nearestRay(math.Atan2(rel.Y, rel.X)) - gf.Angle) / (2 * math.Pi / float64(n))))
+		return LineKey{0, ((i % n) + n) % n}, true
+	case GridRadial:
+		k := math.Max(0, math.Round((p.Sub(gf.Origin).Len()-gf.Offset)/gf.Spacing))
+		return gf.lineKeyAt(k*gf.Spacing + gf.Offset), true
+	case GridHex:
+		for _, l := range gf.HexLines() {
+			if l.Touches(p, center) {
+				return l.LineAt(p, center)
+			}
+		}
+		return LineKey{}, false
+	case GridWavy:
+		if gf.Wavy() {
+			k, ok := gf.wavyLine(p, center)
+			return gf.lineKeyAt(k*gf.Spacing + gf.Offset), ok
+		}
+	case GridCurve:
+		if gf.Curved() {
+			k, ok := gf.curveLine(p, center)
+			return gf.lineKeyAt(k*gf.Spacing + gf.Offset), ok
+		}
+	}
+	k := math.Round((gf.Normal.Dot(p.Sub(center)) - gf.Offset) / gf.Spacing)
+	return gf.lineKeyAt(k*gf.Spacing + gf.Offset), true
+}
+
+// Along returns where p lies along the line of the family touching it: the
+// distance along a straight line, the angle around a ring or the distance
+// out along a ray.
+func (gf *GridFamily) Along(p, center Vec2) float64 {
+	switch gf.Kind {
+	case GridRay:
+		return p.Sub(gf.Origin).Len()
+	case GridRadial:
+		return arcAngle(p.Sub(gf.Origin))
+	case GridHex:
+		for _, l := range gf.HexLines() {
+			if l.Touches(p, center) {
+				return l.Along(p, center)
+			}
+		}
+	}
+	return gf.Normal.Perp().Dot(p.Sub(center))
+}
+
+// flashLevel returns how brightly line key is flashing (0 when it isn't).
+func (gf *GridFamily) flashLevel(key LineKey) float64 {
+	for _, f := range gf.Flash {
+		if f.Key == key {
+			return f.Level
+		}
+	}
+	return 0
+}
+
+// lineStyle returns the width and color to draw line key with, thicker for
+// accented lines and as the family's animation swells, brighter while the
+// line flashes, faded by its opacity. Trigger bands are drawn plain.
+func (gf *GridFamily) lineStyle(key LineKey) (float64, color.Color) {
+	if gf.band > 0 {
+		return gf.band, gf.bandColor()
+	}
+	width := gf.LineWidth() + accentWidth*gf.accentLevel(key)
+	if gf.Anim != nil {
+		width *= 1 + gf.Anim.Depth*gf.Swell
+	}
+	level := gf.flashLevel(key)
+	// The family's opacity scales the premultiplied color as a whole
+	alpha := 1.0
+	if gf.Alpha > 0 {
+		alpha = gf.Alpha
+	}
+	if level <= 0 && alpha == 1 {
+		return width, gf.Color
+	}
+	r, g, b, a := gf.Color.RGBA()
+	mix := func(c uint32) uint8 {
+		return uint8((float64(c) + (float64(a)-float64(c))*level*0.7) * alpha / 257)
+	}
+	return width + 2.5*level, color.RGBA{mix(r), mix(g), mix(b), uint8(float64(a) * alpha / 257)}
+}
@@ -0,0 +1,20 @@
+package geom
+
+// Solid returns a copy of the family without dashes, whose lines run through
+// its dashes and gaps alike.
+func (gf *GridFamily) Solid() GridFamily {
+	s := *gf
+	s.DashLength, s.GapLength, s.DashPattern, s.Euclid = 0, 0, nil, nil
+	return s
+}
+
+// InGap reports whether p lies within the thickness band of a line of a
+// dashed family, but in a gap between its dashes. center is the world anchor
+// of linear families.
+func (gf *GridFamily) InGap(p, center Vec2) bool {
+	if !gf.dashed() || gf.Touches(p, center) {
+		return false
+	}
+	s := gf.Solid()
+	return s.Touches(p, center)
+}
@@ -0,0 +1,632 @@
+// Package geom holds the geometry of grythm: vectors, the camera and the
+// families of grid lines (linear, radial, hex, wavy, ray and curve), with their
+// motion, hit testing and drawing.
+package geom
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"grythm/synth"
+)
+
+// GridKind selects the geometry of a grid family's lines.
+type GridKind int
+
+const (
+	// GridLinear is a family of infinite parallel lines.
+	GridLinear GridKind = iota
+	// GridRadial is a family of concentric circles around Origin that ripple outward.
+	GridRadial
+	// GridHex is a lattice of three line directions at 60° sharing one Spacing.
+	GridHex
+	// GridWavy is a family of parallel sine curves (see wavy.go).
+	GridWavy
+	// GridRay is a set of rays from Origin rotating like clock hands (see ray.go).
+	GridRay
+	// GridCurve is a family of parallel lines drawn as a repeating Bezier curve (see curve.go).
+	GridCurve
+)
+
+var gridKindNames = []string{"linear", "radial", "hex", "wavy", "ray", "curve"}
+
+// MarshalText stores a grid kind by name.
+func (k GridKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(gridKindNames) {
+		return nil, fmt.Errorf("unknown grid kind %d", k)
+	}
+	return []byte(gridKindNames[k]), nil
+}
+
+// UnmarshalText parses a grid kind name.
+func (k *GridKind) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*k = GridLinear
+		return nil
+	}
+	for i, n := range gridKindNames {
+		if n == string(b) {
+			*k = GridKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown grid kind %q", b)
+}
+
+// GridFamily represents a family of infinite parallel grid lines.
+// Each line satisfies n·(x - center) = k*Spacing + Offset for some integer k.
+// Radial families instead consist of circles |x - Origin| = k*Spacing + Offset for k >= 0,
+// hex families of three such line sets rotated by 60° (see hex.go), and wavy
+// families of lines displaced by a sine curve (see wavy.go) and curve families
+// of lines drawn as a repeating Bezier curve (see curve.go). Ray families
+// rotate around Origin instead (see ray.go).
+type GridFamily struct {
+	Kind         GridKind
+	Normal       Vec2    // must be normalized; unused for radial families
+	Origin       Vec2    // center of the circles of a radial family, or of the rays of a ray family (world pixels)
+	Spacing      float64 // pixels between lines
+	Offset       float64 // pixels along normal from center (radial: pixels outward from Origin)
+	Color        color.Color
+	Thickness    float64         // half-width of the band its lines trigger points in (world pixels, see band.go)
+	Width        float64         // drawn width of its lines in window pixels; 0 means DefaultWidth
+	ShowBand     bool            // its trigger band is drawn as a translucent ribbon under the lines
+	DashLength   float64         // length of drawn segment in pixels; 0 means solid
+	GapLength    float64         // length of gap between segments in pixels; 0 means solid
+	DashPhase    float64         // accumulated shift along tangent (pixels) to scroll dash pattern
+	DashOffset   float64         // static phase offset (pixels) applied to the first dash/gap; does not change with motion
+	Wraps        int             // number of Spacings Offset has wrapped back by (animated, see flash.go)
+	DashPattern  []float64       // alternating dash and gap lengths (pixels); overrides DashLength/GapLength when set
+	Euclid       *EuclidSpec     // generates DashPattern from a Euclidean rhythm over Spacing-long steps
+	GapTrigger   bool            // points coming into its gaps trigger too, as a quieter second voice (see gap.go)
+	Wave         synth.Waveform  // oscillator shape for points that don't choose their own; WaveInherit means sine
+	Degree       int             // transposition in scale degrees added to the pitch of points it triggers
+	Motion       *Motion         // independent motion; nil follows the global direction and speed
+	Sample       string          // optional WAV/OGG file for points without their own sample
+	Env          *synth.Envelope // amplitude envelope for points without their own; nil is the classic blip
+	Sends        *synth.Sends    // effect send levels of the voices it triggers; nil uses the global default
+	Filter       *synth.Filter   // filter of the synth voices it triggers; nil leaves them unfiltered
+	Glide        *synth.Glide    // pitch glide of the synth voices it triggers; nil bends only the blip
+	FM           *synth.FM       // FM voice for points without their own, instead of Wave; nil is off
+	Drum         synth.Drum      // percussion the points it triggers play instead of their pitch; none plays pitched notes
+	HexTiling    bool            // hex families: draw and trigger on the honeycomb instead of the full lattice
+	Shift        Vec2            // hex families: accumulated displacement of the lattice (animated)
+	HexWraps     [3]int          // hex families: lattice lines each direction has wrapped back by (animated, see flash.go)
+	Amplitude    float64         // wavy families: curve amplitude in pixels
+	Wavelength   float64         // wavy families: curve wavelength in pixels along the line
+	CurvePhase   float64         // wavy families: static phase of the curve (radians)
+	CurveShift   float64         // wavy and curve families: accumulated travel of the curve along the line (animated)
+	Bezier       []Vec2          // curve families: control points of the repeating curve, X along the line and Y along Normal
+	Rays         int             // ray families: number of evenly spaced rays
+	AngularSpeed float64         // ray families: rotation speed in degrees per second (positive is clockwise on screen)
+	Angle        float64         // ray families: current angle of the first ray in radians (animated)
+	Chance       float64         // probability (0-1) that a crossing sounds; 0 means always
+	Cooldown     float64         // least milliseconds between its triggers of the same point; 0 is none
+	Length       float64         // note length in seconds of the notes it triggers; 0 uses the envelope's
+	Retrigger    synth.Retrigger // cut or overlap a point's sounding note; inherit means overlap
+	Edge         TriggerEdge     // when its lines trigger the points they pass (see edge.go); enter by default
+	Accent       []float64       // accent level (0-1) of successive lines, repeating (see accent.go); nil accents none
+	Alpha        float64         // opacity (0-1) of its lines; 0 means opaque
+	Blend        BlendMode       // how its lines combine with what is under them (see strokes.go)
+	Paused       bool            // its motion is stopped (see transport.go)
+	Rate         float64         // multiplier of its motion speed; negative runs it backwards, 0 means 1
+	Mute         bool            // its notes are silenced (see mix.go)
+	Solo         bool            // only soloed families sound while any is
+	Volume       float64         // level of its notes, up to MaxVolume; 0 means 1
+	Layer        int             // layer it is turned, moved and retimed with, from 1 (see layer.go); 0 is none
+	Anim         *Animator       // animation of its drawn width (see animator.go); nil keeps it steady
+	ID           string          // name a scene file refers to it by; "" until it is saved
+
+	// Flash holds recently triggered lines to highlight, Ripples the pulses
+	// running along them (see ripple.go) and Swell the level (0-1) of the
+	// width animation; set on the copies that are drawn
+	Flash   []LineFlash
+	Ripples []LineRipple
+	Swell   float64
+
+	// Derived state of the line sets of a hex family
+	hexDir  int     // 1 + direction of a line set derived from a hex family; 0 otherwise
+	hexBase float64 // offset of line 0 of that direction, for numbering its lines
+
+	// band is the window width of the trigger band on the copy that draws it
+	// (see band.go) instead of the lines; 0 otherwise
+	band float64
+}
+
+// Motion is a direction and speed of travel for a moving pattern.
+type Motion struct {
+	Dir   Vec2    `json:"dir"`   // normalized direction
+	Speed float64 `json:"speed"` // pixels per second
+}
+
+// KindName returns a short label for the family's geometry.
+func (gf *GridFamily) KindName() string {
+	if gf.Kind < 0 || int(gf.Kind) >= len(gridKindNames) {
+		return "unknown"
+	}
+	return gridKindNames[gf.Kind]
+}
+
+// Clone returns a copy of the family that shares no slices or settings
+// with it, so either can be edited alone. Flashes, ripples and swell are
+// not copied.
+func (gf *GridFamily) Clone() GridFamily {
+	c := *gf
+	c.DashPattern = slices.Clone(gf.DashPattern)
+	c.Accent = slices.Clone(gf.Accent)
+	c.Bezier = slices.Clone(gf.Bezier)
+	c.Flash, c.Ripples, c.Swell = nil, nil, 0
+	c.Euclid = clonePtr(gf.Euclid)
+	c.Motion = clonePtr(gf.Motion)
+	c.Env = clonePtr(gf.Env)
+	c.Sends = clonePtr(gf.Sends)
+	c.Filter = clonePtr(gf.Filter)
+	c.Glide = clonePtr(gf.Glide)
+	c.FM = clonePtr(gf.FM)
+	c.Anim = clonePtr(gf.Anim)
+	return c
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil.
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+// dashed reports whether the family draws dashes rather than solid lines.
+func (gf *GridFamily) dashed() bool {
+	return gf.DashSegments() != nil
+}
+
+// inDash reports whether position pos along a line falls on a dash rather than a gap.
+func (gf *GridFamily) inDash(pos float64) bool {
+	return patternInDash(gf.DashSegments(), pos)
+}
+
+// Advance moves the family by the pattern displacement step.
+func (gf *GridFamily) Advance(step Vec2) {
+	switch gf.Kind {
+	case GridHex:
+		gf.advanceHex(step)
+		return
+	case GridRay:
+		// Rays turn on their own (see rotate) and don't follow the pattern motion
+		return
+	case GridRadial:
+		// Rings expand outward at the pattern speed, regardless of direction
+		gf.Offset += step.Len()
+	default:
+		// normal movement: slides lines across screen
+		gf.Offset += gf.Normal.Dot(step)
+		// tangential movement: scrolls dash pattern along the line direction
+		t := gf.Normal.Perp()
+		projT := t.Dot(step)
+		// Subtract so that a positive motion along +t moves the visible pattern along +t on screen
+		gf.DashPhase -= projT
+		switch gf.Kind {
+		case GridWavy:
+			gf.advanceCurve(step)
+		case GridCurve:
+			gf.advanceBezier(step)
+		}
+	}
+	gf.wrap()
+}
+
+// wrap brings Offset back within one spacing and DashPhase within one dash
+// period, counting whole spacings in Wraps.
+func (gf *GridFamily) wrap() {
+	// Wrap offset so it never drifts far from the origin. This keeps drawing stable without changing the pattern.
+	if sp := gf.Spacing; sp > 0 {
+		o := math.Mod(gf.Offset, sp)
+		if o < 0 {
+			o += sp
+		}
+		gf.Wraps += int(math.Round((gf.Offset - o) / sp))
+		gf.Offset = o
+	}
+	// Wrap dash phase to keep the dashed pattern phase bounded (no visual change)
+	period := PatternPeriod(gf.DashSegments())
+	if period > 0 {
+		dp := math.Mod(gf.DashPhase, period)
+		if dp < 0 {
+			dp += period
+		}
+		gf.DashPhase = dp
+	} else {
+		gf.DashPhase = 0
+	}
+}
+
+// Phase is the animated state of a family: how far its pattern has moved.
+type Phase struct {
+	Offset     float64 `json:"offset"`
+	DashPhase  float64 `json:"dashPhase,omitempty"`
+	CurveShift float64 `json:"curveShift,omitempty"`
+	Angle      float64 `json:"angle,omitempty"`
+	Shift      Vec2    `json:"shift"`
+	Wraps      int     `json:"wraps,omitempty"`
+	HexWraps   [3]int  `json:"hexWraps"`
+}
+
+// Phase returns the family's animated state.
+func (gf *GridFamily) Phase() Phase {
+	return Phase{
+		Offset: gf.Offset, DashPhase: gf.DashPhase, CurveShift: gf.CurveShift, Angle: gf.Angle,
+		Shift: gf.Shift, Wraps: gf.Wraps, HexWraps: gf.HexWraps,
+	}
+}
+
+// SetPhase puts the pattern back where it was when p was taken, leaving the
+// family's settings alone.
+func (gf *GridFamily) SetPhase(p Phase) {
+	gf.Offset, gf.DashPhase, gf.CurveShift, gf.Angle = p.Offset, p.DashPhase, p.CurveShift, p.Angle
+	gf.Shift, gf.Wraps, gf.HexWraps = p.Shift, p.Wraps, p.HexWraps
+}
+
+// NudgePhase moves the pattern by frac of its spacing, leaving the family's
+// settings alone: lines and rings by frac of the distance between them, a hex
+// lattice by frac of its line spacing along its normal and rays by frac of
+// the angle between them.
+func (gf *GridFamily) NudgePhase(frac float64) {
+	switch gf.Kind {
+	case GridHex:
+		gf.advanceHex(gf.Normal.Mul(frac * gf.Spacing))
+	case GridRay:
+		gf.Angle = math.Mod(gf.Angle+frac*2*math.Pi/float64(gf.RayCount()), 2*math.Pi)
+	default:
+		gf.Offset += frac * gf.Spacing
+		gf.wrap()
+	}
+}
+
+// PhaseFraction returns where the pattern sits within one spacing (one ray
+// step for rays), from 0 up to 1, so the phases of families can be compared.
+func (gf *GridFamily) PhaseFraction() float64 {
+	var f float64
+	switch gf.Kind {
+	case GridHex:
+		if gf.Spacing > 0 {
+			f = gf.Normal.Dot(gf.Shift) / gf.Spacing
+		}
+	case GridRay:
+		f = gf.Angle * float64(gf.RayCount()) / (2 * math.Pi)
+	default:
+		if gf.Spacing > 0 {
+			f = gf.Offset / gf.Spacing
+		}
+	}
+	f -= math.Floor(f)
+	if f >= 1 {
+		f = 0
+	}
+	return f
+}
+
+// AlignAt moves the pattern so that one of its lines passes through p,
+// leaving the family's settings alone. Rays are turned so the first points
+// at p. center is the world anchor of linear families.
+func (gf *GridFamily) AlignAt(p, center Vec2) {
+	d := p.Sub(center)
+	switch gf.Kind {
+	case GridRadial:
+		gf.Offset = p.Sub(gf.Origin).Len()
+	case GridHex:
+		gf.Offset, gf.Shift, gf.HexWraps = 0, d, [3]int{}
+	case GridRay:
+		r := p.Sub(gf.Origin)
+		gf.Angle = math.Atan2(r.Y, r.X)
+		return
+	default:
+		gf.Offset = gf.Normal.Dot(d)
+		if gf.Kind == GridWavy && gf.Wavy() {
+			// The line is displaced by its curve where it meets p
+			gf.Offset -= gf.curve(gf.Normal.Perp().Dot(d))
+		}
+		if gf.Kind == GridCurve && gf.Curved() {
+			gf.alignCurve(p, center)
+		}
+	}
+	gf.Wraps = 0
+	// Wrap the new offset or shift like a zero step of motion would
+	gf.Advance(Vec2{})
+}
+
+// MinSegmentSpacing is the shortest segment FamilyFromSegment makes a family
+// of; the lines of a shorter one would run together.
+const MinSegmentSpacing = 4.0
+
+// FamilyFromSegment returns a linear family whose lines cross the segment
+// from a to b at right angles, one through a and the next through b, so the
+// segment sets both their direction and their spacing. Color and the other
+// settings are left for the caller. center is the world anchor of linear
+// families. It reports false for a segment shorter than MinSegmentSpacing.
+func FamilyFromSegment(a, b, center Vec2) (GridFamily, bool) {
+	d := b.Sub(a)
+	l := d.Len()
+	if l < MinSegmentSpacing {
+		return GridFamily{}, false
+	}
+	gf := GridFamily{Kind: GridLinear, Normal: d.Mul(1 / l), Spacing: l, Thickness: 2}
+	gf.AlignAt(a, center)
+	return gf, true
+}
+
+// Distance returns how far p is from the nearest line of the family, gaps
+// included. center is the world anchor of linear families.
+func (gf *GridFamily) Distance(p, center Vec2) float64 {
+	switch gf.Kind {
+	case GridRadial:
+		r := p.Sub(gf.Origin).Len()
+		k := math.Max(0, math.Round((r-gf.Offset)/gf.Spacing))
+		return math.Abs(r - (k*gf.Spacing + gf.Offset))
+	case GridRay:
+		rel := p.Sub(gf.Origin)
+		r := rel.Len()
+		diff := math.Abs(math.Atan2(rel.Y, rel.X) - gf.nearestRay(math.Atan2(rel.Y, rel.X)))
+		if diff >= math.Pi/2 {
+			// Behind the rays the origin is nearest
+			return r
+		}
+		return r * math.Sin(diff)
+	case GridHex:
+		dist := math.Inf(1)
+		for _, l := range gf.HexLines() {
+			dist = math.Min(dist, l.Distance(p, center))
+		}
+		return dist
+	case GridWavy:
+		if gf.Wavy() {
+			n, t := gf.Normal, gf.Normal.Perp()
+			u, s0 := n.Dot(p.Sub(center)), t.Dot(p.Sub(center))
+			a := math.Abs(gf.Amplitude)
+			dist := math.Inf(1)
+			for k := math.Floor((u - a - gf.Offset) / gf.Spacing); k <= math.Ceil((u+a-gf.Offset)/gf.Spacing); k++ {
+				_, d := gf.nearestCurve(s0, u, k*gf.Spacing+gf.Offset)
+				dist = math.Min(dist, d)
+			}
+			return dist
+		}
+	case GridCurve:
+		if gf.Curved() {
+			return gf.nearestFoot(p, center).dist
+		}
+	}
+	return math.Abs(gf.Normal.Dot(p.Sub(center)) - gf.nearestLine(p, center))
+}
+
+// Touches reports whether p lies within the thickness band of a drawn (non-gap)
+// part of any line of the family. center is the world anchor of linear families.
+func (gf *GridFamily) Touches(p, center Vec2) bool {
+	switch gf.Kind {
+	case GridRadial:
+		return gf.touchesRadial(p)
+	case GridRay:
+		return gf.touchesRay(p)
+	case GridHex:
+		for _, l := range gf.HexLines() {
+			if l.Touches(p, center) {
+				return true
+			}
+		}
+		return false
+	case GridWavy:
+		if gf.Wavy() {
+			return gf.touchesWavy(p, center)
+		}
+	case GridCurve:
+		if gf.Curved() {
+			return gf.touchesCurve(p, center)
+		}
+	}
+	// Compute minimal distance to any grid line of this family that could be close to the point.
+	// Distance along normal from center to point.
+	dAlong := gf.Normal.Dot(p.Sub(center))
+	// Find nearest integer k such that |dAlong - (k*Spacing + Offset)| minimized
+	k := math.Round((dAlong - gf.Offset) / gf.Spacing)
+	closest := (k * gf.Spacing) + gf.Offset
+	// Distance to the nearest infinite line in this family
+	dist := math.Abs(dAlong - closest)
+	if dist > gf.Thickness {
+		return false
+	}
+	// Solid lines when dash or gap is non-positive
+	if !gf.dashed() {
+		return true
+	}
+	// Within thickness band, also respect dash/gap so gaps don't trigger.
+	n := gf.Normal
+	t := n.Perp()
+	pt := center.Add(n.Mul(closest))
+	// Signed coordinate of the point along the tangent axis with origin at pt
+	s0 := t.Dot(p.Sub(pt))
+	return gf.inDash(gf.patternPos(s0))
+}
+
+// CrossingSpeed returns how fast (pixels per second) the line touching p moves
+// across it when the family travels with velocity vel: the normal component for
+// straight lines and the full speed for expanding rings.
+func (gf *GridFamily) CrossingSpeed(p, center, vel Vec2) float64 {
+	switch gf.Kind {
+	case GridRadial:
+		return vel.Len()
+	case GridRay:
+		return gf.raySweepSpeed(p)
+	case GridHex:
+		for _, l := range gf.HexLines() {
+			if l.Touches(p, center) {
+				return math.Abs(l.Normal.Dot(vel))
+			}
+		}
+	case GridWavy:
+		if gf.Wavy() {
+			return gf.wavyCrossingSpeed(p, center, vel)
+		}
+	case GridCurve:
+		if gf.Curved() {
+			return gf.curveCrossingSpeed(p, center, vel)
+		}
+	}
+	return math.Abs(gf.Normal.Dot(vel))
+}
+
+// patternPos maps a tangent coordinate s on a linear family's line (relative
+// to the line's foot point from center) to a position in the dash pattern.
+// The pattern runs against +t, so increasing DashPhase scrolls it along +t.
+func (gf *GridFamily) patternPos(s float64) float64 {
+	return -s - (gf.DashPhase + gf.DashOffset)
+}
+
+func (gf *GridFamily) touchesRadial(p Vec2) bool {
+	rel := p.Sub(gf.Origin)
+	r := rel.Len()
+	// Nearest ring radius; rings only exist at non-negative radii
+	k := math.Round((r - gf.Offset) / gf.Spacing)
+	if k < 0 {
+		k = 0
+	}
+	closest := k*gf.Spacing + gf.Offset
+	if math.Abs(r-closest) > gf.Thickness {
+		return false
+	}
+	if !gf.dashed() {
+		return true
+	}
+	// Arc length along the ring, measured counter-clockwise from the +X axis like drawing
+	return gf.inDash(arcAngle(rel)*closest - (gf.DashPhase + gf.DashOffset))
+}
+
+// Draw renders the lines of the family visible through cam with its blend
+// mode. center is the world anchor of linear families.
+func (gf *GridFamily) Draw(dst *ebiten.Image, cam *Camera, center Vec2) {
+	s := newStrokes(dst, gf.Blend, cam.Device())
+	if gf.ShowBand {
+		gf.drawBand(s, cam, center)
+	}
+	gf.draw(s, cam, center)
+	gf.drawRipples(s, cam, center)
+	s.flush()
+}
+
+func (gf *GridFamily) draw(s *strokes, cam *Camera, center Vec2) {
+	switch gf.Kind {
+	case GridRadial:
+		gf.drawRadial(s, cam)
+		return
+	case GridRay:
+		gf.drawRays(s, cam)
+		return
+	case GridHex:
+		for _, l := range gf.HexLines() {
+			l.draw(s, cam, center)
+		}
+		return
+	case GridWavy:
+		if gf.Wavy() {
+			gf.drawWavy(s, cam, center)
+			return
+		}
+	case GridCurve:
+		if gf.Curved() {
+			gf.drawCurve(s, cam, center)
+			return
+		}
+	}
+	n := gf.Normal
+	t := n.Perp()
+	// Determine range of k that covers the view: lines within the view radius of the view center
+	view := cam.Center
+	R := cam.ViewRadius()
+	dView := n.Dot(view.Sub(center))
+	kMin := int(math.Floor((dView-R-gf.Offset)/gf.Spacing)) - 1
+	kMax := int(math.Ceil((dView+R-gf.Offset)/gf.Spacing)) + 1
+	z := cam.Scale()
+	pattern := scalePattern(gf.DashSegments(), z)
+	for k := kMin; k <= kMax; k++ {
+		d := float64(k)*gf.Spacing + gf.Offset
+		pt := center.Add(n.Mul(d))
+		// Draw the part of the line around the view center, from +t to -t
+		sc := t.Dot(view.Sub(pt))
+		s1, s2 := sc+R, sc-R
+		p1 := cam.ToScreen(pt.Add(t.Mul(s1)))
+		p2 := cam.ToScreen(pt.Add(t.Mul(s2)))
+		// Draw solid or dashed line depending on dash/gap settings; the pattern
+		// position at p1 keeps dashes where the hit test expects them
+		width, col := gf.lineStyle(gf.lineKeyAt(d))
+		drawDashedLine(s, p1, p2, width, col, pattern, gf.patternPos(s1)*z)
+	}
+}
+
+func (gf *GridFamily) drawRadial(s *strokes, cam *Camera) {
+	// Rings are visible up to the farthest point of the view from the origin
+	maxR := cam.Center.Sub(gf.Origin).Len() + cam.ViewRadius()
+	o := cam.ToScreen(gf.Origin)
+	z := cam.Scale()
+	for r := gf.Offset; r <= maxR; r += gf.Spacing {
+		if r <= 0 {
+			continue
+		}
+		width, col := gf.lineStyle(gf.lineKeyAt(r))
+		if !gf.dashed() {
+			drawArc(s, o, r*z, 0, 2*math.Pi, width, col)
+			continue
+		}
+		// Arc length runs with the pattern here (unlike straight lines), matching touchesRadial
+		circ := 2 * math.Pi * r
+		phase := -(gf.DashPhase + gf.DashOffset)
+		patternSpans(gf.DashSegments(), phase, circ, func(a, b float64) {
+			drawArc(s, o, r*z, a/r, b/r, width, col)
+		})
+	}
+}
+
+// arcAngle returns the angle of v in [0, 2π).
+func arcAngle(v Vec2) float64 {
+	a := math.Atan2(v.Y, v.X)
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return a
+}
+
+// drawArc strokes a circular arc from angle a0 to a1 (radians) as a polyline.
+func drawArc(s *strokes, c Vec2, r, a0, a1, width float64, col color.Color) {
+	// Segment count keeps chords short (about 4px) so the arc looks round
+	segs := int(math.Ceil((a1 - a0) * r / 4))
+	if segs < 1 {
+		segs = 1
+	}
+	pts := make([]Vec2, segs+1)
+	for i := range pts {
+		a := a0 + (a1-a0)*float64(i)/float64(segs)
+		pts[i] = c.Add(Vec2{math.Cos(a), math.Sin(a)}.Mul(r))
+	}
+	s.polyline(pts, width, col)
+}
+
+// drawDashedLine draws a line from p1 to p2 with optional dashes. pattern holds
+// alternating dash and gap lengths; if it is empty the line is solid. phase is
+// the position within the dash pattern at p1, so a line can start partway
+// through a dash or gap.
+func drawDashedLine(s *strokes, p1, p2 Vec2, width float64, col color.Color, pattern []float64, phase float64) {
+	delta := p2.Sub(p1)
+	L := delta.Len()
+	if L <= 0 {
+		return
+	}
+	if len(pattern) < 2 {
+		s.line(p1, p2, width, col)
+		return
+	}
+	u := delta.Mul(1.0 / L)
+	patternSpans(pattern, phase, L, func(start, end float64) {
+		a := p1.Add(u.Mul(start))
+		b := p1.Add(u.Mul(end))
+		s.line(a, b, width, col)
+	})
+}
@@ -0,0 +1,87 @@
+package geom
+
+import "math"
+
+// A hex family (GridHex) is a lattice of three line directions at 60° that
+// share one Spacing and move as a unit. Its lines are derived on the fly as
+// linear families, so touch detection and drawing reuse the linear code.
+//
+// With HexTiling set only the parts of those lines that form a honeycomb are
+// kept: every edge of a honeycomb with edge length a = 2*Spacing/√3 lies on one
+// of the lattice lines, so the tiling is the lattice drawn with a dash pattern
+// (dash a, gap 2a) whose phase alternates between neighbouring lines.
+
+// HexLines returns the linear families making up a hex family.
+func (gf *GridFamily) HexLines() []GridFamily {
+	s := gf.Spacing
+	shift := gf.Shift.Add(gf.Normal.Mul(gf.Offset))
+	var out []GridFamily
+	for j := 0; j < 3; j++ {
+		n := rotate(gf.Normal, float64(j)*math.Pi/3)
+		t := n.Perp()
+		line := *gf
+		line.Kind = GridLinear
+		line.Normal = n
+		line.Motion = nil
+		line.hexDir = j + 1
+		line.hexBase = n.Dot(shift) + float64(gf.HexWraps[j])*s
+		if !gf.HexTiling {
+			line.Offset = n.Dot(shift)
+			line.DashPhase = -t.Dot(shift)
+			out = append(out, line)
+			continue
+		}
+		// Honeycomb: lines alternate between two dash phases, so each direction
+		// is split into odd and even lines with twice the spacing.
+		a := 2 * s / math.Sqrt(3)
+		for parity := 0; parity < 2; parity++ {
+			l := line
+			l.Spacing = 2 * s
+			l.Offset = n.Dot(shift) + float64(1-parity)*s
+			l.DashLength = a
+			l.GapLength = 2 * a
+			l.DashPattern = nil
+			l.DashPhase = -t.Dot(shift)
+			// Edge centers lie at 0 (odd lines) or 1.5a (even lines) along the tangent;
+			// a dash centered at c needs DashPhase+DashOffset = -c - a/2
+			c := float64(parity) * 1.5 * a
+			l.DashOffset = -c - a/2
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// advanceHex moves a hex family by step and wraps the accumulated shift into one
+// cell of the lattice's translation symmetry, which leaves the pattern unchanged.
+func (gf *GridFamily) advanceHex(step Vec2) {
+	s := gf.Spacing
+	if s <= 0 {
+		return
+	}
+	n := gf.Normal
+	t := n.Perp()
+	shift := gf.Shift.Add(step)
+	// Lattice vectors in (n, t) coordinates: v1 = (2s, 0), v2 = (s, √3 s)
+	x, y := n.Dot(shift), t.Dot(shift)
+	beta := y / (math.Sqrt(3) * s)
+	alpha := (x - beta*s) / (2 * s)
+	fa, fb := math.Floor(alpha), math.Floor(beta)
+	alpha -= fa
+	beta -= fb
+	// Moving by whole lattice vectors renumbers the lines of each direction:
+	// v1 and v2 span 2 and 1, 1 and 2, and -1 and 1 line spacings along them
+	a, b := int(fa), int(fb)
+	gf.HexWraps[0] += 2*a + b
+	gf.HexWraps[1] += a + 2*b
+	gf.HexWraps[2] += -a + b
+	x = alpha*2*s + beta*s
+	y = beta * math.Sqrt(3) * s
+	gf.Shift = n.Mul(x).Add(t.Mul(y))
+}
+
+// rotate returns v rotated by angle radians.
+func rotate(v Vec2, angle float64) Vec2 {
+	c, s := math.Cos(angle), math.Sin(angle)
+	return Vec2{v.X*c - v.Y*s, v.X*s + v.Y*c}
+}
@@ -0,0 +1,65 @@
+package geom
+
+import "math"
+
+// Intersections of two linear families, for triggers where their lines cross.
+
+// solve2 returns the x with n1·x = d1 and n2·x = d2, or false when the
+// normals are parallel.
+func solve2(n1 Vec2, d1 float64, n2 Vec2, d2 float64) (Vec2, bool) {
+	det := n1.X*n2.Y - n1.Y*n2.X
+	if math.Abs(det) < 1e-9 {
+		return Vec2{}, false
+	}
+	return Vec2{(d1*n2.Y - d2*n1.Y) / det, (n1.X*d2 - n2.X*d1) / det}, true
+}
+
+// nearestLine returns the signed distance from center of the family's line
+// closest to p.
+func (gf *GridFamily) nearestLine(p, center Vec2) float64 {
+	d := gf.Normal.Dot(p.Sub(center))
+	return math.Round((d-gf.Offset)/gf.Spacing)*gf.Spacing + gf.Offset
+}
+
+// LineIntersection returns where the lines of a and b nearest to p cross, and how
+// fast that crossing moves when the families travel with velocities va and vb.
+// ok is false for parallel families.
+func LineIntersection(a, b *GridFamily, p, center, va, vb Vec2) (x, vel Vec2, ok bool) {
+	x, ok = solve2(a.Normal, a.nearestLine(p, center), b.Normal, b.nearestLine(p, center))
+	if !ok {
+		return Vec2{}, Vec2{}, false
+	}
+	// The crossing moves so that it stays on both lines: n·u = n·v for each family
+	vel, _ = solve2(a.Normal, a.Normal.Dot(va), b.Normal, b.Normal.Dot(vb))
+	return center.Add(x), vel, true
+}
+
+// Aligned reports whether the lines of two parallel families coincide, and if
+// so the distance from center of a shared line near the view. Families whose
+// spacings are not whole multiples of each other never line up as a whole and
+// are ignored.
+func Aligned(a, b *GridFamily, center, view Vec2) (float64, bool) {
+	dot := a.Normal.Dot(b.Normal)
+	if math.Abs(dot) < 1-1e-9 {
+		return 0, false
+	}
+	// Express b's offset along a's normal
+	ob := b.Offset * math.Copysign(1, dot)
+	fine, coarse := math.Min(a.Spacing, b.Spacing), math.Max(a.Spacing, b.Spacing)
+	ratio := coarse / fine
+	if math.Abs(ratio-math.Round(ratio)) > 1e-6 {
+		return 0, false
+	}
+	diff := math.Mod(math.Mod(a.Offset-ob, fine)+fine, fine)
+	if math.Min(diff, fine-diff) > math.Max(a.Thickness, b.Thickness) {
+		return 0, false
+	}
+	// Every line of the coarser family is a shared line; pick the one nearest the view
+	c := a
+	if b.Spacing > a.Spacing {
+		c = b
+	}
+	d := c.Normal.Dot(view.Sub(center))
+	k := math.Round((d - c.Offset) / c.Spacing)
+	return (k*c.Spacing + c.Offset) * math.Copysign(1, a.Normal.Dot(c.Normal)), true
+}
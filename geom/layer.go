@@ -0,0 +1,36 @@
+package geom
+
+import "math"
+
+// Families can be gathered in layers (see engine.Layer) that are turned and
+// moved as one, on top of their own settings. Turn and Move are what that
+// does to each family of a layer.
+
+// Turn turns the family by a radians about center, the world anchor of
+// linear families: the direction of lines, the shift of a hex lattice, the
+// origin of rings and rays and the angle of rays all go round.
+func (gf *GridFamily) Turn(a float64, center Vec2) {
+	switch gf.Kind {
+	case GridRadial:
+		gf.Origin = center.Add(rotate(gf.Origin.Sub(center), a))
+	case GridRay:
+		gf.Origin = center.Add(rotate(gf.Origin.Sub(center), a))
+		gf.Angle = math.Mod(gf.Angle+a, 2*math.Pi)
+	case GridHex:
+		gf.Normal = rotate(gf.Normal, a)
+		gf.Shift = rotate(gf.Shift, a)
+	default:
+		gf.Normal = rotate(gf.Normal, a)
+	}
+}
+
+// Move moves the family by d: lines and lattices slide as their motion would
+// slide them, and rings and rays take their origin along.
+func (gf *GridFamily) Move(d Vec2) {
+	switch gf.Kind {
+	case GridRadial, GridRay:
+		gf.Origin = gf.Origin.Add(d)
+	default:
+		gf.Advance(d)
+	}
+}
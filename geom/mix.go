@@ -0,0 +1,62 @@
+package geom
+
+import "fmt"
+
+// Each family also has a mixer strip: it can be muted or soloed, and the
+// notes it triggers turned down or up by a volume. While any family is
+// soloed only the soloed ones sound. Silenced families keep moving and
+// cueing the points they cross, and are drawn fainter.
+
+// MaxVolume bounds a family's volume; 1 leaves its notes as they are.
+const MaxVolume = 2.0
+
+// SilencedAlpha is the opacity silenced families are drawn at, relative to
+// their own.
+const SilencedAlpha = 0.3
+
+// ValidateVolume reports a volume an editor could not have set; 0 means 1.
+func ValidateVolume(v float64) error {
+	if v < 0 || v > MaxVolume {
+		return fmt.Errorf("volume %g out of range 0-%g", v, MaxVolume)
+	}
+	return nil
+}
+
+// Level returns the factor the family's notes are scaled by.
+func (gf *GridFamily) Level() float64 {
+	if gf.Volume == 0 {
+		return 1
+	}
+	return gf.Volume
+}
+
+// AnySolo reports whether any of grids is soloed.
+func AnySolo(grids []GridFamily) bool {
+	for i := range grids {
+		if grids[i].Solo {
+			return true
+		}
+	}
+	return false
+}
+
+// Sounds reports whether the family's notes sound; anySolo tells whether any
+// family is soloed.
+func (gf *GridFamily) Sounds(anySolo bool) bool {
+	if anySolo {
+		return gf.Solo
+	}
+	return !gf.Mute
+}
+
+// MixLabel describes the family's mixer strip for display.
+func (gf *GridFamily) MixLabel() string {
+	s := fmt.Sprintf("%.0f%%", gf.Level()*100)
+	if gf.Mute {
+		s += " muted"
+	}
+	if gf.Solo {
+		s += " solo"
+	}
+	return s
+}
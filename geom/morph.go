@@ -0,0 +1,53 @@
+package geom
+
+import "math"
+
+// Morphing blends a family between two versions of itself, a and b, by an
+// amount from 0 (a) to 1 (b). The shape (spacing, angle and origin) is set
+// to the blend outright. The phase (the offset, and the angle of rays) keeps
+// moving with the pattern, so it is shifted by the part of the difference the
+// amount changed by instead, and the pattern runs on while it morphs.
+
+// CanMorph reports whether gf can be morphed between a and b: all three must
+// be of the same kind.
+func (gf *GridFamily) CanMorph(a, b *GridFamily) bool {
+	return a.Kind == gf.Kind && b.Kind == gf.Kind
+}
+
+// Morph moves gf between a and b from amount from to amount to.
+func (gf *GridFamily) Morph(a, b *GridFamily, from, to float64) {
+	lerp := func(x, y float64) float64 { return x + (y-x)*to }
+	gf.Spacing = lerp(a.Spacing, b.Spacing)
+	if gf.Euclid != nil {
+		gf.ApplyEuclid()
+	}
+	gf.Origin = Vec2{X: lerp(a.Origin.X, b.Origin.X), Y: lerp(a.Origin.Y, b.Origin.Y)}
+	step := to - from
+	switch gf.Kind {
+	case GridRadial:
+		gf.Offset += (b.Offset - a.Offset) * step
+		gf.wrap()
+	case GridHex:
+		// The lattice keeps its shift; only its angle blends
+		gf.Normal = blendDir(a.Normal, b.Normal, to)
+	case GridRay:
+		gf.Angle = math.Mod(gf.Angle+turn(a.Angle, b.Angle)*step, 2*math.Pi)
+	default:
+		gf.Normal = blendDir(a.Normal, b.Normal, to)
+		gf.Offset += (b.Offset - a.Offset) * step
+		gf.wrap()
+	}
+}
+
+// blendDir returns the direction t of the way from a to b, turning the
+// shorter way round.
+func blendDir(a, b Vec2, t float64) Vec2 {
+	an := math.Atan2(a.Y, a.X)
+	ang := an + turn(an, math.Atan2(b.Y, b.X))*t
+	return Vec2{X: math.Cos(ang), Y: math.Sin(ang)}
+}
+
+// turn returns the angle (radians) from a to b the shorter way round.
+func turn(a, b float64) float64 {
+	return math.Remainder(b-a, 2*math.Pi)
+}
@@ -0,0 +1,210 @@
+package geom
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// A path lets a point travel instead of standing still, so the pattern and
+// the points both move and play against each other. A path is a closed cycle
+// travelled Speed times per second; Phase is how far along it the point is.
+// Line paths run through their vertices and back again, circles go round
+// clockwise on screen and Lissajous curves trace x and y sines of different
+// frequencies.
+
+// PathKind selects the shape of a path.
+type PathKind int
+
+const (
+	// PathNone means the point stands still.
+	PathNone PathKind = iota
+	// PathLine runs through Line and back.
+	PathLine
+	// PathCircle goes round a circle of Radius about Center.
+	PathCircle
+	// PathLissajous traces Center + Size·(sin 2πFreq.X u, sin 2πFreq.Y u).
+	PathLissajous
+)
+
+var pathKindNames = []string{"none", "line", "circle", "lissajous"}
+
+// String returns the path kind's name.
+func (k PathKind) String() string {
+	if k < 0 || int(k) >= len(pathKindNames) {
+		return "unknown"
+	}
+	return pathKindNames[k]
+}
+
+// MarshalText stores a path kind by name.
+func (k PathKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(pathKindNames) {
+		return nil, fmt.Errorf("unknown path kind %d", k)
+	}
+	return []byte(pathKindNames[k]), nil
+}
+
+// UnmarshalText parses a path kind name.
+func (k *PathKind) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*k = PathNone
+		return nil
+	}
+	for i, n := range pathKindNames {
+		if n == string(b) {
+			*k = PathKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown path kind %q", b)
+}
+
+// Path is the route a moving point travels.
+type Path struct {
+	Kind   PathKind `json:"kind"`
+	Line   []Vec2   `json:"line,omitempty"`   // line paths: the vertices, in world pixels
+	Center Vec2     `json:"center,omitempty"` // circle and Lissajous paths
+	Radius float64  `json:"radius,omitempty"` // circle paths
+	Size   Vec2     `json:"size,omitempty"`   // Lissajous paths: half-width and half-height
+	Freq   Vec2     `json:"freq,omitempty"`   // Lissajous paths: cycles of x and y per round
+	Speed  float64  `json:"speed"`            // rounds per second; negative runs backwards
+	Phase  float64  `json:"phase,omitempty"`  // position along the path in rounds, 0-1 (animated)
+}
+
+// Moves reports whether the path moves its point at all.
+func (p *Path) Moves() bool {
+	return p.Kind != PathNone
+}
+
+// Validate reports a path that has no shape to travel.
+func (p *Path) Validate() error {
+	switch p.Kind {
+	case PathLine:
+		if len(p.Line) < 2 {
+			return fmt.Errorf("line path needs at least two points")
+		}
+	case PathCircle:
+		if p.Radius <= 0 {
+			return fmt.Errorf("circle path radius must be positive")
+		}
+	case PathLissajous:
+		if p.Freq.X <= 0 || p.Freq.Y <= 0 {
+			return fmt.Errorf("lissajous path frequencies must be positive")
+		}
+	}
+	return nil
+}
+
+// Advance moves the phase on by dt seconds of travel.
+func (p *Path) Advance(dt float64) {
+	p.Phase = wrapUnit(p.Phase + p.Speed*dt)
+}
+
+// Pos returns where the path has its point now.
+func (p *Path) Pos() Vec2 {
+	return p.at(p.Phase)
+}
+
+// PosBefore returns where the path had its point the given number of seconds ago.
+func (p *Path) PosBefore(seconds float64) Vec2 {
+	return p.at(p.Phase - p.Speed*seconds)
+}
+
+// pathVelocityStep is the time step (seconds) over which Velocity is measured.
+const pathVelocityStep = 1e-3
+
+// Velocity returns the velocity (pixels per second) of the point now.
+func (p *Path) Velocity() Vec2 {
+	return p.Pos().Sub(p.PosBefore(pathVelocityStep)).Mul(1 / pathVelocityStep)
+}
+
+// Translate moves the whole path by d, keeping the phase. The line vertices
+// are copied, so copies of a point never share them.
+func (p *Path) Translate(d Vec2) {
+	p.Center = p.Center.Add(d)
+	line := p.Line
+	p.Line = nil
+	for _, v := range line {
+		p.Line = append(p.Line, v.Add(d))
+	}
+}
+
+// at returns the position at phase u (in rounds).
+func (p *Path) at(u float64) Vec2 {
+	u = wrapUnit(u)
+	switch p.Kind {
+	case PathLine:
+		// There and back: the far end is reached half way round
+		return p.alongLine(1 - math.Abs(2*u-1))
+	case PathCircle:
+		a := 2 * math.Pi * u
+		return p.Center.Add(Vec2{math.Cos(a), math.Sin(a)}.Mul(p.Radius))
+	case PathLissajous:
+		return p.Center.Add(Vec2{
+			p.Size.X * math.Sin(2*math.Pi*p.Freq.X*u),
+			p.Size.Y * math.Sin(2*math.Pi*p.Freq.Y*u),
+		})
+	}
+	return p.Center
+}
+
+// alongLine returns the position a fraction f (0-1) of the way along Line,
+// measured by length.
+func (p *Path) alongLine(f float64) Vec2 {
+	if len(p.Line) == 0 {
+		return Vec2{}
+	}
+	total := 0.0
+	for i := 1; i < len(p.Line); i++ {
+		total += p.Line[i].Sub(p.Line[i-1]).Len()
+	}
+	left := f * total
+	for i := 1; i < len(p.Line); i++ {
+		seg := p.Line[i].Sub(p.Line[i-1])
+		l := seg.Len()
+		if left <= l && l > 0 {
+			return p.Line[i-1].Add(seg.Mul(left / l))
+		}
+		left -= l
+	}
+	return p.Line[len(p.Line)-1]
+}
+
+// wrapUnit wraps u into [0, 1).
+func wrapUnit(u float64) float64 {
+	u -= math.Floor(u)
+	if u >= 1 {
+		u = 0
+	}
+	return u
+}
+
+// pathDrawSegments is the number of chords a curved path is drawn with.
+const pathDrawSegments = 96
+
+// Draw strokes the path thinly, so the route of a moving point can be seen.
+func (p *Path) Draw(dst *ebiten.Image, cam *Camera, col color.Color) {
+	stroke := func(a, b Vec2) {
+		sa, sb := cam.ToScreen(a), cam.ToScreen(b)
+		vector.StrokeLine(dst, float32(sa.X), float32(sa.Y), float32(sb.X), float32(sb.Y), float32(cam.Device()), col, true)
+	}
+	switch p.Kind {
+	case PathNone:
+		return
+	case PathLine:
+		for i := 1; i < len(p.Line); i++ {
+			stroke(p.Line[i-1], p.Line[i])
+		}
+		return
+	}
+	prev := p.at(0)
+	for i := 1; i <= pathDrawSegments; i++ {
+		cur := p.at(float64(i) / pathDrawSegments)
+		stroke(prev, cur)
+		prev = cur
+	}
+}
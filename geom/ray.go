@@ -0,0 +1,70 @@
+package geom
+
+import "math"
+
+// A ray family (GridRay) is a set of Rays half-lines radiating from Origin at
+// equal angles, rotating like clock hands at AngularSpeed. Points are
+// triggered as a ray sweeps past them, which makes the family a radar-style
+// sequencer: one revolution plays the points in order of their angle. Rays
+// ignore the pattern motion; dashes run outward from the origin.
+
+// RayCount returns the number of rays, at least one.
+func (gf *GridFamily) RayCount() int {
+	if gf.Rays < 1 {
+		return 1
+	}
+	return gf.Rays
+}
+
+// Rotate turns the rays by their angular speed, scaled by the family's
+// rate, over dt seconds.
+func (gf *GridFamily) Rotate(dt float64) {
+	gf.Angle = math.Mod(gf.Angle+gf.AngularSpeed*gf.MotionRate()*math.Pi/180*dt, 2*math.Pi)
+}
+
+// nearestRay returns the angle of the ray closest in angle to direction a.
+func (gf *GridFamily) nearestRay(a float64) float64 {
+	step := 2 * math.Pi / float64(gf.RayCount())
+	return gf.Angle + math.Round((a-gf.Angle)/step)*step
+}
+
+// touchesRay reports whether p lies within the thickness band of a drawn part
+// of a ray.
+func (gf *GridFamily) touchesRay(p Vec2) bool {
+	rel := p.Sub(gf.Origin)
+	r := rel.Len()
+	if r <= gf.Thickness {
+		// All rays meet at the origin
+		return true
+	}
+	diff := math.Atan2(rel.Y, rel.X) - gf.nearestRay(math.Atan2(rel.Y, rel.X))
+	// Only the forward half-line counts; rays are at most π apart
+	if math.Abs(diff) >= math.Pi/2 || r*math.Sin(math.Abs(diff)) > gf.Thickness {
+		return false
+	}
+	if !gf.dashed() {
+		return true
+	}
+	return gf.inDash(r*math.Cos(diff) - (gf.DashPhase + gf.DashOffset))
+}
+
+// raySweepSpeed is how fast (pixels per second) a ray sweeps past p.
+func (gf *GridFamily) raySweepSpeed(p Vec2) float64 {
+	return math.Abs(gf.AngularSpeed*gf.MotionRate()*math.Pi/180) * p.Sub(gf.Origin).Len()
+}
+
+// drawRays renders the rays out to the edge of the view.
+func (gf *GridFamily) drawRays(s *strokes, cam *Camera) {
+	maxR := cam.Center.Sub(gf.Origin).Len() + cam.ViewRadius()
+	o := cam.ToScreen(gf.Origin)
+	z := cam.Scale()
+	pattern := scalePattern(gf.DashSegments(), z)
+	phase := -(gf.DashPhase + gf.DashOffset) * z
+	n := gf.RayCount()
+	for k := 0; k < n; k++ {
+		a := gf.Angle + 2*math.Pi*float64(k)/float64(n)
+		end := cam.ToScreen(gf.Origin.Add(Vec2{math.Cos(a), math.Sin(a)}.Mul(maxR)))
+		width, col := gf.lineStyle(LineKey{0, k})
+		drawDashedLine(s, o, end, width, col, pattern, phase)
+	}
+}
@@ -0,0 +1,124 @@
+package geom
+
+import (
+	"image/color"
+	"math"
+)
+
+// Trigger ripples. When a line touches a point, a pulse runs along that line
+// away from the point in both directions, fading as it goes. A ripple is
+// kept in the line's own coordinates, its LineKey and the position along it
+// (see Along), so it rides the line as the family moves.
+
+// RippleSeconds is how long a ripple runs before it has faded out.
+const RippleSeconds = 0.6
+
+const (
+	rippleSpeed  = 360.0 // world pixels per second the pulses travel
+	rippleLength = 28.0  // world pixels a pulse is long
+	rippleSteps  = 6     // segments a curved pulse is drawn with
+)
+
+// LineRipple is a pulse running along line Key from where it was hit.
+type LineRipple struct {
+	Key  LineKey
+	From float64 // where the line was hit, as returned by Along
+	Age  float64 // seconds since the hit
+}
+
+// keyOffset returns the distance along the normal (or the radius of the ring)
+// of line key, the inverse of lineKeyAt.
+func (gf *GridFamily) keyOffset(key LineKey) float64 {
+	if gf.hexDir > 0 {
+		unit := gf.Spacing
+		if gf.HexTiling {
+			unit /= 2
+		}
+		return float64(key.n)*unit + gf.hexBase
+	}
+	return float64(key.n+gf.Wraps)*gf.Spacing + gf.Offset
+}
+
+// drawRipples draws the family's ripples over its lines.
+func (gf *GridFamily) drawRipples(s *strokes, cam *Camera, center Vec2) {
+	for _, r := range gf.Ripples {
+		level := 1 - r.Age/RippleSeconds
+		if level <= 0 {
+			continue
+		}
+		width, col := gf.rippleStyle(level)
+		head := rippleSpeed * r.Age
+		for _, dir := range []float64{-1, 1} {
+			gf.strokeAlong(s, cam, center, r.Key, r.From, dir*math.Max(0, head-rippleLength), dir*head, width, col)
+		}
+	}
+}
+
+// rippleStyle returns the width and color of a pulse at level (1 when it
+// starts, fading to 0): the family's color brightened towards white.
+func (gf *GridFamily) rippleStyle(level float64) (float64, color.Color) {
+	alpha := level
+	if gf.Alpha > 0 {
+		alpha *= gf.Alpha
+	}
+	r, g, b, a := gf.Color.RGBA()
+	mix := func(c uint32) uint8 {
+		return uint8((float64(c) + (float64(a)-float64(c))*0.8) * alpha / 257)
+	}
+	return 1.5 + 3*level, color.RGBA{mix(r), mix(g), mix(b), uint8(float64(a) * alpha / 257)}
+}
+
+// strokeAlong strokes line key between the world distances d0 and d1 along
+// it from position from.
+func (gf *GridFamily) strokeAlong(s *strokes, cam *Camera, center Vec2, key LineKey, from, d0, d1, width float64, col color.Color) {
+	switch gf.Kind {
+	case GridRay:
+		a := gf.Angle + float64(key.n)*2*math.Pi/float64(gf.RayCount())
+		dir := Vec2{math.Cos(a), math.Sin(a)}
+		// A ray ends at its origin
+		r0, r1 := math.Max(0, from+d0), math.Max(0, from+d1)
+		if r0 != r1 {
+			s.line(cam.ToScreen(gf.Origin.Add(dir.Mul(r0))), cam.ToScreen(gf.Origin.Add(dir.Mul(r1))), width, col)
+		}
+	case GridRadial:
+		r := gf.keyOffset(key)
+		if r <= 0 {
+			return
+		}
+		a0, a1 := from+d0/r, from+d1/r
+		drawArc(s, cam.ToScreen(gf.Origin), r*cam.Scale(), math.Min(a0, a1), math.Max(a0, a1), width, col)
+	case GridHex:
+		for _, l := range gf.HexLines() {
+			if l.hexDir-1 == key.set {
+				l.strokeAlong(s, cam, center, key, from, d0, d1, width, col)
+				return
+			}
+		}
+	case GridCurve:
+		// A motif may double back along the line, so pulses only run along
+		// straight and wavy lines
+		if gf.Curved() {
+			return
+		}
+		fallthrough
+	default:
+		n, t := gf.Normal, gf.Normal.Perp()
+		d := gf.keyOffset(key)
+		at := func(u float64) Vec2 {
+			off := d
+			if gf.Kind == GridWavy && gf.Wavy() {
+				off += gf.curve(u)
+			}
+			return cam.ToScreen(center.Add(n.Mul(off)).Add(t.Mul(u)))
+		}
+		steps := 1
+		if gf.Kind == GridWavy && gf.Wavy() {
+			steps = rippleSteps
+		}
+		pts := make([]Vec2, steps+1)
+		for i := range pts {
+			pts[i] = at(from + d0 + (d1-d0)*float64(i)/float64(steps))
+		}
+		s.polyline(pts, width, col)
+	}
+}
@@ -0,0 +1,147 @@
+package geom
+
+import "math"
+
+// Snapping to the lattice. The lines of the families near a position are
+// taken as exact shapes (straight lines, rays and rings, whole even where
+// they are dashed) and every pair is intersected analytically, so a point
+// snapped to a crossing sits exactly on both lines. Wavy lines have no closed
+// form and are left out.
+
+// snapNeighbors is the number of lines on each side of the nearest one that
+// are intersected, so the nearest crossing is found even between lines.
+const snapNeighbors = 1
+
+// shape is one line of a family: a straight line through o along dir (only
+// ahead of o for a ray), or a circle of radius r around o.
+type shape struct {
+	o      Vec2
+	dir    Vec2
+	r      float64
+	circle bool
+	ray    bool
+}
+
+// snapShapes returns the lines of the family nearest to p.
+func (gf *GridFamily) snapShapes(p, center Vec2) []shape {
+	var out []shape
+	switch gf.Kind {
+	case GridRadial:
+		k0 := math.Round((p.Sub(gf.Origin).Len() - gf.Offset) / gf.Spacing)
+		for k := k0 - snapNeighbors; k <= k0+snapNeighbors; k++ {
+			if r := k*gf.Spacing + gf.Offset; r > 0 {
+				out = append(out, shape{o: gf.Origin, r: r, circle: true})
+			}
+		}
+	case GridRay:
+		rel := p.Sub(gf.Origin)
+		step := 2 * math.Pi / float64(gf.RayCount())
+		a0 := gf.nearestRay(math.Atan2(rel.Y, rel.X))
+		for i := -snapNeighbors; i <= snapNeighbors; i++ {
+			a := a0 + float64(i)*step
+			out = append(out, shape{o: gf.Origin, dir: Vec2{math.Cos(a), math.Sin(a)}, ray: true})
+		}
+	case GridHex:
+		for _, l := range gf.HexLines() {
+			out = append(out, l.snapShapes(p, center)...)
+		}
+	case GridWavy, GridCurve:
+		if gf.Wavy() || gf.Curved() {
+			return nil
+		}
+		fallthrough
+	default:
+		k0 := math.Round((gf.Normal.Dot(p.Sub(center)) - gf.Offset) / gf.Spacing)
+		for k := k0 - snapNeighbors; k <= k0+snapNeighbors; k++ {
+			o := center.Add(gf.Normal.Mul(k*gf.Spacing + gf.Offset))
+			out = append(out, shape{o: o, dir: gf.Normal.Perp()})
+		}
+	}
+	return out
+}
+
+// NearestCrossing returns the crossing of two lines of grids nearest to p
+// within radius (which may be infinite). ok is false when there is none.
+func NearestCrossing(grids []GridFamily, p, center Vec2, radius float64) (x Vec2, ok bool) {
+	var shapes []shape
+	for i := range grids {
+		shapes = append(shapes, grids[i].snapShapes(p, center)...)
+	}
+	best := radius
+	for a := range shapes {
+		for b := a + 1; b < len(shapes); b++ {
+			for _, c := range crossings(shapes[a], shapes[b]) {
+				if d := c.Sub(p).Len(); d <= best {
+					x, ok, best = c, true, d
+				}
+			}
+		}
+	}
+	return x, ok
+}
+
+// crossings returns the points where two shapes meet.
+func crossings(a, b shape) []Vec2 {
+	switch {
+	case a.circle && b.circle:
+		return circleCircle(a, b)
+	case a.circle:
+		return lineCircle(b, a)
+	case b.circle:
+		return lineCircle(a, b)
+	}
+	return lineLine(a, b)
+}
+
+// ahead reports whether the point at parameter t of line l is on it, which
+// for a ray means not behind its origin.
+func (l shape) ahead(t float64) bool {
+	return !l.ray || t >= -1e-9
+}
+
+func cross(a, b Vec2) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+func lineLine(a, b shape) []Vec2 {
+	det := cross(a.dir, b.dir)
+	if math.Abs(det) < 1e-9 {
+		return nil
+	}
+	w := b.o.Sub(a.o)
+	ta, tb := cross(w, b.dir)/det, cross(w, a.dir)/det
+	if !a.ahead(ta) || !b.ahead(tb) {
+		return nil
+	}
+	return []Vec2{a.o.Add(a.dir.Mul(ta))}
+}
+
+func lineCircle(l, c shape) []Vec2 {
+	f := l.o.Sub(c.o)
+	half := f.Dot(l.dir)
+	disc := half*half - (f.Dot(f) - c.r*c.r)
+	if disc < 0 {
+		return nil
+	}
+	var out []Vec2
+	for _, t := range []float64{-half - math.Sqrt(disc), -half + math.Sqrt(disc)} {
+		if l.ahead(t) {
+			out = append(out, l.o.Add(l.dir.Mul(t)))
+		}
+	}
+	return out
+}
+
+func circleCircle(a, b shape) []Vec2 {
+	v := b.o.Sub(a.o)
+	d := v.Len()
+	if d < 1e-9 || d > a.r+b.r || d < math.Abs(a.r-b.r) {
+		return nil
+	}
+	// Distance from a's center to the chord through both crossings, and half the chord
+	along := (a.r*a.r - b.r*b.r + d*d) / (2 * d)
+	h := math.Sqrt(math.Max(0, a.r*a.r-along*along))
+	u := v.Mul(1 / d)
+	m := a.o.Add(u.Mul(along))
+	return []Vec2{m.Add(u.Perp().Mul(h)), m.Sub(u.Perp().Mul(h))}
+}
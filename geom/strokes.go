@@ -0,0 +1,184 @@
+package geom
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Lines are drawn as triangles: a family collects the strokes of all its
+// lines and draws them in one call with its blend mode, so dense families can
+// add up to a glow where they overlap instead of painting over each other.
+// Colors are premultiplied, which lets a family's opacity scale them as a
+// whole (see lineStyle).
+
+// BlendMode is how a family's lines combine with what is drawn under them.
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota // lines are painted over the canvas
+	BlendAdd                     // lines add their light to the canvas
+)
+
+var blendNames = []string{"normal", "add"}
+
+func (b BlendMode) String() string {
+	if b < 0 || int(b) >= len(blendNames) {
+		return "unknown"
+	}
+	return blendNames[b]
+}
+
+// Next returns the following blend mode, wrapping around.
+func (b BlendMode) Next() BlendMode {
+	return (b + 1) % BlendMode(len(blendNames))
+}
+
+// MarshalText stores a blend mode by name.
+func (b BlendMode) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText parses a blend mode name.
+func (b *BlendMode) UnmarshalText(text []byte) error {
+	for i, n := range blendNames {
+		if n == string(text) {
+			*b = BlendMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown blend mode %q", text)
+}
+
+// ebiten returns the blend state of the mode.
+func (b BlendMode) ebiten() ebiten.Blend {
+	if b == BlendAdd {
+		return ebiten.BlendLighter
+	}
+	return ebiten.BlendSourceOver
+}
+
+// ValidateAlpha reports an opacity outside 0-1.
+func ValidateAlpha(a float64) error {
+	if a < 0 || a > 1 {
+		return fmt.Errorf("alpha %g out of range 0-1", a)
+	}
+	return nil
+}
+
+// AlphaSteps are the opacities an editor cycles through, 0 being opaque.
+var AlphaSteps = []float64{0, 0.75, 0.5, 0.25}
+
+// NextAlpha returns the step after opacity a; other values go back to opaque.
+func NextAlpha(a float64) float64 {
+	for i, s := range AlphaSteps {
+		if s == a {
+			return AlphaSteps[(i+1)%len(AlphaSteps)]
+		}
+	}
+	return 0
+}
+
+// strokeFlushVertices is the vertex count at which strokes are drawn before
+// more are added, well below what one draw call takes.
+const strokeFlushVertices = ebiten.MaxVerticesCount - 4096
+
+// maxPolylinePoints bounds the points stroked as one path, so a single path
+// stays below strokeFlushVertices however far the view is zoomed in.
+const maxPolylinePoints = 1024
+
+var whitePixel *ebiten.Image
+
+// drawCalls counts the batches flush drew (see DrawCalls).
+var drawCalls int
+
+// DrawCalls returns the number of batches of lines and fills drawn since it
+// was last called, for profiling.
+func DrawCalls() int {
+	n := drawCalls
+	drawCalls = 0
+	return n
+}
+
+// whiteSource returns the image strokes sample their color from.
+func whiteSource() *ebiten.Image {
+	if whitePixel == nil {
+		img := ebiten.NewImage(3, 3)
+		img.Fill(color.White)
+		// The inner pixel is sampled so filtering never reaches the edge
+		whitePixel = img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+	}
+	return whitePixel
+}
+
+// strokes collects line strokes as triangles to draw in one call.
+type strokes struct {
+	dst   *ebiten.Image
+	blend ebiten.Blend
+	scale float64 // device pixels per window pixel; widths are given in window pixels
+	vs    []ebiten.Vertex
+	is    []uint16
+	path  vector.Path
+}
+
+func newStrokes(dst *ebiten.Image, mode BlendMode, scale float64) *strokes {
+	return &strokes{dst: dst, blend: mode.ebiten(), scale: scale}
+}
+
+// line strokes the segment from a to b.
+func (s *strokes) line(a, b Vec2, width float64, col color.Color) {
+	s.path = vector.Path{}
+	s.path.MoveTo(float32(a.X), float32(a.Y))
+	s.path.LineTo(float32(b.X), float32(b.Y))
+	s.stroke(width, col)
+}
+
+// polyline strokes the path through pts, joining the segments.
+func (s *strokes) polyline(pts []Vec2, width float64, col color.Color) {
+	for len(pts) > 1 {
+		n := min(len(pts), maxPolylinePoints)
+		s.path = vector.Path{}
+		s.path.MoveTo(float32(pts[0].X), float32(pts[0].Y))
+		for _, p := range pts[1:n] {
+			s.path.LineTo(float32(p.X), float32(p.Y))
+		}
+		s.stroke(width, col)
+		// The next part starts where this one ended
+		pts = pts[n-1:]
+	}
+}
+
+// stroke adds the triangles of s.path in col.
+func (s *strokes) stroke(width float64, col color.Color) {
+	if len(s.vs) > strokeFlushVertices {
+		s.flush()
+	}
+	start := len(s.vs)
+	s.vs, s.is = s.path.AppendVerticesAndIndicesForStroke(s.vs, s.is, &vector.StrokeOptions{Width: float32(width * s.scale)})
+	r, g, b, a := col.RGBA()
+	for i := start; i < len(s.vs); i++ {
+		v := &s.vs[i]
+		v.SrcX, v.SrcY = 1, 1
+		v.ColorR = float32(r) / 0xffff
+		v.ColorG = float32(g) / 0xffff
+		v.ColorB = float32(b) / 0xffff
+		v.ColorA = float32(a) / 0xffff
+	}
+}
+
+// flush draws the collected triangles.
+func (s *strokes) flush() {
+	if len(s.is) == 0 {
+		return
+	}
+	s.dst.DrawTriangles(s.vs, s.is, whiteSource(), &ebiten.DrawTrianglesOptions{
+		ColorScaleMode: ebiten.ColorScaleModePremultipliedAlpha,
+		Blend:          s.blend,
+		AntiAlias:      true,
+	})
+	drawCalls++
+	s.vs, s.is = s.vs[:0], s.is[:0]
+}
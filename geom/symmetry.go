@@ -0,0 +1,132 @@
+package geom
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Symmetry. A symmetry maps a position to its images about a center: its
+// mirror image across the vertical axis, the horizontal one or both, or its
+// rotations in N equal steps. Image 0 is always the position itself.
+
+// SymmetryKind selects how images are made.
+type SymmetryKind int
+
+const (
+	SymmetryOff        SymmetryKind = iota
+	SymmetryVertical                // mirrored across the vertical axis (left-right)
+	SymmetryHorizontal              // mirrored across the horizontal axis (top-bottom)
+	SymmetryBoth                    // mirrored across both axes, four images
+	SymmetryRadial                  // rotated in Fold equal steps
+)
+
+var symmetryKindNames = []string{"off", "vertical", "horizontal", "both", "radial"}
+
+// Symmetry is a kind of symmetry and, for radial ones, the number of images.
+type Symmetry struct {
+	Kind SymmetryKind
+	Fold int // images of a radial symmetry, 2 or more
+}
+
+// Count returns the number of images, the position itself included.
+func (s Symmetry) Count() int {
+	switch s.Kind {
+	case SymmetryVertical, SymmetryHorizontal:
+		return 2
+	case SymmetryBoth:
+		return 4
+	case SymmetryRadial:
+		return max(s.Fold, 1)
+	}
+	return 1
+}
+
+// Image returns image i of p about center.
+func (s Symmetry) Image(p, center Vec2, i int) Vec2 {
+	d := p.Sub(center)
+	switch s.Kind {
+	case SymmetryVertical:
+		if i == 1 {
+			d.X = -d.X
+		}
+	case SymmetryHorizontal:
+		if i == 1 {
+			d.Y = -d.Y
+		}
+	case SymmetryBoth:
+		if i&1 != 0 {
+			d.X = -d.X
+		}
+		if i&2 != 0 {
+			d.Y = -d.Y
+		}
+	case SymmetryRadial:
+		d = rotate(d, 2*math.Pi*float64(i)/float64(s.Count()))
+	}
+	return center.Add(d)
+}
+
+// Source returns the position whose image i is q, the inverse of Image.
+func (s Symmetry) Source(q, center Vec2, i int) Vec2 {
+	if s.Kind == SymmetryRadial {
+		return center.Add(rotate(q.Sub(center), -2*math.Pi*float64(i)/float64(s.Count())))
+	}
+	// Mirror images are their own inverse
+	return s.Image(q, center, i)
+}
+
+// String names the symmetry as ParseSymmetry reads it, e.g. "vertical" or
+// "radial-6".
+func (s Symmetry) String() string {
+	if s.Kind < 0 || int(s.Kind) >= len(symmetryKindNames) {
+		return "unknown"
+	}
+	if s.Kind == SymmetryRadial {
+		return fmt.Sprintf("radial-%d", s.Fold)
+	}
+	return symmetryKindNames[s.Kind]
+}
+
+// MarshalText stores a symmetry by name.
+func (s Symmetry) MarshalText() ([]byte, error) {
+	if s.Kind < 0 || int(s.Kind) >= len(symmetryKindNames) {
+		return nil, fmt.Errorf("unknown symmetry kind %d", s.Kind)
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses a symmetry name.
+func (s *Symmetry) UnmarshalText(b []byte) error {
+	v, err := ParseSymmetry(string(b))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// MaxFold bounds the images of a radial symmetry.
+const MaxFold = 12
+
+// ParseSymmetry reads "off", "vertical", "horizontal", "both" or "radial-N"
+// with N from 2 to MaxFold; "" is off.
+func ParseSymmetry(name string) (Symmetry, error) {
+	if n, ok := strings.CutPrefix(name, "radial-"); ok {
+		fold, err := strconv.Atoi(n)
+		if err != nil || fold < 2 || fold > MaxFold {
+			return Symmetry{}, fmt.Errorf("symmetry %q: fold must be 2-%d", name, MaxFold)
+		}
+		return Symmetry{Kind: SymmetryRadial, Fold: fold}, nil
+	}
+	if name == "" {
+		return Symmetry{}, nil
+	}
+	for i, n := range symmetryKindNames[:SymmetryRadial] {
+		if n == name {
+			return Symmetry{Kind: SymmetryKind(i)}, nil
+		}
+	}
+	return Symmetry{}, fmt.Errorf("unknown symmetry %q (want off, vertical, horizontal, both or radial-N)", name)
+}
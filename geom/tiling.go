@@ -0,0 +1,206 @@
+package geom
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Tiling. The straight lines of two or three families cut the plane into
+// cells: parallelograms for two families and, for three, triangles or the
+// convex pieces lines at other angles make. A tiling fills each cell with its
+// own color, so the lines frame shapes that can light up. Cells are numbered
+// by the lines below them, like LineKey, so a cell keeps its key while the
+// families move.
+
+// CellKey identifies a cell by the number of the line of each family on its
+// lower side; the third is 0 for a tiling of two families.
+type CellKey [3]int
+
+// maxTilingCells bounds the cells a tiling draws; zoomed far out the cells
+// are too small to see and it draws none.
+const maxTilingCells = 20000
+
+// minTilingSine is the sine of the smallest angle between the families of a
+// tiling; nearly parallel ones would make cells of endless slivers.
+const minTilingSine = 0.1
+
+// TilingLines returns the straight line sets among grids that tile the
+// plane, at most three: linear families, wavy ones without a curve and the
+// three directions of hex lattices (not honeycombs), in order, leaving out
+// any nearly parallel to one already taken. owners holds the grid each set
+// belongs to. A tiling needs two sets or more.
+func TilingLines(grids []GridFamily) (lines []GridFamily, owners []int) {
+	add := func(gi int, l GridFamily) {
+		if len(lines) == 3 || l.Spacing <= 0 {
+			return
+		}
+		for _, o := range lines {
+			if math.Abs(o.Normal.Cross(l.Normal)) < minTilingSine {
+				return
+			}
+		}
+		lines = append(lines, l)
+		owners = append(owners, gi)
+	}
+	for gi := range grids {
+		gf := &grids[gi]
+		switch {
+		case gf.Kind == GridLinear, gf.Kind == GridWavy && !gf.Wavy(), gf.Kind == GridCurve && !gf.Curved():
+			add(gi, *gf)
+		case gf.Kind == GridHex && !gf.HexTiling:
+			for _, l := range gf.HexLines() {
+				add(gi, l)
+			}
+		}
+	}
+	return lines, owners
+}
+
+// cellBelow returns the number of the line at or below distance d along the
+// normal from center, and that line's distance.
+func (gf *GridFamily) cellBelow(d float64) (int, float64) {
+	at := math.Floor((d-gf.Offset)/gf.Spacing)*gf.Spacing + gf.Offset
+	return gf.lineKeyAt(at).n, at
+}
+
+// CellAt returns the key of the cell of the tiling by lines that holds p.
+// center is the world anchor of linear families.
+func CellAt(lines []GridFamily, p, center Vec2) CellKey {
+	var key CellKey
+	for i := range lines {
+		key[i], _ = lines[i].cellBelow(lines[i].Normal.Dot(p.Sub(center)))
+	}
+	return key
+}
+
+// DrawTiling fills the cells of the tiling by lines (two or three, see
+// TilingLines) visible through cam, each in the color fill gives its key.
+// Colors are premultiplied. center is the world anchor of linear families.
+func DrawTiling(dst *ebiten.Image, cam *Camera, center Vec2, lines []GridFamily, fill func(CellKey) color.Color) {
+	if len(lines) < 2 {
+		return
+	}
+	a, b := &lines[0], &lines[1]
+	view, R := cam.Center, cam.ViewRadius()
+	a0, a1 := a.viewRange(view.Sub(center), R)
+	b0, b1 := b.viewRange(view.Sub(center), R)
+	if (a1-a0+1)*(b1-b0+1) > maxTilingCells {
+		return
+	}
+	// corner returns the point at distances da and db along the normals of a and b
+	det := a.Normal.Cross(b.Normal)
+	corner := func(da, db float64) Vec2 {
+		return center.Add(Vec2{
+			X: (da*b.Normal.Y - db*a.Normal.Y) / det,
+			Y: (db*a.Normal.X - da*b.Normal.X) / det,
+		})
+	}
+	s := newStrokes(dst, BlendNormal, cam.Device())
+	for i := a0; i <= a1; i++ {
+		da := float64(i)*a.Spacing + a.Offset
+		for j := b0; j <= b1; j++ {
+			db := float64(j)*b.Spacing + b.Offset
+			cell := []Vec2{
+				corner(da, db),
+				corner(da+a.Spacing, db),
+				corner(da+a.Spacing, db+b.Spacing),
+				corner(da, db+b.Spacing),
+			}
+			if !nearView(cell, view, R) {
+				continue
+			}
+			var key CellKey
+			key[0] = a.lineKeyAt(da).n
+			key[1] = b.lineKeyAt(db).n
+			if len(lines) == 2 {
+				s.polygon(cam, cell, fill(key))
+				continue
+			}
+			// The third family cuts the cell into strips
+			c := &lines[2]
+			lo, hi := math.Inf(1), math.Inf(-1)
+			for _, p := range cell {
+				d := c.Normal.Dot(p.Sub(center))
+				lo, hi = math.Min(lo, d), math.Max(hi, d)
+			}
+			k, dc := c.cellBelow(lo)
+			for ; dc < hi; k, dc = k+1, dc+c.Spacing {
+				piece := clipStrip(cell, c.Normal, center, dc, dc+c.Spacing)
+				if len(piece) < 3 {
+					continue
+				}
+				key[2] = k
+				s.polygon(cam, piece, fill(key))
+			}
+		}
+	}
+	s.flush()
+}
+
+// viewRange returns the numbers k of the lines k*Spacing+Offset whose cells
+// reach within R of the view center at rel from the anchor.
+func (gf *GridFamily) viewRange(rel Vec2, R float64) (int, int) {
+	d := gf.Normal.Dot(rel) - gf.Offset
+	lo := math.Floor((d-R)/gf.Spacing) - 1
+	hi := math.Ceil((d + R) / gf.Spacing)
+	return int(lo), int(hi)
+}
+
+// nearView reports whether the bounding box of poly meets the square of
+// half-size R around view.
+func nearView(poly []Vec2, view Vec2, R float64) bool {
+	lo, hi := poly[0], poly[0]
+	for _, p := range poly[1:] {
+		lo = Vec2{X: math.Min(lo.X, p.X), Y: math.Min(lo.Y, p.Y)}
+		hi = Vec2{X: math.Max(hi.X, p.X), Y: math.Max(hi.Y, p.Y)}
+	}
+	return hi.X >= view.X-R && lo.X <= view.X+R && hi.Y >= view.Y-R && lo.Y <= view.Y+R
+}
+
+// clipStrip returns the part of the convex polygon poly between the lines at
+// distances lo and hi along normal n from center.
+func clipStrip(poly []Vec2, n, center Vec2, lo, hi float64) []Vec2 {
+	poly = clipHalf(poly, func(p Vec2) float64 { return n.Dot(p.Sub(center)) - lo })
+	return clipHalf(poly, func(p Vec2) float64 { return hi - n.Dot(p.Sub(center)) })
+}
+
+// clipHalf keeps the part of the convex polygon poly where side is not
+// negative.
+func clipHalf(poly []Vec2, side func(Vec2) float64) []Vec2 {
+	var out []Vec2
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		sp, sq := side(p), side(q)
+		if sp >= 0 {
+			out = append(out, p)
+		}
+		if (sp > 0 && sq < 0) || (sp < 0 && sq > 0) {
+			out = append(out, p.Add(q.Sub(p).Mul(sp/(sp-sq))))
+		}
+	}
+	return out
+}
+
+// polygon fills the convex polygon poly (world coordinates) in col.
+func (s *strokes) polygon(cam *Camera, poly []Vec2, col color.Color) {
+	if len(s.vs) > strokeFlushVertices {
+		s.flush()
+	}
+	r, g, b, a := col.RGBA()
+	if a == 0 {
+		return
+	}
+	start := uint16(len(s.vs))
+	for _, p := range poly {
+		sp := cam.ToScreen(p)
+		s.vs = append(s.vs, ebiten.Vertex{
+			DstX: float32(sp.X), DstY: float32(sp.Y), SrcX: 1, SrcY: 1,
+			ColorR: float32(r) / 0xffff, ColorG: float32(g) / 0xffff, ColorB: float32(b) / 0xffff, ColorA: float32(a) / 0xffff,
+		})
+	}
+	for i := 1; i+1 < len(poly); i++ {
+		s.is = append(s.is, start, start+uint16(i), start+uint16(i+1))
+	}
+}
@@ -0,0 +1,68 @@
+package geom
+
+import (
+	"fmt"
+	"math"
+)
+
+// Each family has its own transport on top of the motion it follows: it can
+// be paused, so its lines stand still while the others keep moving, and its
+// speed can be scaled by a rate, where a negative rate runs it backwards.
+// The rate applies to the pattern's travel and to the turning of rays alike.
+
+// MinRate and MaxRate bound the magnitude of a family's rate.
+const (
+	MinRate = 0.125
+	MaxRate = 8.0
+)
+
+// ValidateRate reports a rate an editor could not have set; 0 means 1.
+func ValidateRate(r float64) error {
+	if r == 0 {
+		return nil
+	}
+	if a := math.Abs(r); !(a >= MinRate && a <= MaxRate) {
+		return fmt.Errorf("rate %g out of range %g-%g (negative runs backwards)", r, MinRate, MaxRate)
+	}
+	return nil
+}
+
+// MotionRate returns the factor the family's motion is scaled by: 0 while it
+// is paused, otherwise its rate.
+func (gf *GridFamily) MotionRate() float64 {
+	switch {
+	case gf.Paused:
+		return 0
+	case gf.Rate == 0:
+		return 1
+	}
+	return gf.Rate
+}
+
+// ScaleRate returns rate r multiplied by f, kept within MinRate and MaxRate.
+func ScaleRate(r, f float64) float64 {
+	if r == 0 {
+		r = 1
+	}
+	a := math.Min(MaxRate, math.Max(MinRate, math.Abs(r)*f))
+	if r < 0 {
+		return -a
+	}
+	return a
+}
+
+// RateLabel describes the family's transport for display.
+func (gf *GridFamily) RateLabel() string {
+	r := gf.Rate
+	if r == 0 {
+		r = 1
+	}
+	s := fmt.Sprintf("x%g", math.Abs(r))
+	if r < 0 {
+		s += " reversed"
+	}
+	if gf.Paused {
+		s += " paused"
+	}
+	return s
+}
@@ -1,9 +1,12 @@
-package main
+package geom
 
 import "math"
 
 // Vec2 is a simple 2D vector.
-type Vec2 struct{ X, Y float64 }
+type Vec2 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
 
 func (a Vec2) Add(b Vec2) Vec2    { return Vec2{a.X + b.X, a.Y + b.Y} }
 func (a Vec2) Sub(b Vec2) Vec2    { return Vec2{a.X - b.X, a.Y - b.Y} }
@@ -18,3 +21,7 @@ func (a Vec2) Norm() Vec2 {
 	return Vec2{a.X / l, a.Y / l}
 }
 func (a Vec2) Perp() Vec2 { return Vec2{-a.Y, a.X} }
+
+// Cross returns the z component of the cross product, |a||b| times the sine
+// of the angle from a to b.
+func (a Vec2) Cross(b Vec2) float64 { return a.X*b.Y - a.Y*b.X }
@@ -0,0 +1,132 @@
+package geom
+
+import "math"
+
+// A wavy family (GridWavy) is a linear family whose lines are sine curves: the
+// line at distance d along Normal is displaced to d + curve(s), where s is the
+// coordinate along the tangent. Motion along the normal slides the curves like
+// straight lines; motion along the tangent travels the wave along the lines.
+
+// curve returns the displacement of a wavy line at tangent coordinate s.
+func (gf *GridFamily) curve(s float64) float64 {
+	return gf.Amplitude * math.Sin(gf.curveArg(s))
+}
+
+// curveSlope returns the derivative of curve at s.
+func (gf *GridFamily) curveSlope(s float64) float64 {
+	return gf.Amplitude * 2 * math.Pi / gf.Wavelength * math.Cos(gf.curveArg(s))
+}
+
+// curveBend returns the second derivative of curve at s.
+func (gf *GridFamily) curveBend(s float64) float64 {
+	k := 2 * math.Pi / gf.Wavelength
+	return -gf.Amplitude * k * k * math.Sin(gf.curveArg(s))
+}
+
+func (gf *GridFamily) curveArg(s float64) float64 {
+	return 2*math.Pi*(s-gf.CurveShift)/gf.Wavelength + gf.CurvePhase
+}
+
+// Wavy reports whether the family has a usable curve; without one it behaves
+// like a linear family.
+func (gf *GridFamily) Wavy() bool {
+	return gf.Amplitude != 0 && gf.Wavelength > 0
+}
+
+// advanceCurve travels the wave along the lines by the tangential part of step.
+func (gf *GridFamily) advanceCurve(step Vec2) {
+	if !gf.Wavy() {
+		return
+	}
+	gf.CurveShift = math.Mod(gf.CurveShift+gf.Normal.Perp().Dot(step), gf.Wavelength)
+}
+
+// nearestCurve returns the tangent coordinate of the point on wavy line d
+// closest to the point (s0, u) in the family's frame, and the distance to it.
+// It refines the foot point with a few Newton steps on the squared distance.
+func (gf *GridFamily) nearestCurve(s0, u, d float64) (s, dist float64) {
+	s = s0
+	for i := 0; i < 6; i++ {
+		r := u - d - gf.curve(s)
+		f1 := gf.curveSlope(s)
+		// Gradient and curvature of half the squared distance
+		h := (s - s0) - r*f1
+		h1 := 1 + f1*f1 - r*gf.curveBend(s)
+		if h1 <= 0 {
+			// Not locally convex: fall back to a damped gradient step
+			h1 = 1 + f1*f1
+		}
+		s -= h / h1
+	}
+	return s, math.Hypot(s-s0, u-d-gf.curve(s))
+}
+
+// touchesWavy reports whether p lies within the thickness band of a drawn
+// part of a wavy line. center is the world anchor of the family.
+func (gf *GridFamily) touchesWavy(p, center Vec2) bool {
+	_, ok := gf.wavyLine(p, center)
+	return ok
+}
+
+// wavyLine returns the index k of a drawn wavy line touching p.
+func (gf *GridFamily) wavyLine(p, center Vec2) (float64, bool) {
+	n, t := gf.Normal, gf.Normal.Perp()
+	u, s0 := n.Dot(p.Sub(center)), t.Dot(p.Sub(center))
+	// Any line within an amplitude of the point may pass close to it
+	a := math.Abs(gf.Amplitude)
+	kMin := math.Floor((u - a - gf.Thickness - gf.Offset) / gf.Spacing)
+	kMax := math.Ceil((u + a + gf.Thickness - gf.Offset) / gf.Spacing)
+	for k := kMin; k <= kMax; k++ {
+		s, dist := gf.nearestCurve(s0, u, k*gf.Spacing+gf.Offset)
+		if dist > gf.Thickness {
+			continue
+		}
+		// Dashes are laid out along the tangent, as for straight lines
+		if !gf.dashed() || gf.inDash(gf.patternPos(s)) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// wavyCrossingSpeed is the speed at which the wavy line through p passes it:
+// the component of vel along the curve's normal at p.
+func (gf *GridFamily) wavyCrossingSpeed(p, center, vel Vec2) float64 {
+	n, t := gf.Normal, gf.Normal.Perp()
+	slope := gf.curveSlope(t.Dot(p.Sub(center)))
+	cn := n.Sub(t.Mul(slope)).Norm()
+	return math.Abs(cn.Dot(vel))
+}
+
+// drawWavy renders the visible wavy lines as polylines.
+func (gf *GridFamily) drawWavy(s *strokes, cam *Camera, center Vec2) {
+	n, t := gf.Normal, gf.Normal.Perp()
+	view := cam.Center
+	R := cam.ViewRadius()
+	a := math.Abs(gf.Amplitude)
+	dView := n.Dot(view.Sub(center))
+	kMin := int(math.Floor((dView-R-a-gf.Offset)/gf.Spacing)) - 1
+	kMax := int(math.Ceil((dView+R+a-gf.Offset)/gf.Spacing)) + 1
+	sc := t.Dot(view.Sub(center))
+	// Keep segments about 4 screen pixels long and at most 1/16 wavelength
+	step := math.Min(4/cam.Scale(), gf.Wavelength/16)
+	segs := int(math.Ceil(2 * R / step))
+	for k := kMin; k <= kMax; k++ {
+		d := float64(k)*gf.Spacing + gf.Offset
+		width, col := gf.lineStyle(gf.lineKeyAt(d))
+		at := func(s float64) Vec2 {
+			return cam.ToScreen(center.Add(n.Mul(d + gf.curve(s))).Add(t.Mul(s)))
+		}
+		pos := sc - R
+		prev := at(pos)
+		for i := 0; i < segs; i++ {
+			next := at(pos + step)
+			// Dashes are decided per segment so drawing matches the hit test
+			if !gf.dashed() || gf.inDash(gf.patternPos(pos+step/2)) {
+				s.line(prev, next, width, col)
+			}
+			prev = next
+			pos += step
+		}
+	}
+}
@@ -0,0 +1,24 @@
+-- Example script: every bar, turn the movement a little and transpose the
+-- first grid; points that were just triggered drift upward and wrap around.
+-- Run with: go run ./cmd/grythm -script scripts/wander.lua (tempo mode, T, for onBeat)
+
+local turn = 0
+
+function onBeat(bar, beat)
+  if beat == 1 then
+    turn = turn + 15
+    grythm.setMotion(turn, 120)
+    if grythm.gridCount() > 0 then
+      grythm.setGrid(1, { degree = bar % 5 })
+    end
+  end
+end
+
+function onTrigger(grid, point, velocity)
+  local p = grythm.point(point)
+  local y = p.y - 10 * velocity
+  if y < 0 then
+    y = grythm.height()
+  end
+  grythm.setPoint(point, { y = y })
+end
@@ -0,0 +1,354 @@
+// Package synth generates grythm's sound: synthesized voices and decoded
+// samples, mixed with effects into a 16-bit stereo PCM stream.
+package synth
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Waveform selects the oscillator shape of a voice.
+type Waveform int
+
+const (
+	// WaveInherit defers to the enclosing voice: points use their grid's
+	// waveform and grids fall back to sine.
+	WaveInherit Waveform = iota
+	WaveSine
+	WaveTriangle
+	WaveSquare
+	WaveSaw
+	WaveNoise
+)
+
+var waveformNames = []string{"inherit", "sine", "triangle", "square", "saw", "noise"}
+
+func (w Waveform) String() string {
+	if w < 0 || int(w) >= len(waveformNames) {
+		return "unknown"
+	}
+	return waveformNames[w]
+}
+
+// Next returns the following waveform, wrapping around. Inherit is only part of
+// the cycle when allowInherit is set (points can inherit, grids cannot).
+func (w Waveform) Next(allowInherit bool) Waveform {
+	n := (w + 1) % Waveform(len(waveformNames))
+	if n == WaveInherit && !allowInherit {
+		n = WaveSine
+	}
+	return n
+}
+
+// MarshalText stores a waveform by name.
+func (w Waveform) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+// UnmarshalText parses a waveform name.
+func (w *Waveform) UnmarshalText(b []byte) error {
+	for i, n := range waveformNames {
+		if n == string(b) {
+			*w = Waveform(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown waveform %q", b)
+}
+
+// ResolveWave picks the waveform a trigger should use: the point's own choice
+// wins, then the grid's, then sine.
+func ResolveWave(grid, point Waveform) Waveform {
+	if point != WaveInherit {
+		return point
+	}
+	if grid != WaveInherit {
+		return grid
+	}
+	return WaveSine
+}
+
+// oscillator evaluates one sample of a waveform at the given phase (radians).
+// Noise ignores the phase and draws from rng.
+func oscillator(w Waveform, phase float64, rng *rand.Rand) float64 {
+	switch w {
+	case WaveTriangle:
+		x := math.Mod(phase/(2*math.Pi), 1)
+		return 4*math.Abs(x-0.5) - 1
+	case WaveSquare:
+		if math.Sin(phase) >= 0 {
+			return 1
+		}
+		return -1
+	case WaveSaw:
+		x := math.Mod(phase/(2*math.Pi), 1)
+		return 2*x - 1
+	case WaveNoise:
+		return rng.Float64()*2 - 1
+	default:
+		return math.Sin(phase)
+	}
+}
+
+// waveGain balances perceived loudness; harmonically rich shapes are much louder than a sine.
+func waveGain(w Waveform) float64 {
+	switch w {
+	case WaveSquare, WaveNoise:
+		return 0.45
+	case WaveSaw:
+		return 0.6
+	default:
+		return 1
+	}
+}
+
+// Envelope is an ADSR amplitude envelope. Attack, Decay, Release and Gate are
+// in seconds; Sustain is a level in [0, 1]. The note is held at the sustain
+// level until Gate has passed and then released. The zero Envelope selects the
+// classic percussive blip (fast attack, exponential decay over 60ms).
+type Envelope struct {
+	Attack  float64 `json:"attack"`
+	Decay   float64 `json:"decay"`
+	Sustain float64 `json:"sustain"`
+	Release float64 `json:"release"`
+	Gate    float64 `json:"gate"`
+}
+
+// blipSeconds is the length of the classic blip.
+const blipSeconds = 0.06
+
+// EnvelopePresets are the named envelopes, in the order an editor cycles through them.
+var EnvelopePresets = []struct {
+	Name string
+	Env  Envelope
+}{
+	{"blip", Envelope{}},
+	{"pluck", Envelope{Attack: 0.002, Decay: 0.25, Sustain: 0, Release: 0.05, Gate: 0.05}},
+	{"organ", Envelope{Attack: 0.01, Decay: 0, Sustain: 1, Release: 0.08, Gate: 0.25}},
+	{"pad", Envelope{Attack: 0.3, Decay: 0.3, Sustain: 0.7, Release: 0.8, Gate: 0.6}},
+}
+
+// EnvelopeName returns the preset name of e, or "custom".
+func EnvelopeName(e Envelope) string {
+	for _, p := range EnvelopePresets {
+		if p.Env == e {
+			return p.Name
+		}
+	}
+	return "custom"
+}
+
+// NextEnvelope returns the preset after e. With allowInherit (points) the cycle
+// includes nil, meaning "use the grid's envelope".
+func NextEnvelope(e *Envelope, allowInherit bool) *Envelope {
+	idx := -1
+	if e != nil {
+		for i, p := range EnvelopePresets {
+			if p.Env == *e {
+				idx = i
+				break
+			}
+		}
+	}
+	idx++
+	if idx >= len(EnvelopePresets) {
+		if allowInherit {
+			return nil
+		}
+		idx = 0
+	}
+	env := EnvelopePresets[idx].Env
+	return &env
+}
+
+// ResolveEnvelope picks the point's envelope, then the grid's, then the blip.
+func ResolveEnvelope(grid, point *Envelope) Envelope {
+	if point != nil {
+		return *point
+	}
+	if grid != nil {
+		return *grid
+	}
+	return Envelope{}
+}
+
+// Validate checks that the envelope describes a playable note.
+func (e Envelope) Validate() error {
+	if e.Attack < 0 || e.Decay < 0 || e.Release < 0 || e.Gate < 0 {
+		return fmt.Errorf("envelope times must not be negative")
+	}
+	if e.Sustain < 0 || e.Sustain > 1 {
+		return fmt.Errorf("envelope sustain must be within 0-1")
+	}
+	if e.Length() > 10 {
+		return fmt.Errorf("envelope longer than 10s")
+	}
+	return nil
+}
+
+// isBlip reports whether e selects the classic blip envelope.
+func (e Envelope) isBlip() bool {
+	return e == Envelope{}
+}
+
+// Length returns the total duration of a note in seconds, including release.
+func (e Envelope) Length() float64 {
+	if e.isBlip() {
+		return blipSeconds
+	}
+	return math.Max(e.Gate, e.Attack+e.Decay) + e.Release
+}
+
+// Level returns the ADSR amplitude at time t seconds after note-on.
+func (e Envelope) Level(t float64) float64 {
+	// Level held when the gate closes, so an early release starts from where the note is
+	hold := func(t float64) float64 {
+		switch {
+		case t < e.Attack:
+			return t / e.Attack
+		case t < e.Attack+e.Decay:
+			x := (t - e.Attack) / e.Decay
+			return 1 - (1-e.Sustain)*x
+		default:
+			return e.Sustain
+		}
+	}
+	if t < e.Gate {
+		return hold(t)
+	}
+	start := hold(e.Gate)
+	if e.Release <= 0 {
+		return 0
+	}
+	x := (t - e.Gate) / e.Release
+	if x >= 1 {
+		return 0
+	}
+	return start * (1 - x)
+}
+
+// Voice describes a note to render. It is comparable so rendered notes can be cached by value.
+type Voice struct {
+	Freq   float64
+	Wave   Waveform
+	Env    Envelope
+	Length float64 // note length in seconds; 0 uses the envelope's own (see withLength)
+	Filter Filter  // optional filter stage (see filter.go)
+	Drum   Drum    // percussion instead of a pitched note (see drums.go); Freq, Wave and Env are unused
+	Glide  Glide   // pitch bend over the note (see glide.go); the zero Glide depends on Env
+	FM     FM      // two-operator FM instead of Wave (see fm.go); the zero FM plays Wave
+}
+
+// GenerateBlip renders a note as interleaved stereo float samples
+// (L, R, L, R, ...) in [-1, 1], ready to be summed by the Mixer.
+// With the classic blip envelope it applies a short fade-in (attack), gentle
+// exponential decay and, unless v.Glide says otherwise, a subtle downward
+// pitch glide; other envelopes hold a steady pitch shaped by their ADSR. A very quiet second harmonic gives a warmer
+// tone, and stereo channels are given a tiny phase/pan difference for width.
+// v.Wave selects the oscillator shape; WaveInherit renders a sine. An enabled
+// v.FM replaces the oscillator and its harmonic with an FM pair. An enabled
+// v.Filter is applied last, its cutoff following the envelope. Drums are
+// rendered by generateDrum.
+func GenerateBlip(sampleRate int, v Voice) []float32 {
+	if v.Drum != DrumNone {
+		return generateDrum(sampleRate, v)
+	}
+	adsr, seconds := v.Env.withLength(v.Length)
+	n := int(float64(sampleRate) * seconds)
+	if n <= 1 {
+		return nil
+	}
+	out := make([]float32, 0, 2*n)
+	wave := v.Wave
+	blip := v.Env.isBlip()
+	fm := v.FM.Enabled()
+
+	// Overall amplitude kept conservative to avoid clipping when harmonics combine.
+	amp := 0.22 * waveGain(wave)
+	if fm {
+		amp = 0.22
+	}
+	// Fixed seed so a noise blip sounds the same every time it is rendered.
+	rng := rand.New(rand.NewSource(1))
+
+	// Envelope: short attack to avoid clicks, then exponential decay.
+	attackSec := math.Min(0.005, seconds*0.2) // up to 5ms
+	attackN := int(attackSec * float64(sampleRate))
+	// Decay so that we reach about -60dB near the end of the sample.
+	// exp(-lambda) ~ 0.001 -> lambda ~ 6.9; distribute over n samples.
+	lambda := 6.9
+
+	// Pitch glide: by default a slight downwards bend for a softer percussive
+	// feel (blip only)
+	glide := v.Glide.resolve(blip)
+
+	// Stereo: tiny phase offset and pan difference.
+	phaseOffsetR := 0.015 // radians
+	panL := 0.55
+	panR := 0.45
+
+	filter := v.Filter.Enabled()
+	var filterL, filterR biquad
+
+	phase := 0.0
+	for i := 0; i < n; i++ {
+		// Time fraction 0..1
+		t := float64(i) / float64(n-1)
+
+		var level float64
+		if blip {
+			// Smooth attack
+			var envA float64
+			if i < attackN && attackN > 0 {
+				// cosine fade-in: 0 -> 1 smoothly
+				x := float64(i) / float64(attackN)
+				envA = 0.5 - 0.5*math.Cos(math.Pi*x)
+			} else {
+				envA = 1.0
+			}
+			// Exponential decay over the note duration
+			envD := math.Exp(-lambda * t)
+			level = envA * envD
+		} else {
+			level = adsr.Level(float64(i) / float64(sampleRate))
+		}
+		env := amp * level
+
+		// Glide by interpolating frequency in log domain
+		f := glide.freq(v.Freq, t)
+		phase += 2 * math.Pi * f / float64(sampleRate)
+
+		var l, r float64
+		if fm {
+			// The modulator tracks the carrier, glide included
+			idx := v.FM.index(float64(i) / float64(sampleRate))
+			l = v.FM.operator(phase, idx) * env * panL
+			r = v.FM.operator(phase+phaseOffsetR, idx) * env * panR
+		} else {
+			// Base waveform plus a very quiet second harmonic
+			base := oscillator(wave, phase, rng)
+			second := oscillator(wave, 2*phase, rng) * 0.18
+			mono := (base + second) * env
+
+			// Stereo with tiny right-channel phase offset and pan
+			l = mono * panL
+			r = oscillator(wave, phase+phaseOffsetR, rng)*env*panR + second*env*0.18*panR
+		}
+
+		if filter {
+			if i%filterRetune == 0 && (i == 0 || v.Filter.EnvAmount != 0) {
+				filterL.tune(v.Filter, level, sampleRate)
+				filterR.tune(v.Filter, level, sampleRate)
+			}
+			l, r = filterL.process(l), filterR.process(r)
+		}
+
+		// Write stereo L then R
+		out = append(out, float32(l), float32(r))
+	}
+	return out
+}
+
+func min(a, b float64) float64 { if a < b { return a }; return b }
+func max(a, b float64) float64 { if a > b { return a }; return b }
@@ -0,0 +1,116 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Chords. A point can play a chord instead of a single note: its note plus
+// a set of intervals (in semitones) above it. Every note of the chord is its
+// own voice, and all but the root are detuned a few cents, alternately sharp
+// and flat, so the chord beats gently instead of sounding like one
+// oscillator. The voices share the gain of a single note so chords don't
+// overpower it.
+
+// MaxChordInterval is the widest interval (in semitones) a chord may hold.
+const MaxChordInterval = 24
+
+// MaxChordNotes bounds the notes of a chord, root included.
+const MaxChordNotes = 6
+
+// chordDetune is the detuning (cents) of the first voice above the root;
+// every further pair of voices is detuned a little more.
+const chordDetune = 4.0
+
+// ChordPresets are the named chords, in the order an editor cycles through them.
+var ChordPresets = []struct {
+	Name      string
+	Intervals []int
+}{
+	{"fifth", []int{7}},
+	{"octave", []int{12}},
+	{"major", []int{4, 7}},
+	{"minor", []int{3, 7}},
+	{"sus4", []int{5, 7}},
+	{"maj7", []int{4, 7, 11}},
+	{"min7", []int{3, 7, 10}},
+}
+
+// ChordName returns the preset name of the intervals, "none" when there are
+// none, or the intervals themselves.
+func ChordName(intervals []int) string {
+	if len(intervals) == 0 {
+		return "none"
+	}
+	for _, p := range ChordPresets {
+		if slices.Equal(p.Intervals, intervals) {
+			return p.Name
+		}
+	}
+	return fmt.Sprint(intervals)
+}
+
+// NextChord returns the preset after intervals; the cycle ends with nil, a single note.
+func NextChord(intervals []int) []int {
+	idx := -1
+	if len(intervals) > 0 {
+		for i, p := range ChordPresets {
+			if slices.Equal(p.Intervals, intervals) {
+				idx = i
+				break
+			}
+		}
+	}
+	idx++
+	if idx >= len(ChordPresets) {
+		return nil
+	}
+	return slices.Clone(ChordPresets[idx].Intervals)
+}
+
+// ValidateChord checks that the intervals can be played.
+func ValidateChord(intervals []int) error {
+	if len(intervals) >= MaxChordNotes {
+		return fmt.Errorf("a chord has at most %d intervals", MaxChordNotes-1)
+	}
+	for _, iv := range intervals {
+		if iv < 1 || iv > MaxChordInterval {
+			return fmt.Errorf("chord interval %d out of range 1-%d", iv, MaxChordInterval)
+		}
+	}
+	return nil
+}
+
+// ChordNotes returns the MIDI notes of a chord on note: the note itself,
+// then one per interval.
+func ChordNotes(note int, intervals []int) []int {
+	notes := make([]int, 0, 1+len(intervals))
+	notes = append(notes, note)
+	for _, iv := range intervals {
+		notes = append(notes, note+iv)
+	}
+	return notes
+}
+
+// ChordFreq returns the frequency of the i-th note of a chord (as returned
+// by ChordNotes), detuned unless it is the root.
+func ChordFreq(note, i int) float64 {
+	if i == 0 {
+		return MIDIToFreq(note)
+	}
+	cents := chordDetune * float64((i+1)/2)
+	if i%2 == 0 {
+		cents = -cents
+	}
+	return MIDIToFreq(note) * math.Pow(2, cents/1200)
+}
+
+// ChordGain returns the gain of each of n voices sounding together, keeping
+// the chord about as loud as a single note.
+func ChordGain(n int) float64 {
+	if n <= 1 {
+		return 1
+	}
+	return 1 / math.Sqrt(float64(n))
+}
@@ -0,0 +1,132 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Percussion voices. Besides pitched notes a voice can be a drum: a burst of
+// filtered noise with a fast decay, so a pattern can mix pitched and
+// unpitched parts. A grid family picks its drum; every point it crosses then
+// plays that drum whatever the point's pitch, waveform or envelope.
+
+// Drum selects a percussion voice; DrumNone plays pitched notes.
+type Drum int
+
+const (
+	DrumNone    Drum = iota
+	DrumHat          // closed hi-hat: a tick of high noise
+	DrumOpenHat      // open hi-hat: high noise ringing on
+	DrumSnare        // snare: mid noise over a short tonal body
+)
+
+var drumNames = []string{"none", "hat", "openhat", "snare"}
+
+func (d Drum) String() string {
+	if d < 0 || int(d) >= len(drumNames) {
+		return "unknown"
+	}
+	return drumNames[d]
+}
+
+// Next returns the following drum, wrapping around to none.
+func (d Drum) Next() Drum {
+	return (d + 1) % Drum(len(drumNames))
+}
+
+// MarshalText stores a drum by name.
+func (d Drum) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a drum name.
+func (d *Drum) UnmarshalText(b []byte) error {
+	for i, n := range drumNames {
+		if n == string(b) {
+			*d = Drum(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown drum %q", b)
+}
+
+// MIDINote returns the General MIDI percussion note of the drum.
+func (d Drum) MIDINote() int {
+	switch d {
+	case DrumOpenHat:
+		return 46
+	case DrumSnare:
+		return 38
+	}
+	return 42
+}
+
+// drumSpec is how a drum is rendered.
+type drumSpec struct {
+	noise     Filter  // shapes the noise
+	decay     float64 // seconds until the noise has died away (-60dB)
+	gain      float64
+	body      float64 // frequency (Hz) of a tonal body under the noise; 0 is none
+	bodyDecay float64 // seconds until the body has died away
+	bodyMix   float64
+}
+
+var drumSpecs = []drumSpec{
+	DrumHat:     {noise: Filter{Type: FilterHighpass, Cutoff: 7000, Resonance: 1.2}, decay: 0.05, gain: 0.12},
+	DrumOpenHat: {noise: Filter{Type: FilterHighpass, Cutoff: 6000, Resonance: 1}, decay: 0.35, gain: 0.09},
+	DrumSnare:   {noise: Filter{Type: FilterHighpass, Cutoff: 1500, Resonance: 0.8}, decay: 0.18, gain: 0.11, body: 185, bodyDecay: 0.08, bodyMix: 0.7},
+}
+
+// drumAttack is the fade-in (seconds) that keeps a drum from clicking.
+const drumAttack = 0.001
+
+// generateDrum renders v.Drum like GenerateBlip renders a note. A note
+// length, if set, replaces the drum's own decay; v.Filter is applied last.
+func generateDrum(sampleRate int, v Voice) []float32 {
+	spec := drumSpecs[v.Drum]
+	decay := spec.decay
+	if v.Length > 0 {
+		decay = v.Length
+	}
+	n := int(float64(sampleRate) * decay)
+	if n <= 1 {
+		return nil
+	}
+	out := make([]float32, 0, 2*n)
+	// Fixed seed so a drum sounds the same every time it is rendered
+	rng := rand.New(rand.NewSource(int64(v.Drum)))
+	var noiseL, noiseR, filterL, filterR biquad
+	noiseL.tune(spec.noise, 0, sampleRate)
+	noiseR.tune(spec.noise, 0, sampleRate)
+	filter := v.Filter.Enabled()
+	// Decay to about -60dB (exp(-6.9)) at the end, like the blip
+	const lambda = 6.9
+	attackN := drumAttack * float64(sampleRate)
+	for i := 0; i < n; i++ {
+		sec := float64(i) / float64(sampleRate)
+		level := math.Exp(-lambda * sec / decay)
+		if float64(i) < attackN {
+			level *= float64(i) / attackN
+		}
+		// The channels share most of their noise, so the drum sits in the middle with some width
+		a, b := rng.Float64()*2-1, rng.Float64()*2-1
+		l := noiseL.process(a) * level
+		r := noiseR.process(0.8*a+0.2*b) * level
+		if spec.body > 0 {
+			body := math.Sin(2*math.Pi*spec.body*sec) * math.Exp(-lambda*sec/spec.bodyDecay) * spec.bodyMix
+			l += body
+			r += body
+		}
+		l, r = l*spec.gain, r*spec.gain
+		if filter {
+			if i%filterRetune == 0 && (i == 0 || v.Filter.EnvAmount != 0) {
+				filterL.tune(v.Filter, level, sampleRate)
+				filterR.tune(v.Filter, level, sampleRate)
+			}
+			l, r = filterL.process(l), filterR.process(r)
+		}
+		out = append(out, float32(l), float32(r))
+	}
+	return out
+}
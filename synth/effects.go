@@ -0,0 +1,145 @@
+package synth
+
+// Sends are the levels at which a voice feeds the shared effects.
+type Sends struct {
+	Delay  float64 `json:"delay"`  // 0..1 into the feedback delay
+	Reverb float64 `json:"reverb"` // 0..1 into the reverb
+}
+
+// DefaultSends is used for grids that don't set their own.
+var DefaultSends = Sends{Delay: 0.2, Reverb: 0.3}
+
+// ResolveSends picks the grid's send levels, falling back to def.
+func ResolveSends(grid *Sends, def Sends) Sends {
+	if grid != nil {
+		return *grid
+	}
+	return def
+}
+
+// Delay is a stereo feedback delay with a gentle low-pass in the feedback
+// path, so repeats darken as they fade.
+type Delay struct {
+	bufL, bufR []float64
+	idx        int
+	feedback   float64
+	damp       float64 // one-pole low-pass coefficient in the feedback path
+	lpL, lpR   float64
+}
+
+// NewDelay creates a delay of the given length in seconds.
+func NewDelay(sampleRate int, seconds, feedback float64) *Delay {
+	n := int(seconds * float64(sampleRate))
+	if n < 1 {
+		n = 1
+	}
+	return &Delay{
+		bufL:     make([]float64, n),
+		bufR:     make([]float64, n),
+		feedback: feedback,
+		damp:     0.3,
+	}
+}
+
+// Process feeds one stereo frame in and returns the delayed output.
+func (d *Delay) Process(l, r float64) (float64, float64) {
+	outL, outR := d.bufL[d.idx], d.bufR[d.idx]
+	d.lpL += (outL - d.lpL) * (1 - d.damp)
+	d.lpR += (outR - d.lpR) * (1 - d.damp)
+	d.bufL[d.idx] = l + d.lpL*d.feedback
+	d.bufR[d.idx] = r + d.lpR*d.feedback
+	d.idx++
+	if d.idx == len(d.bufL) {
+		d.idx = 0
+	}
+	return outL, outR
+}
+
+// Reverb is a small Schroeder reverb: four parallel damped comb filters
+// followed by two series all-pass filters per channel. The right channel uses
+// slightly longer delays for stereo width.
+type Reverb struct {
+	combs   [2][4]comb
+	allpass [2][2]allpass
+}
+
+// Comb and all-pass lengths in frames at 44.1kHz (from Freeverb), scaled to
+// the sample rate; stereoSpread is added for the right channel.
+var (
+	combTuning    = [4]int{1557, 1617, 1491, 1422}
+	allpassTuning = [2]int{556, 225}
+)
+
+const stereoSpread = 23
+
+// NewReverb creates a reverb; roomSize (0..1) sets the comb feedback and
+// damping (0..1) how quickly high frequencies die away.
+func NewReverb(sampleRate int, roomSize, damping float64) *Reverb {
+	rv := &Reverb{}
+	scale := float64(sampleRate) / 44100
+	for ch := 0; ch < 2; ch++ {
+		for i, n := range combTuning {
+			rv.combs[ch][i] = comb{
+				buf:      make([]float64, int(float64(n+ch*stereoSpread)*scale)),
+				feedback: 0.7 + 0.28*roomSize,
+				damp:     damping,
+			}
+		}
+		for i, n := range allpassTuning {
+			rv.allpass[ch][i] = allpass{buf: make([]float64, int(float64(n+ch*stereoSpread)*scale))}
+		}
+	}
+	return rv
+}
+
+// Process feeds one stereo frame in and returns the reverberated output.
+func (rv *Reverb) Process(l, r float64) (float64, float64) {
+	// Input gain keeps the summed combs (each with gain up to 1/(1-feedback)) in range
+	in := [2]float64{l * 0.05, r * 0.05}
+	var out [2]float64
+	for ch := 0; ch < 2; ch++ {
+		for i := range rv.combs[ch] {
+			out[ch] += rv.combs[ch][i].process(in[ch])
+		}
+		for i := range rv.allpass[ch] {
+			out[ch] = rv.allpass[ch][i].process(out[ch])
+		}
+	}
+	return out[0], out[1]
+}
+
+// comb is a feedback comb filter with a one-pole low-pass in the loop.
+type comb struct {
+	buf      []float64
+	idx      int
+	feedback float64
+	damp     float64
+	store    float64
+}
+
+func (c *comb) process(x float64) float64 {
+	out := c.buf[c.idx]
+	c.store = out*(1-c.damp) + c.store*c.damp
+	c.buf[c.idx] = x + c.store*c.feedback
+	c.idx++
+	if c.idx == len(c.buf) {
+		c.idx = 0
+	}
+	return out
+}
+
+// allpass is a Schroeder all-pass filter with a fixed 0.5 coefficient.
+type allpass struct {
+	buf []float64
+	idx int
+}
+
+func (a *allpass) process(x float64) float64 {
+	bufOut := a.buf[a.idx]
+	a.buf[a.idx] = x + bufOut*0.5
+	a.idx++
+	if a.idx == len(a.buf) {
+		a.idx = 0
+	}
+	return bufOut - x
+}
@@ -0,0 +1,160 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+)
+
+// Voice filters. A synthesized voice can run through a resonant two-pole
+// (biquad) low- or high-pass filter, from dull thuds to bright clicks. The
+// cutoff can follow the amplitude envelope so a note opens up as it sounds
+// and closes as it fades.
+
+// FilterType selects the response of a voice filter.
+type FilterType int
+
+const (
+	FilterLowpass FilterType = iota
+	FilterHighpass
+)
+
+var filterTypeNames = []string{"lowpass", "highpass"}
+
+func (t FilterType) String() string {
+	if t < 0 || int(t) >= len(filterTypeNames) {
+		return "unknown"
+	}
+	return filterTypeNames[t]
+}
+
+// MarshalText stores a filter type by name.
+func (t FilterType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText parses a filter type name.
+func (t *FilterType) UnmarshalText(b []byte) error {
+	for i, n := range filterTypeNames {
+		if n == string(b) {
+			*t = FilterType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown filter type %q", b)
+}
+
+// Filter is the filter stage of a voice. The zero value (no cutoff) leaves
+// the voice unfiltered.
+type Filter struct {
+	Type      FilterType `json:"type"`
+	Cutoff    float64    `json:"cutoff"`              // Hz
+	Resonance float64    `json:"resonance,omitempty"` // Q; 0 means a flat response without peak
+	EnvAmount float64    `json:"envAmount,omitempty"` // octaves the cutoff rises at full envelope level (falls if negative)
+}
+
+// flatQ is the resonance of a filter without a peak at the cutoff.
+const flatQ = 0.7071
+
+// FilterPresets are the named filters, in the order an editor cycles through them.
+var FilterPresets = []struct {
+	Name   string
+	Filter Filter
+}{
+	{"thud", Filter{Type: FilterLowpass, Cutoff: 300, Resonance: 1, EnvAmount: 2}},
+	{"warm", Filter{Type: FilterLowpass, Cutoff: 1200, Resonance: 2.5, EnvAmount: 1}},
+	{"thin", Filter{Type: FilterHighpass, Cutoff: 800, Resonance: 4}},
+	{"click", Filter{Type: FilterHighpass, Cutoff: 2500}},
+}
+
+// FilterName returns the preset name of f, "off" for no filter, or "custom".
+func FilterName(f *Filter) string {
+	if f == nil || !f.Enabled() {
+		return "off"
+	}
+	for _, p := range FilterPresets {
+		if p.Filter == *f {
+			return p.Name
+		}
+	}
+	return "custom"
+}
+
+// NextFilter returns the preset after f; the cycle ends with nil, no filter.
+func NextFilter(f *Filter) *Filter {
+	idx := -1
+	if f != nil {
+		for i, p := range FilterPresets {
+			if p.Filter == *f {
+				idx = i
+				break
+			}
+		}
+	}
+	idx++
+	if idx >= len(FilterPresets) {
+		return nil
+	}
+	next := FilterPresets[idx].Filter
+	return &next
+}
+
+// Enabled reports whether the filter does anything.
+func (f Filter) Enabled() bool {
+	return f.Cutoff > 0
+}
+
+// Validate checks that the filter can be rendered.
+func (f Filter) Validate() error {
+	if f.Type < 0 || int(f.Type) >= len(filterTypeNames) {
+		return fmt.Errorf("unknown filter type")
+	}
+	if f.Cutoff < 20 || f.Cutoff > 20000 {
+		return fmt.Errorf("filter cutoff must be within 20-20000 Hz")
+	}
+	if f.Resonance != 0 && (f.Resonance < 0.1 || f.Resonance > 20) {
+		return fmt.Errorf("filter resonance must be within 0.1-20")
+	}
+	if math.Abs(f.EnvAmount) > 8 {
+		return fmt.Errorf("filter envelope amount must be within ±8 octaves")
+	}
+	return nil
+}
+
+// filterRetune is how many samples an envelope-following filter keeps its
+// coefficients before they are worked out again.
+const filterRetune = 32
+
+// biquad is a filter running on one channel, in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// tune sets the coefficients for f with the given envelope level (0-1).
+func (q *biquad) tune(f Filter, level float64, sampleRate int) {
+	fc := f.Cutoff * math.Pow(2, f.EnvAmount*level)
+	fc = math.Max(20, math.Min(0.45*float64(sampleRate), fc))
+	res := f.Resonance
+	if res == 0 {
+		res = flatQ
+	}
+	w := 2 * math.Pi * fc / float64(sampleRate)
+	cos, alpha := math.Cos(w), math.Sin(w)/(2*res)
+	a0 := 1 + alpha
+	switch f.Type {
+	case FilterHighpass:
+		q.b0, q.b1, q.b2 = (1+cos)/2, -(1 + cos), (1+cos)/2
+	default:
+		q.b0, q.b1, q.b2 = (1-cos)/2, 1-cos, (1-cos)/2
+	}
+	q.b0, q.b1, q.b2 = q.b0/a0, q.b1/a0, q.b2/a0
+	q.a1, q.a2 = -2*cos/a0, (1-alpha)/a0
+}
+
+// process filters one sample.
+func (q *biquad) process(x float64) float64 {
+	y := q.b0*x + q.b1*q.x1 + q.b2*q.x2 - q.a1*q.y1 - q.a2*q.y2
+	q.x1, q.x2 = x, q.x1
+	q.y1, q.y2 = y, q.y1
+	return y
+}
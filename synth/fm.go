@@ -0,0 +1,119 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+)
+
+// FM is a two-operator frequency modulation voice: a sine modulator at Ratio
+// times the note's frequency bends the phase of a sine carrier at the note's
+// frequency. Index is how far it bends it at note-on, which sets how bright
+// the tone starts; it then falls to Sustain times Index over about Decay
+// seconds, so a note can start as a bell or a brassy bite and mellow out. A
+// ratio of whole numbers gives a harmonic tone, others an inharmonic, metallic
+// one. The zero FM is off: the note plays its waveform instead.
+type FM struct {
+	Ratio   float64 `json:"ratio"`             // modulator frequency over the carrier's
+	Index   float64 `json:"index"`             // modulation index at note-on
+	Sustain float64 `json:"sustain,omitempty"` // fraction of Index the index falls to
+	Decay   float64 `json:"decay,omitempty"`   // seconds the index takes to fall; 0 holds it at Index
+}
+
+// FMPresets are the named FM voices, in the order an editor cycles through them.
+var FMPresets = []struct {
+	Name string
+	FM   FM
+}{
+	{"off", FM{}},
+	{"bell", FM{Ratio: 3.5, Index: 6, Sustain: 0.15, Decay: 0.5}},
+	{"e-piano", FM{Ratio: 1, Index: 3, Sustain: 0.1, Decay: 0.25}},
+	{"brass", FM{Ratio: 1, Index: 5, Sustain: 0.6, Decay: 0.1}},
+	{"bass", FM{Ratio: 0.5, Index: 4, Sustain: 0.25, Decay: 0.15}},
+	{"metal", FM{Ratio: 1.41, Index: 8, Sustain: 0.4, Decay: 0.8}},
+	{"wood", FM{Ratio: 2.76, Index: 2, Decay: 0.03}},
+}
+
+// FMName returns the preset name of f, or "custom".
+func FMName(f FM) string {
+	for _, p := range FMPresets {
+		if p.FM == f {
+			return p.Name
+		}
+	}
+	return "custom"
+}
+
+// NextFM returns the preset after f. With allowInherit (points) the cycle
+// ends with nil, meaning "use the grid's"; grids go from the last preset back
+// to nil, which is off.
+func NextFM(f *FM, allowInherit bool) *FM {
+	idx := -1
+	if f != nil {
+		for i, p := range FMPresets {
+			if p.FM == *f {
+				idx = i
+				break
+			}
+		}
+	}
+	idx++
+	if !allowInherit && idx == 0 {
+		// Grids have no inheriting, nil is already off
+		idx++
+	}
+	if idx >= len(FMPresets) {
+		return nil
+	}
+	fm := FMPresets[idx].FM
+	return &fm
+}
+
+// ResolveFM picks the point's FM voice, then the grid's, then off.
+func ResolveFM(grid, point *FM) FM {
+	if point != nil {
+		return *point
+	}
+	if grid != nil {
+		return *grid
+	}
+	return FM{}
+}
+
+// Validate checks that the FM voice can be rendered.
+func (f FM) Validate() error {
+	if f == (FM{}) {
+		return nil
+	}
+	if f.Ratio <= 0 || f.Ratio > 16 {
+		return fmt.Errorf("fm ratio must be within 0-16")
+	}
+	if f.Index < 0 || f.Index > 20 {
+		return fmt.Errorf("fm index must be within 0-20")
+	}
+	if f.Sustain < 0 || f.Sustain > 1 {
+		return fmt.Errorf("fm sustain must be within 0-1")
+	}
+	if f.Decay < 0 || f.Decay > 10 {
+		return fmt.Errorf("fm decay must be within 0-10s")
+	}
+	return nil
+}
+
+// Enabled reports whether the note plays FM rather than its waveform.
+func (f FM) Enabled() bool {
+	return f.Ratio > 0
+}
+
+// index returns the modulation index sec seconds after note-on.
+func (f FM) index(sec float64) float64 {
+	if f.Decay <= 0 {
+		return f.Index
+	}
+	return f.Index * (f.Sustain + (1-f.Sustain)*math.Exp(-sec/f.Decay))
+}
+
+// operator evaluates one sample of the carrier at phase (radians), modulated
+// at ratio times that phase with index.
+func (f FM) operator(phase, index float64) float64 {
+	return math.Sin(phase + index*math.Sin(f.Ratio*phase))
+}
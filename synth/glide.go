@@ -0,0 +1,105 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+)
+
+// Glide bends the pitch of a note over its length: it starts at Start times
+// the note's frequency and ends at End times it. Curve shapes the bend in
+// time; at 1 the pitch moves evenly (in octaves per second), below 1 most of
+// the bend happens right after note-on, like the drop of a drum, and above 1
+// it comes towards the end. The zero Glide is automatic: the classic blip
+// envelope bends slightly down and other envelopes hold their pitch.
+type Glide struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Curve float64 `json:"curve,omitempty"` // 0 means 1
+}
+
+var (
+	blipGlide = Glide{Start: 1.03, End: 0.92, Curve: 1}
+	noGlide   = Glide{Start: 1, End: 1, Curve: 1}
+)
+
+// GlidePresets are the named glides, in the order an editor cycles through them.
+var GlidePresets = []struct {
+	Name  string
+	Glide Glide
+}{
+	{"none", noGlide},
+	{"bend", blipGlide},
+	{"drop", Glide{Start: 2, End: 1, Curve: 0.15}},
+	{"rise", Glide{Start: 0.94, End: 1, Curve: 0.5}},
+	{"dive", Glide{Start: 1, End: 0.5, Curve: 1.5}},
+}
+
+// GlideName returns the preset name of g, "auto" for nil, or "custom".
+func GlideName(g *Glide) string {
+	if g == nil || *g == (Glide{}) {
+		return "auto"
+	}
+	for _, p := range GlidePresets {
+		if p.Glide == *g {
+			return p.Name
+		}
+	}
+	return "custom"
+}
+
+// NextGlide returns the preset after g; the cycle ends with nil, automatic.
+func NextGlide(g *Glide) *Glide {
+	idx := -1
+	if g != nil {
+		for i, p := range GlidePresets {
+			if p.Glide == *g {
+				idx = i
+				break
+			}
+		}
+	}
+	idx++
+	if idx >= len(GlidePresets) {
+		return nil
+	}
+	next := GlidePresets[idx].Glide
+	return &next
+}
+
+// Validate checks that the glide can be rendered.
+func (g Glide) Validate() error {
+	if g == (Glide{}) {
+		return nil
+	}
+	if g.Start < 0.125 || g.Start > 8 || g.End < 0.125 || g.End > 8 {
+		return fmt.Errorf("glide ratios must be within 0.125-8")
+	}
+	if g.Curve < 0 || g.Curve > 8 {
+		return fmt.Errorf("glide curve must be within 0-8")
+	}
+	return nil
+}
+
+// resolve picks the glide a note renders with; blip tells whether the note
+// uses the classic blip envelope.
+func (g Glide) resolve(blip bool) Glide {
+	switch {
+	case g != (Glide{}):
+	case blip:
+		g = blipGlide
+	default:
+		g = noGlide
+	}
+	if g.Curve == 0 {
+		g.Curve = 1
+	}
+	return g
+}
+
+// freq returns the frequency at fraction t (0-1) of a note at base frequency f.
+func (g Glide) freq(f, t float64) float64 {
+	if g.Start == g.End {
+		return f * g.Start
+	}
+	return f * g.Start * math.Pow(g.End/g.Start, math.Pow(t, g.Curve))
+}
@@ -0,0 +1,56 @@
+package synth
+
+import "math"
+
+// Limiter keeps the mixed output under a ceiling so that many voices
+// sounding at once are turned down together instead of being clipped. Peaks
+// above the ceiling lower the gain at once; the gain comes back smoothly
+// over the release time once they pass, so the turning down isn't heard as
+// distortion.
+type Limiter struct {
+	ceiling float64 // highest output level, 1 is full scale
+	release float64 // per-frame coefficient of the gain's return to 1
+	gain    float64 // current gain, 1 when not limiting
+}
+
+// NewLimiter creates a limiter holding the output under ceiling that lets go
+// over about release seconds.
+func NewLimiter(sampleRate int, ceiling, release float64) *Limiter {
+	return &Limiter{
+		ceiling: ceiling,
+		release: 1 - math.Exp(-1/(release*float64(sampleRate))),
+		gain:    1,
+	}
+}
+
+// Process limits one stereo frame; both channels get the same gain so the
+// stereo image holds.
+func (lm *Limiter) Process(l, r float64) (float64, float64) {
+	target := 1.0
+	if peak := max(math.Abs(l), math.Abs(r)); peak > lm.ceiling {
+		target = lm.ceiling / peak
+	}
+	if target < lm.gain {
+		lm.gain = target
+	} else {
+		lm.gain += (target - lm.gain) * lm.release
+	}
+	return l * lm.gain, r * lm.gain
+}
+
+// Gain returns the gain the limiter applies now, 1 when it isn't limiting.
+func (lm *Limiter) Gain() float64 {
+	return lm.gain
+}
+
+// Meter is what the output did since it was last read (see Mixer.Meter).
+type Meter struct {
+	Peak    float64 // highest level after the master gain, before the limiter; 1 is full scale
+	Limit   float64 // lowest gain of the limiter, 1 when it didn't limit
+	Clipped int     // frames that went past full scale; with the limiter on they were limited instead
+}
+
+// Decibels returns a linear gain in decibels, -Inf for 0.
+func Decibels(gain float64) float64 {
+	return 20 * math.Log10(gain)
+}
@@ -0,0 +1,335 @@
+package synth
+
+import (
+	"io"
+	"math"
+	"sync"
+)
+
+// Mixer is a software mixer that sums active voices into a single stream of
+// 16-bit little-endian stereo PCM. It is read by one long-lived audio.Player,
+// so triggering a note only takes a slot in a pool of voices allocated up
+// front instead of allocating a player; the pool never grows, so dense
+// passages make no garbage for the collector to pause the audio over.
+// Voices also feed a shared delay and reverb through their send levels, and
+// the sum passes a limiter unless it is switched off.
+type Mixer struct {
+	mu        sync.Mutex
+	voices    []mixVoice // the voice pool: sounding voices in start order, never grown past its capacity
+	maxVoices int        // polyphony limit; the oldest voice is stolen beyond it
+	gain      float64    // master gain applied to the summed voices
+	fadeLen   int        // frames over which a stolen voice fades out
+	delay     *Delay
+	reverb    *Reverb
+	limiter   *Limiter
+	limit     bool      // whether the limiter is on
+	meter     Meter     // output since the meter was last read
+	tap       io.Writer // optional copy of the output stream, e.g. a WAV bounce
+	frame     int64     // frames rendered so far; the clock voices are scheduled on
+}
+
+// mixVoice is one playing sample buffer.
+type mixVoice struct {
+	samples []float32 // interleaved stereo, shared with the blip cache (read-only)
+	pos     int       // next frame to play
+	fade    int       // frames left in a fade-out (steal, cut, gate end or release); 0 when not fading
+	fadeLen int       // length of that fade-out; 0 is the mixer's short one
+	end     int       // frame at which the voice starts fading out; 0 plays the whole buffer
+	owner   int       // source of the note for cutting; 0 is none
+	start   int64     // mixer frame at which the voice starts sounding
+	gain    float64   // per-voice gain (trigger velocity)
+	sends   Sends     // effect send levels
+}
+
+// NoteGate controls the lifetime of a voice. The zero NoteGate plays the
+// whole buffer alongside any other voices.
+type NoteGate struct {
+	Frames int   // fade the voice out after this many frames; 0 plays the whole buffer
+	Owner  int   // identifies the note's source (e.g. a point) for Cut; 0 is none
+	Cut    bool  // fade out the voices of the same Owner that are still sounding
+	At     int64 // mixer frame at which to start (see Now); 0 or a past frame starts at once
+}
+
+// The limiter holds the output just under full scale and lets go over a
+// tenth of a second.
+const (
+	limiterCeiling = 0.98
+	limiterRelease = 0.1
+)
+
+// voicePoolFactor is the size of the voice pool in voices of polyphony: room
+// for the voices fading out after a steal besides those sounding.
+const voicePoolFactor = 2
+
+// NewMixer creates a mixer with the given polyphony and master gain.
+func NewMixer(sampleRate, maxVoices int, gain float64) *Mixer {
+	if maxVoices < 1 {
+		maxVoices = 1
+	}
+	return &Mixer{
+		voices:    make([]mixVoice, 0, maxVoices*voicePoolFactor),
+		maxVoices: maxVoices,
+		gain:      gain,
+		fadeLen:   sampleRate / 200, // 5ms
+		delay:     NewDelay(sampleRate, 0.375, 0.35),
+		reverb:    NewReverb(sampleRate, 0.5, 0.4),
+		limiter:   NewLimiter(sampleRate, limiterCeiling, limiterRelease),
+		limit:     true,
+		meter:     Meter{Limit: 1},
+	}
+}
+
+// SetDelay replaces the delay effect, e.g. to change its time or feedback.
+func (m *Mixer) SetDelay(d *Delay) {
+	m.mu.Lock()
+	m.delay = d
+	m.mu.Unlock()
+}
+
+// Play starts a new voice for samples (interleaved stereo) scaled by gain and
+// sent to the effects at the given levels, living as long as gate allows. When
+// all voices are busy the oldest one is faded out quickly rather than cut,
+// avoiding a click.
+func (m *Mixer) Play(samples []float32, gain float64, sends Sends, gate NoteGate) {
+	if len(samples) < 2 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if gate.Cut && gate.Owner != 0 {
+		// The old voices fade out when the new one starts; those scheduled
+		// at or after it never sound
+		for i := range m.voices {
+			v := &m.voices[i]
+			if v.owner != gate.Owner || v.fade != 0 {
+				continue
+			}
+			if v.start >= gate.At && v.start > m.frame {
+				v.pos = len(v.samples) / 2
+				continue
+			}
+			end := v.pos + int(gate.At-max64(v.start, m.frame))
+			if end < 1 {
+				end = 1
+			}
+			if v.end == 0 || end < v.end {
+				v.end = end
+			}
+		}
+	}
+	active := 0
+	for _, v := range m.voices {
+		if v.fade == 0 {
+			active++
+		}
+	}
+	if active >= m.maxVoices {
+		// Voices are appended in start order, so the first non-fading one is the oldest
+		for i := range m.voices {
+			if m.voices[i].fade == 0 {
+				m.voices[i].fade = m.fadeLen
+				break
+			}
+		}
+	}
+	if len(m.voices) == cap(m.voices) {
+		m.reclaim()
+	}
+	m.voices = append(m.voices, mixVoice{samples: samples, gain: gain, sends: sends, end: gate.Frames, owner: gate.Owner, start: gate.At})
+}
+
+// reclaim frees a slot of the full voice pool by dropping the voice closest
+// to silence: the fading one with the least of its fade left, or else the
+// oldest. At most maxVoices voices sound unfaded, so a fading one is there
+// unless notes are scheduled far ahead.
+func (m *Mixer) reclaim() {
+	drop := 0
+	for i, v := range m.voices {
+		if v.fade > 0 && (m.voices[drop].fade == 0 || v.fade < m.voices[drop].fade) {
+			drop = i
+		}
+	}
+	// Shift the rest down in place, keeping start order
+	m.voices = append(m.voices[:drop], m.voices[drop+1:]...)
+}
+
+// Now returns the number of frames rendered so far, the clock that NoteGate.At refers to.
+func (m *Mixer) Now() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.frame
+}
+
+// Release fades every sounding voice out over frames (at least the short
+// steal fade; voices already fading keep their fade) and drops the voices scheduled to start later, so the notes of
+// one scene don't spill into the next. The effect tails ring out.
+func (m *Mixer) Release(frames int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if frames < m.fadeLen {
+		frames = m.fadeLen
+	}
+	for i := range m.voices {
+		v := &m.voices[i]
+		switch {
+		case v.start > m.frame:
+			v.pos = len(v.samples) / 2
+		case v.fade == 0:
+			v.fade, v.fadeLen, v.end = frames, frames, 0
+		}
+	}
+}
+
+// SetGain sets the master gain.
+func (m *Mixer) SetGain(gain float64) {
+	m.mu.Lock()
+	m.gain = gain
+	m.mu.Unlock()
+}
+
+// Gain returns the master gain.
+func (m *Mixer) Gain() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gain
+}
+
+// SetLimiter switches the limiter on or off.
+func (m *Mixer) SetLimiter(on bool) {
+	m.mu.Lock()
+	m.limit = on
+	m.mu.Unlock()
+}
+
+// Limiting reports whether the limiter is on.
+func (m *Mixer) Limiting() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.limit
+}
+
+// Meter returns what the output did since the last call and starts over.
+func (m *Mixer) Meter() Meter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mt := m.meter
+	m.meter = Meter{Limit: 1}
+	return mt
+}
+
+// SetTap sets a writer that receives a copy of every block of output; nil
+// removes it. It is written to on the audio thread with the mixer locked, so
+// it must neither block nor allocate, or it undoes what the voice pool saves.
+func (m *Mixer) SetTap(w io.Writer) {
+	m.mu.Lock()
+	m.tap = w
+	m.mu.Unlock()
+}
+
+// ActiveVoices returns the number of voices currently sounding: started and
+// not yet fading out. Voices scheduled for later and those being released
+// hold a slot in the pool but are not counted.
+func (m *Mixer) ActiveVoices() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, v := range m.voices {
+		if v.start < m.frame && v.fade == 0 && (v.end == 0 || v.pos < v.end) {
+			n++
+		}
+	}
+	return n
+}
+
+// Read implements io.Reader. It always fills whole frames and never returns an
+// error; silence (or the effect tails) is produced when no voice is playing.
+func (m *Mixer) Read(p []byte) (int, error) {
+	frames := len(p) / 4
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for f := 0; f < frames; f++ {
+		var l, r, dl, dr, rl, rr float64
+		now := m.frame + int64(f)
+		for i := range m.voices {
+			v := &m.voices[i]
+			if v.pos*2 >= len(v.samples) || now < v.start {
+				continue
+			}
+			amp := v.gain
+			if v.end > 0 && v.pos >= v.end && v.fade == 0 {
+				// Note length reached: release the voice like a steal
+				v.fade = m.fadeLen
+				v.end = 0
+			}
+			if v.fade > 0 {
+				fadeLen := m.fadeLen
+				if v.fadeLen > 0 {
+					fadeLen = v.fadeLen
+				}
+				amp *= float64(v.fade) / float64(fadeLen)
+				v.fade--
+				if v.fade == 0 {
+					// Fade finished: skip to the end so the voice is dropped
+					v.pos = len(v.samples) / 2
+					continue
+				}
+			}
+			sl := float64(v.samples[v.pos*2]) * amp
+			sr := float64(v.samples[v.pos*2+1]) * amp
+			l += sl
+			r += sr
+			dl += sl * v.sends.Delay
+			dr += sr * v.sends.Delay
+			rl += sl * v.sends.Reverb
+			rr += sr * v.sends.Reverb
+			v.pos++
+		}
+		// Effects run every frame so their tails ring out after the voices end
+		el, er := m.delay.Process(dl, dr)
+		l += el
+		r += er
+		el, er = m.reverb.Process(rl, rr)
+		l += el
+		r += er
+		l *= m.gain
+		r *= m.gain
+		peak := max(math.Abs(l), math.Abs(r))
+		m.meter.Peak = max(m.meter.Peak, peak)
+		if peak > 1 {
+			m.meter.Clipped++
+		}
+		if m.limit {
+			l, r = m.limiter.Process(l, r)
+			m.meter.Limit = min(m.meter.Limit, m.limiter.Gain())
+		}
+		putSample(p[f*4:], l)
+		putSample(p[f*4+2:], r)
+	}
+	// Drop finished voices, keeping start order
+	live := m.voices[:0]
+	for _, v := range m.voices {
+		if v.pos*2 < len(v.samples) {
+			live = append(live, v)
+		}
+	}
+	m.voices = live
+	m.frame += int64(frames)
+	if m.tap != nil {
+		_, _ = m.tap.Write(p[:frames*4])
+	}
+	return frames * 4, nil
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// putSample clamps x to [-1, 1] and writes it as 16-bit little-endian.
+func putSample(b []byte, x float64) {
+	v := int16(max(-1, min(1, x)) * 32767)
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+}
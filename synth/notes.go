@@ -0,0 +1,98 @@
+package synth
+
+import "fmt"
+
+// Note length and retrigger behavior. A grid or point can set how long the
+// notes it triggers last (Length, in seconds; 0 uses the envelope's own
+// length) and what happens when a point is triggered again while its previous
+// note still sounds: overlap lets both ring, cut fades the old note out.
+
+// Retrigger selects what a new note does to the point's note still sounding.
+type Retrigger int
+
+const (
+	// RetriggerInherit defers to the enclosing voice: points use their grid's
+	// mode and grids fall back to overlap.
+	RetriggerInherit Retrigger = iota
+	RetriggerOverlap
+	RetriggerCut
+)
+
+var retriggerNames = []string{"inherit", "overlap", "cut"}
+
+func (r Retrigger) String() string {
+	if r < 0 || int(r) >= len(retriggerNames) {
+		return "unknown"
+	}
+	return retriggerNames[r]
+}
+
+// Next returns the following mode, wrapping around. Inherit is only part of
+// the cycle when allowInherit is set (points can inherit, grids cannot).
+func (r Retrigger) Next(allowInherit bool) Retrigger {
+	n := (r + 1) % Retrigger(len(retriggerNames))
+	if n == RetriggerInherit && !allowInherit {
+		n = RetriggerOverlap
+	}
+	return n
+}
+
+// MarshalText stores a retrigger mode by name.
+func (r Retrigger) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText parses a retrigger mode name.
+func (r *Retrigger) UnmarshalText(b []byte) error {
+	for i, n := range retriggerNames {
+		if n == string(b) {
+			*r = Retrigger(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown retrigger mode %q", b)
+}
+
+// ResolveRetrigger picks the point's mode, then the grid's, then overlap.
+func ResolveRetrigger(grid, point Retrigger) Retrigger {
+	if point != RetriggerInherit {
+		return point
+	}
+	if grid != RetriggerInherit {
+		return grid
+	}
+	return RetriggerOverlap
+}
+
+// maxNoteLength bounds stored note lengths, like envelope lengths.
+const maxNoteLength = 10
+
+// ResolveNoteLength picks the point's length, then the grid's; 0 means the envelope decides.
+func ResolveNoteLength(grid, point float64) float64 {
+	if point > 0 {
+		return point
+	}
+	return grid
+}
+
+// ValidateNoteLength checks a stored note length.
+func ValidateNoteLength(l float64) error {
+	if l < 0 || l > maxNoteLength {
+		return fmt.Errorf("note length %g out of range 0-%d", l, maxNoteLength)
+	}
+	return nil
+}
+
+// withLength returns the envelope and total duration of a note held for
+// length seconds. The blip is stretched to the length; other envelopes keep
+// their shape and open the gate for length, then release.
+func (e Envelope) withLength(length float64) (Envelope, float64) {
+	if length <= 0 {
+		return e, e.Length()
+	}
+	if e.isBlip() {
+		return e, length
+	}
+	e.Gate = length
+	return e, e.Length()
+}
@@ -0,0 +1,45 @@
+package synth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+// LoadSample decodes a WAV or OGG Vorbis file into interleaved stereo float
+// samples at sampleRate, ready for the Mixer.
+func LoadSample(path string, sampleRate int) ([]float32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var src io.Reader
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		src, err = wav.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	case ".ogg", ".oga":
+		src, err = vorbis.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported sample format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	// Decoders yield 16-bit little-endian stereo PCM
+	pcm, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	out := make([]float32, len(pcm)/2)
+	for i := range out {
+		v := int16(uint16(pcm[2*i]) | uint16(pcm[2*i+1])<<8)
+		out[i] = float32(v) / 32768
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale is a set of semitone steps within one octave, starting at 0. Pitches
+// are addressed by scale degree relative to a key root; degrees beyond the
+// scale length (or negative ones) continue into higher (lower) octaves.
+type Scale struct {
+	Name  string `json:"name"`
+	Steps []int  `json:"steps"`
+}
+
+// Scales lists the built-in scales.
+var Scales = []Scale{
+	{"minor pentatonic", []int{0, 3, 5, 7, 10}},
+	{"major pentatonic", []int{0, 2, 4, 7, 9}},
+	{"major", []int{0, 2, 4, 5, 7, 9, 11}},
+	{"minor", []int{0, 2, 3, 5, 7, 8, 10}},
+	{"dorian", []int{0, 2, 3, 5, 7, 9, 10}},
+	{"chromatic", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+}
+
+// ParseScale returns the built-in scale with the given name, or a custom scale
+// from a comma separated list of semitone steps such as "0,2,3,7,9".
+func ParseScale(s string) (Scale, error) {
+	for _, sc := range Scales {
+		if strings.EqualFold(sc.Name, s) {
+			return sc, nil
+		}
+	}
+	var steps []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return Scale{}, fmt.Errorf("unknown scale %q", s)
+		}
+		if n < 0 || n > 11 || (len(steps) > 0 && n <= steps[len(steps)-1]) {
+			return Scale{}, fmt.Errorf("custom scale %q: steps must be ascending within 0-11", s)
+		}
+		steps = append(steps, n)
+	}
+	if len(steps) == 0 || steps[0] != 0 {
+		return Scale{}, fmt.Errorf("custom scale %q must start at 0", s)
+	}
+	return Scale{Name: "custom", Steps: steps}, nil
+}
+
+// Note returns the MIDI note of scale degree deg in the key with MIDI root note root.
+func (s Scale) Note(root, deg int) int {
+	n := len(s.Steps)
+	// Floor division so negative degrees descend into lower octaves
+	oct := int(math.Floor(float64(deg) / float64(n)))
+	idx := deg - oct*n
+	return root + 12*oct + s.Steps[idx]
+}
+
+// Freq returns the frequency in Hz of scale degree deg in the key with MIDI root note root.
+func (s Scale) Freq(root, deg int) float64 {
+	return MIDIToFreq(s.Note(root, deg))
+}
+
+var noteNames = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// MIDIToFreq converts a MIDI note number to a frequency in Hz (A4 = 69 = 440Hz).
+func MIDIToFreq(note int) float64 {
+	return 440 * math.Pow(2, float64(note-69)/12)
+}
+
+// NoteName formats a MIDI note number as e.g. "A4".
+func NoteName(note int) string {
+	octave := int(math.Floor(float64(note)/12)) - 1
+	return fmt.Sprintf("%s%d", noteNames[((note%12)+12)%12], octave)
+}
+
+// KeyName formats a key root without octave, e.g. "A".
+func KeyName(root int) string {
+	return noteNames[((root%12)+12)%12]
+}
+
+// Degree returns the scale degree of MIDI note in the key with root root, or
+// the nearest degree below it when the note is not in the scale.
+func (s Scale) Degree(root, note int) int {
+	n := len(s.Steps)
+	oct := int(math.Floor(float64(note-root) / 12))
+	deg := oct * n
+	for i, st := range s.Steps {
+		if root+12*oct+st <= note {
+			deg = oct*n + i
+		}
+	}
+	return deg
+}